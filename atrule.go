@@ -0,0 +1,78 @@
+package css
+
+import "sync"
+
+// BlockKind is what the block of an at-rule holds, which decides how the
+// parser reads it.
+type BlockKind int
+
+const (
+	// BlockRules blocks hold rules, as those of @media, and are parsed into
+	// AtRule.Rules.
+	BlockRules BlockKind = iota
+	// BlockDeclarations blocks hold declarations, as those of @font-face,
+	// and are parsed into AtRule.Declarations.
+	BlockDeclarations
+	// BlockRaw blocks are not parsed but skipped up to their matching '}'
+	// and kept as written in AtRule.Block.
+	BlockRaw
+)
+
+// AtRuleHandler tells the parser how to read an at-rule.
+type AtRuleHandler struct {
+	Block BlockKind
+	// Parse, if set, is called once the at-rule is read, with its block
+	// as written for BlockRaw and nil otherwise. It may set at.Data. An
+	// error is reported as a parse error at the at-rule, or as a warning
+	// in lenient mode.
+	Parse func(at *AtRule, raw []byte) error
+}
+
+var (
+	atRuleMu       sync.RWMutex
+	atRuleHandlers = builtinAtRules()
+	rawAtRules     int // number of handlers with BlockRaw
+)
+
+func builtinAtRules() map[string]AtRuleHandler {
+	m := make(map[string]AtRuleHandler)
+	for name := range declarationAtRules {
+		m[name] = AtRuleHandler{Block: BlockDeclarations}
+	}
+	for _, name := range []string{"media", "supports", "keyframes", "layer", "container", "scope", "document", "starting-style"} {
+		m[name] = AtRuleHandler{Block: BlockRules}
+	}
+	return m
+}
+
+// RegisterAtRule sets the handler of the at-rules named name, compared
+// ASCII case-insensitively, replacing any built-in one, such as that of
+// @font-face. A registered at-rule is known in strict mode. At-rules
+// without a handler are read as BlockRules.
+func RegisterAtRule(name string, h AtRuleHandler) {
+	name = asciiLower(name)
+	atRuleMu.Lock()
+	defer atRuleMu.Unlock()
+	if old, ok := atRuleHandlers[name]; ok && old.Block == BlockRaw {
+		rawAtRules--
+	}
+	if h.Block == BlockRaw {
+		rawAtRules++
+	}
+	atRuleHandlers[name] = h
+}
+
+// lookupAtRule returns the handler of the at-rules named name.
+func lookupAtRule(name string) (AtRuleHandler, bool) {
+	atRuleMu.RLock()
+	defer atRuleMu.RUnlock()
+	h, ok := atRuleHandlers[asciiLower(name)]
+	return h, ok
+}
+
+// hasRawAtRules reports whether a handler reads its blocks as BlockRaw.
+func hasRawAtRules() bool {
+	atRuleMu.RLock()
+	defer atRuleMu.RUnlock()
+	return rawAtRules > 0
+}
@@ -0,0 +1,189 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AttrSelector is an attribute selector, such as [lang|="en" i].
+type AttrSelector struct {
+	// Name is the unescaped attribute name, with its namespace prefix if
+	// it has one, as in "xlink|href" or "*|href".
+	Name string
+	// Op is "" for a selector of the attribute's presence, or one of "=",
+	// "~=", "|=", "^=", "$=" and "*=".
+	Op    string
+	Value string // unquoted and unescaped
+	Flag  string // "i", "s" or ""
+}
+
+// ParseAttrSelector parses the attribute selector s, brackets included, as
+// the selectors of a stylesheet are read.
+func ParseAttrSelector(s string) (AttrSelector, error) {
+	norm, err := normalizeAttribute(strings.TrimSpace(s))
+	if err != nil {
+		return AttrSelector{}, err
+	}
+	// norm is [name], or [name op "value"] with an optional " flag".
+	var a AttrSelector
+	inner := norm[1 : len(norm)-1]
+	i := attrNameEnd(inner)
+	a.Name = unescape(inner[:i])
+	if i == len(inner) {
+		return a, nil
+	}
+	a.Op = "="
+	if inner[i] != '=' {
+		a.Op = inner[i : i+2]
+	}
+	i += len(a.Op)
+	end := skipString(inner, i)
+	a.Value = familyName(inner[i:end])
+	if flag := strings.TrimSpace(inner[end:]); flag != "" {
+		if flag != "i" && flag != "s" {
+			return AttrSelector{}, fmt.Errorf("attribute selector %q has an unknown flag %s", s, flag)
+		}
+		a.Flag = flag
+	}
+	return a, nil
+}
+
+// attrNameEnd returns the end of the attribute name, with its namespace
+// prefix, that s starts with.
+func attrNameEnd(s string) int {
+	i := 0
+	if strings.HasPrefix(s, "*|") {
+		i = 2
+	} else if strings.HasPrefix(s, "|") {
+		i = 1
+	}
+	i = skipName(s, i)
+	if i < len(s) && s[i] == '|' && !strings.HasPrefix(s[i:], "|=") {
+		i = skipName(s, i+1)
+	}
+	return i
+}
+
+// String returns the CSS text of a.
+func (a AttrSelector) String() string {
+	name := a.Name
+	if i := strings.IndexByte(name, '|'); i >= 0 {
+		prefix := name[:i]
+		if prefix != "*" && prefix != "" {
+			prefix = EscapeIdent(prefix)
+		}
+		name = prefix + "|" + EscapeIdent(name[i+1:])
+	} else {
+		name = EscapeIdent(name)
+	}
+	if a.Op == "" {
+		return "[" + name + "]"
+	}
+	s := "[" + name + a.Op + QuoteString(a.Value)
+	if a.Flag != "" {
+		s += " " + a.Flag
+	}
+	return s + "]"
+}
+
+// MatchAttr reports whether an element with the attributes attrs, keyed by
+// name, matches sel. Attribute names are compared ignoring ASCII case, as
+// HTML compares them; a name in the namespace "*" matches the attribute of
+// its local name, and one with another prefix, as in "xlink|href", the
+// attribute keyed "xlink:href". Values are compared with their case if sel
+// has the s flag and without it if it has the i flag; without a flag, the
+// values of the attributes HTML compares without case, such as type and
+// lang, are compared without it, and the others with it.
+//
+// As the Selectors spec has it, [a~=""] and a ~= value holding whitespace
+// never match, since no word of a space-separated list can be empty or
+// hold a space, and neither do ^=, $= and *= with an empty value. |=
+// matches the value itself or the value followed by '-', so [a|=""]
+// matches an empty attribute or one starting with '-'.
+func MatchAttr(sel AttrSelector, attrs map[string]string) bool {
+	value, ok := attrValue(sel.Name, attrs)
+	if !ok {
+		return false
+	}
+	want := sel.Value
+	if sel.Flag == "i" || sel.Flag == "" && htmlCaseInsensitive[asciiLower(localName(sel.Name))] {
+		value, want = asciiLower(value), asciiLower(want)
+	}
+	switch sel.Op {
+	case "":
+		return true
+	case "=":
+		return value == want
+	case "~=":
+		if want == "" || strings.ContainsAny(want, " \t\n\r\f") {
+			return false
+		}
+		for _, word := range strings.FieldsFunc(value, func(r rune) bool { return r < 0x80 && isSelectorSpace(byte(r)) }) {
+			if word == want {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return value == want || strings.HasPrefix(value, want+"-")
+	case "^=":
+		return want != "" && strings.HasPrefix(value, want)
+	case "$=":
+		return want != "" && strings.HasSuffix(value, want)
+	case "*=":
+		return want != "" && strings.Contains(value, want)
+	}
+	return false
+}
+
+// Matches reports whether an element with the attributes attrs matches a,
+// as by MatchAttr.
+func (a AttrSelector) Matches(attrs map[string]string) bool {
+	return MatchAttr(a, attrs)
+}
+
+// htmlCaseInsensitive lists the attributes whose values HTML compares
+// ignoring ASCII case in selectors without a flag.
+var htmlCaseInsensitive = map[string]bool{
+	"accept": true, "accept-charset": true, "align": true, "alink": true, "axis": true,
+	"bgcolor": true, "charset": true, "checked": true, "clear": true, "codetype": true,
+	"color": true, "compact": true, "declare": true, "defer": true, "dir": true,
+	"direction": true, "disabled": true, "enctype": true, "face": true, "frame": true,
+	"hreflang": true, "http-equiv": true, "lang": true, "language": true, "link": true,
+	"media": true, "method": true, "multiple": true, "nohref": true, "noresize": true,
+	"noshade": true, "nowrap": true, "readonly": true, "rel": true, "rev": true,
+	"rules": true, "scope": true, "scrolling": true, "selected": true, "shape": true,
+	"target": true, "text": true, "type": true, "valign": true, "valuetype": true,
+	"vlink": true,
+}
+
+// localName returns name without its namespace prefix.
+func localName(name string) string {
+	if i := strings.IndexByte(name, '|'); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// attrValue returns the value of the attribute name of attrs, compared
+// as described for MatchAttr.
+func attrValue(name string, attrs map[string]string) (string, bool) {
+	if i := strings.IndexByte(name, '|'); i >= 0 {
+		switch prefix := name[:i]; prefix {
+		case "*", "":
+			name = name[i+1:]
+		default:
+			name = prefix + ":" + name[i+1:]
+		}
+	}
+	if v, ok := attrs[name]; ok {
+		return v, true
+	}
+	key, found := "", false
+	for k := range attrs {
+		if strings.EqualFold(k, name) && (!found || k < key) {
+			key, found = k, true
+		}
+	}
+	return attrs[key], found
+}
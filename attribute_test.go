@@ -0,0 +1,46 @@
+package css
+
+import "testing"
+
+func TestMatchAttr(t *testing.T) {
+	tests := []struct {
+		sel   AttrSelector
+		value string
+		want  bool
+	}{
+		// ~= matches a whole word of a whitespace-separated list.
+		{AttrSelector{"class", "~=", "b", ""}, "a b c", true},
+		{AttrSelector{"class", "~=", "b", ""}, "b", true},
+		{AttrSelector{"class", "~=", "b", ""}, "\tb\n", true},
+		{AttrSelector{"class", "~=", "b", ""}, "a\fb", true},
+		{AttrSelector{"class", "~=", "b", ""}, "ab c", false},
+		{AttrSelector{"class", "~=", "b", ""}, "a-b", false},
+		{AttrSelector{"class", "~=", "b", ""}, "", false},
+		// No word is empty or holds whitespace.
+		{AttrSelector{"class", "~=", "", ""}, "", false},
+		{AttrSelector{"class", "~=", "", ""}, "a  b", false},
+		{AttrSelector{"class", "~=", "a b", ""}, "a b", false},
+		{AttrSelector{"class", "~=", "a\tb", ""}, "a\tb", false},
+		// Non-breaking space is not whitespace to selectors.
+		{AttrSelector{"class", "~=", "a\u00a0b", ""}, "x a\u00a0b", true},
+		{AttrSelector{"class", "~=", "B", ""}, "a b", false},
+		{AttrSelector{"class", "~=", "B", "i"}, "a b", true},
+		// The other operators.
+		{AttrSelector{"lang", "|=", "en", ""}, "en-US", true},
+		{AttrSelector{"lang", "|=", "en", ""}, "english", false},
+		{AttrSelector{"href", "^=", "", ""}, "x", false},
+		{AttrSelector{"href", "$=", ".pdf", ""}, "a.pdf", true},
+		{AttrSelector{"href", "*=", "ex", ""}, "text", true},
+		{AttrSelector{"href", "", "", ""}, "", true},
+		{AttrSelector{"type", "=", "TEXT", ""}, "text", true},
+		{AttrSelector{"type", "=", "TEXT", "s"}, "text", false},
+	}
+	for _, tt := range tests {
+		if got := MatchAttr(tt.sel, map[string]string{tt.sel.Name: tt.value}); got != tt.want {
+			t.Errorf("MatchAttr(%+v, %q) = %v, want %v", tt.sel, tt.value, got, tt.want)
+		}
+	}
+	if MatchAttr(AttrSelector{Name: "class"}, map[string]string{"id": "x"}) {
+		t.Error("MatchAttr matches a missing attribute")
+	}
+}
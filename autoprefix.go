@@ -0,0 +1,180 @@
+package css
+
+// PrefixTargets selects the vendor prefixes AddPrefixes inserts.
+type PrefixTargets uint8
+
+// Vendor prefixes for PrefixTargets.
+const (
+	PrefixWebkit PrefixTargets = 1 << iota
+	PrefixMoz
+	PrefixMs
+	PrefixO
+
+	PrefixAll = PrefixWebkit | PrefixMoz | PrefixMs | PrefixO
+)
+
+var prefixNames = []struct {
+	target PrefixTargets
+	prefix string
+}{
+	{PrefixWebkit, "-webkit-"},
+	{PrefixMoz, "-moz-"},
+	{PrefixMs, "-ms-"},
+	{PrefixO, "-o-"},
+}
+
+func (t PrefixTargets) prefixes(of PrefixTargets) []string {
+	var prefixes []string
+	for _, p := range prefixNames {
+		if t&of&p.target != 0 {
+			prefixes = append(prefixes, p.prefix)
+		}
+	}
+	return prefixes
+}
+
+func prefixTarget(prefix string) PrefixTargets {
+	for _, p := range prefixNames {
+		if p.prefix == prefix {
+			return p.target
+		}
+	}
+	return 0
+}
+
+// prefixedProperties lists the properties AddPrefixes duplicates and the
+// prefixes each one needs.
+var prefixedProperties = map[string]PrefixTargets{
+	"user-select":          PrefixWebkit | PrefixMoz | PrefixMs,
+	"appearance":           PrefixWebkit | PrefixMoz,
+	"backdrop-filter":      PrefixWebkit,
+	"text-size-adjust":     PrefixWebkit | PrefixMoz | PrefixMs,
+	"hyphens":              PrefixWebkit | PrefixMs,
+	"box-decoration-break": PrefixWebkit,
+	"print-color-adjust":   PrefixWebkit,
+	"mask":                 PrefixWebkit,
+	"mask-image":           PrefixWebkit,
+	"mask-size":            PrefixWebkit,
+	"mask-position":        PrefixWebkit,
+	"mask-repeat":          PrefixWebkit,
+	"clip-path":            PrefixWebkit,
+	"tab-size":             PrefixMoz | PrefixO,
+	"text-emphasis":        PrefixWebkit,
+}
+
+// prefixedValues lists the values AddPrefixes adds legacy equivalents for,
+// by property and value, in the order they are emitted.
+var prefixedValues = map[string]map[string][]struct {
+	target PrefixTargets
+	value  string
+}{
+	"display": {
+		"flex": {
+			{PrefixWebkit, "-webkit-box"},
+			{PrefixWebkit, "-webkit-flex"},
+			{PrefixMs, "-ms-flexbox"},
+		},
+		"inline-flex": {
+			{PrefixWebkit, "-webkit-inline-box"},
+			{PrefixWebkit, "-webkit-inline-flex"},
+			{PrefixMs, "-ms-inline-flexbox"},
+		},
+	},
+	"position": {
+		"sticky": {{PrefixWebkit, "-webkit-sticky"}},
+	},
+}
+
+// prefixedAtRules lists the at-rules AddPrefixes duplicates.
+var prefixedAtRules = map[string]PrefixTargets{
+	"keyframes": PrefixWebkit | PrefixMoz | PrefixO,
+}
+
+// AddPrefixes returns a copy of sheet in which the properties, values and
+// at-rules listed in the package's prefix tables are preceded by vendor
+// prefixed duplicates for each of targets. Prefixed forms are placed before
+// the standard one so the cascade prefers it where supported, and are not
+// added again when the block or sheet already contains them. Duplicated
+// @keyframes rules only receive their own vendor's prefixes.
+func AddPrefixes(sheet *StyleSheet, targets PrefixTargets) *StyleSheet {
+	return &StyleSheet{Rules: addPrefixes(sheet.Rules, targets), Comments: sheet.Comments}
+}
+
+func addPrefixes(nodes []Node, targets PrefixTargets) []Node {
+	if nodes == nil {
+		return nil
+	}
+	present := make(map[string]bool)
+	for _, n := range nodes {
+		if at, ok := n.(*AtRule); ok {
+			present[at.Name+" "+at.Prelude] = true
+		}
+	}
+
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			r := *n
+			r.Declarations = prefixDeclarations(n.Declarations, targets)
+			r.Rules = addPrefixes(n.Rules, targets)
+			out = append(out, &r)
+		case *AtRule:
+			for _, p := range targets.prefixes(prefixedAtRules[n.Name]) {
+				name := p + n.Name
+				if present[name+" "+n.Prelude] {
+					continue
+				}
+				present[name+" "+n.Prelude] = true
+				own := prefixTarget(p)
+				out = append(out, &AtRule{
+					Name:         name,
+					Prelude:      n.Prelude,
+					Rules:        addPrefixes(n.Rules, own),
+					Declarations: prefixDeclarations(n.Declarations, own),
+					Pos:          n.Pos,
+					Open:         n.Open,
+					Close:        n.Close,
+				})
+			}
+			at := *n
+			at.Rules = addPrefixes(n.Rules, targets)
+			at.Declarations = prefixDeclarations(n.Declarations, targets)
+			out = append(out, &at)
+		}
+	}
+	return out
+}
+
+func prefixDeclarations(decls []Declaration, targets PrefixTargets) []Declaration {
+	if decls == nil {
+		return nil
+	}
+	present := make(map[string]bool, len(decls))
+	for _, d := range decls {
+		present[d.Property] = true
+		present[d.Property+":"+d.Value] = true
+	}
+
+	out := make([]Declaration, 0, len(decls))
+	for _, d := range decls {
+		for _, p := range targets.prefixes(prefixedProperties[d.Property]) {
+			if !present[p+d.Property] {
+				present[p+d.Property] = true
+				pd := d
+				pd.Property = p + d.Property
+				out = append(out, pd)
+			}
+		}
+		for _, v := range prefixedValues[d.Property][d.Value] {
+			if targets&v.target != 0 && !present[d.Property+":"+v.value] {
+				present[d.Property+":"+v.value] = true
+				pd := d
+				pd.Value = v.value
+				out = append(out, pd)
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
@@ -0,0 +1,390 @@
+package css
+
+//go:generate go run gen_bench.go
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// benchSheets are the stylesheets of testdata/bench: small.css is written
+// by hand, and bootstrap.css and large.css are generated by gen_bench.go.
+var benchSheets = []string{"small", "bootstrap", "large"}
+
+func readBenchSheet(tb testing.TB, name string) []byte {
+	tb.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", "bench", name+".css"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return b
+}
+
+// benchSheet runs fn as a sub-benchmark for each of benchSheets, throughput
+// measured in bytes of the stylesheet.
+func benchSheet(b *testing.B, fn func(b *testing.B, src []byte)) {
+	for _, name := range benchSheets {
+		src := readBenchSheet(b, name)
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			b.ReportAllocs()
+			fn(b, src)
+		})
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Unmarshal(src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkUnmarshalSelectors picks a few selectors out of each stylesheet,
+// the last of them that of its last top-level rule, to compare with
+// BenchmarkUnmarshal.
+func BenchmarkUnmarshalSelectors(b *testing.B) {
+	want := map[string][]string{
+		"small":     {"a", "pre", "footer"},
+		"bootstrap": {".btn-primary", ".navbar-v1-dark", ".my-auto"},
+		"large":     {".bg-rose-900", ".p-64", ".dark .dark\\:text-rose-900"},
+	}
+	for _, name := range benchSheets {
+		src := readBenchSheet(b, name)
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				css, err := UnmarshalSelectors(src, want[name])
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(css) != len(want[name]) {
+					b.Fatalf("got %d selectors, want %d", len(css), len(want[name]))
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkListSelectors lists the selectors of each stylesheet, to compare
+// with BenchmarkParse.
+func BenchmarkListSelectors(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := ListSelectors(bytes.NewReader(src)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkLongValue parses a rule whose background-image is a data URI of
+// 1, 2 and 4 MB. Its throughput should stay about the same across sizes,
+// as value accumulation is linear.
+func BenchmarkLongValue(b *testing.B) {
+	for _, mb := range []int{1, 2, 4} {
+		var src bytes.Buffer
+		src.WriteString(".a { background-image: url(data:image/png;base64,")
+		for src.Len() < mb<<20 {
+			src.WriteString("iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNk")
+		}
+		src.WriteString(")")
+		n := src.Len() - len(".a { background-image: ")
+		src.WriteString("; color: red; }")
+		b.Run(fmt.Sprintf("%dMB", mb), func(b *testing.B) {
+			b.SetBytes(int64(src.Len()))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				css, err := Unmarshal(src.Bytes())
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(css[".a"]["background-image"]) != n {
+					b.Fatal("background-image is cut short")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Parse(src); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkTokenizer measures the tokenizer of the parser alone, in the
+// modes the grammar sets.
+func BenchmarkTokenizer(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		for i := 0; i < b.N; i++ {
+			t := newTokenizer(bytes.NewReader(src), "", options{})
+			g := grammar{prev: tokenFirstToken}
+			for {
+				token, err := t.next()
+				if err == io.EOF {
+					break
+				} else if err != nil {
+					b.Fatal(err)
+				}
+				g.advance(t, token)
+			}
+		}
+	})
+}
+
+// BenchmarkTokenStream measures the tokens parse reads, fed one at a time
+// from the tokenizer with no list in between, and reports their number as
+// tokens/op to tell the allocations per token.
+func BenchmarkTokenStream(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		var n int
+		for i := 0; i < b.N; i++ {
+			ts := newTokenStream(bytes.NewReader(src), "", options{})
+			for n = 0; ; n++ {
+				if _, ok := ts.next(); !ok {
+					break
+				}
+			}
+			if ts.err != nil {
+				b.Fatal(ts.err)
+			}
+		}
+		b.ReportMetric(float64(n), "tokens/op")
+	})
+}
+
+func BenchmarkScanner(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		for i := 0; i < b.N; i++ {
+			s := NewScanner(bytes.NewReader(src))
+			for {
+				if _, err := s.Next(); err == io.EOF {
+					break
+				} else if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+// BenchmarkScannerASCII compares the byte path of ASCII input with the
+// rune path on large.css, read in full so that only scanning is measured.
+func BenchmarkScannerASCII(b *testing.B) {
+	src := string(readBenchSheet(b, "large"))
+	for _, path := range []struct {
+		name  string
+		ascii bool
+	}{{"bytes", true}, {"runes", false}} {
+		b.Run(path.name, func(b *testing.B) {
+			b.SetBytes(int64(len(src)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				s := wholeScanner(src, path.ascii)
+				for {
+					if _, err := s.Next(); err == io.EOF {
+						break
+					} else if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkUnmarshalCompact measures UnmarshalCompact, and reports as
+// heap-B/op the heap its result keeps alive, against that of the result of
+// Unmarshal.
+func BenchmarkUnmarshalCompact(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		var styles *Styles
+		for i := 0; i < b.N; i++ {
+			var err error
+			if styles, err = UnmarshalCompact(src); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(float64(retainedHeap(func() interface{} {
+			styles, _ := UnmarshalCompact(src)
+			return styles
+		})), "heap-B/op")
+		runtime.KeepAlive(styles)
+	})
+}
+
+// BenchmarkUnmarshalHeap reports the heap the result of Unmarshal keeps
+// alive, the baseline of BenchmarkUnmarshalCompact.
+func BenchmarkUnmarshalHeap(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Unmarshal(src); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StopTimer()
+		b.ReportMetric(float64(retainedHeap(func() interface{} {
+			css, _ := Unmarshal(src)
+			return css
+		})), "heap-B/op")
+	})
+}
+
+// retainedHeap returns the bytes of heap still in use, after a collection,
+// by the result of fn.
+func retainedHeap(fn func() interface{}) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	v := fn()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(v)
+	if after.HeapAlloc < before.HeapAlloc {
+		return 0
+	}
+	return after.HeapAlloc - before.HeapAlloc
+}
+
+// matcherBench returns a sheet of 5000 rules, a fifth of each of the kinds
+// of selector a Matcher buckets and of combinators, and 10000 elements.
+func matcherBench(tb testing.TB) (*StyleSheet, []ElementDesc) {
+	var src bytes.Buffer
+	tags := []string{"div", "p", "a", "li", "span"}
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&src, ".c%d { color: red }\n", i)
+		fmt.Fprintf(&src, "#i%d { top: %dpx }\n", i, i)
+		fmt.Fprintf(&src, "%s.c%d { margin: 0 }\n", tags[i%len(tags)], i)
+		fmt.Fprintf(&src, ".c%d.d%d { padding: 0 !important }\n", i, i%7)
+		fmt.Fprintf(&src, ".list > .c%d { display: block }\n", i)
+	}
+	sheet, err := Parse(src.Bytes())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	els := make([]ElementDesc, 10000)
+	for i := range els {
+		els[i] = ElementDesc{
+			Tag:     tags[i%len(tags)],
+			ID:      fmt.Sprintf("i%d", i%1500),
+			Classes: []string{fmt.Sprintf("c%d", i%1200), fmt.Sprintf("d%d", i%7)},
+		}
+	}
+	return sheet, els
+}
+
+// BenchmarkComputeStyle styles 10000 elements with one sheet, by a Matcher
+// and by ComputeStyle for each element.
+func BenchmarkComputeStyle(b *testing.B) {
+	sheet, els := matcherBench(b)
+	b.Run("matcher", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			m := NewMatcher(sheet)
+			for _, el := range els {
+				m.ComputeStyle(el)
+			}
+		}
+	})
+	b.Run("each", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, el := range els {
+				ComputeStyle(sheet, el)
+			}
+		}
+	})
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		sheet, err := Parse(src)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := Marshal(sheet); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// BenchmarkMergeMedia marshals 4000 @media blocks of one query, each after
+// a rule of its own, with MergeMedia.
+func BenchmarkMergeMedia(b *testing.B) {
+	var src bytes.Buffer
+	for i := 0; i < 4000; i++ {
+		fmt.Fprintf(&src, "@media print { .a%d { top: 0; } } .b%d { top: 0; }\n", i, i)
+	}
+	sheet, err := Parse(src.Bytes())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(sheet, MergeMedia()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUnmarshalParallel parses the same stylesheet from GOMAXPROCS
+// goroutines at once; run with -race to check that parses share nothing.
+func BenchmarkUnmarshalParallel(b *testing.B) {
+	benchSheet(b, func(b *testing.B, src []byte) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				if _, err := Unmarshal(src); err != nil {
+					b.Error(err)
+					return
+				}
+			}
+		})
+	})
+}
+
+// TestUnmarshalConcurrent checks that parses of the same input from many
+// goroutines at once all give the result of a parse on its own.
+func TestUnmarshalConcurrent(t *testing.T) {
+	src := readBenchSheet(t, "bootstrap")
+	want, err := Unmarshal(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := Unmarshal(src)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Error("concurrent Unmarshal differs from a single one")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,163 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Builder builds a stylesheet in Go code, one call per rule, declaration
+// or block, as in
+//
+//	out, err := css.New().
+//		Rule(".btn").Decl("color", "white").Decl("background", brand).
+//		Media("(min-width: 768px)", func(m *css.Builder) {
+//			m.Rule(".btn").Decl("padding", "12px")
+//		}).
+//		Bytes()
+//
+// Rules and blocks come in the order of the calls adding them, so the
+// cascade of the sheet follows the code. Selectors, values and preludes
+// are read as AddRule and Set read them. A Builder does not panic on bad
+// input: the call is skipped and its error kept, to be returned by Err,
+// Sheet and Bytes.
+type Builder struct {
+	b     *builder
+	nodes *[]Node   // the list Rule and the block methods add to
+	rule  *RuleNode // the rule Decl adds to
+}
+
+// builder is the state the Builders of a sheet share.
+type builder struct {
+	sheet StyleSheet
+	errs  []error
+}
+
+// New returns a Builder of an empty stylesheet.
+func New() *Builder {
+	b := &builder{}
+	return &Builder{b: b, nodes: &b.sheet.Rules}
+}
+
+// Rule adds a rule for the selector list selector, to which the following
+// calls of Decl add declarations.
+func (b *Builder) Rule(selector string) *Builder {
+	r, err := newRule(selector)
+	if err != nil {
+		b.rule = nil
+		return b.fail(err)
+	}
+	*b.nodes = append(*b.nodes, r)
+	b.rule = r
+	return b
+}
+
+// Decl adds the declaration of prop and value, !important if value ends
+// with it, to the rule of the last call of Rule. Unlike Set, it keeps the
+// earlier declarations of prop, as fallbacks.
+func (b *Builder) Decl(prop, value string) *Builder {
+	if b.rule == nil {
+		return b.fail(fmt.Errorf("declaration %s: %s is not in a rule", prop, value))
+	}
+	d, err := newDeclaration(prop, value)
+	if err != nil {
+		return b.fail(err)
+	}
+	b.rule.Declarations = append(b.rule.Declarations, d)
+	return b
+}
+
+// Media adds an @media block for the media query list query, holding the
+// rules fn adds with the Builder it is given.
+func (b *Builder) Media(query string, fn func(m *Builder)) *Builder {
+	if _, err := ParseMediaQueryList(query); err != nil {
+		b.fail(fmt.Errorf("invalid media query list %q: %w", query, err))
+		return b.block(nil, fn)
+	}
+	return b.Block("media", query, fn)
+}
+
+// Block adds a block of the at-rule name, without its '@', such as
+// "supports" or "layer", with the prelude prelude, holding the rules fn
+// adds with the Builder it is given.
+func (b *Builder) Block(name, prelude string, fn func(m *Builder)) *Builder {
+	at, err := newAtRule(name, prelude)
+	if err != nil {
+		b.fail(err)
+		return b.block(nil, fn)
+	}
+	*b.nodes = append(*b.nodes, at)
+	return b.block(at, fn)
+}
+
+// block calls fn with a Builder adding to the rules of at, or to rules
+// that are dropped if at is nil, so that the errors of fn are kept.
+func (b *Builder) block(at *AtRule, fn func(m *Builder)) *Builder {
+	b.rule = nil
+	if at == nil {
+		at = &AtRule{}
+	}
+	if fn != nil {
+		fn(&Builder{b: b.b, nodes: &at.Rules})
+	}
+	return b
+}
+
+func (b *Builder) fail(err error) *Builder {
+	b.b.errs = append(b.b.errs, err)
+	return b
+}
+
+// Err returns the first error of the calls so far, or nil.
+func (b *Builder) Err() error {
+	if len(b.b.errs) == 0 {
+		return nil
+	}
+	return b.b.errs[0]
+}
+
+// Errs returns the errors of the calls so far, in call order.
+func (b *Builder) Errs() []error {
+	return append([]error(nil), b.b.errs...)
+}
+
+// Sheet returns the stylesheet built, which later calls keep adding to,
+// and Err.
+func (b *Builder) Sheet() (*StyleSheet, error) {
+	sheet := &b.b.sheet
+	sheet.Layers = declaredLayers(sheet.Rules)
+	return sheet, b.Err()
+}
+
+// Bytes returns the stylesheet built as Marshal writes it with opts, or
+// nil and Err if a call failed.
+func (b *Builder) Bytes(opts ...MarshalOption) ([]byte, error) {
+	sheet, err := b.Sheet()
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(sheet, opts...)
+}
+
+// newAtRule returns an empty block of the at-rule name with prelude,
+// parsed from a stylesheet of that block alone.
+func newAtRule(name, prelude string) (*AtRule, error) {
+	if err := checkText(prelude); err != nil {
+		return nil, fmt.Errorf("invalid @%s prelude %q: %w", name, prelude, err)
+	}
+	if !isIdentStart(name) || strings.ContainsAny(name, " \t\n{};") {
+		return nil, fmt.Errorf("invalid at-rule name %q", name)
+	}
+	text := strings.TrimSpace("@" + name + " " + prelude)
+	sheet, err := Parse([]byte(text + " {}"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", text, err)
+	}
+	var at *AtRule
+	if len(sheet.Rules) == 1 {
+		at, _ = sheet.Rules[0].(*AtRule)
+	}
+	if at == nil || at.Rules == nil || len(at.Rules) > 0 || !strings.EqualFold(at.Name, name) {
+		return nil, fmt.Errorf("%s is not a block of rules", text)
+	}
+	return &AtRule{Name: at.Name, Prelude: at.Prelude, Rules: []Node{}}, nil
+}
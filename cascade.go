@@ -0,0 +1,266 @@
+package css
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ElementDesc describes the element ComputeStyle resolves styles for.
+type ElementDesc struct {
+	Tag     string
+	ID      string
+	Classes []string
+}
+
+// ComputeStyle returns the values the cascade gives el from the top-level
+// rules of sheet and the rules nested in them. A declaration wins over
+// another of the same property if it is !important and the other is not,
+// then if its cascade layer comes later in the order of sheet.Layers, or
+// earlier for two !important ones, rules outside of layers coming after
+// all layers, then if its selector is more specific, then if it comes
+// later. Values are returned without their !important flag.
+//
+// Only selectors made of a type or universal selector, ids and classes can
+// match el. Selectors with attributes, pseudo-classes or combinators never
+// match; this includes compounds with :not() or :is(), even when el would
+// satisfy their arguments. Rules inside at-rules other than @layer, such
+// as @media, are ignored; ForEnv applies those of the @media blocks
+// matching a device. To style many elements with one sheet, use a Matcher.
+func ComputeStyle(sheet *StyleSheet, el ElementDesc) map[string]string {
+	return cascadeStyle(matchCandidates(sheet, el))
+}
+
+// Candidate is a declaration that applies to an element, as returned by
+// Trace, with the most specific selector of its rule that matches the
+// element and its specificity.
+type Candidate struct {
+	Declaration Declaration
+	Selector    Rule
+	Specificity Specificity
+
+	layer layerRank
+}
+
+// matchCandidates returns the declarations of sheet that apply to el, in
+// source order.
+func matchCandidates(sheet *StyleSheet, el ElementDesc) []Candidate {
+	var found []Candidate
+	layeredRules(sheet, func(rule *RuleNode, layer layerRank) {
+		var best Rule
+		var specificity Specificity
+		matched := false
+		for _, sel := range rule.Selectors {
+			if s := sel.Specificity(); matches(sel, el) && (!matched || specificity.Less(s)) {
+				best, specificity, matched = sel, s, true
+			}
+		}
+		if matched {
+			found = appendCandidates(found, rule, layer, best, specificity)
+		}
+	})
+	return found
+}
+
+func appendCandidates(found []Candidate, rule *RuleNode, layer layerRank, sel Rule, specificity Specificity) []Candidate {
+	for _, d := range rule.Declarations {
+		found = append(found, Candidate{d, sel, specificity, layer})
+	}
+	return found
+}
+
+// sortCascade sorts the candidates found in source order by precedence,
+// weakest first.
+func sortCascade(found []Candidate) {
+	sort.SliceStable(found, func(i, j int) bool {
+		a, b := found[i], found[j]
+		if a.Declaration.Important != b.Declaration.Important {
+			return b.Declaration.Important
+		}
+		if c := compareRanks(a.layer, b.layer); c != 0 {
+			return c < 0 != a.Declaration.Important
+		}
+		return a.Specificity.Less(b.Specificity)
+	})
+}
+
+// cascadeStyle returns the values the cascade gives the candidates found
+// in source order.
+func cascadeStyle(found []Candidate) map[string]string {
+	sortCascade(found)
+	style := make(map[string]string, len(found))
+	for _, c := range found {
+		style[c.Declaration.Property] = c.Declaration.Value
+	}
+	return style
+}
+
+// Trace returns the declarations of property prop that apply to el, as
+// ComputeStyle finds them, from the one the cascade picks to the one with
+// the least precedence.
+func Trace(sheet *StyleSheet, el ElementDesc, prop string) []Candidate {
+	return traceCascade(matchCandidates(sheet, el), prop)
+}
+
+// traceCascade returns the candidates of found, in source order, for prop,
+// winner first.
+func traceCascade(found []Candidate, prop string) []Candidate {
+	var out []Candidate
+	for _, c := range found {
+		if strings.EqualFold(c.Declaration.Property, prop) {
+			out = append(out, c)
+		}
+	}
+	sortCascade(out)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// ComputeInheritedStyle is like ComputeStyle for an element whose parent has
+// the computed style parent, as returned by an earlier call for the parent.
+// Inherited properties, such as color and the font properties, and custom
+// properties take the parent's value when el has none. A value of inherit
+// takes the parent's value for any property, and unset does so for inherited
+// properties. A property left without a value by inherit or unset is removed
+// from the result, leaving it at its initial value. Values are copied as
+// written; relative values such as percentages and em are not resolved.
+func ComputeInheritedStyle(sheet *StyleSheet, el ElementDesc, parent map[string]string) map[string]string {
+	return inheritStyle(ComputeStyle(sheet, el), parent)
+}
+
+// inheritStyle applies the inheritance of ComputeInheritedStyle to style.
+func inheritStyle(style, parent map[string]string) map[string]string {
+	for prop, v := range style {
+		switch {
+		case strings.EqualFold(v, "inherit"):
+		case strings.EqualFold(v, "unset") && inherits(prop):
+		case strings.EqualFold(v, "unset"):
+			delete(style, prop)
+			continue
+		default:
+			continue
+		}
+		if pv, ok := parent[prop]; ok {
+			style[prop] = pv
+		} else {
+			delete(style, prop)
+		}
+	}
+	for prop, pv := range parent {
+		if _, ok := style[prop]; !ok && inherits(prop) {
+			style[prop] = pv
+		}
+	}
+	return style
+}
+
+// inherits reports whether prop is inherited by default.
+func inherits(prop string) bool {
+	inherited, _ := IsInherited(prop)
+	return inherited
+}
+
+// matches reports whether the compound selector sel matches el.
+func matches(sel Rule, el ElementDesc) bool {
+	c, ok := parseCompound(sel)
+	return ok && c.matches(el)
+}
+
+// compound is a compound selector made of a type or universal selector,
+// ids and classes, unescaped.
+type compound struct {
+	tag     string // "" for any
+	ids     []string
+	classes []string
+}
+
+// parseCompound parses sel as a compound, reporting false if it has
+// anything else.
+func parseCompound(sel Rule) (compound, bool) {
+	var c compound
+	s := strings.TrimSpace(string(sel))
+	if s == "" {
+		return c, false
+	}
+	i := 0
+	switch {
+	case strings.HasPrefix(s, "*"):
+		i++
+	case isNameRune(rune(s[0])) || s[0] == '\\':
+		i = skipName(s, 0)
+		c.tag = unescape(s[:i])
+	}
+	for i < len(s) {
+		kind := s[i]
+		if kind != '#' && kind != '.' {
+			return c, false
+		}
+		end := skipName(s, i+1)
+		name := unescape(s[i+1 : end])
+		switch {
+		case name == "":
+			return c, false
+		case kind == '#':
+			c.ids = append(c.ids, name)
+		default:
+			c.classes = append(c.classes, name)
+		}
+		i = end
+	}
+	return c, true
+}
+
+func (c compound) matches(el ElementDesc) bool {
+	if c.tag != "" && !strings.EqualFold(c.tag, el.Tag) {
+		return false
+	}
+	for _, id := range c.ids {
+		if id != el.ID {
+			return false
+		}
+	}
+	for _, class := range c.classes {
+		if !hasClass(el.Classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasClass(classes []string, name string) bool {
+	for _, c := range classes {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unescape decodes the escapes of the identifier s.
+func unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+		end := skipEscape(s, i)
+		hex := strings.TrimRight(s[i+1:end], " \t\n")
+		if n, err := strconv.ParseUint(hex, 16, 32); err == nil && isHexDigit(rune(s[i+1])) {
+			if n == 0 || n > 0x10ffff || (n >= 0xd800 && n <= 0xdfff) {
+				n = 0xfffd
+			}
+			b.WriteRune(rune(n))
+		} else {
+			b.WriteString(s[i+1 : end])
+		}
+		i = end
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package css
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestImportantPrecedence is the matrix of two declarations of a property
+// colliding: in the merge of Unmarshal, within a block and across blocks,
+// and in the cascade of ComputeStyle, where the second is in a rule of the
+// same specificity.
+func TestImportantPrecedence(t *testing.T) {
+	tests := []struct {
+		name, first, second string
+		want                string // as Unmarshal keeps it; ComputeStyle drops !important
+	}{
+		{"normal then normal", "red", "blue", "blue"},
+		{"important then normal", "red !important", "blue", "red !important"},
+		{"normal then important", "red", "blue !important", "blue !important"},
+		{"important then important", "red !important", "blue !important", "blue !important"},
+	}
+	for _, tt := range tests {
+		for _, src := range []string{
+			".a { color: " + tt.first + " } .a { color: " + tt.second + " }",
+			".a { color: " + tt.first + "; color: " + tt.second + " }",
+		} {
+			css, err := Unmarshal([]byte(src))
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			if got := css[".a"]["color"]; got != tt.want {
+				t.Errorf("%s: Unmarshal(%q) gives %q, want %q", tt.name, src, got, tt.want)
+			}
+		}
+		sheet, err := Parse([]byte(".a { color: " + tt.first + " } .b, .a { color: " + tt.second + " }"))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		want := strings.TrimSuffix(tt.want, " !important")
+		if got := ComputeStyle(sheet, ElementDesc{Tag: "p", Classes: []string{"a"}})["color"]; got != want {
+			t.Errorf("%s: ComputeStyle gives %q, want %q", tt.name, got, want)
+		}
+	}
+}
+
+// TestComputeStyleUnsupported checks that selectors ComputeStyle cannot
+// evaluate never match, even when el satisfies their arguments.
+func TestComputeStyleUnsupported(t *testing.T) {
+	sheet, err := Parse([]byte("div.a { top: 0 } div:not(.b) { color: red } :is(div) { left: 0 } div[id] { right: 0 } body div { bottom: 0 }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ComputeStyle(sheet, ElementDesc{Tag: "div", ID: "x", Classes: []string{"a"}})
+	if want := map[string]string{"top": "0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ComputeStyle gives %v, want %v", got, want)
+	}
+}
+
+// TestLayerOrder checks that the order of cascade layers is that of their
+// first declaration, as by a "@layer a, b;" statement before their blocks,
+// in Layers, ComputeStyle and Unmarshal.
+func TestLayerOrder(t *testing.T) {
+	tests := []struct {
+		src    string
+		layers []string
+		color  string // as Unmarshal keeps it; ComputeStyle drops !important
+	}{
+		{"@layer a, b; @layer b { .x { color: blue } } @layer a { .x { color: red } }", []string{"a", "b"}, "blue"},
+		{"@layer b { .x { color: blue } } @layer a { .x { color: red } }", []string{"b", "a"}, "red"},
+		{"@layer b; @layer a, b; @layer a { .x { color: red } } @layer b { .x { color: blue } }", []string{"b", "a"}, "red"},
+		{"@layer a, b; @layer b { .x { color: blue !important } } @layer a { .x { color: red !important } }", []string{"a", "b"}, "red !important"},
+		{"@layer a, b; .x { color: green } @layer b { .x { color: blue } }", []string{"a", "b"}, "green"},
+	}
+	for _, tt := range tests {
+		sheet, err := Parse([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(sheet.Layers, tt.layers) {
+			t.Errorf("%q declares layers %q, want %q", tt.src, sheet.Layers, tt.layers)
+		}
+		want := strings.TrimSuffix(tt.color, " !important")
+		if got := ComputeStyle(sheet, ElementDesc{Classes: []string{"x"}})["color"]; got != want {
+			t.Errorf("ComputeStyle of %q gives color %q, want %q", tt.src, got, want)
+		}
+		css, err := Unmarshal([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Unmarshal(%q): %v", tt.src, err)
+		} else if got := css[".x"]["color"]; got != tt.color {
+			t.Errorf("Unmarshal(%q) gives color %q, want %q", tt.src, got, tt.color)
+		}
+	}
+}
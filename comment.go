@@ -0,0 +1,210 @@
+package css
+
+import (
+	"bufio"
+	"io"
+	"text/scanner"
+)
+
+// commentFilter blanks out the comments of the stylesheet read from r,
+// replacing every byte but newlines with a space so that positions in its
+// output match the input. A carriage return is read as a newline, or as a
+// space before an LF. Quoted strings and unquoted url() arguments, up to the
+// end of their line if left open, are passed through untouched. Unterminated comments and strings are reported at the
+// position where they start. The comments are kept in comments, in order.
+type commentFilter struct {
+	r   *bufio.Reader
+	o   options
+	pos scanner.Position // position of the next input byte
+	err error
+
+	mode       tokenMode // modeComment, modeString or modeURL inside one, else modeText
+	opening    bool      // the '*' of "/*" is next
+	closing    bool      // the '*' of "*/" was just read
+	commentPos scanner.Position
+	text       []byte // of the comment being read
+	comments   []sourceComment
+	quote      byte
+	quotePos   scanner.Position
+	escape     bool    // the previous byte was a backslash
+	urlStart   bool    // no non-space byte seen yet in url(
+	last       [3]byte // the previous three bytes, lowercased
+}
+
+func newCommentFilter(r io.Reader, filename string, o options) *commentFilter {
+	pos := o.start
+	if !pos.IsValid() {
+		pos = scanner.Position{Filename: filename, Line: 1, Column: 1}
+	}
+	return &commentFilter{
+		r:   bufio.NewReader(r),
+		o:   o,
+		pos: pos,
+	}
+}
+
+func (f *commentFilter) Read(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	n := 0
+	for n < len(p) {
+		c, err := f.r.ReadByte()
+		if err != nil {
+			f.err = f.end(err)
+			return n, f.err
+		}
+		if c == '\r' {
+			// A CR ends the line with or without a following LF.
+			if c = '\n'; f.peek() == '\n' {
+				c = ' '
+			}
+		}
+		pos := f.advance(c)
+		p[n] = f.filter(c, pos)
+		n++
+		if f.err != nil {
+			return n, f.err
+		}
+	}
+	return n, nil
+}
+
+// advance returns the position of c and moves past it.
+func (f *commentFilter) advance(c byte) scanner.Position {
+	pos := f.pos
+	advancePosition(&f.pos, c)
+	return pos
+}
+
+// advancePosition moves pos past the byte c, counting runes as columns.
+func advancePosition(pos *scanner.Position, c byte) {
+	pos.Offset++
+	switch {
+	case c == '\n':
+		pos.Line++
+		pos.Column = 1
+	case c&0xc0 != 0x80:
+		pos.Column++
+	}
+}
+
+// filter returns the byte to emit for the input byte c read at pos.
+func (f *commentFilter) filter(c byte, pos scanner.Position) byte {
+	switch {
+	case f.mode == modeComment:
+		switch {
+		case f.opening:
+			f.opening = false
+		case f.closing:
+			f.mode, f.closing = modeText, false
+			f.comments = append(f.comments, sourceComment{string(f.text), f.commentPos})
+		case c == '*' && f.peek() == '/':
+			f.closing = true
+		case c == '\n':
+			f.text = append(f.text, c)
+			return c
+		default:
+			f.text = append(f.text, c)
+		}
+		return ' '
+	case f.escape:
+		f.escape = false
+		return c
+	case f.mode == modeString:
+		switch {
+		case c == '\\':
+			f.escape = true
+		case modeString.closes(rune(c), rune(f.quote), 0):
+			f.mode = modeText
+		case !modeString.accepts(rune(c), 0):
+			f.mode = modeText
+			if !f.o.lenient {
+				f.err = errorAt(f.quotePos, "unterminated string")
+				break
+			}
+			f.o.diagnose(SeverityWarning, DiagUnterminatedString, f.quotePos, "unterminated string closed at end of line")
+		}
+		return c
+	case f.mode == modeURL:
+		switch {
+		case f.urlStart && (c == '"' || c == '\''):
+			f.mode, f.quote, f.quotePos = modeString, c, pos
+		case modeURL.closes(rune(c), 0, 0) || c == '\n' && !f.urlStart:
+			f.mode = modeText
+		case c != ' ' && c != '\t' && c != '\n':
+			f.urlStart = false
+		}
+		return c
+	}
+
+	switch {
+	case c == '/' && f.peek() == '*':
+		f.mode, f.opening, f.commentPos, f.text = modeComment, true, pos, f.text[:0]
+		return ' '
+	case c == '\\':
+		f.escape = true
+	case c == '"' || c == '\'':
+		f.mode, f.quote, f.quotePos = modeString, c, pos
+	case c == '(' && f.last == [3]byte{'u', 'r', 'l'}:
+		f.mode, f.urlStart = modeURL, true
+	}
+	f.last[0], f.last[1], f.last[2] = f.last[1], f.last[2], lower(c)
+	return c
+}
+
+// sourceComment is the text of a comment between its "/*" and "*/", and
+// the position of its "/*".
+type sourceComment struct {
+	text string
+	pos  scanner.Position
+}
+
+func (f *commentFilter) peek() byte {
+	b, err := f.r.Peek(1)
+	if err != nil {
+		return 0
+	}
+	return b[0]
+}
+
+// end returns the error to report when the input ends with err.
+func (f *commentFilter) end(err error) error {
+	if err != io.EOF {
+		return err
+	}
+	switch {
+	case f.mode == modeComment && f.o.lenient:
+		f.o.diagnose(SeverityWarning, DiagUnterminatedComment, f.commentPos, "unterminated comment closed at end of input")
+	case f.mode == modeComment:
+		return errorAt(f.commentPos, "unterminated comment")
+	case f.mode == modeString && f.o.lenient:
+		f.o.diagnose(SeverityWarning, DiagUnterminatedString, f.quotePos, "unterminated string closed at end of input")
+	case f.mode == modeString:
+		return errorAt(f.quotePos, "unterminated string")
+	}
+	return io.EOF
+}
+
+func lower(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// unterminatedString reports whether s has a quoted string left open.
+func unterminatedString(s string) bool {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0 && c == '\\':
+			i++
+		case c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		}
+	}
+	return quote != 0
+}
@@ -0,0 +1,121 @@
+package css
+
+import (
+	"bytes"
+	"sort"
+)
+
+// Styles is the merged declarations of a stylesheet, as returned by
+// Unmarshal, held compactly for very large stylesheets: the declarations
+// of all selectors share one slice, sorted by property within each
+// selector, and the property names and short values are interned, so that
+// the many small maps of Unmarshal are not needed. It is read only and
+// safe for concurrent use, and is freed at once when dropped.
+type Styles struct {
+	spans   map[Rule]styleSpan
+	entries []styleEntry
+}
+
+type styleSpan struct {
+	start, end uint32
+}
+
+type styleEntry struct {
+	property, value string
+}
+
+// maxInterned is the length of the longest value UnmarshalCompact interns.
+const maxInterned = 32
+
+// UnmarshalCompact is like Unmarshal but returns the result as Styles.
+func UnmarshalCompact(b []byte, opts ...Option) (*Styles, error) {
+	o := newOptions(opts)
+	sheet, err := parseReader(bytes.NewReader(b), o.filename, o)
+	f := newFlattener(o)
+	f.strings = make(map[string]string)
+	f.flatten(sheet)
+	if err == nil {
+		err = f.err
+	}
+	return compact(f.css), err
+}
+
+// compact packs css, whose strings the flattener has interned, into Styles.
+func compact(css map[Rule]map[string]string) *Styles {
+	s := &Styles{spans: make(map[Rule]styleSpan, len(css))}
+	n := 0
+	for _, styles := range css {
+		n += len(styles)
+	}
+	s.entries = make([]styleEntry, 0, n)
+	for sel, styles := range css {
+		start := len(s.entries)
+		for p, v := range styles {
+			s.entries = append(s.entries, styleEntry{p, v})
+		}
+		span := s.entries[start:]
+		sort.Slice(span, func(i, j int) bool { return span[i].property < span[j].property })
+		s.spans[sel] = styleSpan{uint32(start), uint32(len(s.entries))}
+	}
+	return s
+}
+
+// Len returns the number of selectors of s.
+func (s *Styles) Len() int {
+	return len(s.spans)
+}
+
+// Selectors returns the selectors of s, sorted.
+func (s *Styles) Selectors() []Rule {
+	sels := make([]Rule, 0, len(s.spans))
+	for sel := range s.spans {
+		sels = append(sels, sel)
+	}
+	sort.Slice(sels, func(i, j int) bool { return sels[i] < sels[j] })
+	return sels
+}
+
+// Get returns the value of property for selector. Unlike the function Get,
+// it takes both as keyed and does not derive longhands or shorthands.
+func (s *Styles) Get(selector Rule, property string) (string, bool) {
+	span := s.span(selector)
+	i := sort.Search(len(span), func(i int) bool { return span[i].property >= property })
+	if i < len(span) && span[i].property == property {
+		return span[i].value, true
+	}
+	return "", false
+}
+
+// Style returns a new map of the declarations of selector, as Unmarshal
+// would have returned for it, or nil if s has no such selector.
+func (s *Styles) Style(selector Rule) map[string]string {
+	sp, ok := s.spans[selector]
+	if !ok {
+		return nil
+	}
+	style := make(map[string]string, sp.end-sp.start)
+	for _, e := range s.entries[sp.start:sp.end] {
+		style[e.property] = e.value
+	}
+	return style
+}
+
+func (s *Styles) span(selector Rule) []styleEntry {
+	sp := s.spans[selector]
+	return s.entries[sp.start:sp.end]
+}
+
+// intern returns the interned copy of v if it is short enough to intern,
+// or else v.
+func (f *flattener) intern(v string) string {
+	if len(v) > maxInterned {
+		return v
+	}
+	if w, ok := f.strings[v]; ok {
+		return w
+	}
+	// Copied so as not to keep alive a larger string it is part of.
+	v = string([]byte(v))
+	f.strings[v] = v
+	return v
+}
@@ -0,0 +1,100 @@
+package css
+
+import (
+	"sort"
+	"text/scanner"
+)
+
+// Conflict is a selector defined by more than one rule of a media scope,
+// whose declarations Unmarshal merges.
+type Conflict struct {
+	Selector Rule
+	Scope    MediaScope
+	// Rules holds the positions of the rules defining Selector, in source
+	// order. Those of rules parsed from different files, as by ParseFiles,
+	// name their file.
+	Rules []scanner.Position
+	// Collisions lists the properties declared by more than one of the
+	// rules, in the order of their first declaration.
+	Collisions []Collision
+	// Coexisting lists, sorted, the properties declared by only one of the
+	// rules, which the merge combines without loss.
+	Coexisting []string
+}
+
+// Collision is a property declared by more than one of the rules of a
+// Conflict.
+type Collision struct {
+	Property string
+	// Declarations holds every declaration of the property, in source
+	// order.
+	Declarations []Declaration
+	// Winner is the declaration whose value Unmarshal keeps: the last one,
+	// or the last !important one if there is any.
+	Winner Declaration
+}
+
+// Conflicts returns the selectors of sheet defined by more than one rule
+// of the same media scope, as keyed by UnmarshalScoped, in the order of
+// their first rule. Nested rules count with their resolved selectors, and
+// the members of a group such as "h1, h2" count separately.
+func Conflicts(sheet *StyleSheet) []Conflict {
+	type key struct {
+		sel   Rule
+		scope MediaScope
+	}
+	var (
+		order []key
+		rules = make(map[key][]*RuleNode)
+	)
+	walkScoped(unnest(sheet.Rules, DialectCSS), "", func(n *RuleNode, scope MediaScope) {
+		for _, sel := range n.Selectors {
+			k := key{sel, scope}
+			if _, ok := rules[k]; !ok {
+				order = append(order, k)
+			}
+			rules[k] = append(rules[k], n)
+		}
+	})
+	var conflicts []Conflict
+	for _, k := range order {
+		if len(rules[k]) > 1 {
+			conflicts = append(conflicts, conflict(k.sel, k.scope, rules[k]))
+		}
+	}
+	return conflicts
+}
+
+// conflict returns the Conflict of the rules defining sel in scope.
+func conflict(sel Rule, scope MediaScope, rules []*RuleNode) Conflict {
+	c := Conflict{Selector: sel, Scope: scope}
+	var props []string
+	decls := make(map[string][]Declaration)
+	owners := make(map[string]map[*RuleNode]bool)
+	for _, r := range rules {
+		c.Rules = append(c.Rules, r.Pos)
+		for _, d := range r.Declarations {
+			if _, ok := decls[d.Property]; !ok {
+				props = append(props, d.Property)
+				owners[d.Property] = make(map[*RuleNode]bool)
+			}
+			decls[d.Property] = append(decls[d.Property], d)
+			owners[d.Property][r] = true
+		}
+	}
+	for _, p := range props {
+		if len(owners[p]) == 1 {
+			c.Coexisting = append(c.Coexisting, p)
+			continue
+		}
+		col := Collision{Property: p, Declarations: decls[p]}
+		for i, d := range col.Declarations {
+			if i == 0 || DuplicateLastWins.wins(d.Important, col.Winner.Important) {
+				col.Winner = d
+			}
+		}
+		c.Collisions = append(c.Collisions, col)
+	}
+	sort.Strings(c.Coexisting)
+	return c
+}
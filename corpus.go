@@ -0,0 +1,273 @@
+package css
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// TestingT is the part of *testing.T that RunCorpus uses.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// CorpusFailure is a case of a corpus whose output is not the one expected.
+type CorpusFailure struct {
+	Name    string // file name of the case's CSS
+	Message string
+}
+
+func (f CorpusFailure) Error() string {
+	return f.Name + ": " + f.Message
+}
+
+// RunCorpus checks the corpus in dir, reporting each failing case and any
+// error reading the corpus to t. See CheckCorpus.
+func RunCorpus(t TestingT, dir string, opts ...Option) {
+	t.Helper()
+	failures, err := CheckCorpus(dir, opts...)
+	if err != nil {
+		t.Errorf("%v", err)
+	}
+	for _, f := range failures {
+		t.Errorf("%v", f)
+	}
+}
+
+// CheckCorpus parses each .css file of dir with Unmarshal and opts, and
+// compares the result with the case's expected output, held in the file of
+// the same name ending in .json instead. The expected output is what
+// encoding/json writes for the result of Unmarshal: an object mapping each
+// selector to an object mapping its properties to their values, as in
+//
+//	{"a:hover": {"color": "red", "margin": "0 auto !important"}}
+//
+// Each case is also checked after a round trip through Parse and Marshal,
+// so that values the printer cuts short or rewrites fail too, and without
+// the whitespace minifiers leave out, as in
+// ".a{color:red}@media(min-width:600px){.b{top:0}}", which must give the
+// same result. The failures are returned in the order of the names of the
+// cases. A .css file without a .json file, a parse error, or a panic fails
+// its case. A case with a file ending in .tokens instead is also checked
+// against the tokens ScanAll gives for it, written in that file as by
+// FormatTokens, with any line breaks between them. Likewise, a file ending in .out holds the text
+// Marshal writes for the case and one ending in .min the text it writes
+// with Minify, so that any change to the output shows in the golden files.
+// Each output is written twice and must come out the same both times.
+func CheckCorpus(dir string, opts ...Option) ([]CorpusFailure, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "*.css"))
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no .css files in corpus %s", dir)
+	}
+	sort.Strings(names)
+	var failures []CorpusFailure
+	for _, name := range names {
+		if msg, err := checkCase(name, opts); err != nil {
+			return failures, err
+		} else if msg != "" {
+			failures = append(failures, CorpusFailure{filepath.Base(name), msg})
+		}
+	}
+	return failures, nil
+}
+
+// checkCase checks the corpus case of the CSS file name, returning why it
+// fails or "".
+func checkCase(name string, opts []Option) (msg string, err error) {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(strings.TrimSuffix(name, ".css") + ".json")
+	if os.IsNotExist(err) {
+		return "no expected output", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var want map[Rule]map[string]string
+	if err := json.Unmarshal(data, &want); err != nil {
+		return "", fmt.Errorf("reading expected output of %s: %v", name, err)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			msg, err = fmt.Sprintf("panic: %v", r), nil
+		}
+	}()
+	if msg, err := checkTokens(name, src); msg != "" || err != nil {
+		return msg, err
+	}
+	opts = append([]Option{Filename(filepath.Base(name))}, opts...)
+	got, err := Unmarshal(src, opts...)
+	if err != nil {
+		return err.Error(), nil
+	}
+	if diff := diffStyles(got, want); diff != "" {
+		return diff, nil
+	}
+	if got, err = Unmarshal(stripSpace(src), opts...); err != nil {
+		return "without whitespace: " + err.Error(), nil
+	}
+	if diff := diffStyles(got, want); diff != "" {
+		return "without whitespace: " + diff, nil
+	}
+	sheet, err := Parse(src, opts...)
+	if err != nil {
+		return err.Error(), nil
+	}
+	out, err := Marshal(sheet)
+	if err != nil {
+		return "after Marshal: " + err.Error(), nil
+	}
+	for _, golden := range []struct {
+		ext  string
+		opts []MarshalOption
+	}{{".out", nil}, {".min", []MarshalOption{Minify()}}} {
+		if msg, err := checkOutput(name, golden.ext, sheet, golden.opts); msg != "" || err != nil {
+			return msg, err
+		}
+	}
+	if got, err = Unmarshal(out, opts...); err != nil {
+		return "after Marshal: " + err.Error(), nil
+	}
+	if diff := diffStyles(got, want); diff != "" {
+		return "after Marshal: " + diff, nil
+	}
+	return "", nil
+}
+
+// stripSpace returns the CSS text src without the whitespace minifiers
+// leave out: around '{', '}', ';', ',' and '!', after ':' and '(' and
+// before ')'. Strings, url() arguments, escapes and comments are kept as
+// they are, and so is whitespace that may be a descendant combinator.
+func stripSpace(src []byte) []byte {
+	s := string(src)
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		end := i + 1
+		switch {
+		case isSelectorSpace(c):
+			for end < len(s) && isSelectorSpace(s[end]) {
+				end++
+			}
+			out := b.String()
+			if out == "" || end == len(s) || strings.IndexByte("{};,!:(", out[len(out)-1]) >= 0 || strings.IndexByte("{};,!)", s[end]) >= 0 {
+				i = end
+				continue
+			}
+			b.WriteByte(' ')
+			i = end
+			continue
+		case c == '"' || c == '\'':
+			end = skipString(s, i)
+		case c == '\\':
+			end = skipEscape(s, i)
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			if j := strings.Index(s[i+2:], "*/"); j >= 0 {
+				end = i + j + 4
+			} else {
+				end = len(s)
+			}
+		case (c == 'u' || c == 'U') && len(s) > i+3 && strings.EqualFold(s[i:i+4], "url(") && (i == 0 || !isNameRune(rune(s[i-1]))):
+			_, end = parenthesized(s, i+3)
+		}
+		b.WriteString(s[i:end])
+		i = end
+	}
+	return []byte(b.String())
+}
+
+// checkTokens checks the tokens of src, the CSS file name, against the
+// case's .tokens file if it has one, returning why they differ or "".
+func checkTokens(name string, src []byte) (string, error) {
+	golden, err := os.ReadFile(strings.TrimSuffix(name, ".css") + ".tokens")
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	want, err := ParseTokens(string(golden))
+	if err != nil {
+		return "", fmt.Errorf("reading expected tokens of %s: %v", name, err)
+	}
+	got, err := ScanAll(bytes.NewReader(src))
+	if err != nil {
+		return err.Error(), nil
+	}
+	for i := 0; i < len(got) || i < len(want); i++ {
+		switch {
+		case i == len(got):
+			return fmt.Sprintf("tokens end before token %d, want %s", i+1, FormatTokens(want[i:i+1])), nil
+		case i == len(want):
+			return fmt.Sprintf("unexpected token %d %s at %v", i+1, FormatTokens(got[i:i+1]), got[i].Pos), nil
+		case got[i].Kind != want[i].Kind || got[i].Text != want[i].Text:
+			return fmt.Sprintf("token %d at %v is %s, want %s", i+1, got[i].Pos, FormatTokens(got[i:i+1]), FormatTokens(want[i:i+1])), nil
+		}
+	}
+	return "", nil
+}
+
+// checkOutput checks the text Marshal writes for sheet, the case of the CSS
+// file name, with opts against the case's file ending in ext if it has
+// one, returning why they differ or "".
+func checkOutput(name, ext string, sheet *StyleSheet, opts []MarshalOption) (string, error) {
+	out, err := Marshal(sheet, opts...)
+	if err != nil {
+		return fmt.Sprintf("%s: %v", ext, err), nil
+	}
+	if again, _ := Marshal(sheet, opts...); !bytes.Equal(out, again) {
+		return fmt.Sprintf("%s: output differs between two runs from line %d on", ext, 1+bytes.Count(out[:commonPrefix(out, again)], []byte("\n"))), nil
+	}
+	golden, err := os.ReadFile(strings.TrimSuffix(name, ".css") + ext)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Equal(out, golden) {
+		return fmt.Sprintf("%s: output differs from line %d on", ext, 1+bytes.Count(out[:commonPrefix(out, golden)], []byte("\n"))), nil
+	}
+	return "", nil
+}
+
+// diffStyles describes how the styles got differ from want, or returns ""
+// if they are the same.
+func diffStyles(got, want map[Rule]map[string]string) string {
+	var diffs []string
+	for sel, styles := range want {
+		if _, ok := got[sel]; !ok {
+			diffs = append(diffs, fmt.Sprintf("missing selector %q", sel))
+			continue
+		}
+		for p, v := range styles {
+			if g, ok := got[sel][p]; !ok {
+				diffs = append(diffs, fmt.Sprintf("%s: missing %s", sel, p))
+			} else if g != v {
+				diffs = append(diffs, fmt.Sprintf("%s: %s is %q, want %q", sel, p, g, v))
+			}
+		}
+		for p := range got[sel] {
+			if _, ok := styles[p]; !ok {
+				diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", sel, p))
+			}
+		}
+	}
+	for sel := range got {
+		if _, ok := want[sel]; !ok {
+			diffs = append(diffs, fmt.Sprintf("unexpected selector %q", sel))
+		}
+	}
+	sort.Strings(diffs)
+	return strings.Join(diffs, "; ")
+}
@@ -0,0 +1,72 @@
+package css
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCorpus(t *testing.T) {
+	RunCorpus(t, "testdata/corpus")
+}
+
+// TestV1Corpus pins the v1 semantics of Unmarshal; see its doc comment.
+func TestV1Corpus(t *testing.T) {
+	RunCorpus(t, "testdata/v1")
+}
+
+// TestCRLFCorpus checks that each case of testdata/corpus ending in -crlf,
+// which is saved with CRLF line endings, gives the same output and
+// positions as its LF twin.
+func TestCRLFCorpus(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("testdata", "corpus", "*-crlf.css"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("no -crlf cases: %v", err)
+	}
+	for _, name := range names {
+		crlf, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lf, err := os.ReadFile(strings.TrimSuffix(name, "-crlf.css") + ".css")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Contains(crlf, []byte("\r\n")) || !bytes.Equal(bytes.ReplaceAll(crlf, []byte("\r\n"), []byte("\n")), lf) {
+			t.Errorf("%s is not the CRLF twin of its LF case", name)
+			continue
+		}
+		want, got := parseLines(t, lf), parseLines(t, crlf)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s gives\n%s\nwant\n%s", name, strings.Join(got, "\n"), strings.Join(want, "\n"))
+		}
+	}
+}
+
+// parseLines returns the Marshal output of src followed by the line and
+// column of each selector and declaration.
+func parseLines(t *testing.T, src []byte) []string {
+	sheet, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Marshal(sheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := []string{string(out)}
+	Walk(sheet, func(n Node) bool {
+		switch n := n.(type) {
+		case *RuleNode:
+			lines = append(lines, fmt.Sprintf("%d:%d-%d:%d %s", n.Pos.Line, n.Pos.Column, n.Close.Line, n.Close.Column, n.Selectors))
+		case *Declaration:
+			lines = append(lines, fmt.Sprintf("%d:%d %s:%s", n.Pos.Line, n.Pos.Column, n.Property, n.Value))
+		}
+		return true
+	})
+	return lines
+}
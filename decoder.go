@@ -0,0 +1,113 @@
+package css
+
+import (
+	"bytes"
+	"text/scanner"
+)
+
+// Decoder parses a stylesheet that arrives in pieces, such as a file that
+// grows as styles are appended to it. Each call to More parses only the
+// top-level rules completed by the new input and keeps the rest for later,
+// so a rule split across calls comes out as if the input were parsed in one
+// piece, positions included. An error ends only the call that found it.
+// Checks that span rules, such as the duplicate selectors of Strict mode and
+// the MaxRules limit, apply to one call's rules at a time.
+type Decoder struct {
+	o     options
+	buf   []byte           // input not parsed yet
+	start scanner.Position // position of buf[0]
+	n     int              // bytes of buf scanned for complete rules
+	end   int              // bytes of buf holding complete rules
+
+	depth   int
+	comment bool
+	quote   byte
+	escape  bool
+	last    byte
+}
+
+// NewDecoder returns a Decoder parsing with opts.
+func NewDecoder(opts ...Option) *Decoder {
+	o := newOptions(opts)
+	return &Decoder{
+		o:     o,
+		start: scanner.Position{Filename: o.filename, Line: 1, Column: 1},
+	}
+}
+
+// More appends b to the input and returns the top-level rules and at-rules
+// it completes, along with the errors found in them.
+func (d *Decoder) More(b []byte) ([]Node, error) {
+	d.buf = append(d.buf, b...)
+	d.scan()
+	return d.parse(d.end)
+}
+
+// Close parses the rest of the input as the end of the stylesheet, where
+// unclosed blocks, comments and strings are reported.
+func (d *Decoder) Close() ([]Node, error) {
+	return d.parse(len(d.buf))
+}
+
+// scan moves end past the top-level statements completed in buf, skipping
+// the braces and semicolons of comments and strings.
+func (d *Decoder) scan() {
+	for ; d.n < len(d.buf); d.n++ {
+		c := d.buf[d.n]
+		switch {
+		case d.comment:
+			if d.last == '*' && c == '/' {
+				d.comment, c = false, 0
+			}
+		case d.escape:
+			d.escape = false
+		case d.quote != 0:
+			switch c {
+			case '\\':
+				d.escape = true
+			case d.quote, '\n', '\r':
+				d.quote = 0
+			}
+		case d.last == '/' && c == '*':
+			d.comment, c = true, 0
+		case c == '\\':
+			d.escape = true
+		case c == '"' || c == '\'':
+			d.quote = c
+		case c == '{':
+			d.depth++
+		case c == '}':
+			if d.depth > 0 {
+				d.depth--
+			}
+			if d.depth == 0 {
+				d.end = d.n + 1
+			}
+		case c == ';' && d.depth == 0:
+			d.end = d.n + 1
+		}
+		d.last = c
+	}
+}
+
+// parse parses the first end bytes of buf and drops them from it.
+func (d *Decoder) parse(end int) ([]Node, error) {
+	if end == 0 {
+		return nil, nil
+	}
+	o := d.o
+	o.start = d.start
+	sheet, err := parseReader(bytes.NewReader(d.buf[:end]), o.filename, o)
+	for i, c := range d.buf[:end] {
+		if c == '\r' {
+			if c = '\n'; i+1 < len(d.buf) && d.buf[i+1] == '\n' {
+				c = ' '
+			}
+		}
+		advancePosition(&d.start, c)
+	}
+	d.buf = append(d.buf[:0], d.buf[end:]...)
+	d.n -= end
+	d.end = 0
+	return sheet.Rules, err
+}
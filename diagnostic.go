@@ -0,0 +1,96 @@
+package css
+
+import (
+	"fmt"
+	"text/scanner"
+)
+
+// Severity ranks a Diagnostic.
+type Severity int
+
+// Diagnostic severities.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is a non-fatal issue found while parsing, such as input the
+// parser recovered from. Code is a stable identifier like "stray-semicolon".
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Pos      scanner.Position
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", d.Pos, d.Severity, d.Message, d.Code)
+}
+
+// Codes of the diagnostics reported by the parser.
+const (
+	DiagStraySemicolon = "stray-semicolon"
+	DiagUnclosedBlock  = "unclosed-block"
+	DiagInvalidInput   = "invalid-input"
+	// DiagUnterminatedString and DiagUnterminatedComment report a string
+	// closed at the end of its line and a comment closed at the end of
+	// input in Lenient mode.
+	DiagUnterminatedString  = "unterminated-string"
+	DiagUnterminatedComment = "unterminated-comment"
+	// DiagSkippedDeclaration and DiagSkippedRule report content dropped in
+	// Lenient mode.
+	DiagSkippedDeclaration = "skipped-declaration"
+	DiagSkippedRule        = "skipped-rule"
+	// DiagEmptySelector reports a selector list comma with nothing on one
+	// side, dropped in Lenient mode.
+	DiagEmptySelector = "empty-selector"
+	// DiagStrayDeclaration reports a declaration outside of any rule,
+	// skipped in Lenient mode and an error otherwise.
+	DiagStrayDeclaration = "stray-declaration"
+	// DiagMisplacedAtRule reports an @charset that is not first, or an
+	// @import or @namespace after rules, and DiagInvalidKeyframe a rule of
+	// @keyframes whose selector is not from, to or a percentage. They are
+	// errors in Strict mode, skipped in Lenient mode and kept otherwise.
+	DiagMisplacedAtRule = "misplaced-at-rule"
+	DiagInvalidKeyframe = "invalid-keyframe-selector"
+	// DiagInvalidAtRule reports an at-rule its AtRuleHandler failed to
+	// parse, in Lenient mode; it is an error otherwise.
+	DiagInvalidAtRule = "invalid-at-rule"
+	// DiagRawValue reports a declaration value kept as written in Lenient
+	// mode, which it could not read.
+	DiagRawValue = "raw-value"
+	// DiagImportEncoding reports, as information, a stylesheet inlined by
+	// ParseFS or ParseURL in another encoding than the one importing it.
+	DiagImportEncoding = "import-encoding"
+)
+
+// WithDiagnostics calls fn for every diagnostic reported while parsing, in
+// the order they are found. Without it diagnostics are discarded.
+func WithDiagnostics(fn func(Diagnostic)) Option {
+	return func(o *options) {
+		o.diagnostics = fn
+	}
+}
+
+func (o options) diagnose(severity Severity, code string, pos scanner.Position, format string, args ...interface{}) {
+	if o.diagnostics == nil {
+		return
+	}
+	o.diagnostics(Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+		Pos:      pos,
+	})
+}
@@ -0,0 +1,111 @@
+package css
+
+import (
+	"strings"
+	"text/scanner"
+)
+
+// CodeUnusedDirective is the code of the problem Lint reports for a
+// control comment that suppressed nothing.
+const CodeUnusedDirective = "unused-disable-directive"
+
+// directive is a control comment of Lint, as described for it.
+type directive struct {
+	kind  string
+	codes map[string]bool // nil for every code
+	pos   scanner.Position
+	end   scanner.Position // of the enable ending a range, zero if none
+	line  int              // the line a line directive applies to
+	used  bool
+}
+
+const directivePrefix = "css-lint-"
+
+// parseDirectives returns the control comments among comments, with the
+// ranges of disable directives ended by the enable directives matching
+// them.
+func parseDirectives(comments []Comment) []*directive {
+	var ds []*directive
+	for _, c := range comments {
+		text, _, _ := strings.Cut(c.Text, " -- ")
+		fields := strings.Fields(strings.ReplaceAll(text, ",", " "))
+		if len(fields) == 0 || !strings.HasPrefix(fields[0], directivePrefix) {
+			continue
+		}
+		d := &directive{kind: fields[0][len(directivePrefix):], pos: c.Pos}
+		if len(fields) > 1 {
+			d.codes = make(map[string]bool, len(fields)-1)
+			for _, code := range fields[1:] {
+				d.codes[code] = true
+			}
+		}
+		switch d.kind {
+		case "disable":
+		case "disable-line":
+			d.line = c.Pos.Line
+		case "disable-next-line":
+			d.line = c.Pos.Line + strings.Count(c.Text, "\n") + 1
+		case "enable":
+			for _, open := range ds {
+				if open.kind == "disable" && open.end.Line == 0 && open.pos.Filename == c.Pos.Filename && (d.codes == nil || sameCodes(open.codes, d.codes)) {
+					open.end = c.Pos
+				}
+			}
+			continue
+		default:
+			continue
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+func sameCodes(a, b map[string]bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for code := range a {
+		if !b[code] {
+			return false
+		}
+	}
+	return true
+}
+
+// suppresses reports whether d disables the problem p.
+func (d *directive) suppresses(p Problem) bool {
+	if p.Code == CodeUnusedDirective || d.codes != nil && !d.codes[p.Code] || p.Pos.Line == 0 || p.Pos.Filename != d.pos.Filename {
+		return false
+	}
+	if d.kind != "disable" {
+		return p.Pos.Line == d.line
+	}
+	return p.Pos.Offset >= d.pos.Offset && (d.end.Line == 0 || p.Pos.Offset < d.end.Offset)
+}
+
+// applyDirectives drops the problems of l suppressed by the control
+// comments of sheet and reports the directives that suppressed nothing.
+func (l *linter) applyDirectives(sheet *StyleSheet) {
+	ds := parseDirectives(sheet.Comments)
+	if len(ds) == 0 {
+		return
+	}
+	kept := l.problems[:0]
+	for _, p := range l.problems {
+		suppressed := false
+		for _, d := range ds {
+			if d.suppresses(p) {
+				d.used, suppressed = true, true
+			}
+		}
+		if !suppressed {
+			kept = append(kept, p)
+		}
+	}
+	l.problems = kept
+	for _, d := range ds {
+		if !d.used {
+			l.report(Problem{Code: CodeUnusedDirective, Pos: d.pos}, "%s%s directive suppressed no problem", directivePrefix, d.kind)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package css
+
+// DuplicatePolicy decides which declaration wins when a property is
+// declared more than once in a block, or in the rules of a selector merged
+// by Unmarshal. Under every policy an !important declaration wins over a
+// normal one.
+type DuplicatePolicy int
+
+// Duplicate policies for WithDuplicatePolicy.
+const (
+	// DuplicateLastWins lets the last declaration win, as browsers do.
+	// Parse keeps the earlier ones in the block, where they are the
+	// fallbacks of browsers that do not support the later value.
+	DuplicateLastWins DuplicatePolicy = iota
+	// DuplicateFirstWins lets the first declaration win. Parse drops the
+	// later ones.
+	DuplicateFirstWins
+	// DuplicateError makes a duplicate an error naming both declarations,
+	// or a DiagDuplicateProperty diagnostic in Lenient mode. The last
+	// declaration wins otherwise.
+	DuplicateError
+	// DuplicateKeepAll keeps every declaration in the blocks of Parse, as
+	// DuplicateLastWins does. Unmarshal returns a single value, the last;
+	// UnmarshalMulti returns them all under any policy.
+	DuplicateKeepAll
+)
+
+// DiagDuplicateProperty reports a property declared twice under
+// DuplicateError in Lenient mode.
+const DiagDuplicateProperty = "duplicate-property"
+
+// WithDuplicatePolicy sets how duplicate declarations are handled. The
+// default is DuplicateLastWins.
+func WithDuplicatePolicy(p DuplicatePolicy) Option {
+	return func(o *options) {
+		o.duplicates = p
+	}
+}
+
+// wins reports whether a declaration of importance important wins over an
+// earlier one of importance prev.
+func (p DuplicatePolicy) wins(important, prev bool) bool {
+	if important != prev {
+		return important
+	}
+	return p != DuplicateFirstWins
+}
+
+// add returns decls with d added under p, and the earlier declaration of
+// the property of d if there is one. Under DuplicateFirstWins, d is left
+// out or takes the place of an earlier normal declaration if it is
+// !important.
+func (p DuplicatePolicy) add(decls []Declaration, d Declaration) ([]Declaration, Declaration, bool) {
+	for i, prev := range decls {
+		switch {
+		case prev.Property != d.Property:
+			continue
+		case p != DuplicateFirstWins:
+		case !p.wins(d.Important, prev.Important):
+			return decls, prev, true
+		default:
+			decls = append(decls[:i], decls[i+1:]...)
+		}
+		return append(decls, d), prev, true
+	}
+	return append(decls, d), Declaration{}, false
+}
@@ -0,0 +1,214 @@
+package css
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrInvalidEncoding matches every *EncodingError with errors.Is.
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
+// EncodingError reports input that is not valid in its encoding. Parse
+// errors caused by it unwrap to it.
+type EncodingError struct {
+	// Encoding is "utf-8", "utf-16le" or "utf-16be".
+	Encoding string
+	// Offset is the offset in the input of the first bad byte.
+	Offset int64
+}
+
+func (e *EncodingError) Error() string {
+	return fmt.Sprintf("invalid %s at byte offset %d", e.Encoding, e.Offset)
+}
+
+func (e *EncodingError) Is(target error) bool {
+	return target == ErrInvalidEncoding
+}
+
+// decoder transcodes a stylesheet to UTF-8, as a browser decodes it: a
+// UTF-16 byte order mark selects UTF-16, then an @charset rule at the very
+// start may select windows-1252 by any of its labels, such as "iso-8859-1".
+// Anything else, including UTF-16 named by @charset, is read as UTF-8. A
+// UTF-8 byte order mark is passed through, a UTF-16 one is dropped.
+type decoder struct {
+	r      *bufio.Reader
+	next   func() (rune, int, error) // reads a rune and its size in the input
+	enc    string
+	offset int64  // of the next input byte
+	buf    []byte // decoded but not yet returned
+	err    error
+}
+
+func newDecoder(r io.Reader) *decoder {
+	return &decoder{r: bufio.NewReader(r)}
+}
+
+func (d *decoder) Read(p []byte) (int, error) {
+	if d.next == nil {
+		d.sniff()
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	if n == 0 && d.enc == "utf-8" {
+		if n = d.copyValid(p); n > 0 {
+			return n, nil
+		}
+	}
+	for n < len(p) && d.err == nil {
+		c, size, err := d.next()
+		if err != nil {
+			d.err = err
+			break
+		}
+		d.offset += int64(size)
+		if c < utf8.RuneSelf {
+			p[n] = byte(c)
+			n++
+			continue
+		}
+		var b [utf8.UTFMax]byte
+		m := utf8.EncodeRune(b[:], c)
+		k := copy(p[n:], b[:m])
+		d.buf = append(d.buf[:0], b[k:m]...)
+		n += k
+	}
+	if n == 0 && d.err != nil {
+		return 0, d.err
+	}
+	return n, nil
+}
+
+// sniff picks the encoding of the input.
+func (d *decoder) sniff() {
+	d.enc, d.next = "utf-8", d.utf8
+	switch b, _ := d.r.Peek(2); {
+	case bytes.Equal(b, []byte{0xFE, 0xFF}):
+		d.enc, d.next = "utf-16be", d.utf16
+	case bytes.Equal(b, []byte{0xFF, 0xFE}):
+		d.enc, d.next = "utf-16le", d.utf16
+	default:
+		if windows1252Labels[charsetLabel(d.r)] {
+			d.enc, d.next = "windows-1252", d.windows1252
+		}
+		return
+	}
+	d.r.Discard(2)
+	d.offset = 2
+}
+
+// encodingOf returns the encoding the decoder reads b in.
+func encodingOf(b []byte) string {
+	d := newDecoder(bytes.NewReader(b))
+	d.sniff()
+	return d.enc
+}
+
+// charsetLabel returns the lowercased label of the @charset rule that r
+// starts with, or "".
+func charsetLabel(r *bufio.Reader) string {
+	const prefix = `@charset "`
+	b, _ := r.Peek(1024)
+	if !bytes.HasPrefix(b, []byte(prefix)) {
+		return ""
+	}
+	label, _, ok := strings.Cut(string(b[len(prefix):]), `";`)
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(asciiLower(label))
+}
+
+// copyValid moves the valid UTF-8 at the start of the buffered input to p,
+// which is quicker than decoding it rune by rune.
+func (d *decoder) copyValid(p []byte) int {
+	if d.r.Buffered() == 0 {
+		d.r.Peek(1)
+	}
+	n := d.r.Buffered()
+	if n > len(p) {
+		n = len(p)
+	}
+	b, _ := d.r.Peek(n)
+	if !utf8.Valid(b) {
+		for n = 0; n < len(b); {
+			c, size := utf8.DecodeRune(b[n:])
+			if c == utf8.RuneError && size == 1 {
+				break
+			}
+			n += size
+		}
+	}
+	copy(p, b[:n])
+	d.r.Discard(n)
+	d.offset += int64(n)
+	return n
+}
+
+func (d *decoder) utf8() (rune, int, error) {
+	c, size, err := d.r.ReadRune()
+	if err == nil && c == utf8.RuneError && size == 1 {
+		return 0, 0, &EncodingError{Encoding: d.enc, Offset: d.offset}
+	}
+	return c, size, err
+}
+
+func (d *decoder) utf16() (rune, int, error) {
+	c, err := d.unit()
+	if err != nil || !utf16.IsSurrogate(c) {
+		return c, 2, err
+	}
+	if c < 0xDC00 {
+		if low, err := d.unit(); err == nil && low >= 0xDC00 && low <= 0xDFFF {
+			return utf16.DecodeRune(c, low), 4, nil
+		}
+	}
+	return 0, 0, &EncodingError{Encoding: d.enc, Offset: d.offset}
+}
+
+// unit reads a UTF-16 code unit.
+func (d *decoder) unit() (rune, error) {
+	var b [2]byte
+	n, err := io.ReadFull(d.r, b[:])
+	switch {
+	case n == 1:
+		return 0, &EncodingError{Encoding: d.enc, Offset: d.offset}
+	case err != nil:
+		return 0, err
+	case d.enc == "utf-16be":
+		return rune(b[0])<<8 | rune(b[1]), nil
+	}
+	return rune(b[1])<<8 | rune(b[0]), nil
+}
+
+func (d *decoder) windows1252() (rune, int, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, err
+	}
+	if c >= 0x80 && c < 0xA0 {
+		return windows1252[c-0x80], 1, nil
+	}
+	return rune(c), 1, nil
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of windows-1252; the others are
+// those of Latin-1.
+var windows1252 = [32]rune{
+	0x20AC, 0x0081, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0x008D, 0x017D, 0x008F,
+	0x0090, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0x009D, 0x017E, 0x0178,
+}
+
+var windows1252Labels = map[string]bool{
+	"ansi_x3.4-1968": true, "ascii": true, "cp1252": true, "cp819": true,
+	"csisolatin1": true, "ibm819": true, "iso-8859-1": true, "iso-ir-100": true,
+	"iso8859-1": true, "iso88591": true, "iso_8859-1": true, "iso_8859-1:1987": true,
+	"l1": true, "latin1": true, "us-ascii": true, "windows-1252": true, "x-cp1252": true,
+}
@@ -0,0 +1,311 @@
+package css
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/scanner"
+)
+
+// Categories of the errors the parser returns, which errors.Is tells
+// apart: a syntax error stays one however often the input is read again,
+// while a failure to fetch an import may not. ErrEncoding and ErrLimit are
+// ErrInvalidEncoding and ErrLimitExceeded.
+var (
+	// ErrSyntax matches a *ParseError for malformed input.
+	ErrSyntax = errors.New("syntax error")
+	// ErrEncoding matches an *EncodingError and the parse errors it causes.
+	ErrEncoding = ErrInvalidEncoding
+	// ErrLimit matches a *LimitError and the parse errors it causes.
+	ErrLimit = ErrLimitExceeded
+	// ErrResolve matches a *ResolveError and the parse errors it causes.
+	ErrResolve = errors.New("cannot resolve import")
+)
+
+// ParseError is the error returned for malformed input, and for other
+// failures at a position in it, such as an exceeded limit, which it
+// unwraps to as Err. Its Error text is "file: line N: msg", or "line N:
+// msg" for unnamed sources.
+type ParseError struct {
+	Pos scanner.Position
+	// Token is the text of the offending token, if any.
+	Token string
+	// Expected lists the kinds of token that would have been accepted.
+	Expected []tokenType
+	Msg      string
+	// Err is the underlying error, such as a failure reading the input.
+	Err error
+	// Snippet is the source line of Pos and a line with a caret under its
+	// column, each with the line number in a margin, if the error was made
+	// with WithVerboseErrors.
+	Snippet string
+	// Resume is the position just past the last top-level rule or at-rule
+	// read in full before the error, or the start of the input, and
+	// ParsedRules is the number of those, which the StyleSheet returned
+	// with the error holds in full. They are set on the error parsing
+	// stops at, unless WithErrorLimit lets it go on, so that the rest of
+	// the input can be parsed from Resume.Offset on, as with Lenient and
+	// StartAt(Resume).
+	Resume      scanner.Position
+	ParsedRules int
+	verbose     bool // the Error text names Expected
+}
+
+func (e *ParseError) Error() string {
+	msg := e.Msg
+	if want := describeExpected(e.Expected, e.Token); e.verbose && want != "" {
+		msg += "; expected " + want
+	}
+	if e.Snippet != "" {
+		msg += "\n" + e.Snippet
+	}
+	if e.Pos.Filename != "" {
+		return fmt.Sprintf("%s: line %d: %s", e.Pos.Filename, e.Pos.Line, msg)
+	}
+	return fmt.Sprintf("line %d: %s", e.Pos.Line, msg)
+}
+
+// annotate makes e name what was expected and quote the line of src, the
+// input read from start, it is at.
+func (e *ParseError) annotate(src []byte, start scanner.Position) {
+	e.verbose = true
+	off := e.Pos.Offset
+	if start.IsValid() {
+		off -= start.Offset
+	}
+	if !e.Pos.IsValid() || off < 0 || off > len(src) {
+		return
+	}
+	begin := bytes.LastIndexByte(src[:off], '\n') + 1
+	end := bytes.IndexByte(src[off:], '\n')
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += off
+	}
+	line := strings.TrimRight(string(src[begin:end]), "\r")
+	var caret strings.Builder
+	n := e.Pos.Column - 1
+	for _, c := range line {
+		if n--; n < 0 {
+			break
+		}
+		if c == '\t' {
+			caret.WriteByte('\t')
+		} else {
+			caret.WriteByte(' ')
+		}
+	}
+	num := strconv.Itoa(e.Pos.Line)
+	margin := strings.Repeat(" ", len(num))
+	e.Snippet = fmt.Sprintf("  %s | %s\n  %s | %s^", num, line, margin, caret.String())
+}
+
+// annotateErrors annotates the parse errors err is or holds.
+func annotateErrors(err error, src []byte, start scanner.Position) {
+	switch err := err.(type) {
+	case *ParseError:
+		err.annotate(src, start)
+	case ErrorList:
+		for _, e := range err {
+			e.annotate(src, start)
+		}
+	}
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrSyntax and e is for malformed input,
+// that is, has no Err.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrSyntax && e.Err == nil
+}
+
+// ErrorList is the error returned when the parser is set to continue past
+// errors with WithErrorLimit. It holds every error found, in source order.
+type ErrorList []*ParseError
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", l[0], len(l)-1)
+}
+
+// Unwrap returns the individual errors.
+func (l ErrorList) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, e := range l {
+		errs[i] = e
+	}
+	return errs
+}
+
+// err returns the error to report for l: nil if it is empty, the single
+// error when the parser stops at the first one, or l itself.
+func (l ErrorList) err(o options) error {
+	switch {
+	case len(l) == 0:
+		return nil
+	case !o.collectErrors:
+		return l[0]
+	}
+	return l
+}
+
+// ErrLimitExceeded matches every *LimitError with errors.Is.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Names of the limits reported in LimitError.
+const (
+	LimitInputBytes     = "input-bytes"
+	LimitRules          = "rules"
+	LimitDeclarations   = "declarations"
+	LimitSelectorLength = "selector-length"
+	LimitNestingDepth   = "nesting-depth"
+	LimitImportDepth    = "import-depth"
+	LimitImportBytes    = "import-bytes"
+	LimitFetches        = "fetches"
+)
+
+// LimitError reports that input exceeded a resource limit set by an
+// option. Parse errors caused by it unwrap to it.
+type LimitError struct {
+	// Limit names the limit that tripped, such as LimitRules.
+	Limit string
+	Max   int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// ResolveError reports that an @import could not be followed: its
+// stylesheet failed to load, or imports the stylesheet importing it.
+// Parse errors caused by it unwrap to it.
+type ResolveError struct {
+	// URL is the URL of the @import, as written.
+	URL string
+	// Err is the error loading the stylesheet, or errImportCycle.
+	Err error
+}
+
+var errImportCycle = errors.New("import cycle")
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("@import %q: %v", e.URL, e.Err)
+}
+
+func (e *ResolveError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ResolveError) Is(target error) bool {
+	return target == ErrResolve
+}
+
+func limitExceeded(pos scanner.Position, limit string, max int64) error {
+	err := &LimitError{Limit: limit, Max: max}
+	return &ParseError{Pos: pos, Msg: err.Error(), Err: err}
+}
+
+func errorAt(pos scanner.Position, format string, args ...interface{}) error {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func unexpectedToken(token tokenEntry, expected []tokenType) error {
+	text := token.text()
+	return &ParseError{
+		Pos:      token.pos,
+		Token:    text,
+		Expected: expected,
+		Msg:      fmt.Sprintf("unexpected token %s", text),
+	}
+}
+
+// describeExpected names the kinds of token in list, as returned by
+// expected, in words, leaving out the punctuation token itself.
+func describeExpected(list []tokenType, token string) string {
+	has := func(t tokenType) bool {
+		for _, u := range list {
+			if u == t {
+				return true
+			}
+		}
+		return false
+	}
+	var words []string
+	for _, t := range list {
+		if t == newTokenType(token) && t != tokenValue && t != tokenSelector {
+			continue
+		}
+		switch t {
+		case tokenValue:
+			switch {
+			case has(tokenSelector) || has(tokenBlockStart):
+				// Part of a selector.
+			case has(tokenBlockEnd):
+				words = append(words, "a property")
+			default:
+				words = append(words, "a value")
+			}
+		case tokenSelector:
+			words = append(words, "a selector")
+		case tokenAtKeyword:
+			words = append(words, "an at-rule")
+		case tokenPrelude:
+			words = append(words, "an at-rule prelude")
+		case tokenRuleName:
+			words = append(words, "a rule name")
+		case tokenBlockStart:
+			words = append(words, "'{'")
+		case tokenBlockEnd:
+			words = append(words, "'}'")
+		case tokenStyleSeparator:
+			words = append(words, "':'")
+		case tokenStatementEnd:
+			words = append(words, "';'")
+		}
+	}
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
+}
+
+// expected returns the kinds of token the parser accepts after prev.
+// inBlock is set inside a declaration block and property once a property
+// name awaits its ':'.
+func expected(prev tokenType, inBlock, property bool) []tokenType {
+	switch prev {
+	case tokenAtKeyword:
+		return []tokenType{tokenPrelude}
+	case tokenPrelude:
+		return []tokenType{tokenBlockStart, tokenStatementEnd}
+	case tokenSelector, tokenStyleSeparator:
+		return []tokenType{tokenValue}
+	case tokenValue:
+		switch {
+		case !inBlock:
+			return []tokenType{tokenValue, tokenSelector, tokenBlockStart}
+		case property:
+			return []tokenType{tokenStyleSeparator}
+		}
+		return []tokenType{tokenStatementEnd, tokenBlockEnd}
+	}
+	if inBlock {
+		return []tokenType{tokenValue, tokenBlockEnd}
+	}
+	return []tokenType{tokenValue, tokenSelector, tokenAtKeyword, tokenBlockEnd}
+}
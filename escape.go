@@ -0,0 +1,64 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapeIdent returns s escaped as a CSS identifier, as CSS.escape does in
+// browsers, so that "." + EscapeIdent("foo:bar") is a class selector for
+// the class foo:bar. A NUL or invalid UTF-8 becomes U+FFFD.
+func EscapeIdent(s string) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch {
+		case c == 0 || c == utf8.RuneError:
+			b.WriteRune(utf8.RuneError)
+		case c < 0x20 || c == 0x7f,
+			i == 0 && c >= '0' && c <= '9',
+			i == 1 && c >= '0' && c <= '9' && s[0] == '-':
+			escapeCodePoint(&b, c)
+		case i == 0 && c == '-' && len(s) == 1:
+			b.WriteString(`\-`)
+		case c >= 0x80 || c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			b.WriteRune(c)
+		default:
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
+// QuoteString returns s as a double-quoted CSS string, escaped as CSSOM
+// serializes strings: quotes and backslashes are escaped with a backslash,
+// control characters, newlines included, as code points, and a NUL or
+// invalid UTF-8 becomes U+FFFD.
+func QuoteString(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, c := range s {
+		switch {
+		case c == 0 || c == utf8.RuneError:
+			b.WriteRune(utf8.RuneError)
+		case c < 0x20 || c == 0x7f:
+			escapeCodePoint(&b, c)
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(c)
+		default:
+			b.WriteRune(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// escapeCodePoint writes c as a hex escape, ended by a space.
+func escapeCodePoint(b *strings.Builder, c rune) {
+	b.WriteByte('\\')
+	b.WriteString(strconv.FormatInt(int64(c), 16))
+	b.WriteByte(' ')
+}
@@ -0,0 +1,67 @@
+package css
+
+import "testing"
+
+// TestEscapeRoundTrip checks that the identifiers EscapeIdent escapes and
+// the strings QuoteString quotes parse back as what they were escaped
+// from, before and after Marshal writes them out again.
+func TestEscapeRoundTrip(t *testing.T) {
+	idents := []string{
+		"1a", "-1a", "-", "--", "--x", "_", "foo:bar", "a.b#c", "a b",
+		`a"b`, "a'b", `a\b`, "a\nb", "a\tb", "a\x01b", "\x7f", "é😀",
+	}
+	for _, name := range idents {
+		src := []byte("." + EscapeIdent(name) + ", #" + EscapeIdent(name) + " { color: red; }")
+		sheet, err := Parse(src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", src, err)
+			continue
+		}
+		for pass := 0; pass < 2; pass++ {
+			if got := ComputeStyle(sheet, ElementDesc{Classes: []string{name}})["color"]; got != "red" {
+				t.Errorf("%q does not match the class %q (pass %d)", src, name, pass)
+			}
+			if got := ComputeStyle(sheet, ElementDesc{ID: name})["color"]; got != "red" {
+				t.Errorf("%q does not match the ID %q (pass %d)", src, name, pass)
+			}
+			out, err := Marshal(sheet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sheet, err = Parse(out); err != nil {
+				t.Errorf("Parse(%q): %v", out, err)
+				break
+			}
+		}
+	}
+	strs := []string{
+		"", `say "hi"`, "it's", `a\b`, "*/", "/*", "line\nbreak", "a\r\nb",
+		"tab\t", "\x01", "\x7f", ";", "é😀",
+	}
+	for _, s := range strs {
+		src := []byte(".x { content: " + QuoteString(s) + "; }")
+		sheet, err := Parse(src)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", src, err)
+			continue
+		}
+		for pass := 0; pass < 2; pass++ {
+			decls := sheet.Rules[0].(*RuleNode).Declarations
+			if len(decls) != 1 {
+				t.Errorf("%q parses as %v (pass %d)", src, decls, pass)
+				break
+			}
+			if got, q := unquote(decls[0].Value); q == 0 || got != s {
+				t.Errorf("%q reads back as %q (pass %d), want %q", src, decls[0].Value, pass, s)
+			}
+			out, err := Marshal(sheet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sheet, err = Parse(out); err != nil {
+				t.Errorf("Parse(%q): %v", out, err)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explain describes how the cascade picks the value of property prop for
+// el, as ComputeStyle does: it lists the declarations of prop that apply
+// to el, as Trace returns them, one per line with its selector, value,
+// specificity and position, and says which one wins and why each other
+// one loses to it. For example:
+//
+//	color of p#intro.note:
+//	  1. .note { color: blue !important } (0,1,0) at main.css:1:9: wins
+//	  2. #intro { color: red } (1,0,0) at main.css:3:13: loses to 1 on importance
+//	  3. p { color: green } (0,0,1) at main.css:2:5: loses to 1 on importance
+//
+// The output depends only on its arguments, and ends with a newline.
+func Explain(sheet *StyleSheet, el ElementDesc, prop string) string {
+	return explain(Trace(sheet, el, prop), el, prop)
+}
+
+// Explain is like the function Explain for the sheet of m.
+func (m *Matcher) Explain(el ElementDesc, prop string) string {
+	return explain(m.Trace(el, prop), el, prop)
+}
+
+func explain(trace []Candidate, el ElementDesc, prop string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s of %s:", prop, describeElement(el))
+	if len(trace) == 0 {
+		b.WriteString(" no declarations\n")
+		return b.String()
+	}
+	b.WriteByte('\n')
+	for i, c := range trace {
+		d := c.Declaration
+		value := d.Value
+		if d.Important {
+			value += " !important"
+		}
+		fmt.Fprintf(&b, "  %d. %s { %s: %s } %s at %s: ", i+1, c.Selector, d.Property, value, c.Specificity, d.Pos)
+		switch w := trace[0]; {
+		case i == 0:
+			b.WriteString("wins")
+		case w.Declaration.Important != d.Important:
+			b.WriteString("loses to 1 on importance")
+		case compareRanks(w.layer, c.layer) != 0:
+			b.WriteString("loses to 1 on layer order")
+		case w.Specificity != c.Specificity:
+			b.WriteString("loses to 1 on specificity")
+		default:
+			b.WriteString("loses to 1 on source order")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// describeElement returns el as a compound selector, such as
+// "p#intro.note", or "*" if el has nothing to match.
+func describeElement(el ElementDesc) string {
+	s := el.Tag
+	if el.ID != "" {
+		s += "#" + el.ID
+	}
+	for _, c := range el.Classes {
+		s += "." + c
+	}
+	if s == "" {
+		return "*"
+	}
+	return s
+}
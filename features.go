@@ -0,0 +1,374 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+)
+
+// Names of the selector features found by the syntax of a selector rather
+// than by a pseudo-class or pseudo-element name.
+const (
+	FeatureGeneralSibling = "general-sibling"     // a ~ b
+	FeatureCaseFlag       = "attribute-case-flag" // [type="a" i]
+	FeatureNotList        = ":not(<list>)"        // :not(.a, .b > c)
+	FeatureNthOf          = ":nth-child(of S)"    // :nth-child(2 of .a)
+	FeatureNesting        = "nesting"             // & and nested rules
+)
+
+// selectorFeatures is the table of selector features, oldest first, with
+// the pseudo-classes and pseudo-elements, lower-cased, that use them.
+var selectorFeatures = []struct {
+	name    string
+	pseudos []string
+}{
+	{FeatureGeneralSibling, nil},
+	{":not()", []string{":not"}},
+	{"level-3-pseudo-classes", []string{
+		":root", ":nth-child", ":nth-last-child", ":nth-of-type", ":nth-last-of-type",
+		":first-of-type", ":last-of-type", ":only-of-type", ":only-child", ":empty",
+		":target", ":enabled", ":disabled", ":checked",
+	}},
+	{FeatureCaseFlag, nil},
+	{":focus-within", []string{":focus-within"}},
+	{":host", []string{":host", ":host-context"}},
+	{"::slotted", []string{"::slotted"}},
+	{"::part", []string{"::part"}},
+	{":is()", []string{":is", ":matches"}},
+	{":where()", []string{":where"}},
+	{FeatureNotList, nil},
+	{":focus-visible", []string{":focus-visible"}},
+	{FeatureNthOf, nil},
+	{":has()", []string{":has"}},
+	{FeatureNesting, nil},
+	{":user-valid", []string{":user-valid", ":user-invalid"}},
+	{":popover-open", []string{":popover-open"}},
+	{":state()", []string{":state"}},
+}
+
+var (
+	featureRank     = make(map[string]int)
+	featureByPseudo = make(map[string]string)
+)
+
+func init() {
+	for i, f := range selectorFeatures {
+		featureRank[f.name] = i
+		for _, p := range f.pseudos {
+			featureByPseudo[p] = f.name
+		}
+	}
+}
+
+// SelectorFeatures returns the names of the selector features identified by
+// Features and UsedSelectorFeatures, oldest first, roughly in the order
+// browsers shipped them. Which of them a set of browsers supports is left
+// to the caller.
+func SelectorFeatures() []string {
+	names := make([]string, len(selectorFeatures))
+	for i, f := range selectorFeatures {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Features returns the selector features sel uses, oldest first. Like
+// Uses, it reads the arguments of :is(), :not(), :has() and the like, and
+// skips strings, so that [title=":has(a)"] uses none. A nesting selector &
+// is reported as FeatureNesting; a rule nested in another without one is
+// only known to be nested by UsedSelectorFeatures.
+func (sel Rule) Features() []string {
+	used := make(map[string]bool)
+	selectorFeatureSet(string(sel), used)
+	var names []string
+	for _, f := range selectorFeatures {
+		if used[f.name] {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// FeatureUse is a selector using a selector feature, as reported by
+// UsedSelectorFeatures.
+type FeatureUse struct {
+	// Feature is the newest feature Selector uses.
+	Feature  string
+	Selector Rule
+	Rule     *RuleNode
+	Pos      scanner.Position // of Rule
+}
+
+// UsedSelectorFeatures buckets each selector of sheet, at any depth, by
+// the newest of the SelectorFeatures it uses, and returns the selectors of
+// each feature in source order. Selectors are read as written, with
+// nested rules using FeatureNesting; selectors using no feature of the
+// table and those of @keyframes are left out.
+func UsedSelectorFeatures(sheet *StyleSheet) map[string][]FeatureUse {
+	uses := make(map[string][]FeatureUse)
+	collectFeatures(uses, sheet.Rules, false)
+	return uses
+}
+
+func collectFeatures(uses map[string][]FeatureUse, nodes []Node, nested bool) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			for _, sel := range n.Selectors {
+				used := map[string]bool{FeatureNesting: nested}
+				selectorFeatureSet(string(sel), used)
+				newest := -1
+				for name, ok := range used {
+					if r := featureRank[name]; ok && r > newest {
+						newest = r
+					}
+				}
+				if newest >= 0 {
+					name := selectorFeatures[newest].name
+					uses[name] = append(uses[name], FeatureUse{Feature: name, Selector: sel, Rule: n, Pos: n.Pos})
+				}
+			}
+			collectFeatures(uses, n.Rules, true)
+		case *AtRule:
+			if name, _ := Canonical(asciiLower(n.Name)); name != "keyframes" {
+				collectFeatures(uses, n.Rules, nested)
+			}
+		}
+	}
+}
+
+// SelectorFeaturesNewerThan returns the selectors of sheet whose newest
+// feature, as bucketed by UsedSelectorFeatures, comes after feature in
+// SelectorFeatures, in source order.
+func SelectorFeaturesNewerThan(sheet *StyleSheet, feature string) ([]FeatureUse, error) {
+	rank, ok := featureRank[feature]
+	if !ok {
+		return nil, fmt.Errorf("unknown selector feature %q", feature)
+	}
+	uses := UsedSelectorFeatures(sheet)
+	var found []FeatureUse
+	for _, f := range selectorFeatures[rank+1:] {
+		found = append(found, uses[f.name]...)
+	}
+	sortFeatureUses(found)
+	return found, nil
+}
+
+// sortFeatureUses sorts uses by position, keeping the order of uses at
+// the same position, such as the selectors of one rule.
+func sortFeatureUses(uses []FeatureUse) {
+	for i := 1; i < len(uses); i++ {
+		for j := i; j > 0 && uses[j].Pos.Offset < uses[j-1].Pos.Offset; j-- {
+			uses[j], uses[j-1] = uses[j-1], uses[j]
+		}
+	}
+}
+
+// selectorFeatureSet adds the features the selector sel uses to used.
+func selectorFeatureSet(sel string, used map[string]bool) {
+	for i := 0; i < len(sel); {
+		c := sel[i]
+		switch {
+		case c == '[':
+			end := skipBracket(sel, i)
+			if hasCaseFlag(sel[i+1 : end]) {
+				used[FeatureCaseFlag] = true
+			}
+			i = end
+		case c == ':':
+			start := i
+			if i++; i < len(sel) && sel[i] == ':' {
+				i++
+			}
+			end := skipName(sel, i)
+			pseudo := sel[start:i] + strings.ToLower(sel[i:end])
+			i = end
+			if name, ok := featureByPseudo[pseudo]; ok {
+				used[name] = true
+			}
+			if i < len(sel) && sel[i] == '(' {
+				var args string
+				args, i = parenthesized(sel, i)
+				switch pseudo {
+				case ":not":
+					if !isCompound(args) {
+						used[FeatureNotList] = true
+					}
+					selectorFeatureSet(args, used)
+				case ":is", ":where", ":has", ":matches", ":host", ":host-context", "::slotted":
+					selectorFeatureSet(args, used)
+				case ":nth-child", ":nth-last-child":
+					if j := strings.Index(strings.ToLower(args), " of "); j >= 0 {
+						used[FeatureNthOf] = true
+						selectorFeatureSet(args[j+4:], used)
+					}
+				}
+			}
+		case c == '~':
+			used[FeatureGeneralSibling] = true
+			i++
+		case c == '&':
+			used[FeatureNesting] = true
+			i++
+		case c == '"' || c == '\'':
+			i = skipString(sel, i)
+		case isNameRune(rune(c)) || c == '\\':
+			i = skipName(sel, i)
+		default:
+			i++
+		}
+	}
+}
+
+// hasCaseFlag reports whether the text s of an attribute selector, between
+// its brackets, ends with an i or s flag after the value.
+func hasCaseFlag(s string) bool {
+	s = strings.TrimSuffix(s, "]")
+	eq := strings.IndexByte(s, '=')
+	if eq < 0 {
+		return false
+	}
+	v := strings.TrimLeft(s[eq+1:], " \t\n\r\f")
+	end := skipName(v, 0)
+	if v != "" && (v[0] == '"' || v[0] == '\'') {
+		end = skipString(v, 0)
+	}
+	flag := strings.TrimSpace(v[end:])
+	return strings.EqualFold(flag, "i") || strings.EqualFold(flag, "s")
+}
+
+// isCompound reports whether sel is a single compound selector, without a
+// comma or combinator outside its brackets and strings.
+func isCompound(sel string) bool {
+	sel = strings.TrimSpace(sel)
+	for i := 0; i < len(sel); {
+		switch c := sel[i]; c {
+		case ',', ' ', '\t', '\n', '\r', '\f', '>', '+', '~':
+			return false
+		case '[':
+			i = skipBracket(sel, i)
+		case '(':
+			_, i = parenthesized(sel, i)
+		case '"', '\'':
+			i = skipString(sel, i)
+		case '\\':
+			i += 2
+		default:
+			i++
+		}
+	}
+	return true
+}
+
+// groupBreaking lists the features of SelectorFeatures that browsers not
+// supporting them drop the whole selector list for, and which
+// IsFragileSelector reports.
+var groupBreaking = map[string]bool{
+	":focus-within": true, ":host": true, "::slotted": true, "::part": true,
+	":is()": true, ":where()": true, FeatureNotList: true, ":focus-visible": true,
+	FeatureNthOf: true, ":has()": true, ":user-valid": true, ":popover-open": true,
+	":state()": true,
+}
+
+// IsFragileSelector reports whether the selector sel uses a selector
+// feature recent enough that browsers without it drop any selector list
+// holding sel, such as :has() or ::part, or a vendor-prefixed
+// pseudo-class or pseudo-element, such as ::-moz-selection, which other
+// browsers drop lists for too.
+func IsFragileSelector(sel string) bool {
+	for _, f := range Rule(sel).Features() {
+		if groupBreaking[f] {
+			return true
+		}
+	}
+	return hasVendorPseudo(sel)
+}
+
+// hasVendorPseudo reports whether sel has a pseudo-class or pseudo-element
+// with a vendor prefix, outside its strings and attribute selectors.
+func hasVendorPseudo(sel string) bool {
+	for i := 0; i < len(sel); {
+		switch c := sel[i]; c {
+		case ':':
+			if i++; i < len(sel) && sel[i] == ':' {
+				i++
+			}
+			if i+1 < len(sel) && sel[i] == '-' && sel[i+1] != '-' {
+				return true
+			}
+		case '[':
+			i = skipBracket(sel, i)
+		case '"', '\'':
+			i = skipString(sel, i)
+		case '\\':
+			i += 2
+		default:
+			i++
+		}
+	}
+	return false
+}
+
+// IsolateFragileSelectors returns a copy of sheet in which each rule with a
+// selector list holding a selector fragile reports is split, so that a
+// browser dropping that selector keeps the others: the selectors fragile
+// does not report stay grouped in a rule first, followed by a rule for
+// each fragile one, in source order, all with the declarations and nested
+// rules of the original. Since the rules are adjacent and carry the same
+// declarations, the cascade is unchanged. Rules without a fragile selector,
+// or with a single selector, are left grouped. A nil fragile is
+// IsFragileSelector.
+func IsolateFragileSelectors(sheet *StyleSheet, fragile func(sel string) bool) *StyleSheet {
+	if fragile == nil {
+		fragile = IsFragileSelector
+	}
+	out := sheet.Clone()
+	out.Rules = isolateFragile(out.Rules, fragile)
+	return out
+}
+
+func isolateFragile(nodes []Node, fragile func(string) bool) []Node {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			n.Rules = isolateFragile(n.Rules, fragile)
+			var safe, isolated []Rule
+			for _, sel := range n.Selectors {
+				if len(n.Selectors) > 1 && fragile(string(sel)) {
+					isolated = append(isolated, sel)
+				} else {
+					safe = append(safe, sel)
+				}
+			}
+			if len(isolated) == 0 {
+				break
+			}
+			if len(safe) > 0 {
+				r := *n
+				r.Selectors = safe
+				out = append(out, &r)
+			}
+			for i, sel := range isolated {
+				r := *n
+				r.Selectors = []Rule{sel}
+				if i > 0 || len(safe) > 0 {
+					r.Comments = nil
+					r.Declarations = cloneDeclarations(n.Declarations)
+					r.Rules = cloneNodes(n.Rules)
+				}
+				out = append(out, &r)
+			}
+			continue
+		case *AtRule:
+			if name, _ := Canonical(asciiLower(n.Name)); name != "keyframes" {
+				n.Rules = isolateFragile(n.Rules, fragile)
+			}
+		}
+		out = append(out, n)
+	}
+	return out
+}
@@ -0,0 +1,126 @@
+package css
+
+// FilterOption configures FilterProperties.
+type FilterOption func(*filterOptions)
+
+type filterOptions struct {
+	extract bool
+}
+
+// ExtractLonghands makes FilterProperties replace a shorthand that is not
+// allowed with those of its longhands that are, as expanded the way Get
+// expands it, rather than drop it whole: with only background-color
+// allowed, "background: red url(a.png)" gives "background-color: red".
+// An allowed intermediate shorthand, such as border-top for border, is
+// kept in place of its own longhands. Shorthands that cannot be split,
+// such as those holding var(), are dropped.
+func ExtractLonghands() FilterOption {
+	return func(o *filterOptions) {
+		o.extract = true
+	}
+}
+
+// Dropped is a declaration FilterProperties removed.
+type Dropped struct {
+	Declaration Declaration
+	// Selector is the selector list of the rule holding the declaration.
+	Selector string
+	// Longhands lists the allowed longhands ExtractLonghands put in place
+	// of the declaration, if any.
+	Longhands []string
+}
+
+// FilterProperties returns a copy of sheet without the declarations of
+// rules whose property allowed does not accept, along with what it
+// dropped, in source order. Rules and grouping at-rules, such as @media,
+// left empty by it are removed; those that were empty already are kept.
+// The descriptors of at-rules such as @font-face are not properties and
+// are kept too.
+func FilterProperties(sheet *StyleSheet, allowed func(prop string) bool, opts ...FilterOption) (*StyleSheet, []Dropped) {
+	var o filterOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	f := &propertyFilter{allowed: allowed, opts: o}
+	out := sheet.Clone()
+	out.Rules = f.nodes(out.Rules)
+	out.Layers = declaredLayers(out.Rules)
+	return out, f.dropped
+}
+
+type propertyFilter struct {
+	allowed func(string) bool
+	opts    filterOptions
+	dropped []Dropped
+}
+
+func (f *propertyFilter) nodes(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	out := nodes[:0]
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			had := len(n.Declarations) > 0 || len(n.Rules) > 0
+			n.Declarations = f.declarations(selectorText(n.Selectors), n.Declarations)
+			n.Rules = f.nodes(n.Rules)
+			if had && len(n.Declarations) == 0 && len(n.Rules) == 0 {
+				continue
+			}
+		case *AtRule:
+			if name, _ := Canonical(asciiLower(n.Name)); name == "keyframes" || n.Rules == nil {
+				break
+			}
+			had := len(n.Rules) > 0
+			if n.Rules = f.nodes(n.Rules); had && len(n.Rules) == 0 {
+				continue
+			}
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+func (f *propertyFilter) declarations(selector string, decls []Declaration) []Declaration {
+	var out []Declaration
+	for _, d := range decls {
+		if f.allowed(d.Property) {
+			out = append(out, d)
+			continue
+		}
+		drop := Dropped{Declaration: d, Selector: selector}
+		if f.opts.extract {
+			for _, l := range f.longhands(d) {
+				out = append(out, l)
+				drop.Longhands = append(drop.Longhands, l.Property)
+			}
+		}
+		f.dropped = append(f.dropped, drop)
+	}
+	return out
+}
+
+// longhands returns the allowed longhands of the shorthand declaration d,
+// as described for ExtractLonghands, with d as their Origin.
+func (f *propertyFilter) longhands(d Declaration) []Declaration {
+	prop := asciiLower(d.Property)
+	m := expandShorthand(prop, d.Value)
+	if m == nil {
+		return nil
+	}
+	origin := &Origin{Property: d.Property, Value: d.Value, Pos: d.Pos}
+	covered := make(map[string]bool)
+	var out []Declaration
+	for _, l := range shorthandLonghands(prop) {
+		v, ok := m[l]
+		if !ok || covered[l] || !f.allowed(l) {
+			continue
+		}
+		for _, sub := range shorthandLonghands(l) {
+			covered[sub] = true
+		}
+		out = append(out, Declaration{Property: l, Value: v, Important: d.Important, Pos: d.Pos, Origin: origin})
+	}
+	return out
+}
@@ -0,0 +1,273 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Find returns the rules of sheet, including those nested in rules and
+// at-rules other than @keyframes, with a selector matching pattern, in
+// source order. Nested rules are matched by their selectors resolved
+// against their parent's.
+//
+// A pattern made of a single class or id, such as ".legacy-grid", matches
+// selectors that use that class or id anywhere, including in arguments
+// such as those of :not(); names are compared whole, so ".legacy-grid-item"
+// does not match it. A pattern containing '*' is a glob on the selector
+// text in which each '*' matches any run of characters, including a
+// universal selector. Any other pattern must equal the selector, both read
+// as by NormalizeSelector.
+func (sheet *StyleSheet) Find(pattern string) []*RuleNode {
+	pattern = strings.Join(strings.Fields(pattern), " ")
+	exact := pattern
+	if n, err := NormalizeSelector(pattern); err == nil {
+		exact = n
+	}
+	match := func(sel Rule) bool { return string(sel) == exact }
+	switch {
+	case len(pattern) > 1 && (pattern[0] == '.' || pattern[0] == '#') && skipName(pattern, 1) == len(pattern):
+		kind, name := pattern[0], unescape(pattern[1:])
+		match = func(sel Rule) bool { return hasName(string(sel), kind, name) }
+	case strings.Contains(pattern, "*"):
+		match = func(sel Rule) bool { return globMatch(pattern, string(sel)) }
+	}
+	return findRules(nil, sheet.Rules, nil, match)
+}
+
+// findRules appends the rules of nodes with a selector for which match
+// returns true to found, resolving nested selectors against parents. The
+// keyframe blocks of @keyframes are not rules and are skipped.
+func findRules(found []*RuleNode, nodes []Node, parents []Rule, match func(Rule) bool) []*RuleNode {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			sels := n.Selectors
+			if parents != nil {
+				sels = nestSelectors(parents, sels, DialectCSS)
+			}
+			for _, sel := range sels {
+				if match(sel) {
+					found = append(found, n)
+					break
+				}
+			}
+			found = findRules(found, n.Rules, sels, match)
+		case *AtRule:
+			if name, _ := Canonical(asciiLower(n.Name)); name != "keyframes" {
+				found = findRules(found, n.Rules, parents, match)
+			}
+		}
+	}
+	return found
+}
+
+// hasName reports whether the selector sel has a class, if kind is '.', or
+// an id, if kind is '#', of the given unescaped name. Attribute selectors
+// and strings are skipped.
+func hasName(sel string, kind byte, name string) bool {
+	for i := 0; i < len(sel); {
+		switch c := sel[i]; {
+		case c == '\\':
+			i = skipEscape(sel, i)
+		case c == '[':
+			i = skipBracket(sel, i)
+		case c == '"' || c == '\'':
+			i = skipString(sel, i)
+		case c == '.' || c == '#':
+			end := skipName(sel, i+1)
+			if c == kind && unescape(sel[i+1:end]) == name {
+				return true
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return false
+}
+
+// skipString returns the index just past the string whose opening quote is
+// at i.
+func skipString(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case s[i]:
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// globMatch reports whether s matches pattern, which has at least one '*'
+// matching any run of characters; everything else matches itself.
+func globMatch(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, p)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(p):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// RuleKind is a set of kinds of simple selectors, for ByType,
+// FilterRules, Rule.Kind and Problem.Kinds. It is written as its names,
+// as by String, by encoding/json and other text encodings.
+type RuleKind int
+
+// Kinds of simple selectors. They can be combined, as in KindID|KindTag.
+const (
+	KindID        RuleKind = 1 << iota // #main
+	KindClass                          // .btn
+	KindTag                            // div
+	KindUniversal                      // *
+	KindAttribute                      // [href]
+	KindPseudo                         // :hover, ::before
+)
+
+// kindNames are the names of the kinds of simple selectors, in bit order.
+var kindNames = []string{"id", "class", "tag", "universal", "attribute", "pseudo"}
+
+// String returns the names of the kinds of k joined by '|', in the order
+// of the constants, as in "id|tag": "id", "class", "tag", "universal",
+// "attribute" and "pseudo". The empty set is "none".
+func (k RuleKind) String() string {
+	if k == 0 {
+		return "none"
+	}
+	var names []string
+	for i, name := range kindNames {
+		if k&(1<<i) != 0 {
+			names = append(names, name)
+			k &^= 1 << i
+		}
+	}
+	if k != 0 {
+		names = append(names, fmt.Sprintf("RuleKind(%#x)", int(k)))
+	}
+	return strings.Join(names, "|")
+}
+
+// MarshalText returns the String of k, so that encoding/json writes a
+// RuleKind as its names.
+func (k RuleKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText reads a RuleKind written by String.
+func (k *RuleKind) UnmarshalText(text []byte) error {
+	var kind RuleKind
+	if s := string(text); s != "none" {
+	names:
+		for _, name := range strings.Split(s, "|") {
+			for i, n := range kindNames {
+				if name == n {
+					kind |= 1 << i
+					continue names
+				}
+			}
+			return fmt.Errorf("unknown selector kind %q", name)
+		}
+	}
+	*k = kind
+	return nil
+}
+
+// Kind classifies rule by how it starts: KindClass if it starts with '.',
+// KindID if it starts with '#', and KindTag otherwise. Uses tells the
+// kinds of all of its simple selectors.
+func (rule Rule) Kind() RuleKind {
+	switch {
+	case strings.HasPrefix(string(rule), "."):
+		return KindClass
+	case strings.HasPrefix(string(rule), "#"):
+		return KindID
+	}
+	return KindTag
+}
+
+// Uses reports whether rule has a simple selector of one of the kinds in
+// kind in any of its compounds, including the selector arguments of
+// pseudo-classes such as :not(). Unlike Kind, which looks at the start of
+// the selector only, "div#main > a" uses KindID as well as KindTag.
+func (rule Rule) Uses(kind RuleKind) bool {
+	return selectorKinds(string(rule))&kind != 0
+}
+
+// selectorKinds returns the kinds of the simple selectors of sel.
+func selectorKinds(sel string) RuleKind {
+	var kinds RuleKind
+	for i := 0; i < len(sel); {
+		c := sel[i]
+		switch {
+		case c == '#':
+			kinds |= KindID
+			i = skipName(sel, i+1)
+		case c == '.':
+			kinds |= KindClass
+			i = skipName(sel, i+1)
+		case c == '[':
+			kinds |= KindAttribute
+			i = skipBracket(sel, i)
+		case c == ':':
+			kinds |= KindPseudo
+			i++
+			if i < len(sel) && sel[i] == ':' {
+				i++
+			}
+			end := skipName(sel, i)
+			name := strings.ToLower(sel[i:end])
+			i = end
+			if i < len(sel) && sel[i] == '(' {
+				var args string
+				args, i = parenthesized(sel, i)
+				switch name {
+				case "is", "not", "has", "where", "matches", "-webkit-any", "-moz-any", "host", "host-context", "slotted":
+					kinds |= selectorKinds(args)
+				case "nth-child", "nth-last-child":
+					if j := strings.Index(strings.ToLower(args), " of "); j >= 0 {
+						kinds |= selectorKinds(args[j+4:])
+					}
+				}
+			}
+		case c == '*':
+			kinds |= KindUniversal
+			i++
+		case c == '"' || c == '\'':
+			i = skipString(sel, i)
+		case isNameRune(rune(c)) || c == '\\':
+			kinds |= KindTag
+			i = skipName(sel, i)
+		default:
+			i++
+		}
+	}
+	return kinds
+}
+
+// ByType returns the rules of sheet that have a selector using one of the
+// kinds in kind, found as by Find.
+func (sheet *StyleSheet) ByType(kind RuleKind) []*RuleNode {
+	return findRules(nil, sheet.Rules, nil, func(sel Rule) bool { return sel.Uses(kind) })
+}
+
+// FilterRules returns the entries of css, as returned by Unmarshal, whose
+// selector uses one of the kinds in kind. The declaration maps are shared
+// with css.
+func FilterRules(css map[Rule]map[string]string, kind RuleKind) map[Rule]map[string]string {
+	out := make(map[Rule]map[string]string)
+	for sel, styles := range css {
+		if sel.Uses(kind) {
+			out[sel] = styles
+		}
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package css
+
+import "strings"
+
+// FlipRTL returns a copy of sheet mirrored for right-to-left text, as
+// RTLCSS does: left and right are swapped in property names, such as
+// margin-left and border-top-left-radius, and in the values of text-align,
+// float and clear, the left and right values of four-value box shorthands
+// and of border-radius trade places, and the horizontal positions of
+// background-position are mirrored, a percentage x becoming 100% minus x.
+// A declaration with a /* rtl:ignore */ comment before or after it is left
+// as it is, as are all those of a rule or at-rule with one before it.
+func FlipRTL(sheet *StyleSheet) *StyleSheet {
+	out := sheet.Clone()
+	Walk(out, func(n Node) bool {
+		switch n := n.(type) {
+		case *RuleNode:
+			return !rtlIgnored(n.Comments)
+		case *AtRule:
+			return !rtlIgnored(n.Comments)
+		case *Declaration:
+			if !rtlIgnored(n.Comments) && !rtlIgnored(n.TrailingComments) {
+				n.Property, n.Value = flipProperty(n.Property), flipValue(n.Property, n.Value)
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// rtlIgnored reports whether comments has an rtl:ignore directive.
+func rtlIgnored(comments []string) bool {
+	for _, c := range comments {
+		if strings.EqualFold(strings.TrimSpace(c), "rtl:ignore") {
+			return true
+		}
+	}
+	return false
+}
+
+// flipProperty returns prop with left and right swapped.
+func flipProperty(prop string) string {
+	if strings.HasPrefix(prop, "--") {
+		return prop
+	}
+	base, prefix := Canonical(prop)
+	words := strings.Split(base, "-")
+	for i, w := range words {
+		switch strings.ToLower(w) {
+		case "left":
+			words[i] = "right"
+		case "right":
+			words[i] = "left"
+		}
+	}
+	return prefix + strings.Join(words, "-")
+}
+
+// flipValue returns the value of prop mirrored.
+func flipValue(prop, value string) string {
+	if strings.Contains(asciiLower(value), "var(") {
+		return value
+	}
+	base, _ := Canonical(asciiLower(prop))
+	switch base {
+	case "text-align", "float", "clear":
+		return flipKeyword(value)
+	case "margin", "padding", "inset", "border-width", "border-style", "border-color", "scroll-margin", "scroll-padding":
+		if parts := splitComponents(value); len(parts) == 4 {
+			parts[1], parts[3] = parts[3], parts[1]
+			return strings.Join(parts, " ")
+		}
+	case "border-radius":
+		if h, v, ok := splitRadius(value); ok {
+			return joinRadius([4]string{h[1], h[0], h[3], h[2]}, [4]string{v[1], v[0], v[3], v[2]})
+		}
+	case "background-position", "background-position-x":
+		layers := splitSelectorList(value)
+		for i, layer := range layers {
+			parts := splitComponents(layer)
+			for j, p := range parts {
+				if j == 0 {
+					p = flipPercentage(p)
+				}
+				parts[j] = flipKeyword(p)
+			}
+			layers[i] = strings.Join(parts, " ")
+		}
+		return strings.Join(layers, ", ")
+	}
+	return value
+}
+
+// flipKeyword returns right for left and left for right, and any other
+// value unchanged.
+func flipKeyword(value string) string {
+	switch strings.ToLower(value) {
+	case "left":
+		return "right"
+	case "right":
+		return "left"
+	}
+	return value
+}
+
+// flipPercentage returns 100% minus the percentage or zero s, or s if it is
+// neither.
+func flipPercentage(s string) string {
+	n, unit, ok := splitNumber(s)
+	if !ok || unit != "%" && (unit != "" || n != 0) {
+		return s
+	}
+	return Value{Number: 100 - n, Unit: "%"}.String()
+}
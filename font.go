@@ -0,0 +1,157 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseFontFamilies returns the family names of the font-family value, in
+// order, unquoted and unescaped, as in "Helvetica Neue" for
+// "\"Helvetica Neue\"". Generic families such as sans-serif are returned as
+// written, and the words of an unquoted name are joined by single spaces.
+func ParseFontFamilies(value string) []string {
+	var families []string
+	for _, item := range splitSelectorList(value) {
+		if name := familyName(strings.TrimSpace(item)); name != "" {
+			families = append(families, name)
+		}
+	}
+	return families
+}
+
+// familyName returns the unquoted name of the family item.
+func familyName(item string) string {
+	if item != "" && (item[0] == '"' || item[0] == '\'') {
+		end := skipString(item, 0)
+		s := item[1:end]
+		if strings.HasSuffix(s, item[:1]) {
+			s = s[:len(s)-1]
+		}
+		return unescape(strings.ReplaceAll(s, "\\\n", ""))
+	}
+	words := Fields(item)
+	for i, w := range words {
+		words[i] = unescape(w)
+	}
+	return strings.Join(words, " ")
+}
+
+// quoteFontFamilies returns the font-family value with the names that
+// must be quoted to be read back as written quoted: those made of several
+// words, and CSS-wide keywords and "default" in a list of families. Values
+// holding var() or a single CSS-wide keyword are returned as they are.
+func quoteFontFamilies(value string) string {
+	if isWideKeyword(strings.TrimSpace(value)) || strings.Contains(asciiLower(value), "var(") {
+		return value
+	}
+	items := splitSelectorList(value)
+	changed := false
+	for i, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || item[0] == '"' || item[0] == '\'' {
+			continue
+		}
+		words := Fields(item)
+		if len(words) > 1 || len(items) > 1 && (isWideKeyword(item) || strings.EqualFold(item, "default")) {
+			items[i], changed = QuoteString(familyName(item)), true
+		}
+	}
+	if !changed {
+		return value
+	}
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return strings.Join(items, ", ")
+}
+
+// FontSource is an entry of the src descriptor of @font-face: a font file
+// or, if Local is set, a locally installed font, with the format and
+// technology hints that let a browser skip it.
+type FontSource struct {
+	URL   string // unquoted, as in url(f.woff2)
+	Local string // unquoted, as in local("Helvetica Neue")
+	// Format is the unquoted argument of format(), such as "woff2".
+	Format string
+	// Tech lists the arguments of tech(), such as "variations".
+	Tech []string
+
+	// quotes of URL, Local and Format as written, or 0
+	urlQuote, localQuote, formatQuote byte
+}
+
+// ParseFontSources parses the value of the src descriptor of @font-face
+// into its entries, in order of preference.
+func ParseFontSources(value string) ([]FontSource, error) {
+	var srcs []FontSource
+	for _, item := range splitSelectorList(value) {
+		var src FontSource
+		parts := Fields(item)
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("empty source in src: %s", value)
+		}
+		for i, p := range parts {
+			f, ok := ParseFunction(p)
+			switch {
+			case !ok:
+				return nil, fmt.Errorf("invalid font source component %s", p)
+			case i == 0 && f.Name == "url":
+				// An unquoted url() may hold commas.
+				src.URL, src.urlQuote = unquote(strings.TrimSpace(p[len("url(") : len(p)-1]))
+			case i == 0 && f.Name == "local" && len(f.Args) == 1:
+				src.Local, src.localQuote = unquote(f.Args[0])
+			case i > 0 && f.Name == "format" && len(f.Args) == 1 && src.Format == "":
+				src.Format, src.formatQuote = unquote(f.Args[0])
+			case i > 0 && f.Name == "tech" && len(f.Args) > 0 && src.Tech == nil:
+				src.Tech = f.Args
+			default:
+				return nil, fmt.Errorf("unexpected %s in font source %s", p, strings.TrimSpace(item))
+			}
+		}
+		srcs = append(srcs, src)
+	}
+	return srcs, nil
+}
+
+// FormatFontSources returns the value of the src descriptor for srcs,
+// quoting their parts as they were when parsed by ParseFontSources.
+func FormatFontSources(srcs []FontSource) string {
+	items := make([]string, len(srcs))
+	for i, src := range srcs {
+		var item string
+		if src.Local != "" {
+			item = "local(" + quote(src.Local, src.localQuote, strings.ContainsAny(src.Local, "(),\"'\\")) + ")"
+		} else {
+			item = "url(" + quote(src.URL, src.urlQuote, strings.ContainsAny(src.URL, " \t\n()\"'\\")) + ")"
+		}
+		if src.Format != "" {
+			item += " format(" + quote(src.Format, src.formatQuote, skipName(src.Format, 0) != len(src.Format)) + ")"
+		}
+		if len(src.Tech) > 0 {
+			item += " tech(" + strings.Join(src.Tech, ", ") + ")"
+		}
+		items[i] = item
+	}
+	return strings.Join(items, ", ")
+}
+
+// unquote returns s without its quotes, unescaped, and the quote it had, or
+// s as it is and 0 if it is not a string.
+func unquote(s string) (string, byte) {
+	if s == "" || s[0] != '"' && s[0] != '\'' {
+		return s, 0
+	}
+	return familyName(s), s[0]
+}
+
+// quote returns s quoted with q, or quoted with double quotes if q is 0 and
+// must is set.
+func quote(s string, q byte, must bool) string {
+	switch {
+	case q == '\'':
+		return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\a `).Replace(s) + "'"
+	case q == '"' || must:
+		return QuoteString(s)
+	}
+	return s
+}
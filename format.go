@@ -0,0 +1,185 @@
+package css
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FormatOptions configures FormatRange.
+type FormatOptions struct {
+	// Parse holds the options src is parsed with.
+	Parse []Option
+	// Marshal holds the options the rule is written with, after
+	// KeepComments(nil), which keeps its comments unless Marshal holds
+	// another KeepComments.
+	Marshal []MarshalOption
+}
+
+// Reasons FormatRange gives for not reformatting a range, which a
+// *RangeError unwraps to.
+var (
+	ErrRangeSpansRules    = errors.New("range spans more than one rule")
+	ErrRangeOutsideRules  = errors.New("range is outside of any rule")
+	ErrRangeInComment     = errors.New("range starts or ends in a comment")
+	ErrRangeDropsComments = errors.New("rule has comments the printer would drop")
+)
+
+// RangeError is the error FormatRange returns for a range it does not
+// reformat.
+type RangeError struct {
+	Start, End int
+	Err        error // one of the ErrRange errors
+}
+
+func (e *RangeError) Error() string {
+	return fmt.Sprintf("range %d-%d: %v", e.Start, e.End, e.Err)
+}
+
+func (e *RangeError) Unwrap() error {
+	return e.Err
+}
+
+// FormatRange reformats the smallest rule or at-rule of the stylesheet src
+// holding the byte range from start to end, such as the rule a cursor is
+// in, as Marshal writes it, and leaves the rest of src byte for byte as it
+// is. It returns the new text and the range of the reformatted rule in it,
+// comments before the rule included. The rule is written indented for its
+// depth, but for its first line, which keeps the indentation of src.
+//
+// A range ending or starting in a comment, spanning top-level rules or
+// outside of them, or in a rule with comments that Marshal drops, such as
+// those at the end of a block, gives a *RangeError. A parse error is
+// returned as it is.
+func FormatRange(src []byte, start, end int, opts FormatOptions) ([]byte, int, int, error) {
+	if start < 0 || start > end || end > len(src) {
+		return nil, 0, 0, fmt.Errorf("invalid range %d-%d of %d bytes", start, end, len(src))
+	}
+	sheet, err := Parse(src, opts.Parse...)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	fail := func(err error) ([]byte, int, int, error) {
+		return nil, 0, 0, &RangeError{start, end, err}
+	}
+	for _, c := range sheet.Comments {
+		from, to := c.Pos.Offset, c.Pos.Offset+len(c.Text)+4
+		if from < start && start < to || from < end && end < to {
+			return fail(ErrRangeInComment)
+		}
+	}
+	f := rangeFinder{src: src, sheet: sheet, start: start, end: end}
+	if !f.find(sheet.Rules, 0, 0) {
+		return fail(f.err)
+	}
+	var held int
+	countComments(f.node, &held)
+	for _, c := range sheet.Comments {
+		if f.from <= c.Pos.Offset && c.Pos.Offset < f.to {
+			held--
+		}
+	}
+	if held != 0 {
+		return fail(ErrRangeDropsComments)
+	}
+	e := &encoder{}
+	for _, opt := range append([]MarshalOption{KeepComments(nil)}, opts.Marshal...) {
+		opt(&e.opts)
+	}
+	e.nodes([]Node{f.node}, f.depth)
+	text := strings.TrimSuffix(strings.TrimLeft(e.buf.String(), " "), "\n")
+	var out bytes.Buffer
+	out.Grow(len(src) - (f.to - f.from) + len(text))
+	out.Write(src[:f.from])
+	out.WriteString(text)
+	out.Write(src[f.to:])
+	return out.Bytes(), f.from, f.from + len(text), nil
+}
+
+// rangeFinder finds the smallest node holding a range of its source.
+type rangeFinder struct {
+	src        []byte
+	sheet      *StyleSheet
+	start, end int
+
+	node     Node
+	depth    int
+	from, to int   // span of node, with the comments before it
+	err      error // why no node was found
+}
+
+// find looks for the node in nodes, at depth, whose text starts after the
+// offset prev, and reports whether it found one.
+func (f *rangeFinder) find(nodes []Node, depth, prev int) bool {
+	for _, n := range nodes {
+		from, to, ok := f.span(n, prev)
+		if !ok {
+			continue
+		}
+		prev = to
+		switch {
+		case to <= f.start || f.end < from || f.end == from && f.start < f.end:
+			continue
+		case f.start < from || to < f.end:
+			if depth == 0 {
+				f.err = ErrRangeSpansRules
+			}
+			return false
+		}
+		f.node, f.depth, f.from, f.to = n, depth, from, to
+		var open int
+		var decls []Declaration
+		var rules []Node
+		switch n := n.(type) {
+		case *RuleNode:
+			open, decls, rules = n.Open.Offset+1, n.Declarations, n.Rules
+		case *AtRule:
+			open, decls, rules = n.Open.Offset+1, n.Declarations, n.Rules
+		}
+		if len(decls) > 0 {
+			open = decls[len(decls)-1].ValueEnd.Offset
+		}
+		f.find(rules, depth+1, open)
+		return true
+	}
+	if depth == 0 {
+		f.err = ErrRangeOutsideRules
+	}
+	return false
+}
+
+// span returns the offsets of the text of n, from its first comment before
+// it and after prev on, and reports whether n has that text.
+func (f *rangeFinder) span(n Node, prev int) (from, to int, ok bool) {
+	if from, to, ok = nodeSpan(n, f.src); !ok {
+		return 0, 0, false
+	}
+	for _, c := range f.sheet.Comments {
+		if prev <= c.Pos.Offset && c.Pos.Offset < from {
+			from = c.Pos.Offset
+			break
+		}
+	}
+	return from, to, true
+}
+
+// countComments adds the number of comments n holds to count.
+func countComments(n Node, count *int) {
+	var decls []Declaration
+	var rules []Node
+	switch n := n.(type) {
+	case *RuleNode:
+		*count += len(n.Comments)
+		decls, rules = n.Declarations, n.Rules
+	case *AtRule:
+		*count += len(n.Comments)
+		decls, rules = n.Declarations, n.Rules
+	}
+	for _, d := range decls {
+		*count += len(d.Comments) + len(d.TrailingComments)
+	}
+	for _, r := range rules {
+		countComments(r, count)
+	}
+}
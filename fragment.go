@@ -0,0 +1,80 @@
+package css
+
+import (
+	"bytes"
+	"io"
+	"text/scanner"
+)
+
+// FragmentRule sets the key under which UnmarshalFragment files the
+// declarations of a bare declaration list. The default is "&".
+func FragmentRule(r Rule) Option {
+	return func(o *options) {
+		o.fragmentRule = r
+	}
+}
+
+// UnmarshalFragment is like Unmarshal but also accepts a bare declaration
+// list, such as the inside of a block or a style attribute, which it files
+// under the key set by FragmentRule. Input is read as a declaration list if
+// it has a token besides comments, no '{' outside of strings, comments and
+// parentheses and does not start with an at-rule, so a stylesheet like "a:hover { color: red }" is
+// never mistaken for one. Positions are those of the input as given.
+func UnmarshalFragment(b []byte, opts ...Option) (map[Rule]map[string]string, error) {
+	if !isDeclarationList(b) {
+		return Unmarshal(b, opts...)
+	}
+	o := newOptions(opts)
+	key := o.fragmentRule
+	if key == "" {
+		key = "&"
+	}
+	// The list is parsed as the block of an "&" rule, whose "&{" starts two
+	// columns before the input.
+	src := append([]byte("&{"), b...)
+	if t := bytes.TrimRight(b, " \t\r\n\f"); len(t) > 0 && t[len(t)-1] != ';' {
+		src = append(src, ';')
+	}
+	src = append(src, "\n}"...)
+	start := o.start
+	if !start.IsValid() {
+		start = scanner.Position{Filename: o.filename, Line: 1, Column: 1}
+	}
+	start.Column -= 2
+	start.Offset -= 2
+	css, err := Unmarshal(src, append(opts[:len(opts):len(opts)], func(o *options) { o.start = start })...)
+	if styles, ok := css["&"]; ok && key != "&" {
+		delete(css, "&")
+		css[key] = styles
+	}
+	return css, err
+}
+
+// isDeclarationList reports whether b reads as a bare declaration list
+// rather than a stylesheet.
+func isDeclarationList(b []byte) bool {
+	s := NewScanner(bytes.NewReader(b))
+	first, parens := true, 0
+	for {
+		t, err := s.Next()
+		if err == io.EOF {
+			return !first
+		}
+		if err != nil {
+			return false
+		}
+		switch {
+		case t.Kind == TokenWhitespace, t.Kind == TokenComment:
+			continue
+		case first && t.Kind == TokenAtKeyword:
+			return false
+		case t.Kind == TokenFunction, t.Text == "(", t.Text == "[":
+			parens++
+		case t.Text == ")", t.Text == "]":
+			parens--
+		case t.Text == "{" && parens <= 0:
+			return false
+		}
+		first = false
+	}
+}
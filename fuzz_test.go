@@ -0,0 +1,34 @@
+package css
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzUnmarshal checks that no input makes Unmarshal or Parse panic, in
+// the default, Lenient and Strict modes. The seeds in
+// testdata/fuzz/FuzzUnmarshal are short inputs that end the parse early,
+// not past crashers, and the corpus cases are added to them.
+func FuzzUnmarshal(f *testing.F) {
+	names, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.css"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	modes := [][]Option{nil, {Lenient(true)}, {Strict(true)}}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		for _, opts := range modes {
+			Unmarshal(b, opts...)
+			if sheet, err := Parse(b, opts...); err == nil {
+				Marshal(sheet)
+			}
+		}
+	})
+}
@@ -0,0 +1,181 @@
+//go:build ignore
+
+// gen_bench generates the stylesheets of testdata/bench read by the
+// benchmarks: bootstrap.css, of the size and shape of a component
+// framework, and large.css, a utility sheet of the kind generated by
+// utility-first frameworks.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+var (
+	colors = []struct{ name, hex, dark string }{
+		{"primary", "#0d6efd", "#0a58ca"}, {"secondary", "#6c757d", "#565e64"},
+		{"success", "#198754", "#146c43"}, {"info", "#0dcaf0", "#3dd5f3"},
+		{"warning", "#ffc107", "#ffca2c"}, {"danger", "#dc3545", "#b02a37"},
+		{"light", "#f8f9fa", "#f9fafb"}, {"dark", "#212529", "#1a1e21"},
+	}
+	breakpoints = []struct{ name, width string }{
+		{"sm", "576px"}, {"md", "768px"}, {"lg", "992px"}, {"xl", "1200px"}, {"xxl", "1400px"},
+	}
+	components = []string{
+		"btn", "card", "alert", "badge", "list-group-item", "nav-link", "dropdown-item",
+		"table", "toast", "modal-content", "popover", "tooltip", "progress-bar", "accordion-button",
+		"breadcrumb-item", "page-link", "form-control", "form-select", "input-group-text", "navbar",
+	}
+	spacing    = []string{"0", ".25rem", ".5rem", "1rem", "1.5rem", "3rem", "auto"}
+	sides      = []struct{ name, props string }{{"", "margin"}, {"t", "margin-top"}, {"b", "margin-bottom"}, {"s", "margin-left"}, {"e", "margin-right"}, {"x", "margin-left margin-right"}, {"y", "margin-top margin-bottom"}}
+	displays   = []string{"none", "inline", "inline-block", "block", "grid", "table", "flex", "inline-flex"}
+	utilColors = []string{"slate", "gray", "zinc", "red", "orange", "amber", "yellow", "lime", "green", "emerald", "teal", "cyan", "sky", "blue", "indigo", "violet", "purple", "fuchsia", "pink", "rose"}
+	shades     = []int{50, 100, 200, 300, 400, 500, 600, 700, 800, 900}
+	variants   = []string{"", "hover", "focus", "group-hover", "dark"}
+)
+
+func main() {
+	write("testdata/bench/bootstrap.css", bootstrap)
+	write("testdata/bench/large.css", utilities)
+}
+
+func write(name string, gen func(w *bufio.Writer)) {
+	f, err := os.Create(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	gen(w)
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func bootstrap(w *bufio.Writer) {
+	fmt.Fprintln(w, "/*!\n * A component framework sized stylesheet, generated by gen_bench.go.\n */")
+	fmt.Fprintln(w, ":root {")
+	for _, c := range colors {
+		fmt.Fprintf(w, "  --bs-%s: %s;\n  --bs-%s-rgb: %s;\n", c.name, c.hex, c.name, rgb(c.hex))
+	}
+	fmt.Fprintln(w, "  --bs-font-sans-serif: system-ui, -apple-system, \"Segoe UI\", Roboto, \"Helvetica Neue\", Arial, sans-serif;\n  --bs-body-line-height: 1.5;\n}")
+	fmt.Fprintln(w, "*,\n*::before,\n*::after {\n  box-sizing: border-box;\n}")
+	fmt.Fprintln(w, "@font-face {\n  font-family: \"Icons\";\n  src: url(\"fonts/icons.woff2?24e3eb84\") format(\"woff2\"), url(fonts/icons.woff?24e3eb84) format(\"woff\");\n}")
+	for _, keyframes := range []string{"progress-bar-stripes", "spinner-border", "spinner-grow", "placeholder-glow", "placeholder-wave"} {
+		fmt.Fprintf(w, "@keyframes %s {\n  0%% {\n    opacity: 1;\n    transform: scale(0);\n  }\n  50%% {\n    opacity: .5;\n  }\n  to {\n    transform: rotate(360deg);\n  }\n}\n", keyframes)
+	}
+	for pass := 0; pass < 2; pass++ {
+		for _, comp := range components {
+			comp := comp
+			if pass > 0 {
+				comp = fmt.Sprintf("%s-v%d", comp, pass)
+			}
+			fmt.Fprintf(w, "/* %s */\n", comp)
+			fmt.Fprintf(w, ".%s {\n  --bs-%s-padding-x: .75rem;\n  --bs-%s-padding-y: .375rem;\n  display: inline-block;\n  padding: var(--bs-%s-padding-y) var(--bs-%s-padding-x);\n  font-family: var(--bs-font-sans-serif);\n  font-size: 1rem;\n  font-weight: 400;\n  line-height: 1.5;\n  color: #212529;\n  text-align: center;\n  vertical-align: middle;\n  -webkit-user-select: none;\n  -moz-user-select: none;\n  user-select: none;\n  border: 1px solid transparent;\n  border-radius: .375rem;\n  transition: color .15s ease-in-out, background-color .15s ease-in-out, border-color .15s ease-in-out, box-shadow .15s ease-in-out;\n}\n", comp, comp, comp, comp, comp)
+			fmt.Fprintf(w, "@media (prefers-reduced-motion: reduce) {\n  .%s {\n    transition: none;\n  }\n}\n", comp)
+			for _, c := range colors {
+				fmt.Fprintf(w, ".%s-%s {\n  color: #fff;\n  background-color: %s;\n  border-color: %s;\n}\n", comp, c.name, c.hex, c.hex)
+				fmt.Fprintf(w, ".%s-%s:hover, .%s-%s:focus-visible {\n  color: #fff;\n  background-color: %s;\n  border-color: %s;\n}\n", comp, c.name, comp, c.name, c.dark, c.dark)
+				fmt.Fprintf(w, ".%s-check:checked + .%s-%s,\n:not(.%s-check) + .%s-%s:active,\n.%s-%s.active,\n.%s-%s.show {\n  color: #fff;\n  background-color: %s;\n  border-color: %s;\n  box-shadow: inset 0 3px 5px rgba(0, 0, 0, .125);\n}\n", comp, comp, c.name, comp, comp, c.name, comp, c.name, comp, c.name, c.dark, c.dark)
+				fmt.Fprintf(w, ".%s-%s:disabled, .%s-%s.disabled, fieldset:disabled .%s-%s {\n  color: #fff;\n  background-color: %s;\n  border-color: %s;\n  opacity: .65;\n  pointer-events: none;\n}\n", comp, c.name, comp, c.name, comp, c.name, c.hex, c.hex)
+				fmt.Fprintf(w, ".%s-outline-%s {\n  color: %s;\n  border-color: %s;\n  background-image: none;\n}\n", comp, c.name, c.hex, c.hex)
+			}
+			for _, size := range []struct{ name, pad, font string }{{"sm", ".25rem .5rem", ".875rem"}, {"lg", ".5rem 1rem", "1.25rem"}} {
+				fmt.Fprintf(w, ".%s-%s {\n  padding: %s;\n  font-size: %s;\n  border-radius: .25rem;\n}\n", comp, size.name, size.pad, size.font)
+			}
+			fmt.Fprintf(w, ".%s > .%s-header:first-child, .%s .%s-body + .%s-footer:not(:last-child) {\n  border-bottom: 1px solid rgba(0, 0, 0, .175);\n  background: url(\"data:image/svg+xml,%%3csvg xmlns='http://www.w3.org/2000/svg' viewBox='0 0 16 16'%%3e%%3cpath fill='none' stroke='%%23343a40' d='m2 5 6 6 6-6'/%%3e%%3c/svg%%3e\") no-repeat right .75rem center/16px 12px;\n}\n", comp, comp, comp, comp, comp)
+		}
+	}
+	for _, bp := range breakpoints {
+		fmt.Fprintf(w, "@media (min-width: %s) {\n", bp.width)
+		fmt.Fprintf(w, "  .container, .container-%s {\n    max-width: calc(%s - 36px);\n  }\n", bp.name, bp.width)
+		for i := 1; i <= 12; i++ {
+			fmt.Fprintf(w, "  .col-%s-%d {\n    flex: 0 0 auto;\n    width: %.8g%%;\n  }\n", bp.name, i, float64(i)*100/12)
+			fmt.Fprintf(w, "  .offset-%s-%d {\n    margin-left: %.8g%%;\n  }\n", bp.name, i, float64(i)*100/12)
+		}
+		for _, d := range displays {
+			fmt.Fprintf(w, "  .d-%s-%s {\n    display: %s !important;\n  }\n", bp.name, d, d)
+		}
+		fmt.Fprintln(w, "}")
+	}
+	for _, side := range sides {
+		for i, s := range spacing {
+			name := fmt.Sprintf("m%s-%d", side.name, i)
+			if s == "auto" {
+				name = fmt.Sprintf("m%s-auto", side.name)
+			}
+			fmt.Fprintf(w, ".%s {\n", name)
+			for _, p := range strings.Fields(side.props) {
+				fmt.Fprintf(w, "  %s: %s !important;\n", p, s)
+			}
+			fmt.Fprintln(w, "}")
+		}
+	}
+}
+
+func utilities(w *bufio.Writer) {
+	fmt.Fprintln(w, "/* A utility sheet, generated by gen_bench.go. */")
+	rule := func(variant, class, decls string) {
+		sel := "." + class
+		switch variant {
+		case "":
+		case "group-hover":
+			sel = ".group:hover .group-hover\\:" + class
+		case "dark":
+			sel = ".dark .dark\\:" + class
+		default:
+			sel = "." + variant + "\\:" + class + ":" + variant
+		}
+		fmt.Fprintf(w, "%s{%s}\n", sel, decls)
+	}
+	for _, bp := range append([]struct{ name, width string }{{"", ""}}, breakpoints...) {
+		prefix := ""
+		if bp.name != "" {
+			fmt.Fprintf(w, "@media (min-width:%s){\n", bp.width)
+			prefix = bp.name + "\\:"
+		}
+		for _, v := range variants {
+			for _, c := range utilColors {
+				for _, s := range shades {
+					hex := fmt.Sprintf("#%02x%02x%02x", (len(c)*37+s)%256, (s*7)%256, (len(c)*s)%256)
+					rule(v, fmt.Sprintf("%sbg-%s-%d", prefix, c, s), fmt.Sprintf("--tw-bg-opacity:1;background-color:rgb(%s/var(--tw-bg-opacity))", rgb(hex)))
+					rule(v, fmt.Sprintf("%stext-%s-%d", prefix, c, s), fmt.Sprintf("--tw-text-opacity:1;color:rgb(%s/var(--tw-text-opacity))", rgb(hex)))
+					if v == "" || v == "hover" || v == "focus" {
+						rule(v, fmt.Sprintf("%sborder-%s-%d", prefix, c, s), fmt.Sprintf("--tw-border-opacity:1;border-color:rgb(%s/var(--tw-border-opacity))", rgb(hex)))
+					}
+				}
+			}
+			if v != "" && v != "hover" {
+				continue
+			}
+			for i := 0; i <= 64; i++ {
+				size := fmt.Sprintf("%grem", float64(i)/4)
+				for _, side := range []struct{ name, props string }{{"", "padding"}, {"x", "padding-left padding-right"}, {"y", "padding-top padding-bottom"}, {"t", "padding-top"}, {"m", "margin"}, {"mx", "margin-left margin-right"}, {"w", "width"}, {"h", "height"}, {"gap", "gap"}} {
+					name := "p" + side.name
+					if strings.HasPrefix(side.name, "m") || side.name == "w" || side.name == "h" || side.name == "gap" {
+						name = side.name
+					}
+					var decls []string
+					for _, p := range strings.Fields(side.props) {
+						decls = append(decls, p+":"+size)
+					}
+					rule(v, fmt.Sprintf("%s%s-%d", prefix, name, i), strings.Join(decls, ";"))
+				}
+			}
+		}
+		if bp.name != "" {
+			fmt.Fprintln(w, "}")
+		}
+	}
+}
+
+func rgb(hex string) string {
+	var r, g, b int
+	fmt.Sscanf(hex, "#%02x%02x%02x", &r, &g, &b)
+	return fmt.Sprintf("%d %d %d", r, g, b)
+}
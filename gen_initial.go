@@ -0,0 +1,65 @@
+//go:build ignore
+
+// gen_initial generates initial_gen.go from initial.txt.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+func main() {
+	f, err := os.Open("initial.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	var names []string
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			log.Fatalf("initial.txt:%d: want \"property: value\", got %q", n, line)
+		}
+		if _, dup := values[name]; dup {
+			log.Fatalf("initial.txt:%d: duplicate property %s", n, name)
+		}
+		values[name] = value
+		names = append(names, name)
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen_initial.go from initial.txt; DO NOT EDIT.\n\n")
+	b.WriteString("package css\n\n")
+	b.WriteString("// initialValues maps the standard CSS longhand properties to their initial values.\n")
+	b.WriteString("var initialValues = map[string]string{\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t%q: %q,\n", name, values[name])
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("initial_gen.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,87 @@
+//go:build ignore
+
+// gen_properties generates properties_gen.go from properties.txt.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// categories maps the categories of properties.txt to their ValueCategory.
+var categories = map[string]string{
+	"other": "CategoryOther", "shorthand": "CategoryShorthand", "keyword": "CategoryKeyword",
+	"length": "CategoryLength", "number": "CategoryNumber", "color": "CategoryColor",
+	"time": "CategoryTime", "image": "CategoryImage",
+}
+
+func main() {
+	f, err := os.Open("properties.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	type property struct {
+		name, category string
+		inherited      bool
+	}
+	var props []property
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 || len(fields) > 3 || len(fields) == 3 && fields[2] != "inherited" {
+			log.Fatalf("bad line %q", line)
+		}
+		if _, ok := categories[fields[1]]; !ok {
+			log.Fatalf("unknown category %q of %s", fields[1], fields[0])
+		}
+		props = append(props, property{fields[0], fields[1], len(fields) == 3})
+	}
+	if err := s.Err(); err != nil {
+		log.Fatal(err)
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].name < props[j].name })
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by gen_properties.go from properties.txt; DO NOT EDIT.\n\n")
+	b.WriteString("package css\n\n")
+	b.WriteString("// knownProperties is the set of standard CSS property names.\n")
+	b.WriteString("var knownProperties = map[string]bool{\n")
+	for _, p := range props {
+		fmt.Fprintf(&b, "\t%q: true,\n", p.name)
+	}
+	b.WriteString("}\n\n")
+	b.WriteString("// inheritedProperties is the set of standard properties inherited by default.\n")
+	b.WriteString("var inheritedProperties = map[string]bool{\n")
+	for _, p := range props {
+		if p.inherited {
+			fmt.Fprintf(&b, "\t%q: true,\n", p.name)
+		}
+	}
+	b.WriteString("}\n\n")
+	b.WriteString("// propertyCategories maps the standard properties to the category of their value.\n")
+	b.WriteString("var propertyCategories = map[string]ValueCategory{\n")
+	for _, p := range props {
+		fmt.Fprintf(&b, "\t%q: %s,\n", p.name, categories[p.category])
+	}
+	b.WriteString("}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("properties_gen.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,88 @@
+package css
+
+import "strings"
+
+// groupingAtRules lists the at-rules whose blocks group rules under a
+// condition or layer, which Ungroup takes apart.
+var groupingAtRules = map[string]bool{
+	"media": true, "supports": true, "layer": true, "container": true, "scope": true,
+	"document": true, "starting-style": true,
+}
+
+// GroupedNode is a node of a stylesheet with the grouping at-rules, such as
+// @media, @supports and @layer, it is nested in, outermost first.
+type GroupedNode struct {
+	Node Node
+	Path []*AtRule
+}
+
+// Ungroup returns the nodes of sheet, in source order, with the blocks of
+// the grouping at-rules taken apart: each rule, and each other at-rule,
+// such as @import or @keyframes, comes with the path of the @media,
+// @supports, @layer, @container, @scope and @starting-style blocks holding
+// it. Rules keep the rules nested in them, and empty blocks are left out.
+// The nodes are those of sheet, not copies.
+func Ungroup(sheet *StyleSheet) []GroupedNode {
+	return ungroup(nil, sheet.Rules, nil)
+}
+
+func ungroup(out []GroupedNode, nodes []Node, path []*AtRule) []GroupedNode {
+	for _, n := range nodes {
+		if a, ok := n.(*AtRule); ok && a.Rules != nil && groupingAtRules[asciiLower(a.Name)] {
+			out = ungroup(out, a.Rules, append(path[:len(path):len(path)], a))
+			continue
+		}
+		out = append(out, GroupedNode{n, path})
+	}
+	return out
+}
+
+// Regroup returns a stylesheet of nodes, as returned by Ungroup, with the
+// blocks of their paths put back together. Nodes next to each other in
+// blocks of the same name and prelude share one block, as do those in the
+// same anonymous @layer, so that Marshal writes Regroup(Ungroup(sheet)) as
+// it writes sheet but for such blocks, which are merged. The blocks are
+// copies of the first of those they stand for.
+func Regroup(nodes []GroupedNode) *StyleSheet {
+	return &StyleSheet{Rules: regroup(nodes, 0)}
+}
+
+// regroup returns the nodes of nodes, whose paths are the same up to depth,
+// with the blocks of their paths past depth put back together.
+func regroup(nodes []GroupedNode, depth int) []Node {
+	var out []Node
+	for i := 0; i < len(nodes); {
+		if len(nodes[i].Path) <= depth {
+			out = append(out, nodes[i].Node)
+			i++
+			continue
+		}
+		a := nodes[i].Path[depth]
+		j := i + 1
+		for j < len(nodes) && len(nodes[j].Path) > depth && sameBlock(a, nodes[j].Path[depth]) {
+			j++
+		}
+		block := *a
+		block.Rules = regroup(nodes[i:j], depth+1)
+		out = append(out, &block)
+		i = j
+	}
+	return out
+}
+
+// sameBlock reports whether the grouping at-rules a and b group their
+// rules the same way. No two anonymous @layer blocks do.
+func sameBlock(a, b *AtRule) bool {
+	if a == b {
+		return true
+	}
+	name := asciiLower(a.Name)
+	if name != asciiLower(b.Name) {
+		return false
+	}
+	pa, pb := strings.TrimSpace(a.Prelude), strings.TrimSpace(b.Prelude)
+	if name == "media" {
+		pa, pb = mediaKey(pa), mediaKey(pb)
+	}
+	return pa == pb && !(name == "layer" && pa == "")
+}
@@ -0,0 +1,60 @@
+package css
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestUngroupRegroup checks the paths Ungroup gives the rules of @media
+// inside @supports inside @layer, and that Regroup puts the blocks back
+// together as they were.
+func TestUngroupRegroup(t *testing.T) {
+	const src = "@layer base{@supports (display: grid){@media (min-width: 600px){.a{top:0}.b{left:0}}.c{right:0}}.d{color:red}}.e{top:1px}"
+	sheet, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := Ungroup(sheet)
+	var got []string
+	for _, n := range nodes {
+		path := []string{string(n.Node.(*RuleNode).Selectors[0])}
+		for _, at := range n.Path {
+			path = append(path, "@"+at.Name+" "+at.Prelude)
+		}
+		got = append(got, strings.Join(path, " < "))
+	}
+	want := []string{
+		".a < @layer base < @supports (display: grid) < @media (min-width: 600px)",
+		".b < @layer base < @supports (display: grid) < @media (min-width: 600px)",
+		".c < @layer base < @supports (display: grid)",
+		".d < @layer base",
+		".e",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Ungroup gives paths\n%q\nwant\n%q", got, want)
+	}
+	out, err := Marshal(Regroup(nodes), Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != src {
+		t.Errorf("Regroup(Ungroup(sheet)) = %s, want %s", out, src)
+	}
+}
+
+// TestRegroupMerges checks that Regroup merges the blocks of neighbouring
+// nodes in blocks of the same name and prelude, but not those apart.
+func TestRegroupMerges(t *testing.T) {
+	sheet, err := Parse([]byte("@layer a{@media print{.a{top:0}}}@layer a{@media print{.b{top:0}}}.c{top:0}@layer a{@media print{.d{top:0}}}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Marshal(Regroup(Ungroup(sheet)), Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "@layer a{@media print{.a{top:0}.b{top:0}}}.c{top:0}@layer a{@media print{.d{top:0}}}"; string(out) != want {
+		t.Errorf("Regroup(Ungroup(sheet)) = %s, want %s", out, want)
+	}
+}
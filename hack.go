@@ -0,0 +1,59 @@
+package css
+
+import "strings"
+
+// Hack identifies a legacy Internet Explorer targeting hack in a
+// declaration. Such declarations are parsed with the hack characters kept in
+// the property name or value.
+type Hack int
+
+// Known hacks.
+const (
+	NoHack Hack = iota
+	// StarHack is a property prefixed with '*', as in *zoom: 1.
+	StarHack
+	// UnderscoreHack is a property prefixed with '_', as in _height: 1%.
+	UnderscoreHack
+	// BackslashNineHack is a value ending in \9, as in color: red\9.
+	BackslashNineHack
+	// BackslashZeroHack is a value ending in \0 or \0/.
+	BackslashZeroHack
+	// BangIEHack is a value ending in !ie.
+	BangIEHack
+)
+
+func (h Hack) String() string {
+	switch h {
+	case StarHack:
+		return "star"
+	case UnderscoreHack:
+		return "underscore"
+	case BackslashNineHack:
+		return "backslash-9"
+	case BackslashZeroHack:
+		return "backslash-0"
+	case BangIEHack:
+		return "bang-ie"
+	}
+	return "none"
+}
+
+// Hack reports which legacy hack, if any, the declaration uses.
+func (d Declaration) Hack() Hack {
+	switch {
+	case strings.HasPrefix(d.Property, "*"):
+		return StarHack
+	case strings.HasPrefix(d.Property, "_"):
+		return UnderscoreHack
+	}
+	v := strings.TrimSpace(d.Value)
+	switch {
+	case strings.HasSuffix(v, `\9`):
+		return BackslashNineHack
+	case strings.HasSuffix(v, `\0`), strings.HasSuffix(v, `\0/`):
+		return BackslashZeroHack
+	case strings.HasSuffix(strings.ToLower(v), "!ie"):
+		return BangIEHack
+	}
+	return NoHack
+}
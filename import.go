@@ -0,0 +1,288 @@
+package css
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+	"text/scanner"
+)
+
+// importer loads the stylesheets referenced by @import rules.
+type importer interface {
+	// resolve returns the name of the stylesheet ref points to from the
+	// stylesheet named base, or false if the @import should be kept as is.
+	resolve(base, ref string) (string, bool)
+	// load returns the content of the named stylesheet and the name it
+	// should be attributed to, which may differ after a redirect.
+	load(name string) (string, []byte, error)
+}
+
+// ParseFS parses the stylesheet entry read from fsys and replaces every
+// @import of a relative path with the rules of the imported file, resolved
+// against the importing file's directory. Imports carrying a media query
+// list, a supports() condition or a layer are wrapped in the equivalent
+// @media, @supports and @layer rules. Imports of absolute URLs are kept as
+// @import rules. Each file is decoded on its own, as Parse decodes its
+// input, so that files of different encodings merge into one sheet of
+// UTF-8 text: the @charset of an imported file is dropped, and a file in
+// another encoding than the one importing it is reported as a
+// DiagImportEncoding diagnostic. Relative url() references in declaration
+// values of imported files are rewritten to be relative to entry, as
+// ParseURL makes them absolute, so that they still name the same files.
+func ParseFS(fsys fs.FS, entry string, opts ...Option) (*StyleSheet, error) {
+	b, err := fs.ReadFile(fsys, entry)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := parseImports(fsImporter{fsys}, entry, b, nil, newOptions(opts))
+	rebaseURLs(sheet, entry)
+	return sheet, err
+}
+
+type fsImporter struct {
+	fsys fs.FS
+}
+
+func (i fsImporter) resolve(base, ref string) (string, bool) {
+	if isAbsoluteURL(ref) {
+		return "", false
+	}
+	if strings.HasPrefix(ref, "/") {
+		return strings.TrimPrefix(path.Clean(ref), "/"), true
+	}
+	return path.Join(path.Dir(base), ref), true
+}
+
+func (i fsImporter) load(name string) (string, []byte, error) {
+	b, err := fs.ReadFile(i.fsys, name)
+	return name, b, err
+}
+
+// rebaseURLs rewrites the relative url() references of every declaration
+// read from another file than entry to be relative to entry instead.
+func rebaseURLs(sheet *StyleSheet, entry string) {
+	if sheet == nil {
+		return
+	}
+	dir := path.Dir(entry)
+	Walk(sheet, func(n Node) bool {
+		d, ok := n.(*Declaration)
+		if !ok || d.Pos.Filename == entry || path.Dir(d.Pos.Filename) == dir {
+			return true
+		}
+		d.Value = rewriteURLs(d.Value, func(ref string) string {
+			if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(ref, "/") || isAbsoluteURL(ref) {
+				return ref
+			}
+			file, rest := ref, ""
+			if i := strings.IndexAny(ref, "?#"); i >= 0 {
+				file, rest = ref[:i], ref[i:]
+			}
+			return relativePath(dir, path.Join(path.Dir(d.Pos.Filename), file)) + rest
+		})
+		return true
+	})
+}
+
+// relativePath returns the slash-separated path of target relative to the
+// directory dir, both clean and relative to the same root.
+func relativePath(dir, target string) string {
+	if dir == "." {
+		return target
+	}
+	from, to := strings.Split(dir, "/"), strings.Split(target, "/")
+	i := 0
+	for i < len(from) && i < len(to)-1 && from[i] == to[i] {
+		i++
+	}
+	var b strings.Builder
+	for range from[i:] {
+		b.WriteString("../")
+	}
+	b.WriteString(strings.Join(to[i:], "/"))
+	return b.String()
+}
+
+// parseImports parses b as the stylesheet name and inlines its @import
+// rules. stack holds the names of the importing stylesheets, outermost
+// first.
+func parseImports(imp importer, name string, b []byte, stack []string, o options) (*StyleSheet, error) {
+	sheet, err := parseReader(bytes.NewReader(b), name, o)
+	if err != nil {
+		return sheet, err
+	}
+	stack = append(stack, name)
+
+	rules := make([]Node, 0, len(sheet.Rules))
+	var comments []Comment
+	var enc string // of b, once an @import is followed
+	for _, n := range sheet.Rules {
+		at, ok := n.(*AtRule)
+		if !ok || at.Name != "import" {
+			rules = append(rules, n)
+			continue
+		}
+		spec, err := ParseImport(at.Prelude)
+		if err != nil {
+			return sheet, errorAt(at.Pos, "%v", err)
+		}
+		ref := spec.URL
+		target, ok := imp.resolve(name, ref)
+		if !ok {
+			rules = append(rules, n)
+			continue
+		}
+		for i := range stack {
+			if stack[i] == target {
+				cycle := strings.Join(append(stack[i:], target), " -> ")
+				err := &ResolveError{URL: ref, Err: errImportCycle}
+				return sheet, &ParseError{Pos: at.Pos, Msg: "@import cycle: " + cycle, Err: err}
+			}
+		}
+		if o.maxImportDepth > 0 && len(stack) > o.maxImportDepth {
+			err := &LimitError{Limit: LimitImportDepth, Max: int64(o.maxImportDepth)}
+			return sheet, &ParseError{Pos: at.Pos, Msg: fmt.Sprintf("@import %q: %v", ref, err), Err: err}
+		}
+		target, data, err := imp.load(target)
+		if err != nil {
+			msg := fmt.Sprintf("@import %q: %v", ref, err)
+			if _, ok := err.(*LimitError); !ok {
+				err = &ResolveError{URL: ref, Err: err}
+			}
+			return sheet, &ParseError{Pos: at.Pos, Msg: msg, Err: err}
+		}
+		if enc == "" {
+			enc = encodingOf(b)
+		}
+		if e := encodingOf(data); e != enc {
+			o.diagnose(SeverityInfo, DiagImportEncoding, at.Pos, "@import %q is %s, imported from %s", ref, e, enc)
+		}
+		imported, err := parseImports(imp, target, data, stack, o)
+		if err != nil {
+			return sheet, err
+		}
+		if len(imported.Rules) > 0 {
+			if c, ok := imported.Rules[0].(*AtRule); ok && asciiLower(c.Name) == "charset" {
+				imported.Rules = imported.Rules[1:]
+			}
+		}
+
+		comments = append(comments, imported.Comments...)
+		if spec == (Import{URL: ref}) {
+			rules = append(rules, imported.Rules...)
+		} else {
+			rules = append(rules, spec.wrap(imported.Rules, at.Pos)...)
+		}
+	}
+	sheet.Rules = rules
+	sheet.Comments = append(sheet.Comments, comments...)
+	sheet.Layers = declaredLayers(rules)
+	return sheet, nil
+}
+
+// Import is the prelude of an @import rule, such as
+// url("grid.css") layer(base) supports(display: grid) screen.
+type Import struct {
+	URL string // unquoted and unescaped
+	// Layer is set for an import into a cascade layer, which is anonymous
+	// if LayerName is empty.
+	Layer     bool
+	LayerName string
+	// Supports is the argument of supports(), as in "display: grid".
+	Supports string
+	// Media is the media query list, or empty for all media.
+	Media string
+}
+
+// ParseImport parses the prelude of an @import rule.
+func ParseImport(prelude string) (Import, error) {
+	var imp Import
+	s := strings.TrimSpace(prelude)
+	var end int
+	closed := false
+	switch {
+	case strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "'"):
+		end = skipString(s, 0)
+		closed = end > 1 && s[end-1] == s[0]
+		imp.URL, _ = unquote(s[:end])
+	case len(s) > 4 && strings.EqualFold(s[:4], "url("):
+		var ref string
+		ref, end = parenthesized(s, 3)
+		closed = s[end-1] == ')'
+		if url, q := unquote(strings.TrimSpace(ref)); q == 0 {
+			imp.URL = unescape(url)
+		} else {
+			imp.URL = url
+		}
+	}
+	if !closed || imp.URL == "" {
+		return Import{}, fmt.Errorf("invalid @import %s", prelude)
+	}
+	s = strings.TrimSpace(s[end:])
+	if name := skipName(s, 0); strings.EqualFold(s[:name], "layer") {
+		imp.Layer = true
+		if s = s[name:]; strings.HasPrefix(s, "(") {
+			var arg string
+			arg, end = parenthesized(s, 0)
+			imp.LayerName, s = strings.TrimSpace(arg), s[end:]
+		}
+		s = strings.TrimSpace(s)
+	}
+	if name := skipName(s, 0); strings.EqualFold(s[:name], "supports") && strings.HasPrefix(s[name:], "(") {
+		arg, end := parenthesized(s, name)
+		imp.Supports, s = strings.TrimSpace(arg), strings.TrimSpace(s[end:])
+	}
+	imp.Media = s
+	return imp, nil
+}
+
+// String returns the prelude of an @import rule for imp.
+func (imp Import) String() string {
+	s := "url(" + QuoteString(imp.URL) + ")"
+	if imp.Layer {
+		s += " layer"
+		if imp.LayerName != "" {
+			s += "(" + imp.LayerName + ")"
+		}
+	}
+	if imp.Supports != "" {
+		s += " supports(" + imp.Supports + ")"
+	}
+	if imp.Media != "" {
+		s += " " + imp.Media
+	}
+	return s
+}
+
+// wrap returns nodes in the at-rules scoping the rules imported by imp:
+// @layer in @supports in @media.
+func (imp Import) wrap(nodes []Node, pos scanner.Position) []Node {
+	if nodes == nil {
+		nodes = []Node{}
+	}
+	if imp.Layer {
+		nodes = []Node{&AtRule{Name: "layer", Prelude: imp.LayerName, Rules: nodes, Pos: pos}}
+	}
+	if cond := imp.Supports; cond != "" {
+		if end := skipName(cond, 0); end > 0 && strings.HasPrefix(strings.TrimSpace(cond[end:]), ":") {
+			// A declaration, which @supports needs in parentheses.
+			cond = "(" + cond + ")"
+		}
+		nodes = []Node{&AtRule{Name: "supports", Prelude: cond, Rules: nodes, Pos: pos}}
+	}
+	if imp.Media != "" {
+		nodes = []Node{&AtRule{Name: "media", Prelude: imp.Media, Rules: nodes, Pos: pos}}
+	}
+	return nodes
+}
+
+func isAbsoluteURL(ref string) bool {
+	if strings.HasPrefix(ref, "//") {
+		return true
+	}
+	u, err := url.Parse(ref)
+	return err == nil && u.IsAbs()
+}
@@ -0,0 +1,44 @@
+package css
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestParseFSEncodings checks that a UTF-8 file importing a UTF-16LE one,
+// tagged by its byte order mark, gives one sheet with the non-ASCII text of
+// both intact, without the @charset of the imported file, and with the
+// change of encoding reported at the @import.
+func TestParseFSEncodings(t *testing.T) {
+	var diags []string
+	sheet, err := ParseFS(os.DirFS(filepath.Join("testdata", "import-encoding")), "main.css", WithDiagnostics(func(d Diagnostic) {
+		diags = append(diags, fmt.Sprintf("%s %s %d:%d %s", d.Severity, d.Code, d.Pos.Line, d.Pos.Column, d.Message))
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`info import-encoding 1:1 @import "utf16le.css" is utf-16le, imported from utf-8`}; !reflect.DeepEqual(diags, want) {
+		t.Errorf("ParseFS reports %q, want %q", diags, want)
+	}
+	got := map[string][]string{}
+	for _, n := range sheet.Rules {
+		r, ok := n.(*RuleNode)
+		if !ok {
+			t.Errorf("ParseFS keeps the at-rule %+v", n)
+			continue
+		}
+		for _, d := range r.Declarations {
+			got[string(r.Selectors[0])] = append(got[string(r.Selectors[0])], d.Property+": "+d.Value)
+		}
+	}
+	want := map[string][]string{
+		".greeting": {`content: "こんにちは"`, `font-family: "Ärial Ünicode"`},
+		".title":    {`font-family: "Noto Sans"`, `content: "Überschrift"`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFS gives %q, want %q", got, want)
+	}
+}
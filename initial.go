@@ -0,0 +1,46 @@
+package css
+
+//go:generate go run gen_initial.go
+
+import "strings"
+
+// InitialValue returns the initial value of the standard longhand property
+// prop, such as "auto" for width, and whether it has one in the table. A
+// vendor-prefixed property has the initial value of the property it
+// prefixes. Shorthands and properties whose initial value depends on the
+// user agent, such as font-family, have none.
+func InitialValue(prop string) (string, bool) {
+	base, _ := Canonical(options{}.property(strings.TrimSpace(prop)))
+	v, ok := initialValues[base]
+	return v, ok
+}
+
+// WithDefaults returns a copy of styles, an entry of the map Unmarshal
+// returns, in which each of props has a value. A property that is not set is
+// given the value Get derives for it from shorthands, or else its initial
+// value; a property set to initial is given its initial value, keeping its
+// !important flag. Properties without an initial value are left unset.
+func WithDefaults(styles map[string]string, props ...string) map[string]string {
+	out := make(map[string]string, len(styles)+len(props))
+	for p, v := range styles {
+		out[p] = v
+	}
+	for _, p := range props {
+		p = options{}.property(strings.TrimSpace(p))
+		v, ok := getProperty(styles, p)
+		value, important := importance(v)
+		if ok && !strings.EqualFold(value, "initial") {
+			out[p] = v
+			continue
+		}
+		initial, known := InitialValue(p)
+		if !known {
+			continue
+		}
+		if important {
+			initial += " !important"
+		}
+		out[p] = initial
+	}
+	return out
+}
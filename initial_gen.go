@@ -0,0 +1,295 @@
+// Code generated by gen_initial.go from initial.txt; DO NOT EDIT.
+
+package css
+
+// initialValues maps the standard CSS longhand properties to their initial values.
+var initialValues = map[string]string{
+	"accent-color":               "auto",
+	"align-content":              "normal",
+	"align-items":                "normal",
+	"align-self":                 "auto",
+	"animation-composition":      "replace",
+	"animation-delay":            "0s",
+	"animation-direction":        "normal",
+	"animation-duration":         "0s",
+	"animation-fill-mode":        "none",
+	"animation-iteration-count":  "1",
+	"animation-name":             "none",
+	"animation-play-state":       "running",
+	"animation-timing-function":  "ease",
+	"appearance":                 "none",
+	"aspect-ratio":               "auto",
+	"backdrop-filter":            "none",
+	"backface-visibility":        "visible",
+	"background-attachment":      "scroll",
+	"background-blend-mode":      "normal",
+	"background-clip":            "border-box",
+	"background-color":           "transparent",
+	"background-image":           "none",
+	"background-origin":          "padding-box",
+	"background-position":        "0% 0%",
+	"background-position-x":      "0%",
+	"background-position-y":      "0%",
+	"background-repeat":          "repeat",
+	"background-size":            "auto",
+	"block-size":                 "auto",
+	"border-block-end-color":     "currentcolor",
+	"border-block-end-style":     "none",
+	"border-block-end-width":     "medium",
+	"border-block-start-color":   "currentcolor",
+	"border-block-start-style":   "none",
+	"border-block-start-width":   "medium",
+	"border-bottom-color":        "currentcolor",
+	"border-bottom-left-radius":  "0",
+	"border-bottom-right-radius": "0",
+	"border-bottom-style":        "none",
+	"border-bottom-width":        "medium",
+	"border-collapse":            "separate",
+	"border-end-end-radius":      "0",
+	"border-end-start-radius":    "0",
+	"border-image-outset":        "0",
+	"border-image-repeat":        "stretch",
+	"border-image-slice":         "100%",
+	"border-image-source":        "none",
+	"border-image-width":         "1",
+	"border-inline-end-color":    "currentcolor",
+	"border-inline-end-style":    "none",
+	"border-inline-end-width":    "medium",
+	"border-inline-start-color":  "currentcolor",
+	"border-inline-start-style":  "none",
+	"border-inline-start-width":  "medium",
+	"border-left-color":          "currentcolor",
+	"border-left-style":          "none",
+	"border-left-width":          "medium",
+	"border-right-color":         "currentcolor",
+	"border-right-style":         "none",
+	"border-right-width":         "medium",
+	"border-spacing":             "0",
+	"border-start-end-radius":    "0",
+	"border-start-start-radius":  "0",
+	"border-top-color":           "currentcolor",
+	"border-top-left-radius":     "0",
+	"border-top-right-radius":    "0",
+	"border-top-style":           "none",
+	"border-top-width":           "medium",
+	"bottom":                     "auto",
+	"box-decoration-break":       "slice",
+	"box-shadow":                 "none",
+	"box-sizing":                 "content-box",
+	"break-after":                "auto",
+	"break-before":               "auto",
+	"break-inside":               "auto",
+	"caption-side":               "top",
+	"caret-color":                "auto",
+	"clear":                      "none",
+	"clip":                       "auto",
+	"clip-path":                  "none",
+	"color":                      "canvastext",
+	"color-scheme":               "normal",
+	"column-count":               "auto",
+	"column-fill":                "balance",
+	"column-gap":                 "normal",
+	"column-rule-color":          "currentcolor",
+	"column-rule-style":          "none",
+	"column-rule-width":          "medium",
+	"column-span":                "none",
+	"column-width":               "auto",
+	"contain":                    "none",
+	"container-name":             "none",
+	"container-type":             "normal",
+	"content":                    "normal",
+	"content-visibility":         "visible",
+	"counter-increment":          "none",
+	"counter-reset":              "none",
+	"counter-set":                "none",
+	"cursor":                     "auto",
+	"direction":                  "ltr",
+	"display":                    "inline",
+	"empty-cells":                "show",
+	"filter":                     "none",
+	"flex-basis":                 "auto",
+	"flex-direction":             "row",
+	"flex-grow":                  "0",
+	"flex-shrink":                "1",
+	"flex-wrap":                  "nowrap",
+	"float":                      "none",
+	"font-feature-settings":      "normal",
+	"font-kerning":               "auto",
+	"font-language-override":     "normal",
+	"font-optical-sizing":        "auto",
+	"font-size":                  "medium",
+	"font-size-adjust":           "none",
+	"font-stretch":               "normal",
+	"font-style":                 "normal",
+	"font-synthesis":             "weight style small-caps",
+	"font-variant":               "normal",
+	"font-variant-caps":          "normal",
+	"font-variant-east-asian":    "normal",
+	"font-variant-ligatures":     "normal",
+	"font-variant-numeric":       "normal",
+	"font-variant-position":      "normal",
+	"font-variation-settings":    "normal",
+	"font-weight":                "normal",
+	"grid-auto-columns":          "auto",
+	"grid-auto-flow":             "row",
+	"grid-auto-rows":             "auto",
+	"grid-column-end":            "auto",
+	"grid-column-start":          "auto",
+	"grid-row-end":               "auto",
+	"grid-row-start":             "auto",
+	"grid-template-areas":        "none",
+	"grid-template-columns":      "none",
+	"grid-template-rows":         "none",
+	"height":                     "auto",
+	"hyphens":                    "manual",
+	"image-rendering":            "auto",
+	"inline-size":                "auto",
+	"inset-block-end":            "auto",
+	"inset-block-start":          "auto",
+	"inset-inline-end":           "auto",
+	"inset-inline-start":         "auto",
+	"isolation":                  "auto",
+	"justify-content":            "normal",
+	"justify-items":              "legacy",
+	"justify-self":               "auto",
+	"left":                       "auto",
+	"letter-spacing":             "normal",
+	"line-break":                 "auto",
+	"line-height":                "normal",
+	"list-style-image":           "none",
+	"list-style-position":        "outside",
+	"list-style-type":            "disc",
+	"margin-block-end":           "0",
+	"margin-block-start":         "0",
+	"margin-bottom":              "0",
+	"margin-inline-end":          "0",
+	"margin-inline-start":        "0",
+	"margin-left":                "0",
+	"margin-right":               "0",
+	"margin-top":                 "0",
+	"mask-clip":                  "border-box",
+	"mask-composite":             "add",
+	"mask-image":                 "none",
+	"mask-mode":                  "match-source",
+	"mask-origin":                "border-box",
+	"mask-position":              "0% 0%",
+	"mask-repeat":                "repeat",
+	"mask-size":                  "auto",
+	"max-block-size":             "none",
+	"max-height":                 "none",
+	"max-inline-size":            "none",
+	"max-width":                  "none",
+	"min-block-size":             "auto",
+	"min-height":                 "auto",
+	"min-inline-size":            "auto",
+	"min-width":                  "auto",
+	"mix-blend-mode":             "normal",
+	"object-fit":                 "fill",
+	"object-position":            "50% 50%",
+	"offset-anchor":              "auto",
+	"offset-distance":            "0",
+	"offset-path":                "none",
+	"offset-position":            "normal",
+	"offset-rotate":              "auto",
+	"opacity":                    "1",
+	"order":                      "0",
+	"orphans":                    "2",
+	"outline-color":              "auto",
+	"outline-offset":             "0",
+	"outline-style":              "none",
+	"outline-width":              "medium",
+	"overflow-anchor":            "auto",
+	"overflow-wrap":              "normal",
+	"overflow-x":                 "visible",
+	"overflow-y":                 "visible",
+	"overscroll-behavior-x":      "auto",
+	"overscroll-behavior-y":      "auto",
+	"padding-block-end":          "0",
+	"padding-block-start":        "0",
+	"padding-bottom":             "0",
+	"padding-inline-end":         "0",
+	"padding-inline-start":       "0",
+	"padding-left":               "0",
+	"padding-right":              "0",
+	"padding-top":                "0",
+	"page-break-after":           "auto",
+	"page-break-before":          "auto",
+	"page-break-inside":          "auto",
+	"perspective":                "none",
+	"perspective-origin":         "50% 50%",
+	"pointer-events":             "auto",
+	"position":                   "static",
+	"print-color-adjust":         "economy",
+	"quotes":                     "auto",
+	"resize":                     "none",
+	"right":                      "auto",
+	"rotate":                     "none",
+	"row-gap":                    "normal",
+	"ruby-position":              "alternate",
+	"scale":                      "none",
+	"scroll-behavior":            "auto",
+	"scroll-margin-bottom":       "0",
+	"scroll-margin-left":         "0",
+	"scroll-margin-right":        "0",
+	"scroll-margin-top":          "0",
+	"scroll-padding-bottom":      "auto",
+	"scroll-padding-left":        "auto",
+	"scroll-padding-right":       "auto",
+	"scroll-padding-top":         "auto",
+	"scroll-snap-align":          "none",
+	"scroll-snap-stop":           "normal",
+	"scroll-snap-type":           "none",
+	"scrollbar-color":            "auto",
+	"scrollbar-gutter":           "auto",
+	"scrollbar-width":            "auto",
+	"shape-image-threshold":      "0",
+	"shape-margin":               "0",
+	"shape-outside":              "none",
+	"tab-size":                   "8",
+	"table-layout":               "auto",
+	"text-align":                 "start",
+	"text-align-last":            "auto",
+	"text-combine-upright":       "none",
+	"text-decoration-color":      "currentcolor",
+	"text-decoration-line":       "none",
+	"text-decoration-skip-ink":   "auto",
+	"text-decoration-style":      "solid",
+	"text-decoration-thickness":  "auto",
+	"text-emphasis-color":        "currentcolor",
+	"text-emphasis-position":     "over right",
+	"text-emphasis-style":        "none",
+	"text-indent":                "0",
+	"text-justify":               "auto",
+	"text-orientation":           "mixed",
+	"text-overflow":              "clip",
+	"text-rendering":             "auto",
+	"text-shadow":                "none",
+	"text-transform":             "none",
+	"text-underline-offset":      "auto",
+	"text-underline-position":    "auto",
+	"text-wrap":                  "wrap",
+	"top":                        "auto",
+	"touch-action":               "auto",
+	"transform":                  "none",
+	"transform-box":              "view-box",
+	"transform-origin":           "50% 50% 0",
+	"transform-style":            "flat",
+	"transition-behavior":        "normal",
+	"transition-delay":           "0s",
+	"transition-duration":        "0s",
+	"transition-property":        "all",
+	"transition-timing-function": "ease",
+	"translate":                  "none",
+	"unicode-bidi":               "normal",
+	"user-select":                "auto",
+	"vertical-align":             "baseline",
+	"visibility":                 "visible",
+	"white-space":                "normal",
+	"widows":                     "2",
+	"width":                      "auto",
+	"will-change":                "auto",
+	"word-break":                 "normal",
+	"word-spacing":               "normal",
+	"writing-mode":               "horizontal-tb",
+	"z-index":                    "auto",
+}
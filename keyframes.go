@@ -0,0 +1,115 @@
+package css
+
+import "strings"
+
+// RenameKeyframes renames, in place, the @keyframes rules of sheet at any
+// depth, vendor-prefixed ones included, to rename of their names, and
+// rewrites the references to them in the animation-name and animation
+// declarations, prefixed or not, including every animation of a
+// comma-separated list. It returns the new name of each renamed
+// @keyframes. Names are unquoted and unescaped for rename, and written back
+// quoted if they are not identifiers. References to names the sheet does
+// not define are kept, as are var() references.
+func RenameKeyframes(sheet *StyleSheet, rename func(string) string) map[string]string {
+	names := make(map[string]string)
+	Walk(sheet, func(n Node) bool {
+		if at, ok := n.(*AtRule); ok {
+			if base, _ := Canonical(asciiLower(at.Name)); base == "keyframes" {
+				name := keyframesName(strings.TrimSpace(at.Prelude))
+				if _, ok := names[name]; !ok {
+					names[name] = rename(name)
+				}
+				at.Prelude = identOrString(names[name])
+			}
+		}
+		return true
+	})
+	if len(names) == 0 {
+		return names
+	}
+	Walk(sheet, func(n Node) bool {
+		d, ok := n.(*Declaration)
+		if !ok {
+			return true
+		}
+		switch base, _ := Canonical(asciiLower(d.Property)); base {
+		case "animation-name", "animation":
+			d.Value = renameAnimations(d.Value, base == "animation", names)
+		}
+		return true
+	})
+	return names
+}
+
+// keyframesName returns the unquoted and unescaped name of the
+// <keyframes-name> s.
+func keyframesName(s string) string {
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		return familyName(s)
+	}
+	return unescape(s)
+}
+
+// identOrString returns name as an identifier if it is one, or a string.
+func identOrString(name string) string {
+	if startsIdent(name, 0) && skipName(name, 0) == len(name) && !isWideKeyword(name) && !strings.EqualFold(name, "none") {
+		return name
+	}
+	return QuoteString(name)
+}
+
+// renameAnimations returns value, of animation-name or, if shorthand is
+// set, animation, with the names of names replaced.
+func renameAnimations(value string, shorthand bool, names map[string]string) string {
+	items := splitSelectorList(value)
+	changed := false
+	for i, item := range items {
+		words := Fields(item)
+		for j, w := range words {
+			if !isAnimationName(w) {
+				if shorthand {
+					continue
+				}
+				break
+			}
+			// The first word that can be a name is the name.
+			if to, ok := names[keyframesName(w)]; ok {
+				words[j], changed = identOrString(to), true
+			}
+			break
+		}
+		items[i] = strings.Join(words, " ")
+	}
+	if !changed {
+		return value
+	}
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return strings.Join(items, ", ")
+}
+
+// isAnimationName reports whether the word w of an animation value can be
+// the animation name: a string, or an identifier that is not a keyword of
+// another animation property.
+func isAnimationName(w string) bool {
+	if w == "" {
+		return false
+	}
+	if w[0] == '"' || w[0] == '\'' {
+		return true
+	}
+	if !startsIdent(w, 0) || skipName(w, 0) != len(w) {
+		return false
+	}
+	return !animationKeywords[asciiLower(w)] && !isWideKeyword(w)
+}
+
+// animationKeywords lists the keywords of the animation longhands other
+// than animation-name, and "none".
+var animationKeywords = map[string]bool{
+	"linear": true, "ease": true, "ease-in": true, "ease-out": true, "ease-in-out": true,
+	"step-start": true, "step-end": true, "infinite": true, "normal": true, "reverse": true,
+	"alternate": true, "alternate-reverse": true, "none": true, "forwards": true,
+	"backwards": true, "both": true, "running": true, "paused": true,
+}
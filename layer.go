@@ -0,0 +1,142 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+)
+
+// layerRank is the place of a cascade layer in the layer order: the index
+// of each of its names among the sublayers of its parent. The rules of a
+// layer come after those of its sublayers, so a rank is greater than the
+// ranks it is a prefix of, and nil, the rank of the rules outside of any
+// layer, is greater than all.
+type layerRank []int
+
+// compareRanks returns -1 if a comes before b in the layer order, 1 if it
+// comes after, and 0 if they are the same.
+func compareRanks(a, b layerRank) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return 1
+	case len(a) > len(b):
+		return -1
+	}
+	return 0
+}
+
+// layerOrder gives the cascade layers of a stylesheet their ranks by the
+// order in which they are declared.
+type layerOrder struct {
+	index     map[string]int // of each layer, by full name, among its siblings
+	next      map[string]int // index of the next sublayer, by full name
+	names     []string       // of the named layers, in declaration order
+	anonymous int
+}
+
+func newLayerOrder(declared []string) *layerOrder {
+	l := &layerOrder{index: make(map[string]int), next: make(map[string]int)}
+	for _, name := range declared {
+		l.declare(name)
+	}
+	return l
+}
+
+// declare declares the layer of full name name and its parents, unless
+// they already are.
+func (l *layerOrder) declare(name string) {
+	parent := ""
+	for _, part := range strings.Split(name, ".") {
+		full := part
+		if parent != "" {
+			full = parent + "." + part
+		}
+		if _, ok := l.index[full]; !ok {
+			l.index[full] = l.next[parent]
+			l.next[parent]++
+			if !strings.Contains(full, "<") {
+				l.names = append(l.names, full)
+			}
+		}
+		parent = full
+	}
+}
+
+// rank returns the rank of the declared layer of full name name, or nil if
+// name is empty.
+func (l *layerOrder) rank(name string) layerRank {
+	if name == "" {
+		return nil
+	}
+	var rank layerRank
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			rank = append(rank, l.index[name[:i]])
+		}
+	}
+	return rank
+}
+
+// walk declares the layers of nodes, which are in the layer of full name
+// layer, in source order, and calls fn for each rule outside of at-rules
+// other than @layer if cascaded is set. Anonymous layers get names that
+// cannot be declared, as "<1>".
+func (l *layerOrder) walk(nodes []Node, layer string, cascaded bool, fn func(*RuleNode, layerRank)) {
+	within := func(name string) string {
+		if name = strings.TrimSpace(name); layer != "" {
+			return layer + "." + name
+		}
+		return name
+	}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			if cascaded {
+				fn(n, l.rank(layer))
+			}
+		case *AtRule:
+			switch name := asciiLower(n.Name); {
+			case name == "layer" && n.Rules == nil:
+				for _, name := range splitSelectorList(n.Prelude) {
+					l.declare(within(name))
+				}
+			case name == "layer":
+				name := strings.TrimSpace(n.Prelude)
+				if name == "" {
+					l.anonymous++
+					name = "<" + strconv.Itoa(l.anonymous) + ">"
+				}
+				l.declare(within(name))
+				l.walk(n.Rules, within(name), cascaded, fn)
+			case name == "import":
+				if imp, err := ParseImport(n.Prelude); err == nil && imp.Layer && imp.LayerName != "" {
+					l.declare(within(imp.LayerName))
+				}
+			default:
+				l.walk(n.Rules, layer, false, fn)
+			}
+		}
+	}
+}
+
+// declaredLayers returns the names of the layers nodes declare, in order.
+func declaredLayers(nodes []Node) []string {
+	l := newLayerOrder(nil)
+	l.walk(nodes, "", false, nil)
+	return l.names
+}
+
+// layeredRules calls fn, in source order, for the rules of sheet the
+// cascade reads: the top-level rules and those of @layer blocks, with the
+// nested rules resolved, each with the rank of its layer in the order of
+// sheet.Layers and then of the layers it declares.
+func layeredRules(sheet *StyleSheet, fn func(*RuleNode, layerRank)) {
+	newLayerOrder(sheet.Layers).walk(unnest(sheet.Rules, DialectCSS), "", true, fn)
+}
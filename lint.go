@@ -0,0 +1,560 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+	"text/scanner"
+)
+
+// Problem is an issue reported by Lint.
+type Problem struct {
+	// Code identifies the check that reported the problem, such as
+	// "unknown-property".
+	Code    string
+	Message string
+	// Selector is the selector list of the rule the problem was found in,
+	// or the at-rule name for descriptor blocks such as @font-face.
+	Selector string
+	// Kinds holds the kinds of simple selectors Selector uses, as Uses
+	// tells them, and is zero for at-rules.
+	Kinds    RuleKind
+	Property string
+	Pos      scanner.Position
+	// Related holds the positions of other declarations involved in the
+	// problem, such as the earlier occurrence of a duplicate.
+	Related []scanner.Position
+	// Suggestion is the known property an unknown-property problem's
+	// property is likely a typo of, such as "padding" for "pading", or "".
+	Suggestion string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s (%s)", p.Pos, p.Message, p.Code)
+}
+
+// Codes of the checks run by Lint.
+const (
+	CodeUnknownProperty      = "unknown-property"
+	CodeDuplicateDeclaration = "duplicate-declaration"
+	CodeEmptyRule            = "empty-rule"
+	CodeOverridden           = "overridden-declaration"
+	CodeInvalidValue         = "invalid-value"
+	CodeUnusedCustomProperty = "unused-custom-property"
+	CodeUndefinedVar         = "undefined-var"
+	CodeSpecificity          = "specificity-budget"
+)
+
+// LintOptions configures Lint. The zero value runs every check except the
+// opt-in ones enabled by CheckValues, CheckCustomProperties and
+// MaxSpecificity.
+type LintOptions struct {
+	// Disable lists the codes of checks to skip.
+	Disable []string
+	// AllowProperties lists property names accepted by the unknown-property
+	// check in addition to the standard ones.
+	AllowProperties []string
+	// CheckVendorPrefixed makes the unknown-property check verify the
+	// unprefixed name of vendor prefixed properties, which it otherwise
+	// skips. Custom properties are always skipped.
+	CheckVendorPrefixed bool
+	// CheckValues enables the invalid-value check, which validates the
+	// values of common properties. See RegisterPropertySyntax.
+	CheckValues bool
+	// CheckCustomProperties enables the unused-custom-property check, which
+	// reports custom properties no var() of the sheet refers to, and the
+	// undefined-var check, which reports var() references to custom
+	// properties the sheet neither sets nor registers with @property.
+	// References in fallbacks and in the values of custom properties count.
+	CheckCustomProperties bool
+	// MaxSpecificity enables the specificity-budget check, which reports
+	// the selectors more specific than it, as resolved against the rules
+	// they are nested in. A budget of (0,3,0) also rules out ids, which
+	// ReplaceIDSelectors can rewrite.
+	MaxSpecificity *Specificity
+}
+
+// atRuleDescriptors lists the descriptors accepted in the blocks of
+// at-rules holding declarations, besides standard properties for @page.
+var atRuleDescriptors = map[string]map[string]bool{
+	"font-face": {
+		"ascent-override": true, "descent-override": true, "font-display": true, "font-family": true,
+		"font-feature-settings": true, "font-stretch": true, "font-style": true, "font-variation-settings": true,
+		"font-weight": true, "line-gap-override": true, "size-adjust": true, "src": true, "unicode-range": true,
+	},
+	"counter-style": {
+		"additive-symbols": true, "fallback": true, "negative": true, "pad": true, "prefix": true,
+		"range": true, "speak-as": true, "suffix": true, "symbols": true, "system": true,
+	},
+	"property":            {"inherits": true, "initial-value": true, "syntax": true},
+	"font-palette-values": {"base-palette": true, "font-family": true, "override-colors": true},
+	"page":                {"bleed": true, "marks": true, "page-orientation": true, "size": true},
+}
+
+// Lint checks sheet for likely mistakes and returns the problems found in
+// source order, followed by those about control comments. Control comments
+// suppress the problems of the listed codes, or of every code if none are
+// listed:
+//
+//	/* css-lint-disable [codes] */            from here to a matching enable
+//	/* css-lint-enable [codes] */             or the end of the file
+//	/* css-lint-disable-line [codes] */       on the line of the comment
+//	/* css-lint-disable-next-line [codes] */  on the line after it
+//
+// Codes are separated by spaces or commas, and text after " -- " is a
+// description. A directive that suppresses nothing is reported with
+// CodeUnusedDirective.
+func Lint(sheet *StyleSheet, opts LintOptions) []Problem {
+	l := &linter{
+		opts:     opts,
+		disabled: make(map[string]bool),
+		allowed:  make(map[string]bool),
+	}
+	for _, code := range opts.Disable {
+		l.disabled[code] = true
+	}
+	for _, p := range opts.AllowProperties {
+		l.allowed[p] = true
+	}
+	if opts.CheckCustomProperties {
+		l.declared, l.used = customProperties(sheet)
+	}
+	if !l.disabled[CodeOverridden] {
+		l.dead = overridden(sheet.Rules)
+	}
+	l.nodes(sheet.Rules, nil)
+	l.applyDirectives(sheet)
+	return l.problems
+}
+
+type linter struct {
+	opts     LintOptions
+	disabled map[string]bool
+	allowed  map[string]bool
+	problems []Problem
+
+	// declared and used hold the custom properties the sheet sets or
+	// registers and those its var() references name, if
+	// CheckCustomProperties is set.
+	declared, used map[string]bool
+	// dead maps the rules with overridden declarations to them, as
+	// returned by overridden.
+	dead map[*RuleNode]map[int][]scanner.Position
+	// suggested caches the suggestions for unknown properties, since a
+	// sheet tends to repeat its typos.
+	suggested map[string]string
+}
+
+// report records p, with its message built from format and args, unless
+// its check is disabled.
+func (l *linter) report(p Problem, format string, args ...interface{}) {
+	if l.disabled[p.Code] {
+		return
+	}
+	p.Message = fmt.Sprintf(format, args...)
+	if p.Selector != "" && !strings.HasPrefix(p.Selector, "@") {
+		p.Kinds = selectorKinds(p.Selector)
+	}
+	l.problems = append(l.problems, p)
+}
+
+// nodes runs the checks over nodes, nested in rules of the resolved
+// selectors parents if there are any.
+func (l *linter) nodes(nodes []Node, parents []Rule) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			sel := selectorText(n.Selectors)
+			if len(n.Declarations) == 0 && len(n.Rules) == 0 {
+				l.report(Problem{Code: CodeEmptyRule, Selector: sel, Pos: n.Pos}, "empty rule %s", sel)
+			}
+			sels := n.Selectors
+			if parents != nil {
+				sels = nestSelectors(parents, sels, DialectCSS)
+			}
+			l.specificity(sel, sels, n.Pos)
+			l.block(sel, n.Declarations, "", l.dead[n])
+			l.nodes(n.Rules, sels)
+		case *AtRule:
+			if (n.Declarations != nil && len(n.Declarations) == 0) || (n.Rules != nil && len(n.Rules) == 0) {
+				l.report(Problem{Code: CodeEmptyRule, Selector: "@" + n.Name, Pos: n.Pos}, "empty @%s block", n.Name)
+			}
+			if l.used != nil && asciiLower(n.Name) == "property" && !l.used[n.Prelude] {
+				l.report(Problem{Code: CodeUnusedCustomProperty, Selector: "@" + n.Name, Property: n.Prelude, Pos: n.Pos},
+					"custom property %s is registered but never used", n.Prelude)
+			}
+			if n.Declarations != nil {
+				l.block("@"+n.Name, n.Declarations, n.Name, nil)
+			}
+			l.nodes(n.Rules, parents)
+		}
+	}
+}
+
+// specificity reports the resolved selectors sels of the rule of the
+// selector list selector at pos that are over the specificity budget.
+func (l *linter) specificity(selector string, sels []Rule, pos scanner.Position) {
+	if l.opts.MaxSpecificity == nil {
+		return
+	}
+	max := *l.opts.MaxSpecificity
+	for _, sel := range sels {
+		if s := sel.Specificity(); max.Less(s) {
+			l.report(Problem{Code: CodeSpecificity, Selector: selector, Pos: pos},
+				"selector %s has specificity %v, over the budget of %v", sel, s, max)
+		}
+	}
+}
+
+// block runs the declaration checks over a rule block, or over the block of
+// the at-rule named atRule if it is not empty. dead maps the indexes of
+// declarations overridden by later rules to the overriding positions.
+func (l *linter) block(selector string, decls []Declaration, atRule string, dead map[int][]scanner.Position) {
+	last := make(map[string]int, len(decls))
+	for i, d := range decls {
+		l.unknownProperty(selector, d, atRule)
+		if l.opts.CheckValues && atRule == "" {
+			l.value(selector, d)
+		}
+		if l.used != nil && atRule == "" {
+			l.customProperty(selector, d)
+		}
+		if j, ok := last[d.Property]; ok {
+			l.duplicate(selector, decls[j], d)
+		}
+		last[d.Property] = i
+		if related := dead[i]; related != nil {
+			l.report(Problem{
+				Code:     CodeOverridden,
+				Selector: selector,
+				Property: d.Property,
+				Pos:      d.Pos,
+				Related:  related,
+			}, "%q in %s is overridden at line %d", d.Property, selector, related[0].Line)
+		}
+	}
+}
+
+func (l *linter) unknownProperty(selector string, d Declaration, atRule string) {
+	name := strings.TrimLeft(d.Property, "*_")
+	if strings.HasPrefix(name, "--") || l.allowed[d.Property] || l.allowed[name] {
+		return
+	}
+	base, prefix := Canonical(name)
+	if prefix != "" {
+		if !l.opts.CheckVendorPrefixed {
+			return
+		}
+		name = base
+	}
+
+	p := Problem{Code: CodeUnknownProperty, Selector: selector, Property: d.Property, Pos: d.Pos}
+	if descriptors, ok := atRuleDescriptors[atRule]; ok {
+		if descriptors[name] || (atRule == "page" && knownProperties[name]) {
+			return
+		}
+		l.report(p, "unknown descriptor %q in @%s", d.Property, atRule)
+		return
+	}
+	if knownProperties[name] {
+		return
+	}
+	if prefix == "" {
+		s, ok := l.suggested[name]
+		if !ok {
+			s = suggestProperty(name)
+			if l.suggested == nil {
+				l.suggested = make(map[string]string)
+			}
+			l.suggested[name] = s
+		}
+		p.Suggestion = s
+	}
+	if p.Suggestion != "" {
+		l.report(p, "unknown property %q, did you mean %q?", d.Property, p.Suggestion)
+	} else {
+		l.report(p, "unknown property %q", d.Property)
+	}
+}
+
+// customProperty reports d setting a custom property that is never used,
+// and the var() references in d to custom properties never declared.
+func (l *linter) customProperty(selector string, d Declaration) {
+	if strings.HasPrefix(d.Property, "--") && !l.used[d.Property] {
+		l.report(Problem{Code: CodeUnusedCustomProperty, Selector: selector, Property: d.Property, Pos: d.Pos},
+			"custom property %s is never used", d.Property)
+	}
+	for _, name := range varReferences(nil, d.Value) {
+		if !l.declared[name] {
+			l.report(Problem{Code: CodeUndefinedVar, Selector: selector, Property: d.Property, Pos: d.Pos},
+				"var(%s) in %s refers to an undefined custom property", name, d.Property)
+		}
+	}
+}
+
+// customProperties returns the custom properties sheet sets or registers
+// with @property and those named by the var() references of its values.
+func customProperties(sheet *StyleSheet) (declared, used map[string]bool) {
+	declared, used = make(map[string]bool), make(map[string]bool)
+	Walk(sheet, func(n Node) bool {
+		switch n := n.(type) {
+		case *AtRule:
+			if asciiLower(n.Name) == "property" {
+				declared[n.Prelude] = true
+			}
+		case *Declaration:
+			if strings.HasPrefix(n.Property, "--") {
+				declared[n.Property] = true
+			}
+			for _, name := range varReferences(nil, n.Value) {
+				used[name] = true
+			}
+		}
+		return true
+	})
+	return declared, used
+}
+
+// varReferences appends the custom properties named by the var() calls in
+// value to names, including those nested in the fallbacks of others and in
+// other functions. Strings and url() are skipped.
+func varReferences(names []string, value string) []string {
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\'':
+			i = skipString(value, i)
+			continue
+		case c == '\\' || isNameRune(rune(c)):
+		default:
+			i++
+			continue
+		}
+		end := skipName(value, i)
+		if end == len(value) || value[end] != '(' {
+			i = end
+			continue
+		}
+		args, next := parenthesized(value, end)
+		switch asciiLower(value[i:end]) {
+		case "url":
+		case "var":
+			name, fallback, _ := strings.Cut(args, ",")
+			if name = strings.TrimSpace(name); strings.HasPrefix(name, "--") {
+				names = append(names, name)
+			}
+			names = varReferences(names, fallback)
+		default:
+			names = varReferences(names, args)
+		}
+		i = next
+	}
+	return names
+}
+
+func (l *linter) value(selector string, d Declaration) {
+	if d.Hack() != NoHack {
+		return
+	}
+	if err := validateValue(d.Property, d.Value); err != nil {
+		l.report(Problem{Code: CodeInvalidValue, Selector: selector, Property: d.Property, Pos: d.Pos},
+			"invalid value %q for %s: %v", strings.TrimSpace(d.Value), d.Property, err)
+	}
+}
+
+// duplicate reports d setting the same property as prev earlier in the
+// block, unless d looks like a progressive enhancement of prev.
+func (l *linter) duplicate(selector string, prev, d Declaration) {
+	if d.Hack() != NoHack || prev.Hack() != NoHack {
+		return
+	}
+	a, b := normalizeValue(prev.Value), normalizeValue(d.Value)
+	if a != b && progressive(a, b) {
+		return
+	}
+	winner := d
+	if prev.Important && !d.Important {
+		winner = prev
+	}
+	l.report(Problem{
+		Code:     CodeDuplicateDeclaration,
+		Selector: selector,
+		Property: d.Property,
+		Pos:      d.Pos,
+		Related:  []scanner.Position{prev.Pos},
+	}, "duplicate %q at lines %d and %d; line %d wins", d.Property, prev.Pos.Line, d.Pos.Line, winner.Pos.Line)
+}
+
+// overridden finds the declarations of the rules in nodes, at any depth,
+// that lose, for every selector of their rule, to a declaration of another
+// rule with the same selector in the same scope: under the same chain of
+// at-rules, compared with normalized preludes, and nested in rules with
+// the same selectors. The declaration that wins is the last !important
+// one, or the last one if none is, unless it is a fallback the loser is
+// kept for. Such declarations never apply. They are returned by rule and
+// declaration index with the positions of the overriding declarations.
+// Selectors with attribute selectors or functional pseudo-classes such as
+// :is() are left out, as are declarations using hacks.
+func overridden(nodes []Node) map[*RuleNode]map[int][]scanner.Position {
+	type scoped struct {
+		rule  *RuleNode
+		sels  []Rule // resolved against the parents of nested rules
+		scope string
+	}
+	type setting struct {
+		rule int
+		decl Declaration
+	}
+	var rules []scoped
+	var walk func(nodes []Node, parents []Rule, scope string)
+	walk = func(nodes []Node, parents []Rule, scope string) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *RuleNode:
+				sels := n.Selectors
+				if parents != nil {
+					sels = nestSelectors(parents, sels, DialectCSS)
+				}
+				rules = append(rules, scoped{n, sels, scope})
+				walk(n.Rules, sels, scope)
+			case *AtRule:
+				prelude := strings.Join(strings.Fields(n.Prelude), " ")
+				if asciiLower(n.Name) == "media" {
+					prelude = mediaKey(n.Prelude)
+				}
+				walk(n.Rules, parents, scope+"@"+asciiLower(n.Name)+" "+prelude+"{")
+			}
+		}
+	}
+	walk(nodes, nil, "")
+
+	// wins[scope+sel][prop] is the setting of prop for sel that applies:
+	// the last !important one, or the last one if none is. Hacks are left
+	// out, since only some browsers read them.
+	wins := make(map[string]map[string]setting)
+	for i, r := range rules {
+		for _, sel := range r.sels {
+			props := wins[r.scope+string(sel)]
+			if props == nil {
+				props = make(map[string]setting)
+				wins[r.scope+string(sel)] = props
+			}
+			for _, d := range r.rule.Declarations {
+				if w, ok := props[d.Property]; d.Hack() == NoHack && (!ok || d.Important || !w.decl.Important) {
+					props[d.Property] = setting{i, d}
+				}
+			}
+		}
+	}
+
+	dead := make(map[*RuleNode]map[int][]scanner.Position)
+	for i, r := range rules {
+		if len(r.sels) == 0 {
+			continue
+		}
+		provable := true
+		for _, sel := range r.sels {
+			provable = provable && !sel.Uses(KindAttribute) && !strings.Contains(string(sel), "(")
+		}
+		if !provable {
+			continue
+		}
+	decls:
+		for j, d := range r.rule.Declarations {
+			if d.Hack() != NoHack {
+				continue
+			}
+			var related []scanner.Position
+			for _, sel := range r.sels {
+				// A setting in the same rule is a duplicate, reported as one.
+				w, ok := wins[r.scope+string(sel)][d.Property]
+				if !ok || w.rule == i || !overrides(d, w.decl) {
+					continue decls
+				}
+				related = append(related, w.decl.Pos)
+			}
+			if dead[r.rule] == nil {
+				dead[r.rule] = make(map[int][]scanner.Position)
+			}
+			dead[r.rule][j] = related
+		}
+	}
+	return dead
+}
+
+// overrides reports whether later unconditionally replaces earlier, both
+// setting the same property for the same selector.
+func overrides(earlier, later Declaration) bool {
+	if later.Hack() != NoHack {
+		return false
+	}
+	if earlier.Important && !later.Important {
+		return false
+	}
+	a, b := normalizeValue(earlier.Value), normalizeValue(later.Value)
+	return a == b || !progressive(a, b)
+}
+
+// newerSyntax lists keywords and units whose appearance in the later of two
+// declarations marks the earlier one as a fallback.
+var newerSyntax = map[string]bool{
+	"flex": true, "inline-flex": true, "grid": true, "inline-grid": true, "flow-root": true,
+	"contents": true, "sticky": true, "fit-content": true, "min-content": true, "max-content": true,
+	"rem": true, "ch": true, "vw": true, "vh": true, "vmin": true, "vmax": true, "fr": true,
+	"dvh": true, "svh": true, "lvh": true, "dvw": true, "svw": true, "lvw": true,
+}
+
+// progressive reports whether later, a normalized value, is a recognized
+// progressive enhancement over earlier: it uses a vendor prefix, a function
+// or a keyword or unit that earlier does not.
+func progressive(earlier, later string) bool {
+	if _, p := CanonicalValue(earlier); p != "" {
+		return true
+	}
+	if _, p := CanonicalValue(later); p != "" {
+		return true
+	}
+	old := valueWords(earlier)
+	for w := range valueWords(later) {
+		if !old[w] && (strings.HasSuffix(w, "(") || newerSyntax[w]) {
+			return true
+		}
+	}
+	return false
+}
+
+// valueWords returns the identifiers in value, with function names keeping
+// their opening parenthesis and dimensions reduced to their unit.
+func valueWords(value string) map[string]bool {
+	words := make(map[string]bool)
+	for i := 0; i < len(value); {
+		if !isNameRune(rune(value[i])) {
+			i++
+			continue
+		}
+		j := i
+		for j < len(value) && isNameRune(rune(value[j])) {
+			j++
+		}
+		w := strings.TrimLeft(value[i:j], "0123456789")
+		if j < len(value) && value[j] == '(' {
+			w += "("
+		}
+		if w != "" {
+			words[w] = true
+		}
+		i = j
+	}
+	return words
+}
+
+func normalizeValue(v string) string {
+	return strings.Join(strings.Fields(strings.ToLower(v)), " ")
+}
+
+func selectorText(selectors []Rule) string {
+	s := make([]string, len(selectors))
+	for i, sel := range selectors {
+		s[i] = string(sel)
+	}
+	return strings.Join(s, ", ")
+}
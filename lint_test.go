@@ -0,0 +1,42 @@
+package css
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestOverridden checks which declarations are reported as overridden, one
+// rule per line, as "line:property>line of the winner".
+func TestOverridden(t *testing.T) {
+	tests := []struct {
+		name string
+		src  []string
+		want []string
+	}{
+		{"later wins", []string{".a{color:red}", ".a{color:blue}"}, []string{"1:color>2"}},
+		{"important wins", []string{".c{color:red}", ".c{color:blue !important}", ".c{color:green}"}, []string{"1:color>2", "3:color>2"}},
+		{"last important wins", []string{".c{color:red !important}", ".c{color:blue !important}", ".c{color:green}"}, []string{"1:color>2", "3:color>2"}},
+		{"fallback", []string{".a{display:block}", ".a{display:grid}"}, nil},
+		{"every selector", []string{".a, .b{top:0}", ".a{top:1px}"}, nil},
+		{"grouped", []string{".a, .b{top:0}", ".b, .a{top:1px}"}, []string{"1:top>2"}},
+		{"scopes", []string{"@media print{.a{top:0}}", ".a{top:1px}", "@media  print{.a{top:2px}}"}, []string{"1:top>3"}},
+		{"same rule", []string{".a{top:0;top:1px}"}, nil},
+	}
+	for _, tt := range tests {
+		sheet, err := Parse([]byte(strings.Join(tt.src, "\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, p := range Lint(sheet, LintOptions{}) {
+			if p.Code == CodeOverridden {
+				got = append(got, fmt.Sprintf("%d:%s>%d", p.Pos.Line, p.Property, p.Related[0].Line))
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package css
+
+import (
+	"io"
+	"strings"
+	"text/scanner"
+)
+
+// SelectorRef is a selector found by ListSelectors and where it starts.
+type SelectorRef struct {
+	Selector Rule
+	Pos      scanner.Position
+}
+
+// ListSelectors returns the selectors of the top-level rules read from r,
+// in source order, normalized as Parse normalizes them. It only tokenizes
+// the input: rule blocks and at-rules are skipped by counting braces, so
+// it is much faster than Parse but checks nothing beyond their balance. Of
+// opts, those of NewScanner apply.
+func ListSelectors(r io.Reader, opts ...Option) ([]SelectorRef, error) {
+	s := NewScanner(r, opts...)
+	var (
+		refs, list []SelectorRef
+		member     []string
+		parens     int
+		atRule     bool
+	)
+	endMember := func() {
+		if len(member) > 0 {
+			list[len(list)-1].Selector = Rule(ruleKey(strings.Join(member, ""), options{}))
+		}
+		member = member[:0]
+	}
+	for {
+		t, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return refs, err
+		}
+		switch {
+		case t.Kind == TokenComment, t.Text == "<!--", t.Text == "-->":
+		case t.Kind == TokenAtKeyword && len(list) == 0 && len(member) == 0:
+			atRule = true
+		case atRule && t.Text != "{" && t.Text != ";" && t.Text != "}":
+		case t.Text == "{":
+			if err := skipBlock(s, t); err != nil {
+				return refs, err
+			}
+			if !atRule {
+				endMember()
+				for _, ref := range list {
+					if ref.Selector != "" {
+						refs = append(refs, ref)
+					}
+				}
+			}
+			list, member, parens, atRule = list[:0], member[:0], 0, false
+		case t.Text == "}":
+			return refs, errorAt(t.Pos, "unexpected }")
+		case t.Kind == TokenSemicolon && parens == 0:
+			list, member, atRule = list[:0], member[:0], false
+		case t.Kind == TokenComma && parens == 0:
+			endMember()
+		case t.Kind == TokenWhitespace:
+			if len(member) > 0 {
+				member = append(member, " ")
+			}
+		default:
+			if len(member) == 0 {
+				list = append(list, SelectorRef{Pos: t.Pos})
+			}
+			switch {
+			case t.Kind == TokenFunction, t.Text == "(", t.Text == "[":
+				parens++
+			case t.Text == ")", t.Text == "]":
+				parens--
+			}
+			member = append(member, t.Text)
+		}
+	}
+	switch {
+	case atRule:
+		return refs, errorAt(s.pos, "unexpected end of input")
+	case len(list) > 0:
+		return refs, errorAt(list[0].Pos, "unexpected end of input after a selector with no block")
+	}
+	return refs, nil
+}
+
+// skipBlock reads the tokens of s up to the '}' closing the block opened by
+// open.
+func skipBlock(s *Scanner, open Token) error {
+	for depth := 1; depth > 0; {
+		t, err := s.Next()
+		if err == io.EOF {
+			return errorAt(open.Pos, "block is not closed")
+		}
+		if err != nil {
+			return err
+		}
+		switch t.Text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+	return nil
+}
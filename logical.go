@@ -0,0 +1,283 @@
+package css
+
+import (
+	"strings"
+	"text/scanner"
+)
+
+// LogicalOptions configures ToLogical.
+type LogicalOptions struct {
+	// Families lists the families of properties to rewrite: "margin",
+	// "padding", "inset" (top, right, bottom, left and inset), "border"
+	// (the sides of border, border-width, border-style and border-color),
+	// "border-radius", "size" (width, height and their min- and max-
+	// forms), "float" (float and clear), "text-align" and
+	// "background-position", which has no logical form and is only
+	// reported. If it is nil, all of them are.
+	Families []string
+}
+
+// LogicalSkip is a declaration ToLogical could not rewrite.
+type LogicalSkip struct {
+	Property string
+	Value    string
+	Reason   string
+	Pos      scanner.Position
+}
+
+// ToLogical returns a copy of sheet in which the physical properties of
+// opts.Families are rewritten to their logical equivalents for a
+// horizontal, left-to-right writing mode, such as margin-left to
+// margin-inline-start, top to inset-block-start and "float: left" to
+// "float: inline-start", so that the sheet follows the writing mode. Box
+// shorthands with more than one value are split into their block and
+// inline shorthands, as "margin: 1px 2px 3px" into "margin-block: 1px 3px"
+// and "margin-inline: 2px". A declaration is left alone if its block
+// already sets one of the logical properties it would be rewritten to.
+// The declarations that have no logical equivalent are returned in source
+// order.
+func ToLogical(sheet *StyleSheet, opts LogicalOptions) (*StyleSheet, []LogicalSkip) {
+	out := sheet.Clone()
+	l := logicalizer{families: make(map[string]bool)}
+	for _, f := range opts.Families {
+		l.families[f] = true
+	}
+	if opts.Families == nil {
+		for _, f := range logicalFamilies {
+			l.families[f] = true
+		}
+	}
+	Walk(out, func(n Node) bool {
+		switch n := n.(type) {
+		case *RuleNode:
+			n.Declarations = l.declarations(n.Declarations)
+		case *AtRule:
+			n.Declarations = l.declarations(n.Declarations)
+		}
+		return true
+	})
+	return out, l.skipped
+}
+
+var logicalFamilies = []string{
+	"margin", "padding", "inset", "border", "border-radius", "size", "float", "text-align", "background-position",
+}
+
+// logicalSides maps the physical sides to their logical equivalents.
+var logicalSides = map[string]string{
+	"top": "block-start", "bottom": "block-end", "left": "inline-start", "right": "inline-end",
+}
+
+// logicalCorners maps the corners of border-radius to their logical
+// equivalents, in the order of radiusCorners.
+var logicalCorners = [4]string{
+	"border-start-start-radius", "border-start-end-radius", "border-end-end-radius", "border-end-start-radius",
+}
+
+// logicalSizes maps the physical sizes to the logical ones.
+var logicalSizes = map[string]string{
+	"width": "inline-size", "height": "block-size", "min-width": "min-inline-size",
+	"min-height": "min-block-size", "max-width": "max-inline-size", "max-height": "max-block-size",
+}
+
+// logicalShorthands maps the box shorthands to their family and their block
+// and inline shorthands.
+var logicalShorthands = map[string][3]string{
+	"margin":       {"margin", "margin-block", "margin-inline"},
+	"padding":      {"padding", "padding-block", "padding-inline"},
+	"inset":        {"inset", "inset-block", "inset-inline"},
+	"border-width": {"border", "border-block-width", "border-inline-width"},
+	"border-style": {"border", "border-block-style", "border-inline-style"},
+	"border-color": {"border", "border-block-color", "border-inline-color"},
+}
+
+type logicalizer struct {
+	families map[string]bool
+	skipped  []LogicalSkip
+}
+
+// declarations returns decls with the physical properties rewritten.
+func (l *logicalizer) declarations(decls []Declaration) []Declaration {
+	if len(decls) == 0 {
+		return decls
+	}
+	out := make([]Declaration, 0, len(decls))
+	for _, d := range decls {
+		logical := l.declaration(d)
+		for _, n := range logical {
+			if n.Property != d.Property && setsLogical(n.Property, decls) {
+				logical = nil
+				break
+			}
+		}
+		if logical == nil {
+			out = append(out, d)
+			continue
+		}
+		logical[0].Comments = d.Comments
+		logical[len(logical)-1].TrailingComments = d.TrailingComments
+		out = append(out, logical...)
+	}
+	return out
+}
+
+// declaration returns the logical declarations of d, d itself if it needs
+// none, or nil if it has none.
+func (l *logicalizer) declaration(d Declaration) []Declaration {
+	prop := d.Property
+	if _, prefix := Canonical(prop); prefix != "" || strings.HasPrefix(prop, "--") {
+		return []Declaration{d}
+	}
+	prop = asciiLower(prop)
+	with := func(p, v string) Declaration {
+		n := d
+		n.Property, n.Value, n.Comments, n.TrailingComments = p, v, nil, nil
+		return n
+	}
+	family, side := prop, ""
+	if i := strings.LastIndexByte(prop, '-'); i >= 0 && logicalSides[prop[i+1:]] != "" {
+		family, side = prop[:i], prop[i+1:]
+	}
+	switch {
+	case family == "margin" || family == "padding":
+		if side != "" && l.families[family] {
+			return []Declaration{with(family+"-"+logicalSides[side], d.Value)}
+		}
+	case logicalSides[prop] != "":
+		if l.families["inset"] {
+			return []Declaration{with("inset-"+logicalSides[prop], d.Value)}
+		}
+	case family == "border" && side != "" && l.families["border"]:
+		return []Declaration{with("border-"+logicalSides[side], d.Value)}
+	case strings.HasPrefix(prop, "border-") && l.families["border"]:
+		rest := prop[len("border-"):]
+		if s, part, ok := strings.Cut(rest, "-"); ok && logicalSides[s] != "" && (part == "width" || part == "style" || part == "color") {
+			return []Declaration{with("border-"+logicalSides[s]+"-"+part, d.Value)}
+		}
+	case logicalSizes[prop] != "":
+		if l.families["size"] {
+			return []Declaration{with(logicalSizes[prop], d.Value)}
+		}
+	case prop == "float" || prop == "clear":
+		if v := logicalKeyword(d.Value, "inline-"); v != d.Value && l.families["float"] {
+			return []Declaration{with(prop, v)}
+		}
+	case prop == "text-align":
+		if v := logicalKeyword(d.Value, ""); v != d.Value && l.families["text-align"] {
+			return []Declaration{with(prop, v)}
+		}
+	case prop == "background-position" || prop == "background-position-x":
+		if l.families["background-position"] && hasWord(d.Value, "left", "right") {
+			l.skip(d, "no logical equivalent")
+		}
+	}
+	if strings.HasPrefix(prop, "border-") && strings.HasSuffix(prop, "-radius") && l.families["border-radius"] {
+		for i, c := range radiusCorners {
+			if prop == c {
+				return []Declaration{with(logicalCorners[i], d.Value)}
+			}
+		}
+	}
+	if s, ok := logicalShorthands[prop]; ok && l.families[s[0]] {
+		return l.shorthand(d, with, s[1], s[2])
+	}
+	if prop == "border-radius" && l.families["border-radius"] {
+		return l.radius(d, with)
+	}
+	return []Declaration{d}
+}
+
+// shorthand returns the block and inline shorthands of the box shorthand
+// d.
+func (l *logicalizer) shorthand(d Declaration, with func(p, v string) Declaration, block, inline string) []Declaration {
+	if strings.Contains(asciiLower(d.Value), "var(") {
+		if len(Fields(d.Value)) > 1 {
+			l.skip(d, "values hidden by var()")
+		}
+		return []Declaration{d}
+	}
+	box, ok := spreadBox(splitComponents(d.Value))
+	if !ok {
+		l.skip(d, "not a box value")
+		return []Declaration{d}
+	}
+	if box[0] == box[1] && box[1] == box[2] && box[2] == box[3] {
+		return []Declaration{d}
+	}
+	return []Declaration{with(block, compactPair(box[0], box[2])), with(inline, compactPair(box[3], box[1]))}
+}
+
+// radius returns the logical corners of the border-radius d.
+func (l *logicalizer) radius(d Declaration, with func(p, v string) Declaration) []Declaration {
+	if strings.Contains(asciiLower(d.Value), "var(") {
+		return []Declaration{d}
+	}
+	h, v, ok := splitRadius(d.Value)
+	if !ok {
+		l.skip(d, "not a border-radius value")
+		return []Declaration{d}
+	}
+	if h[0] == h[1] && h[1] == h[2] && h[2] == h[3] && v[0] == v[1] && v[1] == v[2] && v[2] == v[3] {
+		return []Declaration{d}
+	}
+	out := make([]Declaration, 4)
+	for i, c := range logicalCorners {
+		out[i] = with(c, compactPair(h[i], v[i]))
+	}
+	return out
+}
+
+func (l *logicalizer) skip(d Declaration, reason string) {
+	l.skipped = append(l.skipped, LogicalSkip{Property: d.Property, Value: d.Value, Reason: reason, Pos: d.Pos})
+}
+
+// setsLogical reports whether one of decls sets the logical property prop,
+// directly or through a logical shorthand or longhand of it.
+func setsLogical(prop string, decls []Declaration) bool {
+	for _, d := range decls {
+		q := asciiLower(d.Property)
+		if q == prop || strings.HasPrefix(q, prop+"-") || strings.HasPrefix(prop, q+"-") && isLogical(q) {
+			return true
+		}
+	}
+	return false
+}
+
+// isLogical reports whether prop is a logical property.
+func isLogical(prop string) bool {
+	return hasWord(strings.ReplaceAll(prop, "-", " "), "inline", "block", "start", "end")
+}
+
+// hasWord reports whether one of the words of value is one of words, in
+// any case.
+func hasWord(value string, words ...string) bool {
+	for _, f := range Fields(value) {
+		for _, w := range words {
+			if strings.EqualFold(f, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// logicalKeyword returns prefix+"start" for the value left and
+// prefix+"end" for right, or value.
+func logicalKeyword(value, prefix string) string {
+	switch strings.ToLower(value) {
+	case "left":
+		return prefix + "start"
+	case "right":
+		return prefix + "end"
+	}
+	return value
+}
+
+// compactPair joins the start and end values of a logical shorthand, or
+// returns one of them if they are the same.
+func compactPair(start, end string) string {
+	if start == end {
+		return start
+	}
+	return start + " " + end
+}
@@ -0,0 +1,497 @@
+package css
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/scanner"
+)
+
+// MarshalOption configures Marshal.
+type MarshalOption func(*marshalOptions)
+
+type marshalOptions struct {
+	order       DeclOrder
+	comments    bool
+	keep        func(text string) bool
+	minify      bool
+	keepColors  bool
+	keepNumbers bool
+	mergeMedia  bool
+	verify      bool
+	collapse    bool
+}
+
+// DeclOrder reports whether property a should be emitted before property b.
+type DeclOrder func(a, b string) bool
+
+// Built-in declaration orders for SortDeclarations.
+var (
+	// AlphabeticalOrder sorts by property name, ignoring vendor prefixes
+	// and placing prefixed variants before the standard property.
+	AlphabeticalOrder DeclOrder = alphabeticalOrder
+	// GroupedOrder sorts positioning properties first, followed by the box
+	// model, typography and visual properties. Longhands sort with their
+	// shorthand and unknown properties sort last, alphabetically.
+	GroupedOrder DeclOrder = groupedOrder
+)
+
+// SortDeclarations emits the declarations of each block in the given order.
+// A declaration is never moved ahead of an earlier declaration of the same
+// property or of a shorthand or longhand of it, since that would change
+// which value wins; repeated properties therefore stay adjacent and in
+// source order.
+func SortDeclarations(order DeclOrder) MarshalOption {
+	return func(o *marshalOptions) {
+		o.order = order
+	}
+}
+
+// KeepComments emits the comments held by the nodes of the stylesheet for
+// which keep returns true, or all of them if keep is nil. keep is given the
+// text between the "/*" and "*/". Comments are written on their own lines
+// before their node, and trailing comments after their declaration.
+func KeepComments(keep func(text string) bool) MarshalOption {
+	return func(o *marshalOptions) {
+		o.comments, o.keep = true, keep
+	}
+}
+
+// Minify writes the stylesheet without the whitespace and final semicolons
+// that can be left out, and with each color in the value of a property
+// taking colors in its shortest exact form, as in "#fff" for "#ffffff" or
+// "red" for "rgba(255,0,0,1)", with numbers in their shortest form, as in
+// "0" for "0px" and ".5em" for "0.5em", and with border-radius values,
+// slash form included, in their shortest form. Comments are dropped, except for those
+// starting with '!', such as "/*! license */", and those KeepComments
+// keeps, which stay in front of the node they precede.
+func Minify(opts ...MinifyOption) MarshalOption {
+	return func(o *marshalOptions) {
+		o.minify = true
+		for _, opt := range opts {
+			opt(o)
+		}
+	}
+}
+
+// MinifyOption configures Minify.
+type MinifyOption func(*marshalOptions)
+
+// KeepColors leaves colors as they are written, for output that is diffed.
+func KeepColors() MinifyOption {
+	return func(o *marshalOptions) {
+		o.keepColors = true
+	}
+}
+
+// KeepNumbers leaves numbers as they are written, rather than dropping the
+// unit of zero lengths, leading zeros and '+' signs.
+func KeepNumbers() MinifyOption {
+	return func(o *marshalOptions) {
+		o.keepNumbers = true
+	}
+}
+
+// VerifyOutput makes Marshal parse the text it writes and marshal it again,
+// and fail if that gives a parse error or other text, as a check on the
+// output of stylesheets built or changed by code.
+func VerifyOutput() MarshalOption {
+	return func(o *marshalOptions) {
+		o.verify = true
+	}
+}
+
+// Marshal returns the CSS text of sheet, which is the same for the same
+// sheet and opts on every platform and run.
+func Marshal(sheet *StyleSheet, opts ...MarshalOption) ([]byte, error) {
+	e := &encoder{}
+	for _, opt := range opts {
+		opt(&e.opts)
+	}
+	e.nodes(sheet.Rules, 0)
+	if e.opts.verify {
+		if err := verifyOutput(e.buf.Bytes(), e.opts); err != nil {
+			return nil, err
+		}
+	}
+	return e.buf.Bytes(), nil
+}
+
+// verifyOutput checks that out, written with opts, is written again as it
+// is once parsed.
+func verifyOutput(out []byte, opts marshalOptions) error {
+	sheet, err := Parse(out)
+	if err != nil {
+		return fmt.Errorf("marshaled stylesheet does not parse: %w", err)
+	}
+	e := &encoder{opts: opts}
+	e.nodes(sheet.Rules, 0)
+	again := e.buf.Bytes()
+	if bytes.Equal(out, again) {
+		return nil
+	}
+	line := 1 + bytes.Count(out[:commonPrefix(out, again)], []byte("\n"))
+	return fmt.Errorf("marshaled stylesheet does not parse back as written, from line %d on", line)
+}
+
+// commonPrefix returns the length of the longest common prefix of a and b.
+func commonPrefix(a, b []byte) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// FormatDeclarations returns styles, such as a computed style or a map of
+// Unmarshal, as the value of an HTML style attribute: "prop: value" pairs
+// separated by "; ", sorted by property or in the order SortDeclarations
+// gives. Empty values are left out. Strings are written with single
+// quotes, and any double quote is escaped, so that the text can be put in a
+// double-quoted attribute as it is. Of opts, SortDeclarations and Minify
+// apply.
+func FormatDeclarations(styles map[string]string, opts ...MarshalOption) string {
+	var o marshalOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	decls := make([]Declaration, 0, len(styles))
+	for prop, value := range styles {
+		v, important := importance(value)
+		if v != "" {
+			decls = append(decls, Declaration{Property: prop, Value: v, Important: important})
+		}
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].Property < decls[j].Property })
+	if o.order != nil {
+		decls = sortDeclarations(decls, o.order)
+	}
+	var b strings.Builder
+	for i, d := range decls {
+		if i > 0 && o.minify {
+			b.WriteByte(';')
+		} else if i > 0 {
+			b.WriteString("; ")
+		}
+		if asciiLower(d.Property) == "font-family" {
+			d.Value = quoteFontFamilies(d.Value)
+		}
+		switch {
+		case o.minify && !o.keepColors:
+			d.Value = shortColors(d.Property, d.Value)
+			fallthrough
+		case o.minify:
+			d.Value = shortRadius(d.Property, d.Value)
+			if !o.keepNumbers {
+				d.Value = shortNumbers(d.Property, d.Value)
+			}
+			b.WriteString(d.Property + ":" + d.Value)
+			if d.Important {
+				b.WriteString("!important")
+			}
+		default:
+			b.WriteString(d.Property + ": " + d.text())
+		}
+	}
+	return attributeSafe(b.String())
+}
+
+// attributeSafe returns the CSS text s without double quotes: strings are
+// single-quoted and other double quotes escaped.
+func attributeSafe(s string) string {
+	if strings.IndexByte(s, '"') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			end := skipEscape(s, i)
+			b.WriteString(quoteEscaper.Replace(s[i:end]))
+			i = end - 1
+		case '\'':
+			end := skipString(s, i)
+			b.WriteString(quoteEscaper.Replace(s[i:end]))
+			i = end - 1
+		case '"':
+			end := skipString(s, i)
+			b.WriteByte('\'')
+			for j := i + 1; j < end; j++ {
+				switch c := s[j]; {
+				case c == '\\' && j+1 < end && s[j+1] == '"':
+					b.WriteString(`\22 `)
+					j++
+				case c == '\\' && j+1 < end:
+					b.WriteString(s[j : j+2])
+					j++
+				case c == '"' && j == end-1:
+				case c == '"':
+					b.WriteString(`\22 `)
+				case c == '\'':
+					b.WriteString(`\'`)
+				default:
+					b.WriteByte(c)
+				}
+			}
+			b.WriteByte('\'')
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// quoteEscaper escapes the double quotes of CSS text that is not in a
+// double-quoted string.
+var quoteEscaper = strings.NewReplacer(`\"`, `\22 `, `"`, `\22 `)
+
+type encoder struct {
+	buf  bytes.Buffer
+	opts marshalOptions
+	sm   *sourceMapper // nil unless a source map is wanted
+}
+
+// mark maps what e writes next to pos if e builds a source map.
+func (e *encoder) mark(pos scanner.Position) {
+	if e.sm != nil {
+		e.sm.mark(e.buf.Bytes(), pos)
+	}
+}
+
+func (e *encoder) nodes(nodes []Node, depth int) {
+	if e.opts.mergeMedia {
+		nodes = mergeMedia(nodes)
+	}
+	for i, n := range nodes {
+		if i > 0 && !e.opts.minify {
+			e.buf.WriteByte('\n')
+		}
+		switch n := n.(type) {
+		case *RuleNode:
+			e.rule(n, depth)
+		case *AtRule:
+			e.atRule(n, depth)
+		}
+	}
+}
+
+// write writes pretty, or minified if e minifies.
+func (e *encoder) write(pretty, minified string) {
+	if e.opts.minify {
+		e.buf.WriteString(minified)
+	} else {
+		e.buf.WriteString(pretty)
+	}
+}
+
+func (e *encoder) rule(n *RuleNode, depth int) {
+	e.comments(n.Comments, depth)
+	e.indent(depth)
+	e.mark(n.Pos)
+	for i, sel := range n.Selectors {
+		if i > 0 {
+			e.write(", ", ",")
+		}
+		if e.opts.minify {
+			e.buf.WriteString(compactSelector(string(sel)))
+		} else {
+			e.buf.WriteString(string(sel))
+		}
+	}
+	e.block(n.Declarations, n.Rules, depth)
+}
+
+func (e *encoder) atRule(n *AtRule, depth int) {
+	e.comments(n.Comments, depth)
+	e.indent(depth)
+	e.mark(n.Pos)
+	e.buf.WriteString("@" + n.Name)
+	if n.Prelude != "" {
+		e.buf.WriteString(" " + n.Prelude)
+	}
+	if n.Block != "" {
+		e.write(" "+n.Block+"\n", n.Block)
+		return
+	}
+	if n.Rules == nil && n.Declarations == nil {
+		e.write(";\n", ";")
+		return
+	}
+	e.block(n.Declarations, n.Rules, depth)
+}
+
+// block writes a block of declarations followed by rules.
+func (e *encoder) block(decls []Declaration, rules []Node, depth int) {
+	e.write(" {\n", "{")
+	e.declarations(decls, len(rules) > 0, depth+1)
+	e.nodes(rules, depth+1)
+	e.indent(depth)
+	e.write("}\n", "}")
+}
+
+// declarations writes decls, each ended by a ';' unless e minifies and it is
+// the last one of its block, which it is if more is not set.
+func (e *encoder) declarations(decls []Declaration, more bool, depth int) {
+	if e.opts.collapse {
+		decls = collapseShorthands(decls)
+	}
+	if e.opts.order != nil {
+		decls = sortDeclarations(decls, e.opts.order)
+	}
+	for i, d := range decls {
+		e.comments(d.Comments, depth)
+		e.indent(depth)
+		e.mark(d.Pos)
+		if asciiLower(d.Property) == "font-family" {
+			d.Value = quoteFontFamilies(d.Value)
+		}
+		if e.opts.minify {
+			v := shortRadius(d.Property, d.Value)
+			if !e.opts.keepNumbers {
+				v = shortNumbers(d.Property, v)
+			}
+			if !e.opts.keepColors {
+				v = shortColors(d.Property, v)
+			}
+			e.buf.WriteString(d.Property + ":" + v)
+			if d.Important {
+				e.buf.WriteString("!important")
+			}
+			if more || i < len(decls)-1 {
+				e.buf.WriteByte(';')
+			}
+		} else {
+			e.buf.WriteString(d.Property + ": " + d.text() + ";")
+		}
+		for _, c := range d.TrailingComments {
+			if e.keepComment(c) {
+				e.write(" /*"+c+"*/", "/*"+c+"*/")
+			}
+		}
+		e.write("\n", "")
+	}
+}
+
+// comments writes the comments to keep on lines of their own.
+func (e *encoder) comments(comments []string, depth int) {
+	for _, c := range comments {
+		if e.keepComment(c) {
+			e.indent(depth)
+			e.write("/*"+c+"*/\n", "/*"+c+"*/")
+		}
+	}
+}
+
+// keepComment reports whether the comment text is written. A minified
+// stylesheet keeps the comments starting with '!', such as license notices.
+func (e *encoder) keepComment(text string) bool {
+	if e.opts.minify && strings.HasPrefix(text, "!") {
+		return true
+	}
+	return e.opts.comments && (e.opts.keep == nil || e.opts.keep(text))
+}
+
+func sortDeclarations(decls []Declaration, less DeclOrder) []Declaration {
+	rest := append([]Declaration(nil), decls...)
+	sorted := make([]Declaration, 0, len(decls))
+	for len(rest) > 0 {
+		best := 0
+		for i := 1; i < len(rest); i++ {
+			if less(rest[i].Property, rest[best].Property) && !overlapsAny(rest[i].Property, rest[:i]) {
+				best = i
+			}
+		}
+		sorted = append(sorted, rest[best])
+		rest = append(rest[:best], rest[best+1:]...)
+	}
+	return sorted
+}
+
+// overlapsAny reports whether prop sets the same property as any of decls,
+// directly or through a shorthand such as margin for margin-top.
+func overlapsAny(prop string, decls []Declaration) bool {
+	p, _ := Canonical(prop)
+	for _, d := range decls {
+		q, _ := Canonical(d.Property)
+		if p == q || strings.HasPrefix(p, q+"-") || strings.HasPrefix(q, p+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *encoder) indent(depth int) {
+	if e.opts.minify {
+		return
+	}
+	for i := 0; i < depth; i++ {
+		e.buf.WriteString("  ")
+	}
+}
+
+func alphabeticalOrder(a, b string) bool {
+	ua, _ := Canonical(a)
+	ub, _ := Canonical(b)
+	if ua != ub {
+		return ua < ub
+	}
+	if pa, pb := a != ua, b != ub; pa != pb {
+		return pa
+	}
+	return a < b
+}
+
+func groupedOrder(a, b string) bool {
+	if ra, rb := groupRank(a), groupRank(b); ra != rb {
+		return ra < rb
+	}
+	return alphabeticalOrder(a, b)
+}
+
+// groupedProperties lists properties in GroupedOrder: positioning, box
+// model, typography, visual.
+var groupedProperties = []string{
+	"position", "inset", "top", "right", "bottom", "left", "z-index",
+
+	"display", "flex", "flex-direction", "flex-wrap", "flex-flow", "flex-grow", "flex-shrink", "flex-basis", "order",
+	"grid", "grid-template", "grid-template-columns", "grid-template-rows", "grid-template-areas", "grid-auto-flow",
+	"grid-auto-columns", "grid-auto-rows", "grid-area", "grid-column", "grid-row", "gap", "row-gap", "column-gap",
+	"place-content", "align-content", "justify-content", "place-items", "align-items", "justify-items",
+	"place-self", "align-self", "justify-self", "float", "clear", "box-sizing",
+	"width", "min-width", "max-width", "height", "min-height", "max-height", "aspect-ratio",
+	"margin", "padding", "border", "border-radius", "overflow", "overflow-x", "overflow-y",
+
+	"font", "font-family", "font-size", "font-style", "font-weight", "font-variant", "line-height",
+	"letter-spacing", "word-spacing", "color", "text-align", "text-decoration", "text-indent",
+	"text-transform", "text-overflow", "text-shadow", "white-space", "word-break", "overflow-wrap",
+	"word-wrap", "vertical-align", "list-style",
+
+	"content", "background", "opacity", "outline", "box-shadow", "filter", "backdrop-filter", "cursor",
+	"visibility", "pointer-events", "transform", "transition", "animation",
+}
+
+var groupRanks = func() map[string]int {
+	m := make(map[string]int, len(groupedProperties))
+	for i, p := range groupedProperties {
+		m[p] = i
+	}
+	return m
+}()
+
+// groupRank returns the index of prop, or of the closest shorthand it is a
+// longhand of, in groupedProperties.
+func groupRank(prop string) int {
+	for p, _ := Canonical(prop); p != ""; {
+		if r, ok := groupRanks[p]; ok {
+			return r
+		}
+		i := strings.LastIndexByte(p, '-')
+		if i <= 0 {
+			break
+		}
+		p = p[:i]
+	}
+	return len(groupedProperties)
+}
@@ -0,0 +1,70 @@
+package css
+
+import "testing"
+
+// TestFormatDeclarationsQuoted checks that a ';' or '}' in a string or a
+// url() is kept inside its value, and that the result reads back as the
+// same declarations in a block.
+func TestFormatDeclarationsQuoted(t *testing.T) {
+	tests := []struct {
+		styles map[string]string
+		want   string
+	}{
+		{map[string]string{"content": `"a;b"`, "color": "red"}, `color: red; content: 'a;b'`},
+		{map[string]string{"content": `"}"`, "top": "0"}, `content: '}'; top: 0`},
+		{map[string]string{"content": `'x"y;'`}, `content: 'x\22 y;'`},
+		{map[string]string{"background": "url(;)", "top": "0"}, `background: url(;); top: 0`},
+		{map[string]string{"background": `url("a;b}.png")`}, `background: url('a;b}.png')`},
+	}
+	for _, tt := range tests {
+		got := FormatDeclarations(tt.styles)
+		if got != tt.want {
+			t.Errorf("FormatDeclarations(%q) = %q, want %q", tt.styles, got, tt.want)
+		}
+		css, err := Unmarshal([]byte(".x{" + got + "}"))
+		if err != nil {
+			t.Errorf("Unmarshal of %q: %v", got, err)
+			continue
+		}
+		if len(css[".x"]) != len(tt.styles) {
+			t.Errorf("%q reads back as %q", got, css[".x"])
+		}
+		if again := FormatDeclarations(css[".x"]); again != got {
+			t.Errorf("%q reads back as %q", got, again)
+		}
+	}
+}
+
+// TestMinifyNumbers checks the short forms Minify writes numbers in, and
+// the zeros that keep their unit, as the basis of flex does.
+func TestMinifyNumbers(t *testing.T) {
+	tests := []struct {
+		decl, want string
+	}{
+		{"flex: 0 0 0%", "flex:0 0 0%"},
+		{"flex: 1 0 0px", "flex:1 0 0px"},
+		{"-webkit-flex: 0 0 0%", "-webkit-flex:0 0 0%"},
+		{"-ms-flex: 0 0 0%", "-ms-flex:0 0 0%"},
+		{"flex-basis: 0%", "flex-basis:0%"},
+		{"margin: 0px 0.50em -0.5px +1px", "margin:0 .50em -.5px 1px"},
+		{"width: 0%", "width:0"},
+		{"transition: opacity 0s", "transition:opacity 0s"},
+		{"transform: rotate(0deg)", "transform:rotate(0deg)"},
+		{"width: calc(0% + 0px)", "width:calc(0% + 0px)"},
+	}
+	for _, tt := range tests {
+		sheet, err := Parse([]byte(".a { " + tt.decl + " }"))
+		if err != nil {
+			t.Errorf("Parse of %q: %v", tt.decl, err)
+			continue
+		}
+		out, err := Marshal(sheet, Minify())
+		if err != nil {
+			t.Errorf("Marshal of %q: %v", tt.decl, err)
+			continue
+		}
+		if want := ".a{" + tt.want + "}"; string(out) != want {
+			t.Errorf("Marshal of %q = %q, want %q", tt.decl, out, want)
+		}
+	}
+}
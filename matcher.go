@@ -0,0 +1,131 @@
+package css
+
+import (
+	"sort"
+	"strings"
+)
+
+// Matcher computes the styles of many elements from one stylesheet. It
+// parses the selectors once and buckets them by the id, class or type they
+// require, as browsers do, so that each element is only tested against the
+// selectors it may match. It gives the same results as ComputeStyle and
+// ComputeInheritedStyle, for the rules the sheet had when the Matcher was
+// made, and is safe for concurrent use.
+type Matcher struct {
+	rules     []*RuleNode
+	layers    []layerRank // of rules
+	ids       map[string][]matcherEntry
+	classes   map[string][]matcherEntry
+	tags      map[string][]matcherEntry // lowercased
+	universal []matcherEntry
+}
+
+// matcherEntry is a selector of the rule rules[rule] of a Matcher.
+type matcherEntry struct {
+	rule        int
+	selector    Rule
+	sel         compound
+	specificity Specificity
+}
+
+// NewMatcher returns a Matcher for the top-level rules of sheet, those of
+// its @layer blocks and the rules nested in them.
+func NewMatcher(sheet *StyleSheet) *Matcher {
+	m := &Matcher{
+		ids:     make(map[string][]matcherEntry),
+		classes: make(map[string][]matcherEntry),
+		tags:    make(map[string][]matcherEntry),
+	}
+	layeredRules(sheet, func(rule *RuleNode, layer layerRank) {
+		for _, sel := range rule.Selectors {
+			c, ok := parseCompound(sel)
+			if !ok {
+				continue
+			}
+			e := matcherEntry{len(m.rules), sel, c, sel.Specificity()}
+			switch {
+			case len(c.ids) > 0:
+				m.ids[c.ids[0]] = append(m.ids[c.ids[0]], e)
+			case len(c.classes) > 0:
+				m.classes[c.classes[0]] = append(m.classes[c.classes[0]], e)
+			case c.tag != "":
+				tag := strings.ToLower(c.tag)
+				m.tags[tag] = append(m.tags[tag], e)
+			default:
+				m.universal = append(m.universal, e)
+			}
+		}
+		m.rules = append(m.rules, rule)
+		m.layers = append(m.layers, layer)
+	})
+	return m
+}
+
+// RulesFor returns the rules with a selector matching el, in source order.
+func (m *Matcher) RulesFor(el ElementDesc) []*RuleNode {
+	matched := m.match(el)
+	rules := make([]*RuleNode, len(matched))
+	for i, e := range matched {
+		rules[i] = m.rules[e.rule]
+	}
+	return rules
+}
+
+// ComputeStyle is like the function ComputeStyle for the sheet of m.
+func (m *Matcher) ComputeStyle(el ElementDesc) map[string]string {
+	return cascadeStyle(m.candidates(el))
+}
+
+// Trace is like the function Trace for the sheet of m.
+func (m *Matcher) Trace(el ElementDesc, prop string) []Candidate {
+	return traceCascade(m.candidates(el), prop)
+}
+
+// candidates returns the declarations that apply to el, in source order.
+func (m *Matcher) candidates(el ElementDesc) []Candidate {
+	var found []Candidate
+	for _, e := range m.match(el) {
+		found = appendCandidates(found, m.rules[e.rule], m.layers[e.rule], e.selector, e.specificity)
+	}
+	return found
+}
+
+// ComputeInheritedStyle is like the function ComputeInheritedStyle for the
+// sheet of m.
+func (m *Matcher) ComputeInheritedStyle(el ElementDesc, parent map[string]string) map[string]string {
+	return inheritStyle(m.ComputeStyle(el), parent)
+}
+
+// match returns, for each rule matching el in source order, the entry of
+// its most specific selector that matches.
+func (m *Matcher) match(el ElementDesc) []matcherEntry {
+	var matched []matcherEntry
+	best := make(map[int]int) // index in matched by rule
+	add := func(entries []matcherEntry) {
+		for _, e := range entries {
+			if !e.sel.matches(el) {
+				continue
+			}
+			i, ok := best[e.rule]
+			switch {
+			case !ok:
+				best[e.rule] = len(matched)
+				matched = append(matched, e)
+			case matched[i].specificity.Less(e.specificity):
+				matched[i] = e
+			}
+		}
+	}
+	if el.ID != "" {
+		add(m.ids[el.ID])
+	}
+	for i, class := range el.Classes {
+		if !hasClass(el.Classes[:i], class) {
+			add(m.classes[class])
+		}
+	}
+	add(m.tags[strings.ToLower(el.Tag)])
+	add(m.universal)
+	sort.Slice(matched, func(i, j int) bool { return matched[i].rule < matched[j].rule })
+	return matched
+}
@@ -0,0 +1,18 @@
+package css
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestMatcherComputeStyle checks that a Matcher gives the styles of
+// ComputeStyle for elements of the sheet of BenchmarkComputeStyle.
+func TestMatcherComputeStyle(t *testing.T) {
+	sheet, els := matcherBench(t)
+	m := NewMatcher(sheet)
+	for i := 0; i < len(els); i += 97 {
+		if got, want := m.ComputeStyle(els[i]), ComputeStyle(sheet, els[i]); !reflect.DeepEqual(got, want) {
+			t.Errorf("for %+v: got %v, want %v", els[i], got, want)
+		}
+	}
+}
@@ -0,0 +1,784 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MediaEnv describes the device MatchMedia evaluates media queries for.
+type MediaEnv struct {
+	// Type is the media type, such as "screen" or "print". Empty means
+	// "screen".
+	Type string
+	// Width and Height are the size of the viewport in CSS pixels.
+	Width, Height float64
+	// Resolution is the number of device pixels per CSS pixel. Zero means 1.
+	Resolution float64
+	// PrefersColorScheme is "light" or "dark". Empty means "light".
+	PrefersColorScheme string
+}
+
+// MatchMedia reports whether the comma-separated media query list query,
+// such as the prelude of an @media rule, matches env. An empty list matches
+// every env. Both the legacy "(min-width: 600px)" form and the range form
+// "(width >= 600px)" of features are supported. Features and media types
+// MatchMedia does not know, and values it cannot read, never match; only a
+// query that does not follow the media query grammar is an error.
+func MatchMedia(query string, env MediaEnv) (bool, error) {
+	list, err := parseMediaList(query)
+	if err != nil {
+		return false, err
+	}
+	if len(list) == 0 {
+		return true, nil
+	}
+	for _, q := range list {
+		if q.Matches(env) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ForMedia returns a copy of sheet with only the rules that apply to the
+// media type mediaType, such as "print": those outside of @media and those
+// of @media blocks with a query for the type or for all media. Features are
+// not evaluated but kept: the media type is dropped from the queries left
+// in the prelude, and a block left with a query of no feature is replaced
+// by its rules, so that for "print", "@media print and (min-width: 10cm)"
+// becomes "@media (min-width: 10cm)", "@media print" is unwrapped and
+// "@media screen" is dropped. Blocks whose prelude cannot be parsed are
+// kept as they are. An empty mediaType means "screen".
+func ForMedia(sheet *StyleSheet, mediaType string) *StyleSheet {
+	typ := asciiLower(mediaType)
+	if typ == "" {
+		typ = "screen"
+	}
+	out := sheet.Clone()
+	out.Rules = forMedia(out.Rules, typ)
+	return out
+}
+
+func forMedia(nodes []Node, typ string) []Node {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			n.Rules = forMedia(n.Rules, typ)
+		case *AtRule:
+			n.Rules = forMedia(n.Rules, typ)
+			if n.Rules == nil || asciiLower(n.Name) != "media" {
+				break
+			}
+			queries, err := ParseMediaQueryList(n.Prelude)
+			if err != nil {
+				break
+			}
+			var kept []string
+			all := len(queries) == 0
+			for _, q := range queries {
+				switch q, ok := q.forType(typ); {
+				case ok && q == nil:
+					all = true
+				case ok:
+					kept = append(kept, q.String())
+				}
+			}
+			if all {
+				out = append(out, n.Rules...)
+				continue
+			}
+			if kept == nil {
+				continue
+			}
+			n.Prelude = strings.Join(kept, ", ")
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// ForEnv returns a copy of sheet with the @media blocks evaluated for
+// env, as MatchMedia does: the blocks whose query list matches are
+// replaced by their rules, and the others dropped. Blocks whose prelude
+// cannot be parsed are kept as they are. ComputeStyle and Matcher ignore
+// the rules of @media blocks, so that those of ForEnv(sheet, env) give
+// the styles of a device, the rules of matching blocks cascading with the
+// others in source order.
+func ForEnv(sheet *StyleSheet, env MediaEnv) *StyleSheet {
+	out := sheet.Clone()
+	out.Rules = forEnv(out.Rules, env)
+	return out
+}
+
+func forEnv(nodes []Node, env MediaEnv) []Node {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			n.Rules = forEnv(n.Rules, env)
+		case *AtRule:
+			n.Rules = forEnv(n.Rules, env)
+			if n.Rules == nil || asciiLower(n.Name) != "media" {
+				break
+			}
+			match, err := MatchMedia(n.Prelude, env)
+			if err != nil {
+				break
+			}
+			if match {
+				out = append(out, n.Rules...)
+			}
+			continue
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// forType returns q without its media type for the media type typ, nil if
+// it is then always true, and whether it can match typ at all.
+func (q *MediaQuery) forType(typ string) (*MediaQuery, bool) {
+	matches := q.Type == "" || q.Type == "all" || q.Type == typ
+	switch {
+	case !matches:
+		// "screen" never matches print, and "not screen" always does.
+		return nil, q.Not
+	case q.Condition == nil:
+		return nil, !q.Not
+	case q.Not:
+		return &MediaQuery{Condition: &MediaCondition{Op: "not", Conditions: []*MediaCondition{q.Condition}}}, true
+	}
+	return &MediaQuery{Condition: q.Condition}, true
+}
+
+// tri is the three-valued result of a media condition: a feature that is
+// not known evaluates to unknown, which the whole query reads as false.
+type tri int8
+
+const (
+	triFalse tri = iota
+	triTrue
+	triUnknown
+)
+
+func (t tri) not() tri {
+	switch t {
+	case triFalse:
+		return triTrue
+	case triTrue:
+		return triFalse
+	}
+	return t
+}
+
+func triOf(b bool) tri {
+	if b {
+		return triTrue
+	}
+	return triFalse
+}
+
+// MediaQuery is one query of a media query list, such as
+// "only screen and (min-width: 600px)".
+type MediaQuery struct {
+	// Not and Only are set by the qualifier before the media type.
+	Not, Only bool
+	// Type is the lowercased media type, or empty if the query has none.
+	Type string
+	// Condition is the condition after the media type, or the whole query
+	// if it has no type. It is nil for a query of only a media type.
+	Condition *MediaCondition
+}
+
+// MediaCondition is a media condition. Op is "and" or "or" for Conditions
+// joined by it, or "not" for the negation of the single one in Conditions.
+// Otherwise Op is empty and the condition is a Feature, or parentheses whose
+// content is neither a condition nor a feature, held in Raw.
+type MediaCondition struct {
+	Op         string
+	Conditions []*MediaCondition
+	Feature    *MediaFeature
+	Raw        string
+}
+
+// MediaFeature is a media feature test. Name is lowercased, without the min-
+// or max- prefix of the legacy form, and each constraint reads Name Op Value,
+// so "(min-width: 600px)" and "(600px <= width)" both hold a constraint of
+// ">=" "600px" on width. A feature without constraints, like "(color)", is
+// tested in a boolean context. Legacy is set for the form written with ':'.
+// Start and End are the byte offsets of the feature, parentheses included,
+// in the text it was parsed from.
+type MediaFeature struct {
+	Name        string
+	Legacy      bool
+	Constraints []MediaConstraint
+	Start, End  int
+}
+
+// MediaConstraint compares a media feature with Value using Op, which is one
+// of "=", "<", "<=", ">" and ">=". Value is as written, except that the
+// spaces around the '/' of ratios are dropped. Start and End are the byte
+// offsets of Value in the text it was parsed from; AtRule.PreludePosition
+// turns them into positions in a stylesheet.
+type MediaConstraint struct {
+	Op         string
+	Value      string
+	Start, End int
+}
+
+// ParseMediaQueryList parses a comma-separated media query list, such as the
+// prelude of an @media rule. An empty list has no queries.
+func ParseMediaQueryList(s string) ([]*MediaQuery, error) {
+	return parseMediaList(s)
+}
+
+// ParseMediaQuery parses a single media query.
+func ParseMediaQuery(s string) (*MediaQuery, error) {
+	list, err := parseMediaList(s)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(list) != 1:
+		return nil, fmt.Errorf("media query %q is not a single query", s)
+	}
+	return list[0], nil
+}
+
+// Features returns the features tested by q, in source order.
+func (q *MediaQuery) Features() []*MediaFeature {
+	var features []*MediaFeature
+	var walk func(c *MediaCondition)
+	walk = func(c *MediaCondition) {
+		if c.Feature != nil {
+			features = append(features, c.Feature)
+		}
+		for _, sub := range c.Conditions {
+			walk(sub)
+		}
+	}
+	if q.Condition != nil {
+		walk(q.Condition)
+	}
+	return features
+}
+
+// Matches reports whether q matches env, as MatchMedia does.
+func (q *MediaQuery) Matches(env MediaEnv) bool {
+	return q.eval(env) == triTrue
+}
+
+func (q *MediaQuery) String() string {
+	var b strings.Builder
+	switch {
+	case q.Not:
+		b.WriteString("not ")
+	case q.Only:
+		b.WriteString("only ")
+	}
+	b.WriteString(q.Type)
+	if q.Condition != nil {
+		if q.Type != "" {
+			b.WriteString(" and ")
+		}
+		b.WriteString(q.Condition.String())
+	}
+	return b.String()
+}
+
+func (c *MediaCondition) String() string {
+	switch c.Op {
+	case "not":
+		return "not " + c.Conditions[0].nested()
+	case "and", "or":
+		parts := make([]string, len(c.Conditions))
+		for i, sub := range c.Conditions {
+			parts[i] = sub.nested()
+		}
+		return strings.Join(parts, " "+c.Op+" ")
+	}
+	if c.Feature != nil {
+		return c.Feature.String()
+	}
+	return "(" + c.Raw + ")"
+}
+
+// nested returns c as written inside another condition, in parentheses
+// unless it is a feature.
+func (c *MediaCondition) nested() string {
+	if c.Op != "" {
+		return "(" + c.String() + ")"
+	}
+	return c.String()
+}
+
+func (f *MediaFeature) String() string {
+	cs := f.Constraints
+	switch {
+	case len(cs) == 0:
+		return "(" + f.Name + ")"
+	case f.Legacy && len(cs) == 1 && strings.HasSuffix(cs[0].Op, "="):
+		prefix := ""
+		switch cs[0].Op {
+		case ">=":
+			prefix = "min-"
+		case "<=":
+			prefix = "max-"
+		}
+		return "(" + prefix + f.Name + ": " + cs[0].Value + ")"
+	case len(cs) == 2:
+		return "(" + cs[0].Value + " " + flipComparison(cs[0].Op) + " " + f.Name + " " + cs[1].Op + " " + cs[1].Value + ")"
+	}
+	parts := make([]string, len(cs))
+	for i, c := range cs {
+		parts[i] = f.Name + " " + c.Op + " " + c.Value
+	}
+	return "(" + strings.Join(parts, ") and (") + ")"
+}
+
+func (q *MediaQuery) eval(env MediaEnv) tri {
+	t := triTrue
+	switch q.Type {
+	case "", "all":
+	case "screen", "print", "speech":
+		typ := strings.ToLower(env.Type)
+		if typ == "" {
+			typ = "screen"
+		}
+		t = triOf(q.Type == typ)
+	default:
+		t = triFalse
+	}
+	if q.Condition != nil && t == triTrue {
+		t = q.Condition.eval(env)
+	}
+	if q.Not {
+		t = t.not()
+	}
+	return t
+}
+
+func (c *MediaCondition) eval(env MediaEnv) tri {
+	switch c.Op {
+	case "not":
+		return c.Conditions[0].eval(env).not()
+	case "and", "or":
+		stop := triOf(c.Op == "or")
+		r := stop.not()
+		for _, sub := range c.Conditions {
+			switch sub.eval(env) {
+			case stop:
+				return stop
+			case triUnknown:
+				r = triUnknown
+			}
+		}
+		return r
+	}
+	if c.Feature == nil {
+		return triUnknown
+	}
+	return c.Feature.eval(env)
+}
+
+func (f *MediaFeature) eval(env MediaEnv) tri {
+	var actual float64
+	var read func(string) (float64, bool)
+	switch f.Name {
+	case "width", "device-width":
+		actual, read = env.Width, mediaLength
+	case "height", "device-height":
+		actual, read = env.Height, mediaLength
+	case "aspect-ratio", "device-aspect-ratio":
+		if env.Height == 0 {
+			return triUnknown
+		}
+		actual, read = env.Width/env.Height, mediaRatio
+	case "resolution":
+		actual, read = env.Resolution, mediaResolution
+		if actual == 0 {
+			actual = 1
+		}
+	case "orientation":
+		orientation := "landscape"
+		if env.Height >= env.Width {
+			orientation = "portrait"
+		}
+		return f.discrete(orientation, "portrait", "landscape")
+	case "prefers-color-scheme":
+		scheme := strings.ToLower(env.PrefersColorScheme)
+		if scheme == "" {
+			scheme = "light"
+		}
+		return f.discrete(scheme, "light", "dark")
+	default:
+		return triUnknown
+	}
+	if len(f.Constraints) == 0 {
+		return triOf(actual != 0)
+	}
+	for _, c := range f.Constraints {
+		v, ok := read(c.Value)
+		if !ok {
+			return triUnknown
+		}
+		var r bool
+		switch c.Op {
+		case "=":
+			r = actual == v
+		case "<":
+			r = actual < v
+		case "<=":
+			r = actual <= v
+		case ">":
+			r = actual > v
+		case ">=":
+			r = actual >= v
+		}
+		if !r {
+			return triFalse
+		}
+	}
+	return triTrue
+}
+
+// discrete evaluates a feature whose value is one of the keywords values,
+// which can only be tested for equality.
+func (f *MediaFeature) discrete(actual string, values ...string) tri {
+	if len(f.Constraints) == 0 {
+		return triTrue
+	}
+	c := f.Constraints[0]
+	if len(f.Constraints) > 1 || c.Op != "=" || !f.Legacy {
+		return triUnknown
+	}
+	v := strings.ToLower(c.Value)
+	for _, known := range values {
+		if v == known {
+			return triOf(v == actual)
+		}
+	}
+	return triUnknown
+}
+
+// mediaLengths holds the size in CSS pixels of the absolute length units,
+// and of em and rem at the initial font size.
+var mediaLengths = map[string]float64{
+	"px": 1, "em": 16, "rem": 16, "in": 96, "cm": 96 / 2.54, "mm": 96 / 25.4,
+	"q": 96 / 101.6, "pt": 96.0 / 72, "pc": 16,
+}
+
+func mediaLength(s string) (float64, bool) {
+	n, unit, ok := splitNumber(s)
+	if !ok || unit == "" && n != 0 {
+		return 0, false
+	}
+	if unit == "" {
+		return 0, true
+	}
+	px, ok := mediaLengths[unit]
+	return n * px, ok
+}
+
+func mediaResolution(s string) (float64, bool) {
+	n, unit, ok := splitNumber(s)
+	if !ok {
+		return 0, false
+	}
+	switch unit {
+	case "dppx", "x":
+		return n, true
+	case "dpi":
+		return n / 96, true
+	case "dpcm":
+		return n * 2.54 / 96, true
+	}
+	return 0, false
+}
+
+func mediaRatio(s string) (float64, bool) {
+	num, den, ok := strings.Cut(s, "/")
+	if !ok {
+		den = "1"
+	}
+	a, unit, ok := splitNumber(strings.TrimSpace(num))
+	if !ok || unit != "" {
+		return 0, false
+	}
+	b, unit, ok := splitNumber(strings.TrimSpace(den))
+	if !ok || unit != "" || b == 0 {
+		return 0, false
+	}
+	return a / b, true
+}
+
+// mediaParser parses a media query list from its tokens: the punctuation
+// "(", ")", ",", ":" and "/", the comparisons, and words, which are runs of
+// anything else.
+type mediaParser struct {
+	src  string
+	toks []string
+	offs []int // of each token in src
+	i    int
+}
+
+func parseMediaList(s string) ([]*MediaQuery, error) {
+	toks, offs := mediaTokens(s)
+	p := &mediaParser{src: s, toks: toks, offs: offs}
+	var list []*MediaQuery
+	if p.peek() == "" {
+		return nil, nil
+	}
+	for {
+		q, err := p.query()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, q)
+		if p.peek() == "" {
+			return list, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// mediaTokens returns the tokens of s and their offsets in it.
+func mediaTokens(s string) ([]string, []int) {
+	var toks []string
+	var offs []int
+	for i := 0; i < len(s); {
+		c := s[i]
+		if !isMediaSpace(c) {
+			offs = append(offs, i)
+		}
+		switch {
+		case isMediaSpace(c):
+			i++
+		case strings.IndexByte("(),:/", c) >= 0:
+			toks = append(toks, s[i:i+1])
+			i++
+		case c == '<' || c == '>' || c == '=':
+			n := 1
+			if c != '=' && i+1 < len(s) && s[i+1] == '=' {
+				n = 2
+			}
+			toks = append(toks, s[i:i+n])
+			i += n
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte(" \t\n\r\f(),:/<>=", s[j]) < 0 {
+				j++
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		}
+	}
+	return toks, offs
+}
+
+func isMediaSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func (p *mediaParser) peek() string {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return ""
+}
+
+// keyword reports whether the next token is the keyword kw, consuming it if
+// so.
+func (p *mediaParser) keyword(kw string) bool {
+	if strings.EqualFold(p.peek(), kw) {
+		p.i++
+		return true
+	}
+	return false
+}
+
+func (p *mediaParser) expect(tok string) error {
+	if p.peek() != tok {
+		return p.unexpected()
+	}
+	p.i++
+	return nil
+}
+
+func (p *mediaParser) unexpected() error {
+	if t := p.peek(); t != "" {
+		return fmt.Errorf("unexpected %q in media query %q", t, p.src)
+	}
+	return fmt.Errorf("unexpected end of media query %q", p.src)
+}
+
+func (p *mediaParser) query() (*MediaQuery, error) {
+	q := &MediaQuery{}
+	if p.peek() == "(" || strings.EqualFold(p.peek(), "not") && p.i+1 < len(p.toks) && p.toks[p.i+1] == "(" {
+		cond, err := p.condition(true)
+		q.Condition = cond
+		return q, err
+	}
+	if q.Not = p.keyword("not"); !q.Not {
+		q.Only = p.keyword("only")
+	}
+	typ := strings.ToLower(p.peek())
+	switch typ {
+	case "not", "and", "or", "only", "layer":
+		return nil, p.unexpected()
+	}
+	if !isIdentStart(typ) {
+		return nil, p.unexpected()
+	}
+	p.i++
+	q.Type = typ
+	if p.keyword("and") {
+		cond, err := p.condition(false)
+		if err != nil {
+			return nil, err
+		}
+		q.Condition = cond
+	}
+	return q, nil
+}
+
+// condition parses a media condition; or is not allowed after a media
+// type, so orOK is unset there.
+func (p *mediaParser) condition(orOK bool) (*MediaCondition, error) {
+	if p.keyword("not") {
+		c, err := p.inParens()
+		if err != nil {
+			return nil, err
+		}
+		return &MediaCondition{Op: "not", Conditions: []*MediaCondition{c}}, nil
+	}
+	first, err := p.inParens()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(p.peek())
+	if op != "and" && (op != "or" || !orOK) {
+		return first, nil
+	}
+	c := &MediaCondition{Op: op, Conditions: []*MediaCondition{first}}
+	for p.keyword(op) {
+		sub, err := p.inParens()
+		if err != nil {
+			return nil, err
+		}
+		c.Conditions = append(c.Conditions, sub)
+	}
+	return c, nil
+}
+
+// inParens parses a parenthesized condition or feature. Parentheses whose
+// content is neither are kept as unknown.
+func (p *mediaParser) inParens() (*MediaCondition, error) {
+	open := p.i
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	start := p.i
+	if strings.EqualFold(p.peek(), "not") || p.peek() == "(" {
+		c, err := p.condition(true)
+		if err == nil && p.peek() == ")" {
+			p.i++
+			return c, nil
+		}
+		p.i = start
+	}
+	depth := 0
+	for ; depth > 0 || p.peek() != ")"; p.i++ {
+		switch p.peek() {
+		case "":
+			return nil, p.unexpected()
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+	}
+	toks, offs := p.toks[start:p.i], p.offs[start:p.i]
+	p.i++
+	if f := parseMediaFeature(toks, offs); f != nil {
+		f.Start, f.End = p.offs[open], p.offs[p.i-1]+1
+		return &MediaCondition{Feature: f}, nil
+	}
+	return &MediaCondition{Raw: strings.Join(toks, " ")}, nil
+}
+
+// parseMediaFeature parses the tokens between the parentheses of a feature,
+// at offs, returning nil if they are not one.
+func parseMediaFeature(toks []string, offs []int) *MediaFeature {
+	// Join ratios such as 16 / 9 into one operand.
+	var parts []string
+	var spans [][2]int
+	for i := 0; i < len(toks); i++ {
+		t, start := toks[i], offs[i]
+		if i+2 < len(toks) && toks[i+1] == "/" {
+			t += "/" + toks[i+2]
+			i += 2
+		}
+		parts = append(parts, t)
+		spans = append(spans, [2]int{start, offs[i] + len(toks[i])})
+	}
+	constraint := func(op string, i int) MediaConstraint {
+		return MediaConstraint{Op: op, Value: parts[i], Start: spans[i][0], End: spans[i][1]}
+	}
+	value := func(s string) bool {
+		return !strings.ContainsAny(s, "(),:<>=")
+	}
+	switch {
+	case len(parts) == 1 && isIdentStart(parts[0]):
+		return &MediaFeature{Name: strings.ToLower(parts[0])}
+	case len(parts) == 3 && parts[1] == ":" && isIdentStart(parts[0]) && value(parts[2]):
+		f := &MediaFeature{Name: strings.ToLower(parts[0]), Legacy: true}
+		op := "="
+		if n := strings.TrimPrefix(f.Name, "min-"); n != f.Name {
+			f.Name, op = n, ">="
+		} else if n := strings.TrimPrefix(f.Name, "max-"); n != f.Name {
+			f.Name, op = n, "<="
+		}
+		f.Constraints = []MediaConstraint{constraint(op, 2)}
+		return f
+	case len(parts) == 3 && isComparison(parts[1]) && isIdentStart(parts[0]) && value(parts[2]):
+		return &MediaFeature{Name: strings.ToLower(parts[0]), Constraints: []MediaConstraint{constraint(parts[1], 2)}}
+	case len(parts) == 3 && isComparison(parts[1]) && isIdentStart(parts[2]) && value(parts[0]):
+		return &MediaFeature{Name: strings.ToLower(parts[2]), Constraints: []MediaConstraint{constraint(flipComparison(parts[1]), 0)}}
+	case len(parts) == 5 && isIdentStart(parts[2]) && value(parts[0]) && value(parts[4]):
+		lo, hi := parts[1], parts[3]
+		if lo[0] != hi[0] || lo[0] == '=' || !isComparison(lo) || !isComparison(hi) {
+			return nil
+		}
+		return &MediaFeature{Name: strings.ToLower(parts[2]), Constraints: []MediaConstraint{constraint(flipComparison(lo), 0), constraint(hi, 4)}}
+	}
+	return nil
+}
+
+func isComparison(s string) bool {
+	switch s {
+	case "=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// flipComparison returns the comparison that holds with its operands
+// swapped.
+func flipComparison(op string) string {
+	switch op[0] {
+	case '<':
+		return ">" + op[1:]
+	case '>':
+		return "<" + op[1:]
+	}
+	return op
+}
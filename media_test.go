@@ -0,0 +1,95 @@
+package css
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMediaScopes checks that the rules for .a at the top level and in two
+// @media blocks of testdata/corpus/media-scopes.css are kept apart by each
+// of UnmarshalScoped, ScopedKeys, ForEnv, Ungroup, Conflicts and MergeMedia.
+func TestMediaScopes(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "corpus", "media-scopes.css"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	top := map[string]string{"color": "red", "margin": "1px"}
+	wide := map[string]string{"color": "blue", "padding": "1px"}
+	printed := map[string]string{"color": "black"}
+
+	scoped, err := UnmarshalScoped(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[MediaScope]map[Rule]map[string]string{
+		"":                   {".a": top},
+		"(min-width: 600px)": {".a": wide},
+		"print":              {".a": printed},
+	}
+	if !reflect.DeepEqual(scoped, want) {
+		t.Errorf("UnmarshalScoped = %q, want %q", scoped, want)
+	}
+
+	keyed, err := Unmarshal(src, ScopedKeys(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKeyed := map[Rule]map[string]string{
+		".a":                              top,
+		"@media (min-width: 600px) :: .a": wide,
+		"@media print :: .a":              printed,
+	}
+	if !reflect.DeepEqual(keyed, wantKeyed) {
+		t.Errorf("Unmarshal with ScopedKeys = %q, want %q", keyed, wantKeyed)
+	}
+
+	sheet, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ElementDesc{Classes: []string{"a"}}
+	if got := ComputeStyle(sheet, a); !reflect.DeepEqual(got, top) {
+		t.Errorf("ComputeStyle = %q, want %q", got, top)
+	}
+	envs := []struct {
+		env  MediaEnv
+		want map[string]string
+	}{
+		{MediaEnv{Width: 400}, top},
+		{MediaEnv{Width: 800}, map[string]string{"color": "blue", "margin": "1px", "padding": "1px"}},
+		{MediaEnv{Type: "print", Width: 800}, map[string]string{"color": "black", "margin": "1px", "padding": "1px"}},
+	}
+	for _, tt := range envs {
+		if got := ComputeStyle(ForEnv(sheet, tt.env), a); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ComputeStyle of ForEnv(%+v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+
+	var paths []string
+	for _, g := range Ungroup(sheet) {
+		var path []string
+		for _, at := range g.Path {
+			path = append(path, "@"+at.Name+" "+at.Prelude)
+		}
+		paths = append(paths, strings.Join(path, " "))
+	}
+	if want := []string{"", "@media (min-width: 600px)", "@media print", ""}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Ungroup gives the paths %q, want %q", paths, want)
+	}
+
+	conflicts := Conflicts(sheet)
+	if len(conflicts) != 1 || conflicts[0].Selector != ".a" || conflicts[0].Scope != "" || len(conflicts[0].Rules) != 2 {
+		t.Errorf("Conflicts = %+v, want the two top-level rules for .a", conflicts)
+	}
+
+	out, err := Marshal(sheet, MergeMedia(), Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ".a{color:red;margin:0}@media (min-width: 600px){.a{color:#00f;padding:1px}}@media print{.a{color:#000}}.a{margin:1px}"; string(out) != want {
+		t.Errorf("Marshal with MergeMedia = %s, want %s", out, want)
+	}
+}
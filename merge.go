@@ -0,0 +1,132 @@
+package css
+
+import "strings"
+
+// MergeMedia moves the rules of @media blocks into the last block with the
+// same media query list, compared once normalized, among the nodes of the
+// same list. The rules keep their relative order. Since a rule written
+// later wins over an earlier one, a block is left in place if a node
+// between it and the last block has a selector in common with it.
+func MergeMedia() MarshalOption {
+	return func(o *marshalOptions) {
+		o.mergeMedia = true
+	}
+}
+
+// mergeMedia returns nodes with the @media blocks merged as described for
+// MergeMedia. The blocks of nodes are not changed; merged ones are copies.
+func mergeMedia(nodes []Node) []Node {
+	groups := make(map[string][]int)
+	var keys []string
+	for i, n := range nodes {
+		if a, ok := n.(*AtRule); ok && a.Rules != nil && asciiLower(a.Name) == "media" {
+			key := mediaKey(a.Prelude)
+			if groups[key] == nil {
+				keys = append(keys, key)
+			}
+			groups[key] = append(groups[key], i)
+		}
+	}
+	moved := make(map[int]bool)
+	merged := make(map[int]*AtRule)
+	for _, key := range keys {
+		group := groups[key]
+		last := group[len(group)-1]
+		// Walking back from the last block, between holds the selectors of
+		// the nodes left between it and the block at hand.
+		between := make(map[Rule]bool)
+		var into []int
+		k := len(group) - 2
+		for j := last - 1; k >= 0; j-- {
+			if j == group[k] {
+				k--
+				sels := nodeSelectors(nil, nodes[j:j+1])
+				if !sharesAny(sels, between) {
+					moved[j] = true
+					into = append(into, j)
+					continue
+				}
+				for s := range sels {
+					between[s] = true
+				}
+			} else if !moved[j] {
+				nodeSelectors(between, nodes[j:j+1])
+			}
+		}
+		for a, b := 0, len(into)-1; a < b; a, b = a+1, b-1 {
+			into[a], into[b] = into[b], into[a]
+		}
+		if len(into) == 0 {
+			continue
+		}
+		m := *nodes[last].(*AtRule)
+		m.Rules, m.Comments = nil, nil
+		for _, i := range append(into, last) {
+			a := nodes[i].(*AtRule)
+			m.Rules = append(m.Rules, a.Rules...)
+			m.Comments = append(m.Comments, a.Comments...)
+		}
+		merged[last] = &m
+	}
+	if len(moved) == 0 {
+		return nodes
+	}
+	out := make([]Node, 0, len(nodes)-len(moved))
+	for i, n := range nodes {
+		switch {
+		case moved[i]:
+		case merged[i] != nil:
+			out = append(out, merged[i])
+		default:
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// mediaKey returns the media query list prelude in a normalized form, so
+// that lists differing only in case or spacing have the same key.
+func mediaKey(prelude string) string {
+	queries, err := ParseMediaQueryList(prelude)
+	if err != nil {
+		return strings.Join(strings.Fields(prelude), " ")
+	}
+	s := make([]string, len(queries))
+	for i, q := range queries {
+		s[i] = q.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// nodeSelectors adds the selectors of the rules in nodes, at any depth, to
+// sels, creating it if nil, and returns it.
+func nodeSelectors(sels map[Rule]bool, nodes []Node) map[Rule]bool {
+	if sels == nil {
+		sels = make(map[Rule]bool)
+	}
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			for _, s := range n.Selectors {
+				sels[s] = true
+			}
+			nodeSelectors(sels, n.Rules)
+		case *AtRule:
+			nodeSelectors(sels, n.Rules)
+		}
+	}
+	return sels
+}
+
+// sharesAny reports whether a and b have a selector in common.
+func sharesAny(a, b map[Rule]bool) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for s := range a {
+		if b[s] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package css
+
+import "testing"
+
+// TestMergeMedia checks that a @media block is merged into the last one of
+// its query unless a node left between them has one of its selectors.
+func TestMergeMedia(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"@media print{.a{top:0}} .b{top:0} @media PRINT{.c{top:0}}", ".b{top:0}@media PRINT{.a{top:0}.c{top:0}}"},
+		{"@media print{.a{top:0}} .a{top:1px} @media print{.b{top:0}}", "@media print{.a{top:0}}.a{top:1px}@media print{.b{top:0}}"},
+		{
+			"@media print{.a{top:0}} .a{top:1px} @media print{.b{top:0}} .c{top:0} @media screen{.c{x:1}} @media print{.d{top:0}} @media screen{.e{x:1}}",
+			"@media print{.a{top:0}}.a{top:1px}.c{top:0}@media print{.b{top:0}.d{top:0}}@media screen{.c{x:1}.e{x:1}}",
+		},
+		// Only the block of .y moves past .x{top:1px}, which .x{top:0} stays before.
+		{"@media print{.x{top:0}} @media print{.y{top:0}} .x{top:1px} @media print{.z{top:0}}", "@media print{.x{top:0}}.x{top:1px}@media print{.y{top:0}.z{top:0}}"},
+	}
+	for _, tt := range tests {
+		sheet, err := Parse([]byte(tt.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := Marshal(sheet, MergeMedia(), Minify())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("MergeMedia of %q:\ngot  %s\nwant %s", tt.src, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package css
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// compactSelector drops the whitespace of sel that is not needed, around
+// combinators and commas.
+func compactSelector(sel string) string {
+	var b strings.Builder
+	for i := 0; i < len(sel); {
+		c := sel[i]
+		end := i + 1
+		switch {
+		case isSelectorSpace(c):
+			for end < len(sel) && isSelectorSpace(sel[end]) {
+				end++
+			}
+			out := b.String()
+			if out != "" && !strings.ContainsRune(">+~,(", rune(out[len(out)-1])) &&
+				end < len(sel) && !strings.ContainsRune(">+~,)", rune(sel[end])) {
+				b.WriteByte(' ')
+			}
+			i = end
+			continue
+		case c == '"' || c == '\'':
+			end = skipString(sel, i)
+		case c == '[':
+			end = skipBracket(sel, i)
+		case c == '\\':
+			end = skipEscape(sel, i)
+		}
+		b.WriteString(sel[i:end])
+		i = end
+	}
+	return b.String()
+}
+
+// colorProperties are the properties other than those ending in "color"
+// whose values may hold colors.
+var colorProperties = map[string]bool{
+	"background": true, "background-image": true, "border": true, "border-top": true,
+	"border-right": true, "border-bottom": true, "border-left": true, "border-block": true,
+	"border-block-start": true, "border-block-end": true, "border-inline": true,
+	"border-inline-start": true, "border-inline-end": true, "border-image": true,
+	"border-image-source": true, "outline": true, "column-rule": true, "text-decoration": true,
+	"text-emphasis": true, "box-shadow": true, "text-shadow": true, "fill": true, "stroke": true,
+	"filter": true, "mask": true, "mask-image": true,
+}
+
+// shortColors returns value, which prop is set to, with each color
+// rewritten to its shortest form giving exactly the same color, such as
+// "#fff" for "#ffffff" and "red" for "rgb(255 0 0)". The values of other
+// properties are returned unchanged, so that a family or animation named
+// "red" is kept.
+func shortColors(prop, value string) string {
+	if strings.HasPrefix(prop, "--") || !takesColors(prop) {
+		return value
+	}
+	return mapColors(value, shortColor)
+}
+
+// shortRadius returns value, which prop is set to, in the shortest form of
+// the same radii if prop is border-radius, such as "4px / 2px" for
+// "4px 4px / 2px 2px 2px". Other values are returned unchanged.
+func shortRadius(prop, value string) string {
+	if base, _ := Canonical(asciiLower(prop)); base != "border-radius" || strings.Contains(asciiLower(value), "var(") {
+		return value
+	}
+	h, v, ok := splitRadius(value)
+	if !ok {
+		return value
+	}
+	return strings.Replace(joinRadius(h, v), " / ", "/", 1)
+}
+
+// keepZeroUnits lists the properties whose zero lengths and percentages keep
+// their unit: in flex, a unitless zero reads as a flex factor rather than
+// the basis, as in "flex: 0 0 0%".
+var keepZeroUnits = map[string]bool{"flex": true, "flex-basis": true}
+
+// shortNumbers returns value, which prop is set to, with the numbers written
+// in their shortest form: without a '+' sign or a leading zero, as ".5" for
+// "+0.5", and with zero lengths and percentages as "0". Units are only
+// dropped outside of functions, such as calc(), whose arguments may need
+// them, and zero times, angles and the like keep theirs. Strings, url()
+// arguments and the values of custom properties and unicode-range are left
+// as they are.
+func shortNumbers(prop, value string) string {
+	base, _ := Canonical(asciiLower(prop))
+	if strings.HasPrefix(prop, "--") || base == "unicode-range" {
+		return value
+	}
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(value); {
+		c := value[i]
+		switch {
+		case c == '"' || c == '\'':
+			end := skipString(value, i)
+			b.WriteString(value[i:end])
+			i = end
+			continue
+		case c == '\\' || c == '#' || isNameRune(rune(c)) && !numberAt(value, i):
+			end := i
+			if c == '#' {
+				end++
+			}
+			if end = skipName(value, end); end == i {
+				end++
+			}
+			if end < len(value) && value[end] == '(' && asciiLower(value[i:end]) == "url" {
+				_, end = parenthesized(value, end)
+			}
+			b.WriteString(value[i:end])
+			i = end
+			continue
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case numberAt(value, i):
+			end, unit := numericEnd(value, i), numberEnd(value, i)
+			b.WriteString(shortNumber(value[i:end], value[end:unit], depth == 0 && !keepZeroUnits[base]))
+			i = unit
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return b.String()
+}
+
+// numberAt reports whether a number starts at s[i] that is not part of a
+// word, as the "+0025" of "U+0025" is.
+func numberAt(s string, i int) bool {
+	return (i == 0 || !isNameRune(rune(s[i-1])) && s[i-1] != '.') && startsNumber(s, i)
+}
+
+// shortNumber returns the shortest form of the number n with unit, dropping
+// the unit of a zero length or percentage if dropUnit is set.
+func shortNumber(n, unit string, dropUnit bool) string {
+	n = strings.TrimPrefix(n, "+")
+	sign := ""
+	if strings.HasPrefix(n, "-") {
+		sign, n = "-", n[1:]
+	}
+	if t := strings.TrimLeft(n, "0"); t != "" && t[0] != 'e' && t[0] != 'E' {
+		n = t
+	}
+	if strings.Trim(n, "0.") == "" {
+		n, sign = "0", ""
+		if dropUnit && (unit == "%" || lengthUnits[asciiLower(unit)]) {
+			unit = ""
+		}
+	}
+	return sign + n + unit
+}
+
+// takesColors reports whether the value of prop may hold colors.
+func takesColors(prop string) bool {
+	base, _ := Canonical(asciiLower(prop))
+	return strings.HasSuffix(base, "color") || colorProperties[base]
+}
+
+// mapColors returns value with each token that may be a color, such as a
+// keyword, a hex color or a call of a color function, replaced by what fn
+// returns for it. The arguments of other functions are searched too, except
+// those of url(), and strings are skipped.
+func mapColors(value string, fn func(token string) string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); {
+		c := value[i]
+		if c == '"' || c == '\'' {
+			end := skipString(value, i)
+			b.WriteString(value[i:end])
+			i = end
+			continue
+		}
+		if c != '#' && c != '\\' && !isNameRune(rune(c)) {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		end := i
+		if c == '#' {
+			end++
+		}
+		end = skipName(value, end)
+		token := value[i:end]
+		if end == len(value) || value[end] != '(' {
+			b.WriteString(fn(token))
+			i = end
+			continue
+		}
+		args, next := parenthesized(value, end)
+		call := value[i:next]
+		switch name := asciiLower(token); {
+		case !strings.HasSuffix(call, ")"), name == "url":
+			b.WriteString(call)
+		case colorFunctions[name] && name != "color-mix" && name != "light-dark":
+			b.WriteString(fn(call))
+		default:
+			b.WriteString(token + "(" + mapColors(args, fn) + ")")
+		}
+		i = next
+	}
+	return b.String()
+}
+
+// shortColor returns the shortest form of the color s, or s if it is not a
+// color that can be rewritten exactly.
+func shortColor(s string) string {
+	rgba, ok := parseRGBA(s)
+	if !ok {
+		return s
+	}
+	short := hexColor(rgba)
+	if rgba[3] == 255 {
+		if name, ok := colorNames[short]; ok && len(name) < len(short) {
+			short = name
+		}
+	}
+	if len(short) > len(s) {
+		return s
+	}
+	return short
+}
+
+// colorNames maps the hex values of the named colors, in the long form
+// namedColors has, to their shortest name.
+var colorNames = func() map[string]string {
+	m := make(map[string]string, len(namedColors))
+	for name, hex := range namedColors {
+		short := hexColor(mustRGBA(hex))
+		if n, ok := m[short]; !ok || len(name) < len(n) || len(name) == len(n) && name < n {
+			m[short] = name
+		}
+	}
+	return m
+}()
+
+func mustRGBA(hex string) [4]uint8 {
+	rgba, ok := parseRGBA(hex)
+	if !ok {
+		panic(fmt.Sprintf("invalid color %q", hex))
+	}
+	return rgba
+}
+
+// hexColor returns the shortest hex notation of rgba, leaving out the alpha
+// if it is opaque.
+func hexColor(rgba [4]uint8) string {
+	n := 4
+	if rgba[3] == 255 {
+		n = 3
+	}
+	short := true
+	for _, c := range rgba[:n] {
+		short = short && c>>4 == c&15
+	}
+	const digits = "0123456789abcdef"
+	b := []byte{'#'}
+	for _, c := range rgba[:n] {
+		if short {
+			b = append(b, digits[c&15])
+		} else {
+			b = append(b, digits[c>>4], digits[c&15])
+		}
+	}
+	return string(b)
+}
+
+// parseRGBA returns the channels of the hex, named or rgb() color s, if they
+// are exactly whole numbers from 0 to 255.
+func parseRGBA(s string) ([4]uint8, bool) {
+	lower := asciiLower(s)
+	if hex, ok := namedColors[lower]; ok {
+		lower = hex
+	} else if lower == "transparent" {
+		return [4]uint8{}, true
+	}
+	if strings.HasPrefix(lower, "#") {
+		if colorValue(lower) != nil {
+			return [4]uint8{}, false
+		}
+		hex := lower[1:]
+		if len(hex) <= 4 {
+			var long []byte
+			for i := range hex {
+				long = append(long, hex[i], hex[i])
+			}
+			hex = string(long)
+		}
+		if len(hex) == 6 {
+			hex += "ff"
+		}
+		var rgba [4]uint8
+		for i := range rgba {
+			rgba[i] = hexValue(hex[2*i])<<4 | hexValue(hex[2*i+1])
+		}
+		return rgba, true
+	}
+	name, ok := functionName(lower)
+	if !ok || name != "rgb" && name != "rgba" {
+		return [4]uint8{}, false
+	}
+	args := lower[len(name)+1 : len(lower)-1]
+	var parts []string
+	if strings.Contains(args, ",") {
+		parts = strings.Split(args, ",")
+	} else {
+		i := strings.IndexByte(args, '/')
+		if i < 0 {
+			parts = strings.Fields(args)
+		} else {
+			parts = append(strings.Fields(args[:i]), args[i+1:])
+		}
+	}
+	if len(parts) < 3 || len(parts) > 4 {
+		return [4]uint8{}, false
+	}
+	rgba := [4]uint8{3: 255}
+	for i, p := range parts {
+		n, unit, ok := splitNumber(strings.TrimSpace(p))
+		if !ok {
+			return [4]uint8{}, false
+		}
+		switch {
+		case unit == "%":
+			n = n * 255 / 100
+		case unit != "":
+			return [4]uint8{}, false
+		case i == 3:
+			n *= 255
+		}
+		r := math.Round(n)
+		if r < 0 || r > 255 || math.Abs(n-r) > 1e-9 {
+			return [4]uint8{}, false
+		}
+		rgba[i] = uint8(r)
+	}
+	return rgba, true
+}
+
+func hexValue(c byte) uint8 {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
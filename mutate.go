@@ -0,0 +1,323 @@
+package css
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/scanner"
+)
+
+// AddRule appends an empty rule for the selector list selector to sheet and
+// returns it. The selector list is read as Parse reads it, so that
+// marshaling the rule gives the same result as marshaling a parsed one.
+func (sheet *StyleSheet) AddRule(selector string) (*RuleNode, error) {
+	r, err := newRule(selector)
+	if err != nil {
+		return nil, err
+	}
+	sheet.Rules = append(sheet.Rules, r)
+	return r, nil
+}
+
+// InsertRuleBefore is like AddRule but inserts the rule just before mark,
+// which may be nested in an at-rule or rule. It fails if mark is not in
+// sheet.
+func (sheet *StyleSheet) InsertRuleBefore(mark Node, selector string) (*RuleNode, error) {
+	return sheet.insertRule(mark, selector, 0)
+}
+
+// InsertRuleAfter is like InsertRuleBefore but inserts the rule just after
+// mark.
+func (sheet *StyleSheet) InsertRuleAfter(mark Node, selector string) (*RuleNode, error) {
+	return sheet.insertRule(mark, selector, 1)
+}
+
+func (sheet *StyleSheet) insertRule(mark Node, selector string, offset int) (*RuleNode, error) {
+	r, err := newRule(selector)
+	if err != nil {
+		return nil, err
+	}
+	if !insertNode(&sheet.Rules, mark, r, offset) {
+		return nil, fmt.Errorf("rule to insert %q next to is not in the stylesheet", selector)
+	}
+	return r, nil
+}
+
+// insertNode inserts n at offset from mark in the list *nodes or the lists
+// nested in it, and reports whether mark was found.
+func insertNode(nodes *[]Node, mark, n Node, offset int) bool {
+	for i, c := range *nodes {
+		if c == mark {
+			i += offset
+			*nodes = append((*nodes)[:i], append([]Node{n}, (*nodes)[i:]...)...)
+			return true
+		}
+		switch c := c.(type) {
+		case *RuleNode:
+			if insertNode(&c.Rules, mark, n, offset) {
+				return true
+			}
+		case *AtRule:
+			if insertNode(&c.Rules, mark, n, offset) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RemoveRule removes the selector selector, read as by AddRule, from the
+// rules of sheet, at any depth, and reports whether any rule had it. A
+// rule left without selectors is removed along with the rules nested in it;
+// the others keep their declarations for their remaining selectors.
+func (sheet *StyleSheet) RemoveRule(selector string) (bool, error) {
+	r, err := newRule(selector)
+	if err != nil {
+		return false, err
+	}
+	if len(r.Selectors) != 1 {
+		return false, fmt.Errorf("selector %q is not a single selector", selector)
+	}
+	var removed bool
+	sheet.Rules = removeSelector(sheet.Rules, r.Selectors[0], &removed)
+	return removed, nil
+}
+
+func removeSelector(nodes []Node, sel Rule, removed *bool) []Node {
+	out := nodes[:0]
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			sels := n.Selectors[:0]
+			for _, s := range n.Selectors {
+				if s == sel {
+					*removed = true
+					continue
+				}
+				sels = append(sels, s)
+			}
+			if n.Selectors = sels; len(sels) == 0 {
+				continue
+			}
+			n.Rules = removeSelector(n.Rules, sel, removed)
+		case *AtRule:
+			n.Rules = removeSelector(n.Rules, sel, removed)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// Set sets the property prop of r to value, read as by Parse, along with
+// its !important flag if it has one. The first declaration of prop takes
+// the value and later ones are removed; if there is none, the declaration
+// is appended.
+func (r *RuleNode) Set(prop, value string) error {
+	d, err := newDeclaration(prop, value)
+	if err != nil {
+		return err
+	}
+	r.set(d)
+	return nil
+}
+
+// SetImportant is like Set but always flags the declaration !important.
+func (r *RuleNode) SetImportant(prop, value string) error {
+	d, err := newDeclaration(prop, value)
+	if err != nil {
+		return err
+	}
+	d.Important = true
+	r.set(d)
+	return nil
+}
+
+func (r *RuleNode) set(d Declaration) {
+	decls := r.Declarations[:0]
+	found := false
+	for _, c := range r.Declarations {
+		if c.Property != d.Property {
+			decls = append(decls, c)
+		} else if !found {
+			decls, found = append(decls, d), true
+		}
+	}
+	if !found {
+		decls = append(decls, d)
+	}
+	r.Declarations = decls
+}
+
+// Remove removes every declaration of the property prop from r and reports
+// whether there was any.
+func (r *RuleNode) Remove(prop string) bool {
+	prop = options{}.property(prop)
+	decls := r.Declarations[:0]
+	for _, d := range r.Declarations {
+		if d.Property != prop {
+			decls = append(decls, d)
+		}
+	}
+	removed := len(decls) < len(r.Declarations)
+	r.Declarations = decls
+	return removed
+}
+
+// MustRule returns the Rule key of the single selector selector, as Parse
+// keys it, for selectors known to be valid, such as constants. It panics
+// if selector is not one AddRule accepts or is a selector list.
+func MustRule(selector string) Rule {
+	r, err := newRule(selector)
+	if err == nil && len(r.Selectors) != 1 {
+		err = fmt.Errorf("selector %q is not a single selector", selector)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return r.Selectors[0]
+}
+
+// MustValue returns value, for declaration values known to be valid, such
+// as constants. It panics if value is not one Set accepts.
+func MustValue(value string) string {
+	if _, err := newDeclaration("x", value); err != nil {
+		panic(err)
+	}
+	return value
+}
+
+// newRule returns an empty rule for the selector list selector, parsed
+// from a stylesheet of that rule alone. A selector Marshal would not write
+// back as it is read, such as one with an unclosed bracket, is an error.
+func newRule(selector string) (*RuleNode, error) {
+	if err := checkText(selector); err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	sheet, err := Parse([]byte(selector + " {}"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	if len(sheet.Rules) != 1 {
+		return nil, fmt.Errorf("selector %q is not a single selector list", selector)
+	}
+	r, ok := sheet.Rules[0].(*RuleNode)
+	if !ok || len(r.Rules) > 0 || len(r.Declarations) > 0 {
+		return nil, fmt.Errorf("selector %q is not a single selector list", selector)
+	}
+	r = &RuleNode{Selectors: r.Selectors}
+	if !roundTrips(r) {
+		return nil, fmt.Errorf("selector %q does not parse back from its CSS text", selector)
+	}
+	return r, nil
+}
+
+// newDeclaration returns the declaration of prop and value, parsed from a
+// rule holding it alone. As for newRule, a value that would not be written
+// back as it is read is an error.
+func newDeclaration(prop, value string) (Declaration, error) {
+	if err := checkText(value); err != nil {
+		return Declaration{}, fmt.Errorf("invalid declaration %s: %s: %w", prop, value, err)
+	}
+	sheet, err := Parse([]byte("x {" + prop + ": " + value + "}"))
+	if err != nil {
+		return Declaration{}, fmt.Errorf("invalid declaration %s: %s: %w", prop, value, err)
+	}
+	var r *RuleNode
+	if len(sheet.Rules) == 1 {
+		r, _ = sheet.Rules[0].(*RuleNode)
+	}
+	if r == nil || len(r.Rules) > 0 || len(r.Declarations) != 1 || r.Declarations[0].Property != (options{}).property(prop) {
+		return Declaration{}, fmt.Errorf("%s: %s is not a single declaration", prop, value)
+	}
+	d := r.Declarations[0]
+	d.Pos, d.ValuePos, d.ValueEnd = scanner.Position{}, scanner.Position{}, scanner.Position{}
+	if !roundTrips(&RuleNode{Selectors: []Rule{"x"}, Declarations: []Declaration{d}}) {
+		return Declaration{}, fmt.Errorf("declaration %s: %s does not parse back from its CSS text", prop, value)
+	}
+	return d, nil
+}
+
+// checkText reports an unclosed bracket, string or comment, or a trailing
+// backslash, in the selector or value s, which would make the parser read
+// the text after s as part of it, and a ';', '{' or '}' outside a string
+// or url(), which would make it end s there.
+func checkText(s string) error {
+	var open []byte
+	for i := 0; i < len(s); i++ {
+		if end := unquotedURLEnd(s, i); end > 0 {
+			if s[end-1] != ')' {
+				return errors.New("unclosed url(")
+			}
+			i = end - 1
+			continue
+		}
+		switch c := s[i]; c {
+		case ';', '{', '}':
+			return fmt.Errorf("unexpected %c", c)
+		case '\\':
+			if i++; i == len(s) {
+				return errors.New("trailing backslash")
+			}
+		case '"', '\'':
+			j := i + 1
+			for ; j < len(s) && s[j] != c && s[j] != '\n'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) || s[j] != c {
+				return errors.New("unterminated string")
+			}
+			i = j
+		case '/':
+			if i+1 < len(s) && s[i+1] == '*' {
+				j := strings.Index(s[i+2:], "*/")
+				if j < 0 {
+					return errors.New("unterminated comment")
+				}
+				i += j + 3
+			}
+		case '(', '[':
+			open = append(open, c)
+		case ')', ']':
+			want := byte('(')
+			if c == ']' {
+				want = '['
+			}
+			if len(open) == 0 || open[len(open)-1] != want {
+				return fmt.Errorf("unexpected %c", c)
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return fmt.Errorf("unclosed %c", open[len(open)-1])
+	}
+	return nil
+}
+
+// roundTrips reports whether r, a rule without nested rules, parses back
+// from the text Marshal writes for it.
+func roundTrips(r *RuleNode) bool {
+	out, _ := Marshal(&StyleSheet{Rules: []Node{r}})
+	sheet, err := Parse(out)
+	if err != nil || len(sheet.Rules) != 1 {
+		return false
+	}
+	p, ok := sheet.Rules[0].(*RuleNode)
+	if !ok || len(p.Rules) > 0 || len(p.Selectors) != len(r.Selectors) || len(p.Declarations) != len(r.Declarations) {
+		return false
+	}
+	for i, sel := range r.Selectors {
+		if p.Selectors[i] != sel {
+			return false
+		}
+	}
+	for i, d := range r.Declarations {
+		q := p.Declarations[i]
+		if q.Property != d.Property || q.Value != d.Value || q.Important != d.Important {
+			return false
+		}
+	}
+	return true
+}
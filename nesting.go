@@ -0,0 +1,347 @@
+package css
+
+import "strings"
+
+// NestingDialect selects how rules nested in rule blocks are read.
+type NestingDialect int
+
+// Nesting dialects for WithNestingDialect.
+const (
+	// DialectCSS keeps nested rules in RuleNode.Rules, as written, and so
+	// the conditional group rules nested in rules, such as @media, as
+	// AtRule nodes holding their declarations, which apply to the
+	// selectors of the rule. Where the selectors are resolved, '&' stands
+	// for :is() of the parent's, as CSS Nesting reads it.
+	DialectCSS NestingDialect = iota
+	// DialectSCSS reads nesting the way Sass does and flattens it while
+	// parsing, so that the stylesheet holds plain CSS rules only. A nested
+	// selector list is combined with its parent's in parent order, as in
+	// ".a, .b { .c, .d {} }" giving ".a .c, .a .d, .b .c, .b .d", and '&'
+	// can take a suffix, as in "&__title". Declarations after a nested rule
+	// go in a rule of their own after it, keeping source order. No other
+	// Sass feature is supported.
+	DialectSCSS
+)
+
+// nestedAtRules are the at-rules whose blocks may be nested in rules, the
+// conditional group rules, holding declarations and rules for the
+// selectors of the rules they are in.
+var nestedAtRules = map[string]bool{
+	"media": true, "supports": true, "container": true, "layer": true, "scope": true, "starting-style": true,
+}
+
+// WithNestingDialect sets the dialect nested rules are read in. The default
+// is DialectCSS.
+func WithNestingDialect(d NestingDialect) Option {
+	return func(o *options) {
+		o.nesting = d
+	}
+}
+
+// unnest returns nodes with the rules nested in rule blocks moved after
+// their parent rule, with their selectors resolved against the parent's as
+// if written at the top level according to dialect. nodes is returned as is
+// if nothing is nested.
+func unnest(nodes []Node, dialect NestingDialect) []Node {
+	if !hasNesting(nodes) {
+		return nodes
+	}
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			out = appendUnnested(out, n, n.Selectors, dialect)
+		case *AtRule:
+			at := *n
+			at.Rules = unnest(n.Rules, dialect)
+			out = append(out, &at)
+		}
+	}
+	return out
+}
+
+func hasNesting(nodes []Node) bool {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			if len(n.Rules) > 0 {
+				return true
+			}
+		case *AtRule:
+			if hasNesting(n.Rules) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appendUnnested appends n, with the resolved selectors sels, and the rules
+// nested in it to out.
+func appendUnnested(out []Node, n *RuleNode, sels []Rule, dialect NestingDialect) []Node {
+	if len(n.Declarations) > 0 || len(n.Rules) == 0 {
+		r := *n
+		r.Selectors, r.Rules = sels, nil
+		out = append(out, &r)
+	}
+	for _, c := range n.Rules {
+		switch c := c.(type) {
+		case *RuleNode:
+			out = appendUnnested(out, c, nestSelectors(sels, c.Selectors, dialect), dialect)
+		case *AtRule:
+			out = append(out, unnestAtRule(c, sels, dialect))
+		}
+	}
+	return out
+}
+
+// unnestAtRule returns the at-rule at nested in a rule of the resolved
+// selectors sels, as if written at the top level: with its declarations in
+// a rule of sels and its rules resolved against them.
+func unnestAtRule(at *AtRule, sels []Rule, dialect NestingDialect) *AtRule {
+	out := *at
+	out.Declarations, out.Rules = nil, []Node{}
+	if len(at.Declarations) > 0 {
+		out.Rules = append(out.Rules, &RuleNode{Selectors: sels, Declarations: at.Declarations, Pos: at.Pos, Open: at.Open, Close: at.Close})
+	}
+	for _, c := range at.Rules {
+		switch c := c.(type) {
+		case *RuleNode:
+			out.Rules = appendUnnested(out.Rules, c, nestSelectors(sels, c.Selectors, dialect), dialect)
+		case *AtRule:
+			out.Rules = append(out.Rules, unnestAtRule(c, sels, dialect))
+		}
+	}
+	return &out
+}
+
+// nestSelectors resolves the selectors of a nested rule against those of
+// its parent, giving one selector for each pair. They are ordered by
+// nested selector first, like the :is() list '&' stands for in CSS, or by
+// parent first in DialectSCSS.
+func nestSelectors(parents, sels []Rule, dialect NestingDialect) []Rule {
+	out := make([]Rule, 0, len(parents)*len(sels))
+	if dialect == DialectSCSS {
+		for _, p := range parents {
+			for _, sel := range sels {
+				out = append(out, scssSelector(p, sel))
+			}
+		}
+		return out
+	}
+	for _, sel := range sels {
+		for _, p := range parents {
+			out = append(out, nestSelector(p, sel))
+		}
+	}
+	return out
+}
+
+// scssSelector replaces each '&' of sel by parent, as Sass does, so that
+// a name after it is a suffix of the parent's, as in "&__title", or puts
+// parent and a space in front of sel if it has none.
+func scssSelector(parent, sel Rule) Rule {
+	s := string(sel)
+	var b strings.Builder
+	found := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			b.WriteByte(c)
+			i++
+			c = s[i]
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '&':
+			b.WriteString(string(parent))
+			found = true
+			continue
+		}
+		b.WriteByte(c)
+	}
+	if !found {
+		return parent + " " + sel
+	}
+	return Rule(b.String())
+}
+
+// nestSelector resolves sel against parent as CSS Nesting does, '&'
+// standing for :is(parent), or puts parent and a space in front of sel if
+// it has no '&'. The compound holding an '&' is merged with parent, its
+// type selector first, as in "div" for "div" and ".x&", and "div.a" for
+// ".a" and "&div": with the last compound of parent if it is the first
+// compound of sel, so that "&.x" in ".a .b" gives ".a .b.x", and with
+// the whole of parent otherwise, in :is() if parent has combinators, since
+// ".dark &" in ".a .b" matches ".a .b" inside .dark wherever .a is. A name
+// right after '&' is a type selector, not a suffix as in DialectSCSS.
+func nestSelector(parent, sel Rule) Rule {
+	s := string(sel)
+	if !hasNestingSelector(s) {
+		return parent + " " + sel
+	}
+	var b strings.Builder
+	first := true
+	for i := 0; i < len(s); {
+		if c := s[i]; c == ' ' || c == '>' || c == '+' || c == '~' || c == '|' && strings.HasPrefix(s[i:], "||") {
+			b.WriteByte(c)
+			i++
+			first = false
+			continue
+		}
+		end := compoundEnd(s, i)
+		b.WriteString(nestCompound(string(parent), s[i:end], first))
+		first = false
+		i = end
+	}
+	return Rule(b.String())
+}
+
+// nestCompound returns the compound selector c of a nested selector with
+// its '&' resolved against parent, and the '&' in the arguments of its
+// pseudo-classes. first is set if c is the first compound of its selector.
+func nestCompound(parent, c string, first bool) string {
+	var typ, rest strings.Builder
+	found := false
+	for i := 0; i < len(c); {
+		end := simpleEnd(c, i)
+		switch part := c[i:end]; {
+		case part == "&":
+			found = true
+		case (i == 0 || c[i-1] == '&') && isTypeSelector(part):
+			typ.WriteString(part)
+		case part[0] == ':' && strings.HasSuffix(part, ")") && hasNestingSelector(part):
+			name := skipName(part, strings.LastIndexByte(part[:2], ':')+1)
+			args := splitSelectorList(part[name+1 : len(part)-1])
+			for j, arg := range args {
+				if arg = strings.TrimSpace(arg); hasNestingSelector(arg) {
+					arg = string(nestSelector(Rule(parent), Rule(arg)))
+				}
+				args[j] = arg
+			}
+			rest.WriteString(part[:name] + "(" + strings.Join(args, ", ") + ")")
+		default:
+			rest.WriteString(part)
+		}
+		i = end
+	}
+	if !found {
+		return typ.String() + rest.String()
+	}
+	prefix, last := "", parent
+	if i := lastCompound(parent); i > 0 && first {
+		prefix, last = parent[:i], parent[i:]
+	} else if i > 0 {
+		last = ":is(" + parent + ")"
+	}
+	ptyp := ""
+	if end := simpleEnd(last, 0); isTypeSelector(last[:end]) {
+		ptyp, last = last[:end], last[end:]
+	}
+	switch t := typ.String(); {
+	case t == "" || t == "*":
+		typ.Reset()
+		typ.WriteString(ptyp)
+		if ptyp == "" && last == "" {
+			typ.WriteString(t)
+		}
+	case ptyp != "" && ptyp != "*" && !strings.EqualFold(ptyp, t):
+		// No element is of both types, as none matches the selector.
+		last = ":is(" + ptyp + last + ")"
+	}
+	return prefix + typ.String() + last + rest.String()
+}
+
+// hasNestingSelector reports whether the selector s has an '&' outside of
+// strings and escapes.
+func hasNestingSelector(s string) bool {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"', '\'':
+			i = skipString(s, i) - 1
+		case '&':
+			return true
+		}
+	}
+	return false
+}
+
+// compoundEnd returns the index just past the compound selector of s
+// starting at i, at the combinator or space after it or at len(s).
+func compoundEnd(s string, i int) int {
+	for i < len(s) {
+		switch s[i] {
+		case ' ', '>', '+', '~':
+			return i
+		case '|':
+			if strings.HasPrefix(s[i:], "||") {
+				return i
+			}
+		}
+		i = simpleEnd(s, i)
+	}
+	return len(s)
+}
+
+// lastCompound returns the index of the last compound selector of the
+// complex selector s, 0 if it has only one.
+func lastCompound(s string) int {
+	last := 0
+	for i := 0; i < len(s); {
+		if end := compoundEnd(s, i); end > i {
+			last, i = i, end
+		} else {
+			i++
+		}
+	}
+	return last
+}
+
+// simpleEnd returns the index just past the simple selector of s starting
+// at i: a type selector, possibly namespaced, '*', an id, a class, an
+// attribute selector, a pseudo-class or pseudo-element with its arguments,
+// or a '&'.
+func simpleEnd(s string, i int) int {
+	switch c := s[i]; {
+	case c == '[':
+		return skipBracket(s, i)
+	case c == ':':
+		end := i + 1
+		if end < len(s) && s[end] == ':' {
+			end++
+		}
+		if end = skipName(s, end); end < len(s) && s[end] == '(' {
+			_, end = parenthesized(s, end)
+		}
+		return end
+	case c == '.' || c == '#':
+		return skipName(s, i+1)
+	case c == '*' || c == '\\' || isNameRune(rune(c)):
+		end := i + 1
+		if c != '*' {
+			end = skipName(s, i)
+		}
+		if end < len(s) && s[end] == '|' && !strings.HasPrefix(s[end:], "||") {
+			if end++; end < len(s) && s[end] == '*' {
+				end++
+			} else {
+				end = skipName(s, end)
+			}
+		}
+		return end
+	}
+	return i + 1
+}
+
+// isTypeSelector reports whether the simple selector s is a type selector
+// or '*'.
+func isTypeSelector(s string) bool {
+	return s != "" && (s[0] == '*' || s[0] == '\\' || isNameRune(rune(s[0])) || s[0] == '|')
+}
@@ -0,0 +1,73 @@
+package css
+
+import "testing"
+
+func TestNestSelector(t *testing.T) {
+	tests := []struct {
+		parent, sel, css, scss Rule
+	}{
+		{"div", ".x&", "div.x", ".xdiv"},
+		{".a", "&div", "div.a", ".adiv"},
+		{".a", "&-title", "-title.a", ".a-title"},
+		{".a", "&:hover", ".a:hover", ".a:hover"},
+		{".a", ".b", ".a .b", ".a .b"},
+		{".a", "> .b", ".a > .b", ".a > .b"},
+		{".a", "& + &", ".a + .a", ".a + .a"},
+		{".card", ".dark &", ".dark .card", ".dark .card"},
+		{".a .b", "&.c", ".a .b.c", ".a .b.c"},
+		{".a .b", ".c&", ".a .b.c", ".c.a .b"},
+		{".a .b", ".dark &", ".dark :is(.a .b)", ".dark .a .b"},
+		{".a .b", ":is(&) > .c", ":is(.a .b) > .c", ":is(.a .b) > .c"},
+		{".a", "&:not(&.b)", ".a:not(.a.b)", ".a:not(.a.b)"},
+		{"p", "&p", "p", "pp"},
+		{"p", "&div", "div:is(p)", "pdiv"},
+		{"*", "&.x", "*.x", "*.x"},
+		{"a:hover", ".y &::before", ".y a:hover::before", ".y a:hover::before"},
+		{`[x="&"]`, ".c", `[x="&"] .c`, `[x="&"] .c`},
+	}
+	for _, tt := range tests {
+		if got := nestSelector(tt.parent, tt.sel); got != tt.css {
+			t.Errorf("nestSelector(%q, %q) = %q, want %q", tt.parent, tt.sel, got, tt.css)
+		}
+		if got := scssSelector(tt.parent, tt.sel); got != tt.scss {
+			t.Errorf("scssSelector(%q, %q) = %q, want %q", tt.parent, tt.sel, got, tt.scss)
+		}
+	}
+}
+
+func TestNestedMedia(t *testing.T) {
+	const src = ".a { color: blue; @media print { color: red; .b { top: 0 } } }"
+	sheet, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Marshal(sheet, Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ".a{color:#00f;@media print{color:red;.b{top:0}}}"; string(out) != want {
+		t.Errorf("Marshal gives %s, want %s", out, want)
+	}
+	sheet, err = Parse([]byte(src), WithNestingDialect(DialectSCSS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err = Marshal(sheet, Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ".a{color:#00f}@media print{.a{color:red}.a .b{top:0}}"; string(out) != want {
+		t.Errorf("Marshal of DialectSCSS gives %s, want %s", out, want)
+	}
+}
+
+func TestNestedAtRuleErrors(t *testing.T) {
+	for src, want := range map[string]string{
+		".a { @font-face { src: x } }": "line 1: unexpected token @font-face",
+		".a { @layer x; color: red }":  "line 1: unexpected token ;",
+	} {
+		if _, err := Parse([]byte(src)); err == nil || err.Error() != want {
+			t.Errorf("Parse(%q) error = %v, want %s", src, err, want)
+		}
+	}
+}
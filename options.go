@@ -0,0 +1,280 @@
+package css
+
+import (
+	"context"
+	"strings"
+	"text/scanner"
+)
+
+// Option configures optional parsing behavior. Parse, ParseReader,
+// Unmarshal, ParseFS and ParseURL accept options; all parser behavior flags
+// are options, and with none the parser behaves as it always has.
+type Option func(*options)
+
+type options struct {
+	preserveCase   bool
+	rawValues      bool
+	rawSelectors   bool
+	maxImportDepth int
+	maxImportBytes int64
+	maxFetches     int
+	diagnostics    func(Diagnostic)
+	filename       string
+	collectErrors  bool
+	errorLimit     int
+	lenient        bool
+	strict         bool
+	ctx            context.Context
+	maxInputBytes  int64
+	maxRules       int
+	maxDecls       int
+	maxSelector    int
+	maxDepth       int
+	nesting        NestingDialect
+	duplicates     DuplicatePolicy
+	verboseErrors  bool
+	fragmentRule   Rule
+	fullScan       bool
+	scopedKeys     bool
+	valueFuncs     map[string]func(args []string) (string, error)
+	expand         bool
+	start          scanner.Position // where a Decoder's input resumes
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// StartAt makes the parser report positions as if the input started at
+// pos, such as the Resume position of a ParseError when parsing the rest
+// of the input from there, rather than at line 1, column 1. The file is
+// still named by Filename.
+func StartAt(pos scanner.Position) Option {
+	return func(o *options) {
+		o.start = pos
+	}
+}
+
+// PreserveCase keeps property names, type selectors and at-rule names as
+// written. By default they are lowercased, since CSS treats them case
+// insensitively; class names, ids, attribute values and custom property
+// names always keep their case.
+func PreserveCase(preserve bool) Option {
+	return func(o *options) {
+		o.preserveCase = preserve
+	}
+}
+
+// RawValues keeps declaration values as written, for output that has to
+// round-trip. By default runs of whitespace outside strings, including
+// newlines and comments, are read as a single space, and whitespace after
+// '(' or before ')' is dropped, so that values written differently compare
+// equal.
+func RawValues(raw bool) Option {
+	return func(o *options) {
+		o.rawValues = raw
+	}
+}
+
+// RawSelectors keeps selectors as written, apart from lowercased type
+// selectors, instead of normalizing them with NormalizeSelector.
+func RawSelectors(raw bool) Option {
+	return func(o *options) {
+		o.rawSelectors = raw
+	}
+}
+
+// Filename sets the name reported in positions and errors for input that
+// is not read from a named source, as with Unmarshal.
+func Filename(name string) Option {
+	return func(o *options) {
+		o.filename = name
+	}
+}
+
+func (o options) property(name string) string {
+	if o.preserveCase || strings.HasPrefix(name, "--") {
+		return name
+	}
+	return asciiLower(name)
+}
+
+func (o options) atKeyword(name string) string {
+	if o.preserveCase {
+		return name
+	}
+	return asciiLower(name)
+}
+
+// typeSelector lowercases the element name leading a selector token,
+// leaving any attribute selector or other suffix untouched.
+func (o options) typeSelector(sel string) string {
+	if o.preserveCase {
+		return sel
+	}
+	n := 0
+	for n < len(sel) && isNameRune(rune(sel[n])) {
+		n++
+	}
+	return asciiLower(sel[:n]) + sel[n:]
+}
+
+func asciiLower(s string) string {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 'A' && c <= 'Z' {
+			b := []byte(s)
+			for j := i; j < len(b); j++ {
+				if c := b[j]; c >= 'A' && c <= 'Z' {
+					b[j] = c + 'a' - 'A'
+				}
+			}
+			return string(b)
+		}
+	}
+	return s
+}
+
+// MaxImportDepth limits how deeply @import rules are followed. A value of
+// zero or less removes the limit.
+func MaxImportDepth(n int) Option {
+	return func(o *options) {
+		o.maxImportDepth = n
+	}
+}
+
+// MaxImportBytes limits the total number of bytes read across a stylesheet
+// and everything it imports. A value of zero or less removes the limit.
+func MaxImportBytes(n int64) Option {
+	return func(o *options) {
+		o.maxImportBytes = n
+	}
+}
+
+// MaxFetches limits the number of stylesheets fetched, including the first.
+// A value of zero or less removes the limit.
+func MaxFetches(n int) Option {
+	return func(o *options) {
+		o.maxFetches = n
+	}
+}
+
+// WithErrorLimit makes the parser recover from errors and continue, up to n
+// errors, instead of stopping at the first one. The partial result is
+// returned together with an ErrorList. After an error in a declaration the
+// parser resumes at the next ';' or '}'; after an error in a selector or
+// at-rule it skips the statement or block. A value of zero or less removes
+// the limit.
+func WithErrorLimit(n int) Option {
+	return func(o *options) {
+		o.collectErrors, o.errorLimit = true, n
+	}
+}
+
+// WithVerboseErrors makes a *ParseError for an unexpected token name what
+// the parser expected instead, and every *ParseError show the source line
+// of its position with a caret under the column:
+//
+//	line 2: unexpected token =; expected ':'
+//	  2 |   color = red;
+//	    |         ^
+//
+// The input is kept in memory while parsing to quote it.
+func WithVerboseErrors(verbose bool) Option {
+	return func(o *options) {
+		o.verboseErrors = verbose
+	}
+}
+
+// Lenient makes the parser handle malformed content the way browsers do
+// instead of failing: an invalid declaration is dropped up to the next ';'
+// or the end of its block, and an invalid rule or at-rule is dropped along
+// with its block. Each dropped item is reported through WithDiagnostics.
+// A declaration whose value cannot be read, because of an unterminated
+// string, an unbalanced bracket or what looks like a missing ';', is kept
+// instead, with its value as written up to the ';' or '}' ending it, past
+// any ';' inside brackets, and flagged Raw, so that no author content is
+// lost. Errors reading the input are still returned.
+func Lenient(lenient bool) Option {
+	return func(o *options) {
+		o.lenient = lenient
+	}
+}
+
+// Strict turns input the parser otherwise tolerates into errors: a
+// declaration missing its ';' before '}', empty blocks, unknown at-rules, a
+// selector used by more than one rule in the same scope, blocks left open at
+// end of input, a misplaced @charset, @import or @namespace and a rule of
+// @keyframes whose selector is not a keyframe selector.
+func Strict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// WithContext makes parsing stop with an error wrapping ctx.Err() once ctx
+// is done. The context is checked every ctxCheckInterval tokens while
+// tokenizing and parsing.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+const ctxCheckInterval = 256
+
+// canceled returns an error at pos if the context set by WithContext is
+// done, checking only every ctxCheckInterval calls as counted by n.
+func (o options) canceled(n int, pos scanner.Position) error {
+	if o.ctx == nil || n%ctxCheckInterval != 0 {
+		return nil
+	}
+	if err := o.ctx.Err(); err != nil {
+		return &ParseError{Pos: pos, Msg: err.Error(), Err: err}
+	}
+	return nil
+}
+
+// MaxInputBytes limits the number of bytes read from the input of a single
+// stylesheet. A value of zero or less removes the limit.
+func MaxInputBytes(n int64) Option {
+	return func(o *options) {
+		o.maxInputBytes = n
+	}
+}
+
+// MaxRules limits the number of rule and at-rule blocks in a stylesheet. A
+// value of zero or less removes the limit.
+func MaxRules(n int) Option {
+	return func(o *options) {
+		o.maxRules = n
+	}
+}
+
+// MaxDeclarations limits the number of declarations in a stylesheet. A
+// value of zero or less removes the limit.
+func MaxDeclarations(n int) Option {
+	return func(o *options) {
+		o.maxDecls = n
+	}
+}
+
+// MaxSelectorLength limits the length in bytes of a single selector. A
+// value of zero or less removes the limit.
+func MaxSelectorLength(n int) Option {
+	return func(o *options) {
+		o.maxSelector = n
+	}
+}
+
+// MaxNestingDepth limits how deeply blocks may nest, counting each rule
+// and at-rule block as one level. A value of zero or less removes the
+// limit.
+func MaxNestingDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = n
+	}
+}
@@ -2,24 +2,71 @@ package css
 
 import (
 	"bytes"
-	"container/list"
-	"errors"
-	"fmt"
+	"context"
 	"io"
 	"strings"
 	"text/scanner"
+	"unicode/utf8"
 )
 
 type tokenEntry struct {
 	value string
 	pos   scanner.Position
+	kind  tokenType
 }
 
 type tokenizer struct {
-	s *scanner.Scanner
+	s        *scanner.Scanner
+	r        *errReader
+	comments *commentFilter
+	src      *bytes.Buffer // the input read, with WithVerboseErrors
+	mode     tokenMode     // how the next token is read, set by the parser
+
+	o      options
+	start  scanner.Position // where the input starts, if not at 1:1
+	inner  tokenMode        // modeString or modeURL inside one, else mode
+	escape bool             // an escape's backslash was just read
+	hex    int              // hex digits read in a selector escape
+	quote  rune             // quote of the string being read
+	urlArg bool             // no non-space rune seen yet in a url(
+	last   [3]rune          // the previous three runes of a value, lowercased
+}
+
+// errReader records the first read error other than io.EOF, since
+// text/scanner treats every read error as the end of input. It fails with
+// a *LimitError once more than max bytes are read, if max is positive.
+type errReader struct {
+	r    io.Reader
+	err  error
+	max  int64
+	read int64
+}
+
+func (r *errReader) Read(p []byte) (int, error) {
+	if r.max > 0 && int64(len(p)) > r.max-r.read+1 {
+		p = p[:r.max-r.read+1]
+	}
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.max > 0 && r.read > r.max {
+		n -= int(r.read - r.max)
+		err = &LimitError{Limit: LimitInputBytes, Max: r.max}
+	}
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
 }
 
 type tokenType int
+
+// Rule is one selector of a rule's selector list, such as "div.btn > a" or
+// "a:hover", with each run of whitespace between its tokens read as a
+// single space. Escapes such as ".hover\:underline" or ".\31 23" are kept
+// verbatim, not decoded. Parse makes every Rule canonical, so that all ways
+// of writing a selector give the same map key: without whitespace at
+// either end and normalized as by NormalizeSelector unless RawSelectors is
+// set. NormalizeRule makes the key of a selector written by hand.
 type Rule string
 
 const (
@@ -31,20 +78,28 @@ const (
 	tokenSelector
 	tokenStyleSeparator
 	tokenStatementEnd
+	tokenAtKeyword
+	tokenPrelude
 )
 
+// Type returns "class", "id" or "tag", the String of the Kind of rule.
+//
+// Deprecated: Use Kind, or Uses for the kinds of all the simple selectors
+// of rule.
 func (rule Rule) Type() string {
-	if strings.HasPrefix(string(rule), ".") {
-		return "class"
-	} else if strings.HasPrefix(string(rule), "#") {
-		return "id"
-	} else {
-		return "tag"
-	}
+	return rule.Kind().String()
 }
 
 func (e tokenEntry) typ() tokenType {
-	return newTokenType(e.value)
+	return e.kind
+}
+
+// text returns the token as written, with the '@' of an at-keyword.
+func (e tokenEntry) text() string {
+	if e.kind == tokenAtKeyword {
+		return "@" + e.value
+	}
+	return e.value
 }
 
 func newTokenType(typ string) tokenType {
@@ -64,12 +119,335 @@ func newTokenType(typ string) tokenType {
 	return tokenValue
 }
 
-func newTokenizer(r io.Reader) *tokenizer {
+func newTokenizer(r io.Reader, filename string, o options) *tokenizer {
+	var src *bytes.Buffer
+	if r = newDecoder(r); o.verboseErrors || hasRawAtRules() {
+		src = &bytes.Buffer{}
+		r = io.TeeReader(r, src)
+	}
+	cf := newCommentFilter(r, filename, o)
+	er := &errReader{r: cf, max: o.maxInputBytes}
 	s := &scanner.Scanner{}
-	s.Init(r)
-	return &tokenizer{
-		s,
+	s.Init(er)
+	s.Filename = filename
+	// Numbers and comments are left to the identifier predicates, so that
+	// ".2" is not scanned as a float and "//" does not start a comment.
+	s.Mode = scanner.ScanIdents
+	t := &tokenizer{
+		s:        s,
+		r:        er,
+		comments: cf,
+		src:      src,
+		mode:     modeSelector,
+		o:        o,
+		start:    o.start,
+	}
+	s.Error = func(s *scanner.Scanner, msg string) {
+		if er.err == nil {
+			o.diagnose(SeverityWarning, DiagInvalidInput, t.position(s.Pos()), "%s", msg)
+		}
+	}
+	s.IsIdentRune = t.identRune
+	return t
+}
+
+// position returns the scanner position p relative to the start of the
+// stylesheet, when the input picks up where earlier input left off.
+func (t *tokenizer) position(p scanner.Position) scanner.Position {
+	if !t.start.IsValid() || !p.IsValid() {
+		return p
+	}
+	if p.Line == 1 {
+		p.Column += t.start.Column - 1
+	}
+	p.Line += t.start.Line - 1
+	p.Offset += t.start.Offset
+	return p
+}
+
+// tokenMode is the state of the tokenizer that decides how the runes of
+// the next token are read. The parser sets it along the grammar, as
+// grammar.advance tells, to one of modeSelector, modeName, modeValue and
+// modePrelude. Within a token, the tokenizer moves to modeString and
+// modeURL for the strings and unquoted url() arguments of selectors and
+// values, and back once they end, and the comment filter reads comments in
+// modeComment before the tokenizer sees them.
+type tokenMode int
+
+const (
+	// modeText is the text the comment filter passes through to the
+	// tokenizer, outside of comments, strings and url() arguments.
+	modeText tokenMode = iota
+	// modeSelector reads selectors, where a ':' starts a pseudo-class and
+	// '#', '.' and whitespace end an identifier.
+	modeSelector
+	// modeName reads declaration names, and the selectors of rules nested
+	// in declaration blocks, where a ':' is a token of its own.
+	modeName
+	// modeValue reads a declaration value as a single token, up to the ';',
+	// '{' or '}' that ends it outside of strings, escapes and unquoted url()
+	// arguments.
+	modeValue
+	// modePrelude reads an at-rule prelude raw, up to the ';', '{' or '}'
+	// that ends it outside of strings and parentheses.
+	modePrelude
+	// modeString reads a quoted string up to its closing quote, or to the
+	// end of its line if left open.
+	modeString
+	// modeURL reads an unquoted url() argument up to its ')', or to the end
+	// of its line if left open.
+	modeURL
+	// modeComment reads a comment up to its "*/".
+	modeComment
+)
+
+// accepts reports whether the rune ch, at index i of a token, is read as
+// part of it in mode m, escapes aside. In modeString, modeURL and
+// modeComment it tells whether ch is part of the string, url() argument
+// or comment; closes tells which rune also ends them there.
+func (m tokenMode) accepts(ch rune, i int) bool {
+	switch m {
+	case modeSelector:
+		return ch == ':' || isSelectorRune(ch, i)
+	case modeName:
+		return isSelectorRune(ch, i)
+	case modeValue, modePrelude:
+		return isValueRune(ch, i)
+	case modeString:
+		return ch != '\n' && ch != scanner.EOF
+	case modeURL, modeComment, modeText:
+		return ch != scanner.EOF
+	}
+	return false
+}
+
+// closes reports whether ch, read in mode m, is the last rune of the
+// string quoted by quote, the url() argument or the comment m reads,
+// after which the mode of the token holding it applies again. A comment
+// closes at the '/' of its "*/", which prev is the rune before. A string
+// or url() left open at the end of its line closes before the newline,
+// which accepts leaves out.
+func (m tokenMode) closes(ch, quote, prev rune) bool {
+	switch m {
+	case modeString:
+		return ch == quote
+	case modeURL:
+		return ch == ')'
+	case modeComment:
+		return prev == '*' && ch == '/'
+	}
+	return false
+}
+
+// identRune is the IsIdentRune of the scanner, set once so that scanning
+// allocates no predicate per token. It reads the runes of the tokenizer's
+// mode.
+func (t *tokenizer) identRune(ch rune, i int) bool {
+	if t.mode == modeValue {
+		return t.valueRune(ch, i)
+	}
+	return t.selectorRune(ch, i)
+}
+
+// valueRune is the IsIdentRune of values. A ';', '{' or '}' in a string,
+// after a backslash or in an unquoted url() argument, as in
+// content: "a;b" or url(data:image/png;base64,...), is part of the value,
+// so that it does not end the declaration early and leave the rest of the
+// block to be read out of step. A string ends at its closing quote or at
+// the end of its line, and an unquoted url() argument at its ')' or at the
+// end of its line, as the comment filter reads them, so that one left open
+// takes no more than its line with it.
+func (t *tokenizer) valueRune(ch rune, i int) bool {
+	if i == 0 {
+		t.escape, t.inner, t.quote, t.last = false, modeValue, 0, [3]rune{}
+	}
+	if ch == scanner.EOF {
+		return false
+	}
+	switch {
+	case t.escape:
+		t.escape = false
+		return true
+	case ch == '\\':
+		t.escape = true
+		return true
+	case t.inner == modeString:
+		if modeString.closes(ch, t.quote, 0) || !modeString.accepts(ch, i) {
+			t.inner = modeValue
+		}
+		return true
+	case t.inner == modeURL:
+		switch {
+		case t.urlArg && (ch == '"' || ch == '\''):
+			t.inner, t.quote = modeString, ch
+		case modeURL.closes(ch, 0, 0) || ch == '\n' && !t.urlArg:
+			t.inner = modeValue
+		case ch != ' ' && ch != '\t' && ch != '\n':
+			t.urlArg = false
+		}
+		return true
+	case ch == '"' || ch == '\'':
+		t.inner, t.quote = modeString, ch
+	case ch == '(' && t.last == [3]rune{'u', 'r', 'l'}:
+		t.inner, t.urlArg = modeURL, true
+	}
+	low := ch
+	if ch >= 'A' && ch <= 'Z' {
+		low += 'a' - 'A'
+	}
+	t.last[0], t.last[1], t.last[2] = t.last[1], t.last[2], low
+	return modeValue.accepts(ch, i)
+}
+
+// selectorRune is the IsIdentRune of selectors and declaration names.
+// Escapes are kept verbatim in the identifier: a backslash with the
+// character after it, or with up to six hex digits and one optional
+// whitespace. So are the strings of selectors, such as that of
+// [title="a  b"], up to the end of their line.
+func (t *tokenizer) selectorRune(ch rune, i int) bool {
+	if i == 0 {
+		t.escape, t.hex, t.inner, t.quote = false, 0, t.mode, 0
+	}
+	switch {
+	case t.escape:
+		t.escape = false
+		if isHexDigit(ch) {
+			t.hex = 1
+			return true
+		}
+		return ch != scanner.EOF && ch != '\n'
+	case t.hex > 0:
+		if isHexDigit(ch) && t.hex < 6 {
+			t.hex++
+			return true
+		}
+		t.hex = 0
+		if ch == ' ' || ch == '\t' || ch == '\n' {
+			return true
+		}
+	case ch == '\\':
+		t.escape = true
+		return true
+	}
+	switch {
+	case t.inner == modeString:
+		ok := modeString.accepts(ch, i)
+		if !ok || modeString.closes(ch, t.quote, 0) {
+			t.inner = t.mode
+		}
+		return ok
+	case (ch == '"' || ch == '\'') && t.mode == modeSelector:
+		t.inner, t.quote = modeString, ch
+		return true
+	}
+	return t.mode.accepts(ch, i)
+}
+
+func isSelectorRune(ch rune, i int) bool {
+	if ch == -1 || ch == '#' || ch == '.' || ch == '\n' || ch == '\t' || ch == ' ' || ch == ':' || ch == ';' ||
+		ch == '{' || ch == '}' || (ch == '@' && i == 0) {
+		return false
+	}
+	return true
+}
+
+func isValueRune(ch rune, i int) bool {
+	if ch == -1 || ch == ';' || ch == '{' || ch == '}' {
+		return false
 	}
+	return true
+}
+
+func isHexDigit(ch rune) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+// isIdentStart reports whether s starts like a CSS identifier: with "--",
+// or with an optional '-' followed by a letter, '_', a non-ASCII rune or an
+// escape.
+func isIdentStart(s string) bool {
+	if strings.HasPrefix(s, "--") {
+		return true
+	}
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return false
+	}
+	c := s[0]
+	return c == '_' || c == '\\' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// isNameRune reports whether ch can appear in a CSS name. Every rune from
+// U+0080 up is a name rune, and so is every byte of its UTF-8 encoding.
+func isNameRune(ch rune) bool {
+	return ch == '-' || ch == '_' || ch >= 0x80 ||
+		(ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+}
+
+// normalizeSpace replaces the runs of whitespace in value outside strings
+// and escapes with a single space, or with nothing after '(' and before
+// ')'. A comma gets no space before it and one after it, unless ')'
+// follows, so that "rgb(0,0,0)" and "rgb(0 , 0 , 0)" are "rgb(0, 0, 0)".
+// Unquoted url() arguments are kept as they are.
+func normalizeSpace(value string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(value); {
+		c := value[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' {
+			space = true
+			i++
+			continue
+		}
+		if space && b.Len() > 0 && c != ')' && c != ',' && !strings.HasSuffix(b.String(), "(") {
+			b.WriteByte(' ')
+		}
+		space = c == ','
+		if space {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		end := i + 1
+		switch c {
+		case '"', '\'':
+			end = skipString(value, i)
+		case '\\':
+			end = skipEscape(value, i)
+		case 'u', 'U':
+			if j := unquotedURLEnd(value, i); j > 0 {
+				end = j
+			}
+		}
+		b.WriteString(value[i:end])
+		i = end
+	}
+	return b.String()
+}
+
+// unquotedURLEnd returns the index just past the url() at i of value if its
+// argument is unquoted, such as url(data:image/png;base64,iVBO=), whose
+// spaces and commas are kept as they are, or 0.
+func unquotedURLEnd(value string, i int) int {
+	if len(value) < i+4 || !strings.EqualFold(value[i:i+4], "url(") || i > 0 && isNameRune(rune(value[i-1])) {
+		return 0
+	}
+	j := i + 4
+	for j < len(value) && isSelectorSpace(value[j]) {
+		j++
+	}
+	if j < len(value) && (value[j] == '"' || value[j] == '\'') {
+		return 0
+	}
+	for ; j < len(value); j++ {
+		switch value[j] {
+		case '\\':
+			j++
+		case ')':
+			return j + 1
+		}
+	}
+	return len(value)
 }
 
 func (t tokenType) String() string {
@@ -86,124 +464,1741 @@ func (t tokenType) String() string {
 		return "STYLE_SEPARATOR"
 	case tokenStatementEnd:
 		return "STATEMENT_END"
+	case tokenAtKeyword:
+		return "AT_KEYWORD"
+	case tokenPrelude:
+		return "PRELUDE"
 	}
 	return "VALUE"
 }
 
+// next returns the next token read in the mode set by the parser, or
+// io.EOF at the end of the input.
 func (t *tokenizer) next() (tokenEntry, error) {
+	if t.mode == modePrelude {
+		return t.prelude(), nil
+	}
 	token := t.s.Scan()
 	if token == scanner.EOF {
-		return tokenEntry{}, errors.New("EOF")
+		if err, ok := t.r.err.(*ParseError); ok {
+			return tokenEntry{}, err
+		}
+		if t.r.err != nil {
+			return tokenEntry{}, &ParseError{Pos: t.position(t.s.Pos()), Msg: t.r.err.Error(), Err: t.r.err}
+		}
+		return tokenEntry{}, io.EOF
 	}
 	value := t.s.TokenText()
-	pos := t.s.Pos()
-	if newTokenType(value).String() == "STYLE_SEPARATOR" {
-		t.s.IsIdentRune = func(ch rune, i int) bool {
-			if ch == -1 || ch == '\n' || ch == '\t' || ch == ':' || ch == ';' {
+	pos := t.position(t.s.Position)
+	kind := newTokenType(value)
+	switch {
+	case t.mode == modeValue && (kind == tokenSelector || value == "@"):
+		// A lone #, . or @ after the ':' is a value, not a selector or at-rule.
+		kind = tokenValue
+	case value == "@":
+		value, kind = t.atKeyword(), tokenAtKeyword
+	}
+	return tokenEntry{
+		value,
+		pos,
+		kind,
+	}, nil
+}
+
+// setMode sets the mode the next token is read in.
+func (t *tokenizer) setMode(m tokenMode) {
+	t.mode = m
+}
+
+// grammar is the state of the parser that decides the mode of the
+// tokenizer: whether each open block holds declarations, and the last
+// at-keyword and token read.
+type grammar struct {
+	blocks []bool
+	atRule string
+	prev   tokenType
+}
+
+// advance moves g past token and sets the mode t reads the next token in:
+// an at-keyword starts a prelude, the ':' of a declaration starts its
+// value, and any other token leaves t reading selectors, or declaration
+// names inside declaration blocks.
+func (g *grammar) advance(t *tokenizer, token tokenEntry) {
+	switch token.kind {
+	case tokenAtKeyword:
+		g.atRule = token.value
+	case tokenBlockStart:
+		h, _ := lookupAtRule(t.o.atKeyword(g.atRule))
+		g.blocks = append(g.blocks, g.prev != tokenPrelude || h.Block == BlockDeclarations || !g.inRules())
+	case tokenBlockEnd:
+		if len(g.blocks) > 0 {
+			g.blocks = g.blocks[:len(g.blocks)-1]
+		}
+	}
+	g.prev = token.kind
+	switch {
+	case token.kind == tokenAtKeyword:
+		t.setMode(modePrelude)
+	case token.kind == tokenStyleSeparator:
+		t.setMode(modeValue)
+	case g.inRules():
+		t.setMode(modeSelector)
+	default:
+		t.setMode(modeName)
+	}
+}
+
+// inRules reports whether the parser is outside of declaration blocks,
+// where a ':' is part of a selector rather than a property separator.
+func (g *grammar) inRules() bool {
+	return len(g.blocks) == 0 || !g.blocks[len(g.blocks)-1]
+}
+
+// atKeyword reads the name following an '@'.
+func (t *tokenizer) atKeyword() string {
+	var b strings.Builder
+	for isNameRune(t.s.Peek()) {
+		b.WriteRune(t.s.Next())
+	}
+	return b.String()
+}
+
+// prelude reads the raw text of an at-rule up to the ';' or '{' that ends
+// it, skipping comments and keeping quoted strings and parentheses intact.
+func (t *tokenizer) prelude() tokenEntry {
+	for ch := t.s.Peek(); ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'; ch = t.s.Peek() {
+		t.s.Next()
+	}
+	var (
+		b     strings.Builder
+		pos   = t.position(t.s.Pos())
+		depth int
+		quote rune
+	)
+	for {
+		ch := t.s.Peek()
+		if ch == scanner.EOF {
+			break
+		}
+		if quote == 0 && depth == 0 && !modePrelude.accepts(ch, 0) {
+			break
+		}
+		t.s.Next()
+		switch {
+		case quote != 0:
+			if ch == '\\' && t.s.Peek() != scanner.EOF {
+				b.WriteRune(ch)
+				ch = t.s.Next()
+			} else if modeString.closes(ch, quote, 0) {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')' && depth > 0:
+			depth--
+		case ch == '/' && t.s.Peek() == '*':
+			t.s.Next()
+			for prev := rune(0); ; {
+				c := t.s.Next()
+				if !modeComment.accepts(c, 0) || modeComment.closes(c, 0, prev) {
+					break
+				}
+				prev = c
+			}
+			continue
+		}
+		b.WriteRune(ch)
+	}
+	return tokenEntry{strings.TrimSpace(b.String()), pos, tokenPrelude}
+}
+
+// parseState is the part of the grammar the parser is in, which decides
+// what the next token may be.
+type parseState int
+
+const (
+	// stateSelector is outside of declaration blocks, reading the selector
+	// list of a rule up to the '{' of its block, or the '}' closing an
+	// at-rule around it.
+	stateSelector parseState = iota
+	// stateName is in a declaration block, reading a declaration name up to
+	// its ':', or the selector list of a rule nested in the block.
+	stateName
+	// stateValue is in a declaration block past the ':' of a declaration,
+	// reading its value up to the ';' or '}' ending it.
+	stateValue
+	// statePrelude is past an at-keyword, reading the prelude of its
+	// at-rule up to the '{' or ';' ending it.
+	statePrelude
+)
+
+// step tells the parser what to do once a token is handled.
+type step int
+
+const (
+	stepNext step = iota // finish the token and read the next one
+	stepSkip             // read the next one, leaving prevToken as it is
+	stepStop             // stop parsing
+)
+
+// parser holds the state of parse: the rule, declaration or at-rule read
+// so far, the blocks open around it and the errors found. Each token is
+// handled by the method of the state the parser is in, as given by state.
+type parser struct {
+	ts    *tokenStream
+	o     options
+	sheet *StyleSheet
+	errs  ErrorList
+	limit error // a limit parsing stopped at, returned instead of errs
+
+	rule      []string
+	rulePos   scanner.Position
+	style     string
+	stylePos  scanner.Position
+	value     string
+	valuePos  scanner.Position
+	valueEnd  scanner.Position
+	selector  string
+	selPos    scanner.Position
+	selText   strings.Builder // the selector list read so far
+	selEnd    int             // offset just past its last token
+	selDepth  int
+	selQuote  byte
+	commas    []selectorComma
+	atRule    *AtRule
+	skipAt    bool // whether atRule is misplaced and skipped
+	declBlock *AtRule
+	open      []*AtRule
+	openPos   []scanner.Position
+	blockPos  scanner.Position
+	isBlock   bool
+	decls     []Declaration
+	nested    []Node    // the nested rules of the rule block
+	trailing  *RuleNode // the "&" rule holding declarations after them
+	nestAt    *AtRule   // the at-rule of the block, if nested in a rule
+	nest      []*nestFrame
+	item      []tokenEntry    // the tokens of the rule block item read so far
+	itemBad   error           // the first error in item, unless it is a rule
+	itemTok   tokenEntry      // the token of itemBad
+	comments  []string        // of the rule being read
+	next      int             // index of the next comment to attach
+	trailLine int             // line of the ';' after lastDecls, if on it
+	lastDecls *[]Declaration  // the list holding the last declaration
+	rawValue  bool            // whether value is read on past a ';' in brackets
+	rawText   strings.Builder // value as read on, while rawValue
+	rawDepth  int             // brackets of rawText left open
+	seen      map[interface{}]map[Rule]scanner.Position
+	prevToken tokenType
+}
+
+// parse parses the tokens of ts into a stylesheet.
+func parse(ts *tokenStream, o options) (*StyleSheet, error) {
+	p := newParser(ts, o)
+	for {
+		token, ok := ts.next()
+		if !ok {
+			break
+		}
+		if p.read(token) {
+			return p.sheet, p.stop()
+		}
+	}
+	return p.sheet, p.end()
+}
+
+// newParser returns a parser reading the tokens of ts.
+func newParser(ts *tokenStream, o options) *parser {
+	return &parser{
+		ts:        ts,
+		o:         o,
+		sheet:     &StyleSheet{},
+		seen:      map[interface{}]map[Rule]scanner.Position{},
+		prevToken: tokenFirstToken,
+	}
+}
+
+// stop returns the error parsing stops with.
+func (p *parser) stop() error {
+	if p.limit != nil {
+		return p.limit
+	}
+	return p.errs.err(p.o)
+}
+
+// state returns the state the parser reads the next token in.
+func (p *parser) state() parseState {
+	switch {
+	case p.atRule != nil && (p.prevToken == tokenAtKeyword || p.prevToken == tokenPrelude):
+		return statePrelude
+	case !p.isBlock:
+		return stateSelector
+	case p.prevToken == tokenStyleSeparator || p.prevToken == tokenValue && p.value != "":
+		return stateValue
+	}
+	return stateName
+}
+
+// inRule reports whether the parser is in the declaration block of a rule,
+// where rules may nest.
+func (p *parser) inRule() bool {
+	return p.isBlock && p.declBlock == nil
+}
+
+// read handles token and reports whether parsing must stop.
+func (p *parser) read(token tokenEntry) bool {
+	p.trail(token.pos.Offset)
+	if p.rawValue {
+		// Lenient mode reads a value with a bracket open at its ';' on, as
+		// written, up to the ';' or '}' once the brackets close.
+		switch typ := token.typ(); {
+		case typ == tokenStatementEnd && p.rawDepth > 0, typ != tokenStatementEnd && typ != tokenBlockEnd && typ != tokenBlockStart:
+			if token.pos.Offset > p.valueEnd.Offset {
+				p.rawText.WriteByte(' ')
+			}
+			text := strings.TrimRight(token.value, " \t\r\n\f")
+			p.rawText.WriteString(text)
+			p.rawDepth = bracketDepth(p.rawDepth, text)
+			p.valueEnd = advance(token.pos, text)
+			return false
+		}
+		p.value = p.rawText.String()
+	}
+	inRule := p.inRule()
+	inItem := false
+	switch token.typ() {
+	case tokenValue, tokenSelector, tokenStyleSeparator:
+		if inRule {
+			p.item, inItem = append(p.item, token), true
+		}
+	case tokenStatementEnd, tokenBlockEnd:
+		if inRule && p.itemBad != nil {
+			if p.reportItem() {
+				return true
+			}
+			if p.item, p.prevToken = p.item[:0], tokenStatementEnd; token.typ() == tokenStatementEnd {
 				return false
 			}
+		}
+	}
+	var next step
+	var bad error
+	switch p.state() {
+	case stateSelector:
+		next, bad = p.selectorToken(token)
+	case stateName:
+		next, bad = p.nameToken(token)
+	case stateValue:
+		next, bad = p.valueToken(token)
+	case statePrelude:
+		next, bad = p.preludeToken(token)
+	}
+	switch next {
+	case stepSkip:
+		return false
+	case stepStop:
+		return true
+	}
+
+	switch token.typ() {
+	case tokenStatementEnd, tokenBlockStart, tokenBlockEnd:
+		p.item = p.item[:0]
+	}
+	if token.typ() == tokenBlockEnd {
+		// Comments left at the end of a block have no node to go with.
+		p.takeComments(token.pos.Offset)
+	}
+	if bad != nil && inItem {
+		// The item may yet turn out to be a nested rule.
+		if p.itemBad == nil {
+			p.itemBad, p.itemTok = bad, token
+		}
+		p.prevToken = token.typ()
+		return false
+	}
+	if bad != nil {
+		if inRule && p.itemBad != nil {
+			// Report the error that started the item first.
+			bad, token, p.itemBad = p.itemBad, p.itemTok, nil
+		}
+		if p.o.lenient {
+			what, code := "the rule", DiagSkippedRule
+			if p.isBlock {
+				what, code = "the declaration", DiagSkippedDeclaration
+			}
+			p.o.diagnose(SeverityWarning, code, token.pos, "unexpected token %s, skipped %s", token.text(), what)
+		} else if p.fail(bad) {
 			return true
 		}
+		if p.isBlock {
+			p.style, p.value = "", ""
+			p.prevToken = resync(p.ts, token, true)
+		} else {
+			if token.typ() == tokenBlockEnd && len(p.open) > 0 {
+				p.open[len(p.open)-1].Close = token.pos
+				p.open, p.openPos = p.open[:len(p.open)-1], p.openPos[:len(p.openPos)-1]
+			}
+			p.rule, p.atRule = p.rule[:0], nil
+			p.resetSelector()
+			p.prevToken = resync(p.ts, token, false)
+		}
+		return false
+	}
+	if n := len(p.sheet.Rules); n > p.ts.parsed && len(p.open) == 0 && !p.isBlock && p.atRule == nil {
+		end := token.pos
+		if at, ok := p.sheet.Rules[n-1].(*AtRule); ok && at.Block != "" {
+			end = at.Close
+		}
+		end.Offset++
+		end.Column++
+		p.ts.resume, p.ts.parsed = end, n
+	}
+	p.prevToken = token.typ()
+	return false
+}
+
+// selectorToken handles token in stateSelector. The errors it returns are
+// those of token, to be recovered from by skipping the rule.
+func (p *parser) selectorToken(token tokenEntry) (step, error) {
+	switch token.typ() {
+	case tokenValue:
+		if (token.value == "<!--" || token.value == "-->") && p.prevToken != tokenStyleSeparator {
+			// CDO and CDC are ignored between top-level rules only.
+			if len(p.open) == 0 && (p.prevToken == tokenFirstToken || p.prevToken == tokenBlockEnd) {
+				return stepSkip, nil
+			}
+			return stepNext, p.unexpected(token)
+		}
+		switch p.prevToken {
+		case tokenFirstToken, tokenBlockStart, tokenStatementEnd, tokenBlockEnd, tokenValue:
+			p.addSelector(p.o.typeSelector(token.value), token.pos)
+		case tokenSelector:
+			// A '.' in a keyframe selector is a decimal point, as in 12.5%.
+			if !isIdentStart(token.value) && !inKeyframes(p.open) {
+				return stepNext, errorAt(p.selPos, "invalid selector %s%s", p.selector, token.value)
+			}
+			p.addSelector(token.value, token.pos)
+		case tokenStyleSeparator:
+			// After an error the tokenizer may still be reading declarations.
+			p.setValue(token)
+		default:
+			return stepNext, p.unexpected(token)
+		}
+	case tokenSelector:
+		p.selector, p.selPos = token.value, token.pos
+		p.addSelector(token.value, token.pos)
+	case tokenStyleSeparator:
+	case tokenAtKeyword:
+		if p.selText.Len() > 0 || p.atRule != nil {
+			return stepNext, p.unexpected(token)
+		}
+		p.atRule = &AtRule{Name: p.o.atKeyword(token.value), Pos: token.pos, Comments: p.takeComments(token.pos.Offset)}
+		_, registered := lookupAtRule(p.atRule.Name)
+		if base, _ := Canonical(asciiLower(p.atRule.Name)); p.o.strict && !knownAtRules[base] && !registered {
+			if p.fail(errorAt(token.pos, "unknown at-rule @%s", p.atRule.Name)) {
+				return stepStop, nil
+			}
+		}
+		p.skipAt = false
+		if where := misplacedAtRule(asciiLower(p.atRule.Name), p.sheet.Rules, len(p.open) > 0); where != "" {
+			var stop bool
+			if p.skipAt, stop = p.misplaced(token.pos, DiagMisplacedAtRule, "@%s %s", p.atRule.Name, where); stop {
+				return stepStop, nil
+			}
+		}
+	case tokenBlockStart:
+		if p.prevToken != tokenValue {
+			return stepNext, p.unexpected(token)
+		}
+		if err := p.selectors(); err != nil {
+			return stepNext, err
+		}
+		if len(p.rule) == 0 {
+			return stepNext, p.unexpected(token)
+		}
+		for _, r := range p.rule {
+			if p.o.maxSelector > 0 && len(r) > p.o.maxSelector {
+				p.limit = limitExceeded(p.rulePos, LimitSelectorLength, int64(p.o.maxSelector))
+				return stepStop, nil
+			}
+		}
+		if sel := invalidKeyframe(p.open, p.rule); sel != "" {
+			skip, stop := p.misplaced(p.rulePos, DiagInvalidKeyframe, "invalid keyframe selector %s in @%s", sel, p.open[len(p.open)-1].Name)
+			if stop {
+				return stepStop, nil
+			}
+			if skip {
+				p.rule = p.rule[:0]
+				p.prevToken = resync(p.ts, token, false)
+				return stepSkip, nil
+			}
+		}
+		p.isBlock, p.blockPos = true, token.pos
+		p.comments = p.takeComments(token.pos.Offset)
+	case tokenStatementEnd:
+		if stray, stop := p.strayDeclaration(); stop {
+			return stepStop, nil
+		} else if stray {
+			p.prevToken = tokenStatementEnd
+			return stepSkip, nil
+		}
+		if p.selText.Len() == 0 && (p.prevToken == tokenFirstToken || p.prevToken == tokenBlockEnd) {
+			p.o.diagnose(SeverityWarning, DiagStraySemicolon, token.pos, "stray ; between rules")
+			return stepSkip, nil
+		}
+		return stepNext, p.unexpected(token)
+	case tokenBlockEnd:
+		return p.closeAtRule(token)
+	default:
+		return stepNext, p.unexpected(token)
+	}
+	return stepNext, nil
+}
+
+// closeAtRule handles the '}' token closing the at-rule block the parser is
+// in outside of declaration blocks.
+func (p *parser) closeAtRule(token tokenEntry) (step, error) {
+	if stray, stop := p.strayDeclaration(); stop {
+		return stepStop, nil
+	} else if stray && len(p.open) == 0 {
+		return stepNext, p.unexpected(token)
+	}
+	if len(p.open) == 0 || p.selText.Len() > 0 {
+		return stepNext, p.unexpected(token)
+	}
+	if p.o.strict && p.strict() {
+		return stepStop, nil
+	}
+	at := p.open[len(p.open)-1]
+	at.Close = token.pos
+	p.open, p.openPos = p.open[:len(p.open)-1], p.openPos[:len(p.openPos)-1]
+	if p.handle(at, nil) {
+		return stepStop, nil
+	}
+	return stepNext, nil
+}
+
+// nameToken handles token in stateName. The errors it returns are those of
+// token, to be recovered from by skipping the declaration.
+func (p *parser) nameToken(token tokenEntry) (step, error) {
+	switch token.typ() {
+	case tokenValue:
+		switch p.prevToken {
+		case tokenBlockStart, tokenStatementEnd, tokenBlockEnd:
+			if token.value != "<!--" && token.value != "-->" {
+				p.style, p.stylePos = p.o.property(token.value), token.pos
+				return stepNext, nil
+			}
+		}
+		return stepNext, p.unexpected(token)
+	case tokenStyleSeparator:
+		if p.prevToken != tokenValue || p.style == "" {
+			return stepNext, p.unexpected(token)
+		}
+	case tokenAtKeyword:
+		if p.inRule() && p.style == "" && len(p.item) == 0 && p.atRule == nil && nestedAtRules[asciiLower(p.o.atKeyword(token.value))] {
+			// A conditional group rule nested in a rule, holding
+			// declarations and rules for the rule's selectors.
+			p.atRule = &AtRule{Name: p.o.atKeyword(token.value), Pos: token.pos, Comments: p.takeComments(token.pos.Offset)}
+			p.skipAt = false
+			return stepNext, nil
+		}
+		return stepNext, p.unexpected(token)
+	case tokenBlockStart:
+		return p.openNested(token)
+	case tokenStatementEnd:
+		if p.style == "" && (p.prevToken == tokenBlockStart || p.prevToken == tokenStatementEnd || p.prevToken == tokenBlockEnd) {
+			p.o.diagnose(SeverityWarning, DiagStraySemicolon, token.pos, "stray ; in block")
+			return stepSkip, nil
+		}
+		return stepNext, p.unexpected(token)
+	case tokenBlockEnd:
+		return p.closeDeclarations(token)
+	default:
+		return stepNext, p.unexpected(token)
+	}
+	return stepNext, nil
+}
+
+// valueToken handles token in stateValue. The errors it returns are those
+// of token, to be recovered from by skipping the declaration.
+func (p *parser) valueToken(token tokenEntry) (step, error) {
+	switch token.typ() {
+	case tokenValue:
+		if p.prevToken != tokenStyleSeparator {
+			return stepNext, p.unexpected(token)
+		}
+		p.setValue(token)
+	case tokenBlockStart:
+		return p.openNested(token)
+	case tokenStatementEnd:
+		if p.prevToken != tokenValue || p.style == "" {
+			return stepNext, p.unexpected(token)
+		}
+		if p.o.lenient && !p.rawValue && openBrackets(p.value) > 0 {
+			p.value += ";"
+			p.valueEnd = advance(token.pos, ";")
+			p.rawValue, p.rawDepth = true, openBrackets(p.value)
+			p.rawText.Reset()
+			p.rawText.WriteString(p.value)
+			return stepSkip, nil
+		}
+		if p.addDecl(token.pos.Offset) {
+			return stepStop, nil
+		}
+		p.trailLine = token.pos.Line
+		p.style, p.value = "", ""
+	case tokenBlockEnd:
+		return p.closeDeclarations(token)
+	default:
+		return stepNext, p.unexpected(token)
+	}
+	return stepNext, nil
+}
+
+// setValue sets the value of the declaration read so far to that of
+// token.
+func (p *parser) setValue(token tokenEntry) {
+	p.value = strings.TrimSpace(token.value)
+	p.valuePos = token.pos
+	p.valueEnd = advance(token.pos, strings.TrimRight(token.value, " \t\r\n\f"))
+}
+
+// openNested handles a '{' token in a declaration block, opening the block
+// of the rule whose selector list the rule block item read so far is.
+func (p *parser) openNested(token tokenEntry) (step, error) {
+	if !p.inRule() || len(p.item) == 0 {
+		return stepNext, p.unexpected(token)
+	}
+	f := &nestFrame{p.rule, p.rulePos, p.blockPos, p.decls, p.nested, p.trailing, p.comments, p.nestAt}
+	p.rule = nil
+	bad := p.nestedSelectors()
+	if bad == nil && len(p.rule) == 0 {
+		bad = p.unexpected(token)
+	}
+	if bad != nil {
+		p.rule, p.rulePos = f.rule, f.rulePos
+		return stepNext, bad
+	}
+	p.nest = append(p.nest, f)
+	p.decls, p.nested, p.trailing, p.itemBad, p.nestAt = nil, nil, nil, nil, nil
+	p.style, p.value = "", ""
+	p.blockPos = token.pos
+	p.comments = p.takeComments(token.pos.Offset)
+	return stepNext, nil
+}
+
+// closeDeclarations handles the '}' token closing the declaration block the
+// parser is in, adding the declaration read so far.
+func (p *parser) closeDeclarations(token tokenEntry) (step, error) {
+	if p.style != "" && p.value == "" {
+		// A name without a value is an error at '}' as at ';', but the
+		// '}' still closes the block.
+		if p.o.lenient {
+			p.o.diagnose(SeverityWarning, DiagSkippedDeclaration, token.pos, "unexpected token }, skipped the declaration")
+		} else if p.fail(p.unexpected(token)) {
+			return stepStop, nil
+		}
+		p.style = ""
+	}
+	if p.o.strict && p.strict() {
+		return stepStop, nil
+	}
+	if p.style != "" && p.value != "" && p.addDecl(token.pos.Offset) {
+		return stepStop, nil
+	}
+	at := p.declBlock
+	p.closeBlock(token.pos)
+	if at != nil && p.handle(at, nil) {
+		return stepStop, nil
+	}
+	return stepNext, nil
+}
+
+// preludeToken handles token in statePrelude: the prelude itself, then the
+// '{' opening the block of the at-rule or the ';' ending it.
+func (p *parser) preludeToken(token tokenEntry) (step, error) {
+	switch {
+	case token.typ() == tokenPrelude && p.prevToken == tokenAtKeyword:
+		p.atRule.Prelude = token.value
+		if token.value != "" {
+			p.atRule.PreludePos = token.pos
+		}
+	case p.prevToken != tokenPrelude:
+		return stepNext, p.unexpected(token)
+	case token.typ() == tokenBlockStart && p.inRule():
+		f := &nestFrame{p.rule, p.rulePos, p.blockPos, p.decls, p.nested, p.trailing, p.comments, p.nestAt}
+		p.nest = append(p.nest, f)
+		p.atRule.Open = token.pos
+		p.nestAt, p.rulePos, p.blockPos, p.atRule = p.atRule, p.atRule.Pos, token.pos, nil
+		p.decls, p.nested, p.trailing, p.itemBad = nil, nil, nil, nil
+		p.style, p.value = "", ""
+		p.comments = p.takeComments(token.pos.Offset)
+	case token.typ() == tokenBlockStart:
+		return p.openAtRule(token)
+	case token.typ() == tokenStatementEnd && p.inRule():
+		// Only the blocks of at-rules nest in rules.
+		p.atRule = nil
+		return stepNext, p.unexpected(token)
+	case token.typ() == tokenStatementEnd:
+		p.atRule.Comments = append(p.atRule.Comments, p.takeComments(token.pos.Offset)...)
+		if !p.skipAt {
+			p.appendNode(p.atRule)
+			if p.handle(p.atRule, nil) {
+				return stepStop, nil
+			}
+		}
+		p.atRule = nil
+	case token.typ() == tokenBlockEnd && p.isBlock:
+		// The '}' closes the block around the at-rule, which is left
+		// unfinished.
+		return p.closeDeclarations(token)
+	case token.typ() == tokenBlockEnd:
+		return p.closeAtRule(token)
+	default:
+		return stepNext, p.unexpected(token)
+	}
+	return stepNext, nil
+}
+
+// openAtRule handles the '{' token opening the block of an at-rule outside
+// of rules, which holds declarations, rules, or raw text read here.
+func (p *parser) openAtRule(token tokenEntry) (step, error) {
+	at := p.atRule
+	at.Comments = append(at.Comments, p.takeComments(token.pos.Offset)...)
+	p.appendNode(at)
+	at.Open = token.pos
+	switch h, _ := lookupAtRule(at.Name); h.Block {
+	case BlockDeclarations:
+		at.Declarations = []Declaration{}
+		p.declBlock, p.isBlock, p.blockPos = at, true, token.pos
+	case BlockRaw:
+		var raw []byte
+		at.Close, raw = rawBlock(p.ts, token.pos, p.o.start)
+		at.Block = "{" + string(raw) + "}"
+		if !at.Close.IsValid() {
+			p.unclosed(token.pos, "missing } at end of input for the @%s block opened at line %d", at.Name, token.pos.Line)
+		}
+		if p.handle(at, raw) {
+			return stepStop, nil
+		}
+		p.atRule, p.prevToken = nil, tokenBlockEnd
+		return stepSkip, nil
+	default:
+		at.Rules = []Node{}
+		p.open, p.openPos = append(p.open, at), append(p.openPos, token.pos)
+		p.atRule, p.prevToken = nil, tokenFirstToken
+		return stepSkip, nil
+	}
+	p.atRule = nil
+	return stepNext, nil
+}
+
+// end finishes the stylesheet at the end of input, closing the blocks left
+// open, and returns the error parsing ends with.
+func (p *parser) end() error {
+	if p.rawValue {
+		p.value = p.rawText.String()
+	}
+	if p.itemBad != nil && p.reportItem() {
+		return p.stop()
+	}
+	if p.atRule != nil {
+		if p.o.lenient {
+			p.o.diagnose(SeverityWarning, DiagSkippedRule, p.atRule.Pos, "unexpected end of input after @%s, skipped the rule", p.atRule.Name)
+			return nil
+		}
+		p.errs = append(p.errs, errorAt(p.atRule.Pos, "unexpected end of input after @%s", p.atRule.Name).(*ParseError))
+		return p.stop()
+	}
+	// A selector with no block after it is dropped.
+	if stray, stop := p.strayDeclaration(); stop {
+		return p.stop()
+	} else if !stray && !p.isBlock && p.selText.Len() > 0 {
+		sel := strings.TrimSpace(p.selText.String())
+		if p.o.lenient {
+			p.o.diagnose(SeverityWarning, DiagSkippedRule, p.rulePos, "unexpected end of input after selector %s, skipped the rule", sel)
+		} else if p.fail(errorAt(p.rulePos, "unexpected end of input after selector %s", sel)) {
+			return p.stop()
+		}
+	}
+	for p.isBlock {
+		if p.style != "" && p.value != "" && p.addDecl(p.ts.t.comments.pos.Offset) {
+			return p.stop()
+		}
+		if at := p.declBlock; at != nil || p.nestAt != nil {
+			if at == nil {
+				at = p.nestAt
+			}
+			p.unclosed(p.blockPos, "missing } at end of input for the @%s block opened at line %d", at.Name, p.blockPos.Line)
+		} else {
+			p.unclosed(p.blockPos, "missing } at end of input for the %s block opened at line %d", strings.Join(p.rule, ", "), p.blockPos.Line)
+		}
+		p.closeBlock(scanner.Position{})
+	}
+	for i := len(p.open) - 1; i >= 0; i-- {
+		p.unclosed(p.openPos[i], "missing } at end of input for the @%s block opened at line %d", p.open[i].Name, p.openPos[i].Line)
+	}
+	return p.stop()
+}
+
+// appendNode appends n to the rules of the innermost open at-rule, or of
+// the stylesheet.
+func (p *parser) appendNode(n Node) {
+	if len(p.open) > 0 {
+		top := p.open[len(p.open)-1]
+		top.Rules = append(top.Rules, n)
+		return
+	}
+	p.sheet.Rules = append(p.sheet.Rules, n)
+}
+
+// fail records err and reports whether parsing must stop.
+func (p *parser) fail(err error) bool {
+	p.errs = append(p.errs, err.(*ParseError))
+	return !p.o.collectErrors || (p.o.errorLimit > 0 && len(p.errs) >= p.o.errorLimit)
+}
+
+// strict checks the block about to be closed in Strict mode.
+func (p *parser) strict() bool {
+	var scope *AtRule
+	if len(p.open) > 0 {
+		scope = p.open[len(p.open)-1]
+	}
+	var key interface{} = scope
+	if len(p.nest) > 0 {
+		key = p.nest[len(p.nest)-1].rulePos
+	}
+	switch {
+	case !p.isBlock && len(scope.Rules) == 0:
+		return p.fail(errorAt(scope.Pos, "empty @%s block", scope.Name))
+	case !p.isBlock:
+		return false
+	case p.style != "" && p.value != "":
+		return p.fail(errorAt(p.stylePos, "missing ; after %s: %s", p.style, p.value))
+	case len(p.decls) == 0 && p.declBlock != nil:
+		return p.fail(errorAt(p.declBlock.Pos, "empty @%s block", p.declBlock.Name))
+	case p.nestAt != nil && len(p.decls) == 0 && len(p.nested) == 0:
+		return p.fail(errorAt(p.nestAt.Pos, "empty @%s block", p.nestAt.Name))
+	case p.nestAt != nil:
+		return false
+	case len(p.decls) == 0 && len(p.nested) == 0:
+		return p.fail(errorAt(p.rulePos, "empty rule %s", strings.Join(p.rule, ", ")))
+	case p.declBlock != nil:
+		return false
+	}
+	if p.seen[key] == nil {
+		p.seen[key] = make(map[Rule]scanner.Position)
+	}
+	for _, r := range p.rule {
+		if first, ok := p.seen[key][Rule(r)]; ok {
+			if p.fail(errorAt(p.rulePos, "duplicate selector %s, first used at line %d", r, first.Line)) {
+				return true
+			}
+			continue
+		}
+		p.seen[key][Rule(r)] = p.rulePos
+	}
+	return false
+}
+
+// takeComments returns the comments before offset not attached yet.
+func (p *parser) takeComments(offset int) []string {
+	var texts []string
+	all := p.ts.t.comments.comments
+	for ; p.next < len(all) && all[p.next].pos.Offset < offset; p.next++ {
+		texts = append(texts, all[p.next].text)
+	}
+	return texts
+}
+
+// trail attaches the comments before offset on the line of the ';' that
+// ended the last declaration to it.
+func (p *parser) trail(offset int) {
+	all := p.ts.t.comments.comments
+	for ; p.trailLine > 0 && p.lastDecls != nil && p.next < len(all) && all[p.next].pos.Line == p.trailLine && all[p.next].pos.Offset < offset; p.next++ {
+		d := &(*p.lastDecls)[len(*p.lastDecls)-1]
+		d.TrailingComments = append(d.TrailingComments, all[p.next].text)
+	}
+	p.trailLine = 0
+}
+
+// misplaced reports misplaced content at pos, as an error in Strict
+// mode, with a warning skipping it in Lenient mode, and with a warning
+// otherwise. It reports whether to skip the content and whether
+// parsing must stop.
+func (p *parser) misplaced(pos scanner.Position, code, format string, args ...interface{}) (skip, stop bool) {
+	switch {
+	case p.o.strict:
+		return true, p.fail(errorAt(pos, format, args...))
+	case p.o.lenient:
+		p.o.diagnose(SeverityWarning, code, pos, format+", skipped it", args...)
+		return true, false
+	}
+	p.o.diagnose(SeverityWarning, code, pos, format, args...)
+	return false, false
+}
+
+// unexpected returns the error for token, with what was expected instead.
+func (p *parser) unexpected(token tokenEntry) error {
+	return unexpectedToken(token, expected(p.prevToken, p.isBlock, p.style != "" && p.value == ""))
+}
+
+// addDecl adds the declaration read so far, which ends before the
+// offset end, and reports whether a duplicate ended parsing.
+func (p *parser) addDecl(end int) bool {
+	p.lastDecls = nil
+	raw := false
+	if p.o.lenient {
+		why := ""
+		switch {
+		case p.rawValue || openBrackets(p.value) != 0:
+			why = "unbalanced brackets"
+		case unterminatedString(p.value):
+			why = "unterminated string"
+		case missingSemicolon(p.value) >= 0 && !strings.HasPrefix(p.style, "--"):
+			why = "missing ';'"
+		}
+		if raw = why != ""; raw {
+			p.o.diagnose(SeverityWarning, DiagRawValue, p.valuePos, "%s in the value of %s, kept it as written", why, p.style)
+		}
+		p.rawValue = false
+	} else if i := missingSemicolon(p.value); i >= 0 && !strings.HasPrefix(p.style, "--") {
+		return p.fail(errorAt(advance(p.valuePos, p.value[:i]), "missing ';' after the value of %s", p.style))
+	}
+	v, important := importance(p.value)
+	if !p.o.rawValues && !raw {
+		v = normalizeSpace(v)
+	}
+	d := Declaration{Property: p.style, Value: v, Important: important, Pos: p.stylePos, ValuePos: p.valuePos, ValueEnd: p.valueEnd, Raw: raw}
+	d.Comments = p.takeComments(p.stylePos.Offset)
+	d.TrailingComments = p.takeComments(end)
+	list := &p.decls
+	if len(p.nested) > 0 {
+		// Declarations after nested rules go in an "&" rule after them,
+		// to keep their order in the cascade.
+		if p.trailing == nil {
+			p.trailing = &RuleNode{Selectors: []Rule{"&"}, Pos: p.stylePos}
+			p.nested = append(p.nested, p.trailing)
+		}
+		list = &p.trailing.Declarations
+	}
+	if p.o.duplicates != DuplicateFirstWins && p.o.duplicates != DuplicateError {
+		*list, p.lastDecls = append(*list, d), list
+		return false
+	}
+	var prev Declaration
+	var dup bool
+	if *list, prev, dup = p.o.duplicates.add(*list, d); (*list)[len(*list)-1].Pos == d.Pos {
+		p.lastDecls = list
+	}
+	if !dup || p.o.duplicates != DuplicateError {
+		return false
+	}
+	const format = "duplicate %s, first declared at line %d"
+	if p.o.lenient {
+		p.o.diagnose(SeverityWarning, DiagDuplicateProperty, d.Pos, format, d.Property, prev.Pos.Line)
+		return false
+	}
+	return p.fail(errorAt(d.Pos, format, d.Property, prev.Pos.Line))
+}
+
+// addSelector appends the selector token text read at pos to selText,
+// after a space if whitespace separates it from the previous token, and
+// records the commas that separate the members of the selector list.
+func (p *parser) addSelector(text string, pos scanner.Position) {
+	if p.selText.Len() == 0 {
+		p.rulePos = pos
+	} else if pos.Offset != p.selEnd {
+		p.selText.WriteByte(' ')
+	}
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; {
+		case c == '\\':
+			i++
+		case p.selQuote != 0:
+			if c == p.selQuote {
+				p.selQuote = 0
+			}
+		case c == '"' || c == '\'':
+			p.selQuote = c
+		case c == '(' || c == '[':
+			p.selDepth++
+		case c == ')' || c == ']':
+			p.selDepth--
+		case c == ',' && p.selDepth == 0:
+			at := pos
+			at.Offset += i
+			at.Column += utf8.RuneCountInString(text[:i])
+			p.commas = append(p.commas, selectorComma{p.selText.Len() + i, at})
+		}
+	}
+	p.selText.WriteString(text)
+	p.selEnd = pos.Offset + len(text)
+}
+
+// resetSelector empties the selector list read so far.
+func (p *parser) resetSelector() {
+	p.selText.Reset()
+	p.commas, p.selDepth, p.selQuote = p.commas[:0], 0, 0
+}
+
+// strayDeclaration reports the selector text read so far if it is a
+// declaration outside of any rule, skipping it. It reports whether it
+// did and whether parsing must stop.
+func (p *parser) strayDeclaration() (stray, stop bool) {
+	prop, ok := declarationText(p.selText.String())
+	if p.isBlock || p.atRule != nil || !ok {
+		return false, false
+	}
+	p.resetSelector()
+	p.rule = p.rule[:0]
+	const format = "declaration of %s outside of a rule"
+	if p.o.lenient {
+		p.o.diagnose(SeverityWarning, DiagStrayDeclaration, p.rulePos, format+", skipped it", prop)
+		return true, false
+	}
+	return true, p.fail(errorAt(p.rulePos, format, prop))
+}
+
+// selectors splits the selector list read before a '{' into rule. An
+// empty member is an error, or dropped with a diagnostic in Lenient mode.
+func (p *parser) selectors() error {
+	text, cs := p.selText.String(), p.commas
+	p.resetSelector()
+	start := 0
+	for i := 0; i <= len(cs); i++ {
+		end := len(text)
+		if i < len(cs) {
+			end = cs[i].at
+		}
+		if member := strings.TrimSpace(text[start:end]); member != "" {
+			p.rule = append(p.rule, ruleKey(member, p.o))
+		} else if len(cs) > 0 {
+			c, where := cs[len(cs)-1], "after"
+			if i < len(cs) {
+				c, where = cs[i], "before"
+			}
+			if !p.o.lenient {
+				return errorAt(c.pos, "empty selector %s ,", where)
+			}
+			p.o.diagnose(SeverityWarning, DiagEmptySelector, c.pos, "empty selector %s , dropped", where)
+		}
+		if i < len(cs) {
+			start = cs[i].at + 1
+		}
+	}
+	return nil
+}
+
+// nestedSelectors reads the selector list of a rule nested in a rule
+// block from the tokens of item, which are declaration tokens to the
+// tokenizer.
+func (p *parser) nestedSelectors() error {
+	p.resetSelector()
+	for i, tok := range p.item {
+		text := tok.value
+		switch {
+		case i > 0 && p.item[i-1].kind == tokenSelector:
+			if !isIdentStart(text) {
+				return errorAt(p.item[i-1].pos, "invalid selector %s%s", p.item[i-1].value, text)
+			}
+		case i > 0 && p.item[i-1].kind == tokenStyleSeparator && tok.kind == tokenValue:
+			text = strings.TrimRight(text, " \t\n")
+			end := tok.pos.Offset + len(text)
+			p.addSelector(strings.Join(strings.Fields(text), " "), tok.pos)
+			p.selEnd = end
+			continue
+		case tok.kind == tokenValue:
+			text = p.o.typeSelector(text)
+		}
+		p.addSelector(text, tok.pos)
+	}
+	return p.selectors()
+}
+
+// unclosed reports a block the input ended in.
+func (p *parser) unclosed(pos scanner.Position, format string, args ...interface{}) {
+	if p.o.strict {
+		p.fail(errorAt(pos, format, args...))
+		return
+	}
+	p.o.diagnose(SeverityWarning, DiagUnclosedBlock, pos, format, args...)
+}
+
+// handle calls the Parse hook of the handler of at, if any, with the
+// block raw, and reports whether its error stops parsing.
+func (p *parser) handle(at *AtRule, raw []byte) bool {
+	h, _ := lookupAtRule(at.Name)
+	if h.Parse == nil {
+		return false
+	}
+	err := h.Parse(at, raw)
+	switch {
+	case err == nil:
+		return false
+	case p.o.lenient:
+		p.o.diagnose(SeverityWarning, DiagInvalidAtRule, at.Pos, "invalid @%s: %v", at.Name, err)
+		return false
+	}
+	return p.fail(errorAt(at.Pos, "invalid @%s: %v", at.Name, err))
+}
+
+// closeBlock ends the declaration block closed at end, which is zero if
+// the input ended first.
+func (p *parser) closeBlock(end scanner.Position) {
+	if p.declBlock != nil {
+		p.declBlock.Declarations = append(p.declBlock.Declarations, p.decls...)
+		p.declBlock.Close, p.declBlock = end, nil
 	} else {
-		t.s.IsIdentRune = func(ch rune, i int) bool {
-			if ch == -1 || ch == '#' || ch == '.' || ch == '\n' || ch == '\t' || ch == ' ' || ch == ':' || ch == ';' {
-				return false
+		var node Node
+		if p.nestAt != nil {
+			p.nestAt.Declarations, p.nestAt.Rules, p.nestAt.Close = p.decls, p.nested, end
+			if p.nested == nil {
+				p.nestAt.Rules = []Node{}
 			}
-			return true
+			node = p.nestAt
+		} else {
+			r := &RuleNode{Declarations: p.decls, Rules: p.nested, Comments: p.comments, Pos: p.rulePos, Open: p.blockPos, Close: end, Selectors: make([]Rule, len(p.rule))}
+			for i := range p.rule {
+				r.Selectors[i] = Rule(p.rule[i])
+			}
+			node = r
 		}
+		if len(p.nest) > 0 {
+			f := p.nest[len(p.nest)-1]
+			p.nest = p.nest[:len(p.nest)-1]
+			p.rule, p.rulePos, p.blockPos, p.decls, p.comments, p.nestAt = f.rule, f.rulePos, f.blockPos, f.decls, f.comments, f.at
+			p.nested, p.trailing = append(f.nested, node), nil
+			p.style, p.value = "", ""
+			return
+		}
+		p.appendNode(node)
 	}
 
-	return tokenEntry{
-		value,
-		pos,
-	}, nil
+	p.rule, p.decls, p.comments = p.rule[:0], nil, nil
+	p.nested, p.trailing = nil, nil
+	p.style, p.value = "", ""
+	p.isBlock = false
 }
 
-func parse(l *list.List) (map[Rule]map[string]string, error) {
-	var (
-		rule      []string
-		style     string
-		value     string
-		selector  string
-		isBlock   bool
-		css       = make(map[Rule]map[string]string)
-		styles    = make(map[string]string)
-		prevToken = tokenType(tokenFirstToken)
-	)
-	for e := l.Front(); e != nil; e = l.Front() {
-		token := e.Value.(tokenEntry)
-		l.Remove(e)
+// reportItem reports the error deferred to the end of a rule block item.
+func (p *parser) reportItem() bool {
+	bad, tok := p.itemBad, p.itemTok
+	p.itemBad, p.style, p.value = nil, "", ""
+	if p.o.lenient {
+		p.o.diagnose(SeverityWarning, DiagSkippedDeclaration, tok.pos, "unexpected token %s, skipped the declaration", tok.text())
+		return false
+	}
+	return p.fail(bad)
+}
+
+// rawBlock skips the tokens of the block opened at open up to its matching
+// '}', and returns the position of that '}', which is zero if the input
+// ends first, and the text between the braces as written. start is the
+// position the input starts at, if set.
+func rawBlock(ts *tokenStream, open, start scanner.Position) (scanner.Position, []byte) {
+	var end scanner.Position
+	for depth := 1; depth > 0; {
+		token, ok := ts.next()
+		if !ok {
+			break
+		}
 		switch token.typ() {
-		case tokenValue:
-			switch prevToken {
-			case tokenFirstToken:
-				rule = append(rule, token.value)
-			case tokenSelector:
-				rule = append(rule, selector+token.value)
-			case tokenBlockStart:
-				style = token.value
-			case tokenStyleSeparator:
-				value = token.value
-			case tokenValue:
-				rule = append(rule, token.value)
-			default:
-				return css, fmt.Errorf("line %d: unexpected token %s", token.pos.Line, token.value)
-			}
-		case tokenSelector:
-			selector = token.value
 		case tokenBlockStart:
-			if prevToken != tokenValue {
-				return css, fmt.Errorf("line %d: unexpected token %s", token.pos.Line, token.value)
+			depth++
+		case tokenBlockEnd:
+			if depth--; depth == 0 {
+				end = token.pos
+			}
+		}
+	}
+	src := ts.t.src.Bytes()
+	base := 0
+	if start.IsValid() {
+		base = start.Offset
+	}
+	from, to := open.Offset+1-base, len(src)
+	if end.IsValid() {
+		to = end.Offset - base
+	}
+	if from < 0 || from > to || to > len(src) {
+		return end, nil
+	}
+	return end, append([]byte(nil), src[from:to]...)
+}
+
+// declarationText returns the property of the selector text sel if it
+// reads as a declaration: a standard or custom property, a ':' and a value.
+func declarationText(sel string) (string, bool) {
+	prop, value, ok := strings.Cut(sel, ":")
+	prop = strings.TrimSpace(prop)
+	if !ok || strings.TrimSpace(value) == "" || !startsIdent(prop, 0) || skipName(prop, 0) != len(prop) {
+		return "", false
+	}
+	base, _ := Canonical(asciiLower(prop))
+	return prop, strings.HasPrefix(prop, "--") || knownProperties[base]
+}
+
+// misplacedAtRule returns where the statement at-rule name is misplaced
+// after the nodes before it at its level, or "" if it is not. inBlock is
+// set if it is in a block.
+func misplacedAtRule(name string, before []Node, inBlock bool) string {
+	allowed := map[string]map[string]bool{
+		"charset":   {},
+		"import":    {"charset": true, "import": true, "layer": true},
+		"namespace": {"charset": true, "import": true, "layer": true, "namespace": true},
+	}[name]
+	switch {
+	case allowed == nil:
+		return ""
+	case inBlock:
+		return "inside a block"
+	}
+	for _, n := range before {
+		a, ok := n.(*AtRule)
+		if !ok || !allowed[asciiLower(a.Name)] || a.Rules != nil {
+			if name == "charset" {
+				return "not at the start of the stylesheet"
+			}
+			return "after other rules"
+		}
+	}
+	return ""
+}
+
+// invalidKeyframe returns the first member of the selector list rule that
+// is not from, to or a percentage if the innermost of open is @keyframes.
+func invalidKeyframe(open []*AtRule, rule []string) string {
+	if !inKeyframes(open) {
+		return ""
+	}
+	for _, r := range rule {
+		switch n, unit, ok := splitNumber(r); {
+		case strings.EqualFold(r, "from"), strings.EqualFold(r, "to"):
+		case ok && unit == "%" && n >= 0 && n <= 100:
+		default:
+			return r
+		}
+	}
+	return ""
+}
+
+// inKeyframes reports whether the innermost of open is @keyframes.
+func inKeyframes(open []*AtRule) bool {
+	if len(open) == 0 {
+		return false
+	}
+	base, _ := Canonical(asciiLower(open[len(open)-1].Name))
+	return base == "keyframes"
+}
+
+// missingSemicolon returns the offset in the value v where a ';' seems to
+// be missing: the end of a line followed by one starting with a property
+// name and ':', outside of parentheses and strings. It returns -1 if there
+// is none.
+func missingSemicolon(v string) int {
+	depth := 0
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '"', '\'':
+			i = skipString(v, i) - 1
+		case '\\':
+			i++
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '\n':
+			j := i + 1
+			for j < len(v) && isSelectorSpace(v[j]) {
+				j++
+			}
+			if depth > 0 || !startsIdent(v, j) {
+				continue
+			}
+			for j = skipName(v, j); j < len(v) && isSelectorSpace(v[j]); j++ {
+			}
+			if j < len(v) && v[j] == ':' {
+				return len(strings.TrimRight(v[:i], " \t\r\f"))
+			}
+		}
+	}
+	return -1
+}
+
+// openBrackets returns the number of '(' and '[' of the value v left open at
+// its end, outside of strings, or a negative number if more are closed.
+func openBrackets(v string) int {
+	return bracketDepth(0, v)
+}
+
+// bracketDepth is openBrackets for v following text with depth brackets
+// left open, so that a value read a token at a time is scanned once.
+func bracketDepth(depth int, v string) int {
+	if depth < 0 {
+		return depth
+	}
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case '"', '\'':
+			i = skipString(v, i) - 1
+		case '\\':
+			i++
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth--; depth < 0 {
+				return depth
 			}
-			isBlock = true
+		}
+	}
+	return depth
+}
+
+// nestFrame holds the state of a rule block while a rule nested in it is
+// parsed.
+type nestFrame struct {
+	rule              []string
+	rulePos, blockPos scanner.Position
+	decls             []Declaration
+	nested            []Node
+	trailing          *RuleNode
+	comments          []string
+	at                *AtRule
+}
+
+// advance returns the position just past s when s starts at pos.
+func advance(pos scanner.Position, s string) scanner.Position {
+	pos.Offset += len(s)
+	for _, c := range s {
+		if c == '\n' {
+			pos.Line++
+			pos.Column = 0
+		}
+		pos.Column++
+	}
+	return pos
+}
+
+// selectorComma is a comma separating the members of a selector list, at
+// index at of the selector text.
+type selectorComma struct {
+	at  int
+	pos scanner.Position
+}
+
+// resync skips the tokens following the offending token bad up to the
+// point where parsing can resume, and returns the token type to resume
+// with. Inside a declaration block the rest of the declaration is skipped
+// up to the next ';', or up to the '}' closing the block, which is left to
+// be parsed. Elsewhere the rest of the statement is skipped up to the next
+// ';' or through the end of the block it opens, stopping before a '}' that
+// closes an enclosing at-rule.
+func resync(ts *tokenStream, bad tokenEntry, inBlock bool) tokenType {
+	depth := 0
+	switch bad.typ() {
+	case tokenStatementEnd:
+		if inBlock {
+			return tokenStatementEnd
+		}
+		return tokenBlockEnd
+	case tokenBlockStart:
+		depth++
+	case tokenBlockEnd:
+		if !inBlock {
+			return tokenBlockEnd
+		}
+	}
+	resume := tokenStatementEnd
+	if !inBlock {
+		resume = tokenBlockEnd
+	}
+	for token, ok := ts.peek(); ok; token, ok = ts.peek() {
+		switch token.typ() {
 		case tokenStatementEnd:
-			if prevToken != tokenValue || style == "" || value == "" {
-				return css, fmt.Errorf("line %d: unexpected token %s", token.pos.Line, token.value)
+			if depth == 0 {
+				ts.next()
+				return resume
 			}
-			styles[style] = value
+		case tokenBlockStart:
+			depth++
 		case tokenBlockEnd:
-			if !isBlock {
-				return css, fmt.Errorf("line %d: unexpected token %s", token.pos.Line, token.value)
-			}
-
-			for i := range rule {
-				r := Rule(rule[i])
-				oldRule, ok := css[r]
-				if ok {
-					for style, value := range oldRule {
-						if _, ok := styles[style]; !ok {
-							styles[style] = value
-						}
-					}
-					continue
+			if depth == 0 {
+				return resume
+			}
+			depth--
+			if depth == 0 {
+				ts.next()
+				return resume
+			}
+		}
+		ts.next()
+	}
+	return resume
+}
+
+// flatten folds the rules of sheet into the selector keyed maps returned by
+// Unmarshal and UnmarshalWithPositions.
+func flatten(sheet *StyleSheet, o options) *flattener {
+	f := newFlattener(o)
+	f.flatten(sheet)
+	return f
+}
+
+// flatten merges the rules of sheet.
+func (f *flattener) flatten(sheet *StyleSheet) {
+	layeredRules(sheet, f.add)
+	if f.o.scopedKeys {
+		scopedRules(unnest(sheet.Rules, DialectCSS), "", f.addScoped)
+	}
+}
+
+// flattenScoped is like flatten but keeps the rules of each media scope
+// apart, for UnmarshalScoped.
+func flattenScoped(sheet *StyleSheet, o options) (map[MediaScope]map[Rule]map[string]string, error) {
+	scopes := make(map[MediaScope]*flattener)
+	var err error
+	walkScoped(unnest(sheet.Rules, DialectCSS), "", func(n *RuleNode, scope MediaScope) {
+		f, ok := scopes[scope]
+		if !ok {
+			f = newFlattener(o)
+			scopes[scope] = f
+		}
+		if f.add(n, nil); err == nil {
+			err = f.err
+		}
+	})
+	css := make(map[MediaScope]map[Rule]map[string]string, len(scopes))
+	for scope, f := range scopes {
+		css[scope] = f.css
+	}
+	return css, err
+}
+
+// walkScoped calls fn for the rules of nodes and of the @media rules among
+// them, at any depth, with their MediaScope. Rules inside other at-rules
+// are skipped.
+func walkScoped(nodes []Node, scope MediaScope, fn func(*RuleNode, MediaScope)) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			fn(n, scope)
+		case *AtRule:
+			if !strings.EqualFold(n.Name, "media") {
+				continue
+			}
+			inner := MediaScope(strings.TrimSpace(n.Prelude))
+			if scope != "" {
+				inner = scope + " and " + inner
+			}
+			walkScoped(n.Rules, inner, fn)
+		}
+	}
+}
+
+// flattener merges rules into the map of flatten, tracking which values
+// are !important, where they were declared and, for those of cascade
+// layers, the rank of their layer.
+type flattener struct {
+	css       map[Rule]map[string]string
+	important map[Rule]map[string]bool
+	pos       map[Rule]map[string]scanner.Position
+	layers    map[Rule]map[string]layerRank
+	o         options
+	err       error // the first duplicate across rules under DuplicateError
+	// strings, if not nil, interns the property names and short values
+	// merged, for UnmarshalCompact.
+	strings map[string]string
+}
+
+func newFlattener(o options) *flattener {
+	return &flattener{
+		o:         o,
+		css:       make(map[Rule]map[string]string),
+		important: make(map[Rule]map[string]bool),
+		pos:       make(map[Rule]map[string]scanner.Position),
+		layers:    make(map[Rule]map[string]layerRank),
+	}
+}
+
+// add merges the rule node of the layer of rank layer.
+func (f *flattener) add(node *RuleNode, layer layerRank) {
+	for _, r := range node.Selectors {
+		styles, ok := f.css[r]
+		if !ok {
+			styles = make(map[string]string, len(node.Declarations))
+			f.css[r] = styles
+			f.important[r] = make(map[string]bool)
+			f.pos[r] = make(map[string]scanner.Position)
+		}
+		own := make(map[string]bool, len(node.Declarations))
+		for _, decl := range node.Declarations {
+			if f.strings != nil {
+				decl.Property = f.intern(decl.Property)
+			}
+			prev, ok := f.pos[r][decl.Property]
+			if ok && f.o.duplicates == DuplicateError && !own[decl.Property] {
+				f.duplicate(r, decl, prev)
+			}
+			own[decl.Property] = true
+			if ok && !f.wins(r, decl, layer) {
+				continue
+			}
+			value := decl.text()
+			if f.strings != nil {
+				value = f.intern(value)
+			}
+			styles[decl.Property] = value
+			f.important[r][decl.Property] = decl.Important
+			f.pos[r][decl.Property] = decl.Pos
+			if layer != nil {
+				if f.layers[r] == nil {
+					f.layers[r] = make(map[string]layerRank)
 				}
-				css[r] = styles
+				f.layers[r][decl.Property] = layer
+			} else if f.layers[r] != nil {
+				delete(f.layers[r], decl.Property)
 			}
+		}
+	}
+}
 
-			styles = map[string]string{}
-			style, value = "", ""
-			isBlock = false
+// wins reports whether decl, of a rule for r in the layer of rank layer,
+// wins over the value merged so far. Of two normal declarations, that of
+// the later layer wins, and of two !important ones, that of the earlier.
+func (f *flattener) wins(r Rule, decl Declaration, layer layerRank) bool {
+	prev := f.important[r][decl.Property]
+	if decl.Important == prev {
+		if c := compareRanks(layer, f.layers[r][decl.Property]); c != 0 {
+			return c > 0 != decl.Important
 		}
+	}
+	return f.o.duplicates.wins(decl.Important, prev)
+}
 
-		prevToken = token.typ()
+// duplicate reports decl of r as a duplicate of the declaration at prev in
+// an earlier rule; those of one block are reported by the parser.
+func (f *flattener) duplicate(r Rule, decl Declaration, prev scanner.Position) {
+	const format = "duplicate %s in %s, first declared at line %d"
+	switch {
+	case f.o.lenient:
+		f.o.diagnose(SeverityWarning, DiagDuplicateProperty, decl.Pos, format, decl.Property, r, prev.Line)
+	case f.err == nil:
+		f.err = errorAt(decl.Pos, format, decl.Property, r, prev.Line)
 	}
+}
 
-	return css, nil
+// flattenMulti is like flatten but keeps every value of a property.
+func flattenMulti(sheet *StyleSheet) map[Rule]map[string][]string {
+	css := make(map[Rule]map[string][]string)
+	for _, n := range unnest(sheet.Rules, DialectCSS) {
+		node, ok := n.(*RuleNode)
+		if !ok {
+			continue
+		}
+		for _, r := range node.Selectors {
+			styles, ok := css[r]
+			if !ok {
+				styles = make(map[string][]string, len(node.Declarations))
+				css[r] = styles
+			}
+			for _, decl := range node.Declarations {
+				styles[decl.Property] = append(styles[decl.Property], decl.text())
+			}
+		}
+	}
+	return css
 }
 
-func buildList(r io.Reader) *list.List {
-	l := list.New()
-	t := newTokenizer(r)
-	for {
-		token, err := t.next()
-		if err != nil {
-			break
+// tokenStream feeds the tokens of a stylesheet to parse one at a time,
+// enforcing the cancellation and token count limits. It ends at the first
+// read, limit or cancellation error, which is kept in err.
+type tokenStream struct {
+	t      *tokenizer
+	g      grammar
+	o      options
+	err    error
+	peeked bool
+	token  tokenEntry // the peeked token
+
+	n, rules, decls, depth int
+
+	resume scanner.Position // just past the last top-level node read
+	parsed int              // number of top-level nodes read up to resume
+}
+
+func newTokenStream(r io.Reader, filename string, o options) *tokenStream {
+	return &tokenStream{t: newTokenizer(r, filename, o), g: grammar{prev: tokenFirstToken}, o: o}
+}
+
+// next returns the next token, or false at the end of the stream.
+func (ts *tokenStream) next() (tokenEntry, bool) {
+	token, ok := ts.peek()
+	ts.peeked = false
+	return token, ok
+}
+
+// peek returns the next token without consuming it.
+func (ts *tokenStream) peek() (tokenEntry, bool) {
+	if ts.peeked {
+		return ts.token, true
+	}
+	if ts.err != nil {
+		return tokenEntry{}, false
+	}
+	token, err := ts.read()
+	if err != nil {
+		if err != io.EOF {
+			ts.err = err
 		}
-		l.PushBack(token)
+		return tokenEntry{}, false
+	}
+	ts.token, ts.peeked = token, true
+	return token, true
+}
+
+func (ts *tokenStream) read() (tokenEntry, error) {
+	if err := ts.o.canceled(ts.n, ts.t.position(ts.t.s.Pos())); err != nil {
+		return tokenEntry{}, err
+	}
+	ts.n++
+	token, err := ts.t.next()
+	if err != nil {
+		return token, err
+	}
+	ts.g.advance(ts.t, token)
+	switch token.kind {
+	case tokenBlockStart:
+		ts.rules++
+		ts.depth++
+		if ts.o.maxRules > 0 && ts.rules > ts.o.maxRules {
+			return token, limitExceeded(token.pos, LimitRules, int64(ts.o.maxRules))
+		}
+		if ts.o.maxDepth > 0 && ts.depth > ts.o.maxDepth {
+			return token, limitExceeded(token.pos, LimitNestingDepth, int64(ts.o.maxDepth))
+		}
+	case tokenBlockEnd:
+		if ts.depth > 0 {
+			ts.depth--
+		}
+	case tokenStyleSeparator:
+		ts.decls++
+		if ts.o.maxDecls > 0 && ts.decls > ts.o.maxDecls {
+			return token, limitExceeded(token.pos, LimitDeclarations, int64(ts.o.maxDecls))
+		}
+	}
+	return token, nil
+}
+
+// parseReader parses the stylesheet read from r. A read error takes
+// precedence over the parse errors the truncated input may cause.
+func parseReader(r io.Reader, filename string, o options) (*StyleSheet, error) {
+	ts := newTokenStream(r, filename, o)
+	ts.resume = ts.t.position(scanner.Position{Filename: filename, Line: 1, Column: 1})
+	sheet, err := parse(ts, o)
+	sheet.Layers = declaredLayers(sheet.Rules)
+	for _, c := range ts.t.comments.comments {
+		sheet.Comments = append(sheet.Comments, Comment{c.text, c.pos})
+	}
+	if o.nesting == DialectSCSS {
+		sheet.Rules = unnest(sheet.Rules, DialectSCSS)
+	}
+	if ts.err != nil {
+		err = ts.err
+	}
+	if o.valueFuncs != nil {
+		if ferr := callValueFunctions(sheet, o); err == nil {
+			err = ferr
+		}
+	}
+	if o.expand {
+		expandShorthands(sheet.Rules)
+	}
+	if e, ok := err.(*ParseError); ok {
+		e.Resume, e.ParsedRules = ts.resume, ts.parsed
+	}
+	if o.verboseErrors {
+		annotateErrors(err, ts.t.src.Bytes(), o.start)
+	}
+	return sheet, err
+}
+
+// UnmarshalContext is like Unmarshal but stops with an error wrapping
+// ctx.Err() once ctx is done.
+func UnmarshalContext(ctx context.Context, b []byte, opts ...Option) (map[Rule]map[string]string, error) {
+	return Unmarshal(b, append([]Option{WithContext(ctx)}, opts...)...)
+}
+
+// Unmarshal parses the stylesheet b into a map from selector to the
+// declarations that apply to it. The rules of a selector are merged in
+// source order: a later declaration of a property replaces an earlier one
+// unless only the earlier one is !important. Rules in @layer blocks are
+// merged too, in the layer order of ComputeStyle, while those of other
+// at-rules are left out. Values keep their !important flag. Every selector
+// gets its own map, so the declarations of a group such as "h1, h2" are
+// copied to both; Parse keeps the group instead. Nested rules are keyed by
+// their selectors resolved against their parent's, so "&:hover" and
+// ".title" nested in ".card" give ".card:hover" and ".card .title".
+//
+// These are the v1 semantics of Unmarshal, pinned by the corpus in
+// testdata/v1, which CheckCorpus checks: a change to what Unmarshal returns
+// for any input there is a change to its contract, to be called out as one.
+func Unmarshal(b []byte, opts ...Option) (map[Rule]map[string]string, error) {
+	o := newOptions(opts)
+	sheet, err := parseReader(bytes.NewReader(b), o.filename, o)
+	f := flatten(sheet, o)
+	if err == nil {
+		err = f.err
+	}
+	return f.css, err
+}
+
+// Flatten merges the rules of sheet into a map from selector to
+// declarations as Unmarshal does, such as those of a sheet made by Combine.
+func (sheet *StyleSheet) Flatten() map[Rule]map[string]string {
+	return flatten(sheet, options{}).css
+}
+
+// UnmarshalWithPositions is like Unmarshal but also returns, for each
+// selector and property, the position of the declaration whose value
+// Unmarshal returns.
+func UnmarshalWithPositions(b []byte, opts ...Option) (map[Rule]map[string]string, map[Rule]map[string]scanner.Position, error) {
+	o := newOptions(opts)
+	sheet, err := parseReader(bytes.NewReader(b), o.filename, o)
+	f := flatten(sheet, o)
+	if err == nil {
+		err = f.err
+	}
+	return f.css, f.pos, err
+}
+
+// MediaScope is the media query list of the @media rules enclosing a rule,
+// as keyed by UnmarshalScoped. It is empty for top-level rules, and the
+// conditions of nested @media rules are joined with " and ".
+type MediaScope string
+
+// UnmarshalScoped is like Unmarshal but keys the selector maps by the
+// MediaScope of their rules, so that rules for the same selector inside
+// different @media rules are not merged. Rules inside other at-rules, such
+// as @supports, are left out.
+func UnmarshalScoped(b []byte, opts ...Option) (map[MediaScope]map[Rule]map[string]string, error) {
+	o := newOptions(opts)
+	sheet, err := parseReader(bytes.NewReader(b), o.filename, o)
+	css, merr := flattenScoped(sheet, o)
+	if err == nil {
+		err = merr
 	}
-	return l
+	return css, err
 }
 
-func Unmarshal(b []byte) (map[Rule]map[string]string, error) {
-	return parse(buildList(bytes.NewReader(b)))
+// UnmarshalMulti is like Unmarshal but keeps every value declared for a
+// property in source order, such as the fallbacks of
+// "background: #07c; background: linear-gradient(#07c, #05a)". Unmarshal
+// returns the last value, or the last !important one if there is any.
+func UnmarshalMulti(b []byte, opts ...Option) (map[Rule]map[string][]string, error) {
+	o := newOptions(opts)
+	sheet, err := parseReader(bytes.NewReader(b), o.filename, o)
+	return flattenMulti(sheet), err
 }
@@ -0,0 +1,509 @@
+package css
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// TestReaderError checks that an error of the reader partway through the
+// input is returned, wrapped in a ParseError at where it happened, and not
+// taken for the end of the input.
+func TestReaderError(t *testing.T) {
+	errRead := errors.New("read failed")
+	for _, opts := range [][]Option{nil, {Lenient(true)}, {Strict(true)}} {
+		r := io.MultiReader(strings.NewReader(".a{color:red;}\n.b{top:0"), iotest.ErrReader(errRead))
+		_, err := ParseReader(r, opts...)
+		if !errors.Is(err, errRead) {
+			t.Errorf("ParseReader with %d options: got %v, want %v", len(opts), err, errRead)
+			continue
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Pos.Line != 2 {
+			t.Errorf("ParseReader with %d options: got %#v, want a ParseError at line 2", len(opts), err)
+		}
+		if errors.Is(err, ErrSyntax) {
+			t.Errorf("ParseReader with %d options: %v is ErrSyntax", len(opts), err)
+		}
+	}
+}
+
+// errCountCtx is a context that is never done and counts its Err calls.
+type errCountCtx struct {
+	context.Context
+	calls int
+}
+
+func (c *errCountCtx) Err() error {
+	c.calls++
+	return nil
+}
+
+// TestCanceledContext checks that parsing with a context already canceled
+// stops at once, and that the context is checked every ctxCheckInterval
+// tokens rather than at each.
+func TestCanceledContext(t *testing.T) {
+	src := []byte(strings.Repeat(".a { color: red }\n", 1000))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	css, err := UnmarshalContext(ctx, src)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Pos.Line != 1 {
+		t.Errorf("got %#v, want a ParseError at line 1", err)
+	}
+	if len(css) != 0 {
+		t.Errorf("got %d rules, want none", len(css))
+	}
+	if _, err := UnmarshalContext(ctx, []byte(".a{}")); !errors.Is(err, context.Canceled) {
+		t.Errorf("of a short input: got %v, want %v", err, context.Canceled)
+	}
+
+	counted := &errCountCtx{Context: context.Background()}
+	if _, err := UnmarshalContext(counted, src); err != nil {
+		t.Fatal(err)
+	}
+	// Each rule is 7 tokens, the spaces included.
+	if tokens := 7 * 1000; counted.calls == 0 || counted.calls > 2*tokens/ctxCheckInterval+2 {
+		t.Errorf("context checked %d times for %d tokens, want about %d", counted.calls, tokens, tokens/ctxCheckInterval)
+	}
+}
+
+// TestUnclosedBlock checks that the declarations of a block left open at
+// the end of the input are kept in lenient and default mode, and that
+// strict mode reports the line the block was opened at.
+func TestUnclosedBlock(t *testing.T) {
+	const src = ".x { top: 0; }\n.a { color: red; margin: 0"
+	want := map[string]string{"color": "red", "margin": "0"}
+	for _, opts := range [][]Option{{Lenient(true)}, nil} {
+		css, err := Unmarshal([]byte(src), opts...)
+		if err != nil {
+			t.Errorf("with %d options: %v", len(opts), err)
+			continue
+		}
+		if !reflect.DeepEqual(css[".a"], want) {
+			t.Errorf("with %d options: got %v for .a, want %v", len(opts), css[".a"], want)
+		}
+	}
+	_, err := Unmarshal([]byte(src), Strict(true))
+	if err == nil || !strings.Contains(err.Error(), "opened at line 2") {
+		t.Errorf("in strict mode: got %v, want an error citing line 2", err)
+	}
+}
+
+// TestTruncatedInput checks that input ending in a selector or a
+// declaration name parses with no error in the default mode.
+func TestTruncatedInput(t *testing.T) {
+	tests := []struct {
+		src  string
+		want map[Rule]map[string]string
+	}{
+		{":", map[Rule]map[string]string{}},
+		{".a{color", map[Rule]map[string]string{".a": {}}},
+	}
+	for _, tt := range tests {
+		css, err := Unmarshal([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Unmarshal(%q): %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(css, tt.want) {
+			t.Errorf("Unmarshal(%q) = %v, want %v", tt.src, css, tt.want)
+		}
+		if _, err := Parse([]byte(tt.src)); err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+		}
+	}
+}
+
+// TestTrailingTrivia checks that comments and whitespace after the last
+// rule parse with no error in every mode and leave its declarations alone.
+// A trailing comment is kept in the Comments of the sheet but, held by no
+// node, is dropped by Marshal.
+func TestTrailingTrivia(t *testing.T) {
+	tests := []struct {
+		src     string
+		comment string // and its position, or "" for none
+	}{
+		{".a { color: red; } /* trailing note */\n\n  ", "1:20  trailing note "},
+		{".a { color: red; }/**/", "1:19 "},
+		{".a { color: red; }\n\t\n", ""},
+	}
+	for _, tt := range tests {
+		for _, opts := range [][]Option{nil, {Lenient(true)}, {Strict(true)}} {
+			sheet, err := Parse([]byte(tt.src), opts...)
+			if err != nil {
+				t.Errorf("Parse(%q): %v", tt.src, err)
+				continue
+			}
+			var got []string
+			for _, c := range sheet.Comments {
+				got = append(got, fmt.Sprintf("%d:%d %s", c.Pos.Line, c.Pos.Column, c.Text))
+			}
+			if strings.Join(got, ", ") != tt.comment {
+				t.Errorf("Parse(%q) keeps comments %q, want %q", tt.src, got, tt.comment)
+			}
+			out, err := Marshal(sheet, KeepComments(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := ".a {\n  color: red;\n}\n"; string(out) != want {
+				t.Errorf("Marshal of %q = %q, want %q", tt.src, out, want)
+			}
+		}
+	}
+	// An unterminated comment is an error, except in lenient mode.
+	src := []byte(".a { color: red; } /* unterminated")
+	if _, err := Parse(src); err == nil {
+		t.Errorf("Parse(%q) gives no error", src)
+	}
+	if css, err := Unmarshal(src, Lenient(true)); err != nil || css[".a"]["color"] != "red" {
+		t.Errorf("Unmarshal(%q) in lenient mode = %v, %v", src, css, err)
+	}
+}
+
+// TestDanglingSelector checks that a selector with no block after it at
+// the end of input is an error at the selector, or a skipped-rule warning
+// in lenient mode, and that the rules before it are kept.
+func TestDanglingSelector(t *testing.T) {
+	src := []byte(".a { color: red; } bogus")
+	css, err := Unmarshal(src)
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Pos.Line != 1 || perr.Pos.Column != 20 {
+		t.Errorf("Unmarshal(%q): got %v, want an error at 1:20", src, err)
+	}
+	if css[".a"]["color"] != "red" {
+		t.Errorf("Unmarshal(%q) = %v, want .a kept", src, css)
+	}
+	var diags []Diagnostic
+	css, err = Unmarshal(src, Lenient(true), WithDiagnostics(func(d Diagnostic) { diags = append(diags, d) }))
+	if err != nil || css[".a"]["color"] != "red" {
+		t.Errorf("Unmarshal(%q) in lenient mode = %v, %v", src, css, err)
+	}
+	if len(diags) != 1 || diags[0].Code != DiagSkippedRule || diags[0].Pos.Column != 20 {
+		t.Errorf("Unmarshal(%q) in lenient mode reports %v, want one skipped rule at 1:20", src, diags)
+	}
+	refs, err := ListSelectors(strings.NewReader(string(src)))
+	if !errors.As(err, &perr) || perr.Pos.Column != 20 || len(refs) != 1 {
+		t.Errorf("ListSelectors(%q) = %v, %v, want .a and an error at 1:20", src, refs, err)
+	}
+}
+
+func TestMissingLastSemicolon(t *testing.T) {
+	tests := []struct {
+		src  string
+		want map[Rule]map[string]string
+	}{
+		{".a { color: red }", map[Rule]map[string]string{".a": {"color": "red"}}},
+		{".a{color:red}", map[Rule]map[string]string{".a": {"color": "red"}}},
+		{".a { color: red; top: 0 }", map[Rule]map[string]string{".a": {"color": "red", "top": "0"}}},
+		{".a { color: red; top: 0; } .b { left: 0 }", map[Rule]map[string]string{".a": {"color": "red", "top": "0"}, ".b": {"left": "0"}}},
+		{".a { color: red !important }", map[Rule]map[string]string{".a": {"color": "red !important"}}},
+	}
+	for _, tt := range tests {
+		css, err := Unmarshal([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Unmarshal(%q): %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(css, tt.want) {
+			t.Errorf("Unmarshal(%q) = %v, want %v", tt.src, css, tt.want)
+		}
+	}
+	// A name with no colon or value before } is not a declaration.
+	if _, err := Unmarshal([]byte(".a { color: red; top }")); err == nil {
+		t.Error(`Unmarshal(".a { color: red; top }") gives no error`)
+	}
+}
+
+// TestDuplicateSelector checks that the later of two blocks for a selector
+// wins, and that merging into one selector of a group leaves the others.
+func TestDuplicateSelector(t *testing.T) {
+	css, err := Unmarshal([]byte(".a{color:red} .a{color:blue}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"color": "blue"}; !reflect.DeepEqual(css[".a"], want) {
+		t.Errorf("got %v for .a, want %v", css[".a"], want)
+	}
+	css, err = Unmarshal([]byte("h1, h2 { color: red; top: 0 } h1 { color: blue }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[Rule]map[string]string{
+		"h1": {"color": "blue", "top": "0"},
+		"h2": {"color": "red", "top": "0"},
+	}
+	if !reflect.DeepEqual(css, want) {
+		t.Errorf("got %v, want %v", css, want)
+	}
+}
+
+// TestKeyframeSelectors checks that decimal percentages are keyframe
+// selectors, and that a '.' starting a number is otherwise no class.
+func TestKeyframeSelectors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want [][]Rule
+	}{
+		{"@keyframes spin { 12.5% {top:1px;} }", [][]Rule{{"12.5%"}}},
+		{"@keyframes spin { 0%, 33.3% {top:1px;} .5% {top:0;} }", [][]Rule{{"0%", "33.3%"}, {".5%"}}},
+		{"@-webkit-keyframes spin { from {top:0;} 99.99% {top:1px;} }", [][]Rule{{"from"}, {"99.99%"}}},
+	}
+	for _, tt := range tests {
+		for _, opts := range [][]Option{nil, {Strict(true)}} {
+			sheet, err := Parse([]byte(tt.src), opts...)
+			if err != nil {
+				t.Errorf("Parse(%q): %v", tt.src, err)
+				continue
+			}
+			var got [][]Rule
+			for _, n := range sheet.Rules[0].(*AtRule).Rules {
+				got = append(got, n.(*RuleNode).Selectors)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) gives keyframes %q, want %q", tt.src, got, tt.want)
+			}
+		}
+	}
+	for _, src := range []string{"@keyframes spin { 12.5x {top:1px;} }", ".5 {top:0;}", "a.5% {top:0;}"} {
+		if _, err := Parse([]byte(src), Strict(true)); err == nil {
+			t.Errorf("Parse(%q) in strict mode gives no error", src)
+		}
+	}
+}
+
+// TestRawValue checks that Lenient mode keeps a value it cannot read as
+// written, up to the ';' once its brackets close, and reads the
+// declarations after it.
+func TestRawValue(t *testing.T) {
+	const src = ".a { width: @@weird<stuff>[not;css] (x; y); color: red; margin: 0 }"
+	sheet, err := Parse([]byte(src), Lenient(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range sheet.Rules[0].(*RuleNode).Declarations {
+		got = append(got, fmt.Sprintf("%s=%s raw=%v", d.Property, d.Value, d.Raw))
+	}
+	want := []string{"width=@@weird<stuff>[not;css] (x; y) raw=true", "color=red raw=false", "margin=0 raw=false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(%q) gives\n%q\nwant\n%q", src, got, want)
+	}
+	if _, err := Parse([]byte(src), Strict(true)); err == nil {
+		t.Errorf("Parse(%q) in strict mode gives no error", src)
+	}
+
+	// The brackets of the value are counted as it is read, so that a long
+	// one does not take quadratic time.
+	long := ".a{x:" + strings.Repeat("(;", 1<<19) + "}"
+	start := time.Now()
+	if _, err := Parse([]byte(long), Lenient(true)); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("Parse of a 1MB raw value takes %v", d)
+	}
+}
+
+// TestNonASCIIFontFamily checks that CJK, emoji and accented names keep
+// every rune through Parse and Marshal, in selectors and in font-family
+// and content values.
+func TestNonASCIIFontFamily(t *testing.T) {
+	tests := []struct {
+		src, value, out string
+	}{
+		{
+			`.日本語 { font-family: 微软雅黑, "ヒラギノ角ゴ Pro", sans-serif }`,
+			`微软雅黑, "ヒラギノ角ゴ Pro", sans-serif`,
+			".日本語{font-family:微软雅黑, \"ヒラギノ角ゴ Pro\", sans-serif}",
+		},
+		{
+			`.café { font-family: Crème Brûlée, Señor }`,
+			`Crème Brûlée, Señor`,
+			`.café{font-family:"Crème Brûlée", Señor}`,
+		},
+		{
+			`.🎉 { font-family: 😀Emoji, "🎉 Party" }`,
+			`😀Emoji, "🎉 Party"`,
+			`.🎉{font-family:😀Emoji, "🎉 Party"}`,
+		},
+	}
+	for _, tt := range tests {
+		css, err := Unmarshal([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Unmarshal(%q): %v", tt.src, err)
+			continue
+		}
+		sel := Rule(tt.src[:strings.Index(tt.src, " {")])
+		if got := css[sel]["font-family"]; got != tt.value {
+			t.Errorf("Unmarshal(%q) gives font-family %q for %q, want %q", tt.src, got, sel, tt.value)
+		}
+		sheet, err := Parse([]byte(tt.src))
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.src, err)
+			continue
+		}
+		out, err := Marshal(sheet, Minify())
+		if err != nil {
+			t.Errorf("Marshal of %q: %v", tt.src, err)
+			continue
+		}
+		if string(out) != tt.out {
+			t.Errorf("Marshal of %q = %q, want %q", tt.src, out, tt.out)
+		}
+		again, err := Unmarshal(out)
+		if err != nil || len(again[sel]) != 1 {
+			t.Errorf("Unmarshal of the output %q = %q, %v", out, again, err)
+		}
+	}
+}
+
+// TestVerboseErrors pins the wording of the errors WithVerboseErrors gives
+// for classic mistakes, and that of the short form without it.
+func TestVerboseErrors(t *testing.T) {
+	tests := []struct {
+		name, src     string
+		short, detail string
+	}{
+		{
+			"missing ;", ".a {\n  color: red\n  top: 0;\n}\n",
+			"line 2: missing ';' after the value of color",
+			"line 2: missing ';' after the value of color\n  2 |   color: red\n    |             ^",
+		},
+		{
+			"= for :", ".a {\n  color = red;\n}\n",
+			"line 2: unexpected token =",
+			"line 2: unexpected token =; expected ':'\n  2 |   color = red;\n    |         ^",
+		},
+		{
+			"missing :", ".a {\n  color red;\n}\n",
+			"line 2: unexpected token red",
+			"line 2: unexpected token red; expected ':'\n  2 |   color red;\n    |         ^",
+		},
+		{
+			"extra }", ".a {\n  color: red;\n}\n}\n",
+			"line 4: unexpected token }",
+			"line 4: unexpected token }; expected a selector or an at-rule\n  4 | }\n    | ^",
+		},
+		{
+			"missing }", ".a {\n  color: red;\n",
+			"line 1: missing } at end of input for the .a block opened at line 1",
+			"line 1: missing } at end of input for the .a block opened at line 1\n  1 | .a {\n    |    ^",
+		},
+	}
+	for _, tt := range tests {
+		for _, verbose := range []bool{false, true} {
+			want := tt.short
+			if verbose {
+				want = tt.detail
+			}
+			_, err := Parse([]byte(tt.src), Strict(true), WithVerboseErrors(verbose))
+			if err == nil || err.Error() != want {
+				t.Errorf("%s, verbose %v: got error\n%v\nwant\n%s", tt.name, verbose, err, want)
+			}
+		}
+	}
+}
+
+// TestParseStates checks the state the parser reads each token of a
+// stylesheet in, written as the state and the token.
+func TestParseStates(t *testing.T) {
+	names := map[parseState]string{stateSelector: "selector", stateName: "name", stateValue: "value", statePrelude: "prelude"}
+	tests := []struct {
+		src, want string
+	}{
+		{
+			".a:hover > b { color: red; top: 0 }",
+			"selector ., selector a:hover, selector >, selector b, selector {, name color, name :, value red, value ;, name top, name :, value 0, value }",
+		},
+		{
+			"@media print { .a { top: 0 } }",
+			"selector @media, prelude print, prelude {, selector ., selector a, selector {, name top, name :, value 0, value }, selector }",
+		},
+		{
+			"@import url(x.css); .a{}",
+			"selector @import, prelude url(x.css), prelude ;, selector ., selector a, selector {, name }",
+		},
+		{
+			"@font-face { font-family: x }",
+			"selector @font-face, prelude, prelude {, name font-family, name :, value x, value }",
+		},
+		{
+			".a { color: red; &:hover { top: 0 } @media print { left: 0 } }",
+			"selector ., selector a, selector {, name color, name :, value red, value ;, " +
+				"name &, name :, value hover, value {, name top, name :, value 0, value }, " +
+				"name @media, prelude print, prelude {, name left, name :, value 0, value }, name }",
+		},
+	}
+	for _, tt := range tests {
+		o := newOptions(nil)
+		p := newParser(newTokenStream(strings.NewReader(tt.src), "", o), o)
+		var got []string
+		for token, ok := p.ts.next(); ok; token, ok = p.ts.next() {
+			got = append(got, strings.TrimSpace(names[p.state()]+" "+strings.TrimSpace(token.text())))
+			if p.read(token) {
+				break
+			}
+		}
+		if strings.Join(got, ", ") != tt.want {
+			t.Errorf("%q read as\n%s\nwant\n%s", tt.src, strings.Join(got, ", "), tt.want)
+		}
+	}
+}
+
+// TestMisplaced checks the cases of testdata/misplaced, each a stylesheet
+// with a misplaced at-rule, rule or declaration. The file of a case ending
+// in .want instead of .css holds a "== mode" line for each of the default,
+// Lenient and Strict modes, followed by the diagnostics and error parsing
+// gives in that mode and the output of Marshal with Minify.
+func TestMisplaced(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("testdata", "misplaced", "*.css"))
+	if err != nil || len(names) == 0 {
+		t.Fatalf("no cases in testdata/misplaced: %v", err)
+	}
+	modes := []struct {
+		name string
+		opts []Option
+	}{{"default", nil}, {"lenient", []Option{Lenient(true)}}, {"strict", []Option{Strict(true)}}}
+	for _, name := range names {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		for _, m := range modes {
+			fmt.Fprintf(&b, "== %s\n", m.name)
+			opts := append(m.opts, WithDiagnostics(func(d Diagnostic) {
+				fmt.Fprintf(&b, "%s %s %d:%d %s\n", d.Severity, d.Code, d.Pos.Line, d.Pos.Column, d.Message)
+			}))
+			sheet, err := Parse(src, opts...)
+			if err != nil {
+				fmt.Fprintf(&b, "error %v\n", err)
+			}
+			out, err := Marshal(sheet, Minify())
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprintln(&b, strings.TrimSpace("out "+string(out)))
+		}
+		want, err := os.ReadFile(strings.TrimSuffix(name, ".css") + ".want")
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if b.String() != string(want) {
+			t.Errorf("%s gives\n%s\nwant\n%s", name, b.String(), want)
+		}
+	}
+}
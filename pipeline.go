@@ -0,0 +1,240 @@
+package css
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"text/scanner"
+	"time"
+)
+
+// Pass is a step of a Pipeline: it returns the stylesheet made from sheet,
+// with the diagnostics of what it changed or could not do. A Pass must not
+// modify sheet, which may be the caller's, and its result may share the
+// nodes it left unchanged with sheet; a Pass modifying the nodes it is
+// given in place, such as one built on Transform, works on sheet.Clone().
+// The transforms of the package are available as passes by the functions
+// ending in Pass, such as FlipRTLPass, and any other function of a sheet
+// by Apply.
+type Pass func(sheet *StyleSheet) (*StyleSheet, []Diagnostic, error)
+
+// Apply returns the Pass of fn, a function returning a new stylesheet for
+// sheet without diagnostics, such as FlipRTL.
+func Apply(fn func(sheet *StyleSheet) *StyleSheet) Pass {
+	return func(sheet *StyleSheet) (*StyleSheet, []Diagnostic, error) {
+		return fn(sheet), nil, nil
+	}
+}
+
+// Pipeline is a list of passes run in order, each on the result of the
+// one before it.
+type Pipeline struct {
+	Passes []Pass
+	// Stats, if set, is called after each pass that ran, the failing one
+	// included, for build profiling and for telling which pass reported a
+	// diagnostic.
+	Stats func(PassStats)
+}
+
+// PassStats is what Pipeline.Stats is told about a pass.
+type PassStats struct {
+	Index       int // in Pipeline.Passes
+	Elapsed     time.Duration
+	Diagnostics []Diagnostic
+	Err         error
+}
+
+// PassError is the error of the pass Index of a Pipeline, which stopped at
+// it.
+type PassError struct {
+	Index int
+	Err   error
+}
+
+func (e *PassError) Error() string {
+	return fmt.Sprintf("pass %d: %v", e.Index, e.Err)
+}
+
+func (e *PassError) Unwrap() error {
+	return e.Err
+}
+
+var errNoSheet = errors.New("returned no stylesheet")
+
+// Run runs the passes on sheet, as Pipeline.Run does.
+func Run(sheet *StyleSheet, passes ...Pass) (*StyleSheet, []Diagnostic, error) {
+	return Pipeline{Passes: passes}.Run(sheet)
+}
+
+// Run applies the passes of p to sheet in order and returns the result of
+// the last one, with the diagnostics of all of them in the order they ran.
+// sheet itself is not modified. If a pass fails, or returns no stylesheet,
+// Run stops at it and returns the result of the pass before it, the
+// diagnostics so far, those of the failing pass included, and a *PassError.
+func (p Pipeline) Run(sheet *StyleSheet) (*StyleSheet, []Diagnostic, error) {
+	var diags []Diagnostic
+	for i, pass := range p.Passes {
+		start := time.Now()
+		out, d, err := pass(sheet)
+		if err == nil && out == nil {
+			err = errNoSheet
+		}
+		diags = append(diags, d...)
+		if p.Stats != nil {
+			p.Stats(PassStats{Index: i, Elapsed: time.Since(start), Diagnostics: d, Err: err})
+		}
+		if err != nil {
+			return sheet, diags, &PassError{Index: i, Err: err}
+		}
+		sheet = out
+	}
+	return sheet, diags, nil
+}
+
+// Codes of the diagnostics, all SeverityInfo, reported by the passes of
+// ToLogical, RemovePrefixes, UnprefixLone and FilterProperties for the
+// items they return.
+const (
+	DiagLogicalSkipped   = "logical-skipped"
+	DiagPrefixRemoved    = "prefix-removed"
+	DiagPrefixRenamed    = "prefix-renamed"
+	DiagPropertyFiltered = "property-filtered"
+)
+
+// FlipRTLPass returns the Pass of FlipRTL.
+func FlipRTLPass() Pass {
+	return Apply(FlipRTL)
+}
+
+// ReplaceIDSelectorsPass returns the Pass of ReplaceIDSelectors.
+func ReplaceIDSelectorsPass() Pass {
+	return Apply(ReplaceIDSelectors)
+}
+
+// ForMediaPass returns the Pass of ForMedia for mediaType.
+func ForMediaPass(mediaType string) Pass {
+	return Apply(func(sheet *StyleSheet) *StyleSheet {
+		return ForMedia(sheet, mediaType)
+	})
+}
+
+// ForEnvPass returns the Pass of ForEnv for env.
+func ForEnvPass(env MediaEnv) Pass {
+	return Apply(func(sheet *StyleSheet) *StyleSheet {
+		return ForEnv(sheet, env)
+	})
+}
+
+// AddPrefixesPass returns the Pass of AddPrefixes for targets.
+func AddPrefixesPass(targets PrefixTargets) Pass {
+	return Apply(func(sheet *StyleSheet) *StyleSheet {
+		return AddPrefixes(sheet, targets)
+	})
+}
+
+// ResolvePass returns the Pass of ResolveScoped for scopes, or of Resolve
+// if scopes is empty.
+func ResolvePass(scopes []string, opts ResolveOptions) Pass {
+	return Apply(func(sheet *StyleSheet) *StyleSheet {
+		return ResolveScoped(sheet, scopes, opts)
+	})
+}
+
+// IsolateFragileSelectorsPass returns the Pass of IsolateFragileSelectors
+// for fragile.
+func IsolateFragileSelectorsPass(fragile func(sel string) bool) Pass {
+	return Apply(func(sheet *StyleSheet) *StyleSheet {
+		return IsolateFragileSelectors(sheet, fragile)
+	})
+}
+
+// RenameKeyframesPass returns the Pass of RenameKeyframes for rename, which
+// renames the @keyframes of a copy of the sheet it is given.
+func RenameKeyframesPass(rename func(string) string) Pass {
+	return Apply(func(sheet *StyleSheet) *StyleSheet {
+		out := sheet.Clone()
+		RenameKeyframes(out, rename)
+		return out
+	})
+}
+
+// SanitizePass returns the Pass of Sanitize for policy, whose diagnostics
+// are those Sanitize reports to policy.Diagnostics, which is still called
+// if set.
+func SanitizePass(policy Policy) Pass {
+	return func(sheet *StyleSheet) (*StyleSheet, []Diagnostic, error) {
+		var diags []Diagnostic
+		report := policy.Diagnostics
+		policy.Diagnostics = func(d Diagnostic) {
+			diags = append(diags, d)
+			if report != nil {
+				report(d)
+			}
+		}
+		return Sanitize(sheet, policy), diags, nil
+	}
+}
+
+// ToLogicalPass returns the Pass of ToLogical for opts, reporting each
+// declaration it could not rewrite as DiagLogicalSkipped.
+func ToLogicalPass(opts LogicalOptions) Pass {
+	return func(sheet *StyleSheet) (*StyleSheet, []Diagnostic, error) {
+		out, skipped := ToLogical(sheet, opts)
+		var diags []Diagnostic
+		for _, s := range skipped {
+			diags = append(diags, infoDiagnostic(DiagLogicalSkipped, s.Pos, "kept %s: %s, %s", s.Property, s.Value, s.Reason))
+		}
+		return out, diags, nil
+	}
+}
+
+// RemovePrefixesPass returns the Pass of RemovePrefixes for keep, reporting
+// each removed item as DiagPrefixRemoved.
+func RemovePrefixesPass(keep func(prefixedProp string) bool) Pass {
+	return prefixPass(RemovePrefixes, keep)
+}
+
+// UnprefixLonePass returns the Pass of UnprefixLone for keep, reporting
+// each removed item as DiagPrefixRemoved and each renamed one as
+// DiagPrefixRenamed.
+func UnprefixLonePass(keep func(prefixedProp string) bool) Pass {
+	return prefixPass(UnprefixLone, keep)
+}
+
+func prefixPass(fn func(*StyleSheet, func(string) bool) (*StyleSheet, []PrefixRemoval), keep func(string) bool) Pass {
+	return func(sheet *StyleSheet) (*StyleSheet, []Diagnostic, error) {
+		out, removed := fn(sheet, keep)
+		var diags []Diagnostic
+		for _, r := range removed {
+			if r.Renamed {
+				diags = append(diags, infoDiagnostic(DiagPrefixRenamed, r.Pos, "renamed %s to its standard name", r.Name))
+			} else {
+				diags = append(diags, infoDiagnostic(DiagPrefixRemoved, r.Pos, "removed %s", r.Name))
+			}
+		}
+		return out, diags, nil
+	}
+}
+
+// FilterPropertiesPass returns the Pass of FilterProperties for allowed and
+// opts, reporting each dropped declaration as DiagPropertyFiltered.
+func FilterPropertiesPass(allowed func(prop string) bool, opts ...FilterOption) Pass {
+	return func(sheet *StyleSheet) (*StyleSheet, []Diagnostic, error) {
+		out, dropped := FilterProperties(sheet, allowed, opts...)
+		var diags []Diagnostic
+		for _, d := range dropped {
+			msg := "removed %s from %s"
+			args := []interface{}{d.Declaration.Property, d.Selector}
+			if len(d.Longhands) > 0 {
+				msg += ", kept %s"
+				args = append(args, strings.Join(d.Longhands, ", "))
+			}
+			diags = append(diags, infoDiagnostic(DiagPropertyFiltered, d.Declaration.Pos, msg, args...))
+		}
+		return out, diags, nil
+	}
+}
+
+func infoDiagnostic(code string, pos scanner.Position, format string, args ...interface{}) Diagnostic {
+	return Diagnostic{Severity: SeverityInfo, Code: code, Message: fmt.Sprintf(format, args...), Pos: pos}
+}
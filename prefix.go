@@ -0,0 +1,125 @@
+package css
+
+import "strings"
+
+// Canonical splits a vendor-prefixed property name such as -webkit-transform
+// into its standard name and prefix, "transform" and "-webkit-". Names
+// without a vendor prefix, including custom properties, are returned as is
+// with an empty prefix.
+func Canonical(prop string) (base, prefix string) {
+	if n := vendorPrefixLen(prop); n > 0 {
+		return prop[n:], prop[:n]
+	}
+	return prop, ""
+}
+
+// CanonicalValue strips the vendor prefixes from the keywords and function
+// names in value, as in display: -webkit-box or background:
+// -moz-linear-gradient(...), and returns the result with the first prefix
+// found. Quoted strings and url() references are left alone.
+func CanonicalValue(value string) (base, prefix string) {
+	var (
+		b     strings.Builder
+		quote byte
+	)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && i+1 < len(value) {
+				b.WriteByte(c)
+				i++
+				c = value[i]
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case i > 0 && isNameRune(rune(value[i-1])):
+		case strings.HasPrefix(strings.ToLower(value[i:]), "url("):
+			end := strings.IndexByte(value[i:], ')')
+			if end < 0 {
+				end = len(value) - i - 1
+			}
+			b.WriteString(value[i : i+end+1])
+			i += end
+			continue
+		default:
+			if n := vendorPrefixLen(value[i:]); n > 0 {
+				if prefix == "" {
+					prefix = value[i : i+n]
+				}
+				i += n - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), prefix
+}
+
+// vendorPrefixLen returns the length of the -vendor- prefix s starts with,
+// or 0 if it has none.
+func vendorPrefixLen(s string) int {
+	if len(s) < 3 || s[0] != '-' {
+		return 0
+	}
+	i := 1
+	for i < len(s) && (s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z') {
+		i++
+	}
+	if i == 1 || i+1 >= len(s) || s[i] != '-' || !isNameRune(rune(s[i+1])) || s[i+1] == '-' {
+		return 0
+	}
+	return i + 1
+}
+
+// PropertyGroup is the set of declarations in a block that set the same
+// property, with or without a vendor prefix.
+type PropertyGroup struct {
+	Base         string
+	Declarations []Declaration
+}
+
+// Prefixes returns the vendor prefixes used in the group in source order,
+// with "" standing for the unprefixed property.
+func (g PropertyGroup) Prefixes() []string {
+	var prefixes []string
+	seen := make(map[string]bool)
+	for _, d := range g.Declarations {
+		_, p := Canonical(d.Property)
+		if !seen[p] {
+			seen[p] = true
+			prefixes = append(prefixes, p)
+		}
+	}
+	return prefixes
+}
+
+// HasStandard reports whether the group contains the unprefixed property.
+func (g PropertyGroup) HasStandard() bool {
+	for _, d := range g.Declarations {
+		if d.Property == g.Base {
+			return true
+		}
+	}
+	return false
+}
+
+// PrefixGroups groups the declarations of n by their unprefixed property
+// name, in order of first appearance. Every declaration is kept.
+func (n *RuleNode) PrefixGroups() []PropertyGroup {
+	var groups []PropertyGroup
+	index := make(map[string]int)
+	for _, d := range n.Declarations {
+		base, _ := Canonical(d.Property)
+		i, ok := index[base]
+		if !ok {
+			i = len(groups)
+			index[base] = i
+			groups = append(groups, PropertyGroup{Base: base})
+		}
+		groups[i].Declarations = append(groups[i].Declarations, d)
+	}
+	return groups
+}
@@ -0,0 +1,106 @@
+package css
+
+//go:generate go run gen_properties.go
+
+import (
+	"sort"
+	"strings"
+)
+
+// ValueCategory is the coarse category of the values a property takes.
+type ValueCategory int
+
+// Value categories.
+const (
+	// CategoryOther is for values of other or mixed types, such as those
+	// of transform or grid-template-columns.
+	CategoryOther ValueCategory = iota
+	// CategoryShorthand is for shorthands, whose values are those of
+	// their longhands.
+	CategoryShorthand
+	CategoryKeyword
+	// CategoryLength is for lengths and percentages, and the keywords
+	// that may stand for them, such as auto.
+	CategoryLength
+	CategoryNumber
+	CategoryColor
+	CategoryTime
+	CategoryImage
+)
+
+func (c ValueCategory) String() string {
+	switch c {
+	case CategoryShorthand:
+		return "shorthand"
+	case CategoryKeyword:
+		return "keyword"
+	case CategoryLength:
+		return "length"
+	case CategoryNumber:
+		return "number"
+	case CategoryColor:
+		return "color"
+	case CategoryTime:
+		return "time"
+	case CategoryImage:
+		return "image"
+	}
+	return "other"
+}
+
+// PropertyInfo describes a standard property, as known to Lint.
+type PropertyInfo struct {
+	Name      string
+	Inherited bool
+	// Initial is the initial value, as given by InitialValue, or "" if the
+	// property has none in the table.
+	Initial  string
+	Category ValueCategory
+}
+
+// KnownProperties returns the standard properties Lint accepts, sorted by
+// name.
+func KnownProperties() []PropertyInfo {
+	props := make([]PropertyInfo, 0, len(propertyCategories))
+	for name := range propertyCategories {
+		props = append(props, propertyInfo(name))
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	return props
+}
+
+// LookupProperty returns the PropertyInfo of the standard property name,
+// and whether it is known. A vendor-prefixed property has the PropertyInfo
+// of the property it prefixes, if it is not known itself.
+func LookupProperty(name string) (PropertyInfo, bool) {
+	name = asciiLower(strings.TrimSpace(name))
+	if _, ok := propertyCategories[name]; !ok {
+		name, _ = Canonical(name)
+	}
+	if _, ok := propertyCategories[name]; !ok {
+		return PropertyInfo{}, false
+	}
+	return propertyInfo(name), true
+}
+
+// IsInherited reports whether the property prop is inherited by default,
+// and whether it is known: a standard property, looked up as by
+// LookupProperty, or a custom property, which is always inherited. An
+// unknown property is reported as not inherited.
+func IsInherited(prop string) (inherited, known bool) {
+	if strings.HasPrefix(strings.TrimSpace(prop), "--") {
+		return true, true
+	}
+	info, ok := LookupProperty(prop)
+	return info.Inherited, ok
+}
+
+func propertyInfo(name string) PropertyInfo {
+	initial, _ := InitialValue(name)
+	return PropertyInfo{
+		Name:      name,
+		Inherited: inheritedProperties[name],
+		Initial:   initial,
+		Category:  propertyCategories[name],
+	}
+}
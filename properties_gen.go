@@ -0,0 +1,1096 @@
+// Code generated by gen_properties.go from properties.txt; DO NOT EDIT.
+
+package css
+
+// knownProperties is the set of standard CSS property names.
+var knownProperties = map[string]bool{
+	"accent-color":                  true,
+	"align-content":                 true,
+	"align-items":                   true,
+	"align-self":                    true,
+	"align-tracks":                  true,
+	"all":                           true,
+	"anchor-name":                   true,
+	"animation":                     true,
+	"animation-composition":         true,
+	"animation-delay":               true,
+	"animation-direction":           true,
+	"animation-duration":            true,
+	"animation-fill-mode":           true,
+	"animation-iteration-count":     true,
+	"animation-name":                true,
+	"animation-play-state":          true,
+	"animation-range":               true,
+	"animation-range-end":           true,
+	"animation-range-start":         true,
+	"animation-timeline":            true,
+	"animation-timing-function":     true,
+	"appearance":                    true,
+	"aspect-ratio":                  true,
+	"backdrop-filter":               true,
+	"backface-visibility":           true,
+	"background":                    true,
+	"background-attachment":         true,
+	"background-blend-mode":         true,
+	"background-clip":               true,
+	"background-color":              true,
+	"background-image":              true,
+	"background-origin":             true,
+	"background-position":           true,
+	"background-position-x":         true,
+	"background-position-y":         true,
+	"background-repeat":             true,
+	"background-size":               true,
+	"block-size":                    true,
+	"border":                        true,
+	"border-block":                  true,
+	"border-block-color":            true,
+	"border-block-end":              true,
+	"border-block-end-color":        true,
+	"border-block-end-style":        true,
+	"border-block-end-width":        true,
+	"border-block-start":            true,
+	"border-block-start-color":      true,
+	"border-block-start-style":      true,
+	"border-block-start-width":      true,
+	"border-block-style":            true,
+	"border-block-width":            true,
+	"border-bottom":                 true,
+	"border-bottom-color":           true,
+	"border-bottom-left-radius":     true,
+	"border-bottom-right-radius":    true,
+	"border-bottom-style":           true,
+	"border-bottom-width":           true,
+	"border-collapse":               true,
+	"border-color":                  true,
+	"border-end-end-radius":         true,
+	"border-end-start-radius":       true,
+	"border-image":                  true,
+	"border-image-outset":           true,
+	"border-image-repeat":           true,
+	"border-image-slice":            true,
+	"border-image-source":           true,
+	"border-image-width":            true,
+	"border-inline":                 true,
+	"border-inline-color":           true,
+	"border-inline-end":             true,
+	"border-inline-end-color":       true,
+	"border-inline-end-style":       true,
+	"border-inline-end-width":       true,
+	"border-inline-start":           true,
+	"border-inline-start-color":     true,
+	"border-inline-start-style":     true,
+	"border-inline-start-width":     true,
+	"border-inline-style":           true,
+	"border-inline-width":           true,
+	"border-left":                   true,
+	"border-left-color":             true,
+	"border-left-style":             true,
+	"border-left-width":             true,
+	"border-radius":                 true,
+	"border-right":                  true,
+	"border-right-color":            true,
+	"border-right-style":            true,
+	"border-right-width":            true,
+	"border-spacing":                true,
+	"border-start-end-radius":       true,
+	"border-start-start-radius":     true,
+	"border-style":                  true,
+	"border-top":                    true,
+	"border-top-color":              true,
+	"border-top-left-radius":        true,
+	"border-top-right-radius":       true,
+	"border-top-style":              true,
+	"border-top-width":              true,
+	"border-width":                  true,
+	"bottom":                        true,
+	"box-decoration-break":          true,
+	"box-shadow":                    true,
+	"box-sizing":                    true,
+	"break-after":                   true,
+	"break-before":                  true,
+	"break-inside":                  true,
+	"caption-side":                  true,
+	"caret":                         true,
+	"caret-color":                   true,
+	"caret-shape":                   true,
+	"clear":                         true,
+	"clip":                          true,
+	"clip-path":                     true,
+	"clip-rule":                     true,
+	"color":                         true,
+	"color-interpolation":           true,
+	"color-interpolation-filters":   true,
+	"color-rendering":               true,
+	"color-scheme":                  true,
+	"column-count":                  true,
+	"column-fill":                   true,
+	"column-gap":                    true,
+	"column-rule":                   true,
+	"column-rule-color":             true,
+	"column-rule-style":             true,
+	"column-rule-width":             true,
+	"column-span":                   true,
+	"column-width":                  true,
+	"columns":                       true,
+	"contain":                       true,
+	"contain-intrinsic-block-size":  true,
+	"contain-intrinsic-height":      true,
+	"contain-intrinsic-inline-size": true,
+	"contain-intrinsic-size":        true,
+	"contain-intrinsic-width":       true,
+	"container":                     true,
+	"container-name":                true,
+	"container-type":                true,
+	"content":                       true,
+	"content-visibility":            true,
+	"counter-increment":             true,
+	"counter-reset":                 true,
+	"counter-set":                   true,
+	"cursor":                        true,
+	"cx":                            true,
+	"cy":                            true,
+	"d":                             true,
+	"direction":                     true,
+	"display":                       true,
+	"dominant-baseline":             true,
+	"empty-cells":                   true,
+	"field-sizing":                  true,
+	"fill":                          true,
+	"fill-opacity":                  true,
+	"fill-rule":                     true,
+	"filter":                        true,
+	"flex":                          true,
+	"flex-basis":                    true,
+	"flex-direction":                true,
+	"flex-flow":                     true,
+	"flex-grow":                     true,
+	"flex-shrink":                   true,
+	"flex-wrap":                     true,
+	"float":                         true,
+	"flood-color":                   true,
+	"flood-opacity":                 true,
+	"font":                          true,
+	"font-family":                   true,
+	"font-feature-settings":         true,
+	"font-kerning":                  true,
+	"font-language-override":        true,
+	"font-optical-sizing":           true,
+	"font-palette":                  true,
+	"font-size":                     true,
+	"font-size-adjust":              true,
+	"font-stretch":                  true,
+	"font-style":                    true,
+	"font-synthesis":                true,
+	"font-synthesis-position":       true,
+	"font-synthesis-small-caps":     true,
+	"font-synthesis-style":          true,
+	"font-synthesis-weight":         true,
+	"font-variant":                  true,
+	"font-variant-alternates":       true,
+	"font-variant-caps":             true,
+	"font-variant-east-asian":       true,
+	"font-variant-emoji":            true,
+	"font-variant-ligatures":        true,
+	"font-variant-numeric":          true,
+	"font-variant-position":         true,
+	"font-variation-settings":       true,
+	"font-weight":                   true,
+	"forced-color-adjust":           true,
+	"gap":                           true,
+	"grid":                          true,
+	"grid-area":                     true,
+	"grid-auto-columns":             true,
+	"grid-auto-flow":                true,
+	"grid-auto-rows":                true,
+	"grid-column":                   true,
+	"grid-column-end":               true,
+	"grid-column-gap":               true,
+	"grid-column-start":             true,
+	"grid-gap":                      true,
+	"grid-row":                      true,
+	"grid-row-end":                  true,
+	"grid-row-gap":                  true,
+	"grid-row-start":                true,
+	"grid-template":                 true,
+	"grid-template-areas":           true,
+	"grid-template-columns":         true,
+	"grid-template-rows":            true,
+	"hanging-punctuation":           true,
+	"height":                        true,
+	"hyphenate-character":           true,
+	"hyphenate-limit-chars":         true,
+	"hyphens":                       true,
+	"image-orientation":             true,
+	"image-rendering":               true,
+	"image-resolution":              true,
+	"ime-mode":                      true,
+	"initial-letter":                true,
+	"inline-size":                   true,
+	"inset":                         true,
+	"inset-area":                    true,
+	"inset-block":                   true,
+	"inset-block-end":               true,
+	"inset-block-start":             true,
+	"inset-inline":                  true,
+	"inset-inline-end":              true,
+	"inset-inline-start":            true,
+	"isolation":                     true,
+	"justify-content":               true,
+	"justify-items":                 true,
+	"justify-self":                  true,
+	"justify-tracks":                true,
+	"left":                          true,
+	"letter-spacing":                true,
+	"lighting-color":                true,
+	"line-break":                    true,
+	"line-clamp":                    true,
+	"line-height":                   true,
+	"line-height-step":              true,
+	"list-style":                    true,
+	"list-style-image":              true,
+	"list-style-position":           true,
+	"list-style-type":               true,
+	"margin":                        true,
+	"margin-block":                  true,
+	"margin-block-end":              true,
+	"margin-block-start":            true,
+	"margin-bottom":                 true,
+	"margin-inline":                 true,
+	"margin-inline-end":             true,
+	"margin-inline-start":           true,
+	"margin-left":                   true,
+	"margin-right":                  true,
+	"margin-top":                    true,
+	"margin-trim":                   true,
+	"marker":                        true,
+	"marker-end":                    true,
+	"marker-mid":                    true,
+	"marker-start":                  true,
+	"mask":                          true,
+	"mask-border":                   true,
+	"mask-border-mode":              true,
+	"mask-border-outset":            true,
+	"mask-border-repeat":            true,
+	"mask-border-slice":             true,
+	"mask-border-source":            true,
+	"mask-border-width":             true,
+	"mask-clip":                     true,
+	"mask-composite":                true,
+	"mask-image":                    true,
+	"mask-mode":                     true,
+	"mask-origin":                   true,
+	"mask-position":                 true,
+	"mask-repeat":                   true,
+	"mask-size":                     true,
+	"mask-type":                     true,
+	"masonry-auto-flow":             true,
+	"math-depth":                    true,
+	"math-shift":                    true,
+	"math-style":                    true,
+	"max-block-size":                true,
+	"max-height":                    true,
+	"max-inline-size":               true,
+	"max-width":                     true,
+	"min-block-size":                true,
+	"min-height":                    true,
+	"min-inline-size":               true,
+	"min-width":                     true,
+	"mix-blend-mode":                true,
+	"object-fit":                    true,
+	"object-position":               true,
+	"offset":                        true,
+	"offset-anchor":                 true,
+	"offset-distance":               true,
+	"offset-path":                   true,
+	"offset-position":               true,
+	"offset-rotate":                 true,
+	"opacity":                       true,
+	"order":                         true,
+	"orphans":                       true,
+	"outline":                       true,
+	"outline-color":                 true,
+	"outline-offset":                true,
+	"outline-style":                 true,
+	"outline-width":                 true,
+	"overflow":                      true,
+	"overflow-anchor":               true,
+	"overflow-block":                true,
+	"overflow-clip-margin":          true,
+	"overflow-inline":               true,
+	"overflow-wrap":                 true,
+	"overflow-x":                    true,
+	"overflow-y":                    true,
+	"overlay":                       true,
+	"overscroll-behavior":           true,
+	"overscroll-behavior-block":     true,
+	"overscroll-behavior-inline":    true,
+	"overscroll-behavior-x":         true,
+	"overscroll-behavior-y":         true,
+	"padding":                       true,
+	"padding-block":                 true,
+	"padding-block-end":             true,
+	"padding-block-start":           true,
+	"padding-bottom":                true,
+	"padding-inline":                true,
+	"padding-inline-end":            true,
+	"padding-inline-start":          true,
+	"padding-left":                  true,
+	"padding-right":                 true,
+	"padding-top":                   true,
+	"page":                          true,
+	"page-break-after":              true,
+	"page-break-before":             true,
+	"page-break-inside":             true,
+	"paint-order":                   true,
+	"perspective":                   true,
+	"perspective-origin":            true,
+	"place-content":                 true,
+	"place-items":                   true,
+	"place-self":                    true,
+	"pointer-events":                true,
+	"position":                      true,
+	"position-anchor":               true,
+	"position-area":                 true,
+	"position-try":                  true,
+	"position-try-fallbacks":        true,
+	"position-try-order":            true,
+	"position-visibility":           true,
+	"print-color-adjust":            true,
+	"quotes":                        true,
+	"r":                             true,
+	"resize":                        true,
+	"right":                         true,
+	"rotate":                        true,
+	"row-gap":                       true,
+	"ruby-align":                    true,
+	"ruby-merge":                    true,
+	"ruby-position":                 true,
+	"rx":                            true,
+	"ry":                            true,
+	"scale":                         true,
+	"scroll-behavior":               true,
+	"scroll-margin":                 true,
+	"scroll-margin-block":           true,
+	"scroll-margin-block-end":       true,
+	"scroll-margin-block-start":     true,
+	"scroll-margin-bottom":          true,
+	"scroll-margin-inline":          true,
+	"scroll-margin-inline-end":      true,
+	"scroll-margin-inline-start":    true,
+	"scroll-margin-left":            true,
+	"scroll-margin-right":           true,
+	"scroll-margin-top":             true,
+	"scroll-padding":                true,
+	"scroll-padding-block":          true,
+	"scroll-padding-block-end":      true,
+	"scroll-padding-block-start":    true,
+	"scroll-padding-bottom":         true,
+	"scroll-padding-inline":         true,
+	"scroll-padding-inline-end":     true,
+	"scroll-padding-inline-start":   true,
+	"scroll-padding-left":           true,
+	"scroll-padding-right":          true,
+	"scroll-padding-top":            true,
+	"scroll-snap-align":             true,
+	"scroll-snap-stop":              true,
+	"scroll-snap-type":              true,
+	"scroll-timeline":               true,
+	"scroll-timeline-axis":          true,
+	"scroll-timeline-name":          true,
+	"scrollbar-color":               true,
+	"scrollbar-gutter":              true,
+	"scrollbar-width":               true,
+	"shape-image-threshold":         true,
+	"shape-margin":                  true,
+	"shape-outside":                 true,
+	"shape-rendering":               true,
+	"speak":                         true,
+	"speak-as":                      true,
+	"stop-color":                    true,
+	"stop-opacity":                  true,
+	"stroke":                        true,
+	"stroke-dasharray":              true,
+	"stroke-dashoffset":             true,
+	"stroke-linecap":                true,
+	"stroke-linejoin":               true,
+	"stroke-miterlimit":             true,
+	"stroke-opacity":                true,
+	"stroke-width":                  true,
+	"tab-size":                      true,
+	"table-layout":                  true,
+	"text-align":                    true,
+	"text-align-last":               true,
+	"text-anchor":                   true,
+	"text-box":                      true,
+	"text-box-edge":                 true,
+	"text-box-trim":                 true,
+	"text-combine-upright":          true,
+	"text-decoration":               true,
+	"text-decoration-color":         true,
+	"text-decoration-line":          true,
+	"text-decoration-skip":          true,
+	"text-decoration-skip-ink":      true,
+	"text-decoration-style":         true,
+	"text-decoration-thickness":     true,
+	"text-emphasis":                 true,
+	"text-emphasis-color":           true,
+	"text-emphasis-position":        true,
+	"text-emphasis-style":           true,
+	"text-indent":                   true,
+	"text-justify":                  true,
+	"text-orientation":              true,
+	"text-overflow":                 true,
+	"text-rendering":                true,
+	"text-shadow":                   true,
+	"text-size-adjust":              true,
+	"text-spacing-trim":             true,
+	"text-transform":                true,
+	"text-underline-offset":         true,
+	"text-underline-position":       true,
+	"text-wrap":                     true,
+	"text-wrap-mode":                true,
+	"text-wrap-style":               true,
+	"timeline-scope":                true,
+	"top":                           true,
+	"touch-action":                  true,
+	"transform":                     true,
+	"transform-box":                 true,
+	"transform-origin":              true,
+	"transform-style":               true,
+	"transition":                    true,
+	"transition-behavior":           true,
+	"transition-delay":              true,
+	"transition-duration":           true,
+	"transition-property":           true,
+	"transition-timing-function":    true,
+	"translate":                     true,
+	"unicode-bidi":                  true,
+	"user-select":                   true,
+	"vector-effect":                 true,
+	"vertical-align":                true,
+	"view-timeline":                 true,
+	"view-timeline-axis":            true,
+	"view-timeline-inset":           true,
+	"view-timeline-name":            true,
+	"view-transition-class":         true,
+	"view-transition-name":          true,
+	"visibility":                    true,
+	"white-space":                   true,
+	"white-space-collapse":          true,
+	"widows":                        true,
+	"width":                         true,
+	"will-change":                   true,
+	"word-break":                    true,
+	"word-spacing":                  true,
+	"word-wrap":                     true,
+	"writing-mode":                  true,
+	"x":                             true,
+	"y":                             true,
+	"z-index":                       true,
+	"zoom":                          true,
+}
+
+// inheritedProperties is the set of standard properties inherited by default.
+var inheritedProperties = map[string]bool{
+	"accent-color":                true,
+	"border-collapse":             true,
+	"border-spacing":              true,
+	"caption-side":                true,
+	"caret-color":                 true,
+	"clip-rule":                   true,
+	"color":                       true,
+	"color-interpolation":         true,
+	"color-interpolation-filters": true,
+	"color-rendering":             true,
+	"color-scheme":                true,
+	"cursor":                      true,
+	"direction":                   true,
+	"dominant-baseline":           true,
+	"empty-cells":                 true,
+	"fill":                        true,
+	"fill-opacity":                true,
+	"fill-rule":                   true,
+	"font":                        true,
+	"font-family":                 true,
+	"font-feature-settings":       true,
+	"font-kerning":                true,
+	"font-language-override":      true,
+	"font-optical-sizing":         true,
+	"font-palette":                true,
+	"font-size":                   true,
+	"font-size-adjust":            true,
+	"font-stretch":                true,
+	"font-style":                  true,
+	"font-synthesis":              true,
+	"font-synthesis-small-caps":   true,
+	"font-synthesis-style":        true,
+	"font-synthesis-weight":       true,
+	"font-variant":                true,
+	"font-variant-alternates":     true,
+	"font-variant-caps":           true,
+	"font-variant-east-asian":     true,
+	"font-variant-emoji":          true,
+	"font-variant-ligatures":      true,
+	"font-variant-numeric":        true,
+	"font-variant-position":       true,
+	"font-variation-settings":     true,
+	"font-weight":                 true,
+	"forced-color-adjust":         true,
+	"hanging-punctuation":         true,
+	"hyphenate-character":         true,
+	"hyphenate-limit-chars":       true,
+	"hyphens":                     true,
+	"image-orientation":           true,
+	"image-rendering":             true,
+	"letter-spacing":              true,
+	"line-break":                  true,
+	"line-height":                 true,
+	"list-style":                  true,
+	"list-style-image":            true,
+	"list-style-position":         true,
+	"list-style-type":             true,
+	"marker":                      true,
+	"marker-end":                  true,
+	"marker-mid":                  true,
+	"marker-start":                true,
+	"math-depth":                  true,
+	"math-shift":                  true,
+	"math-style":                  true,
+	"orphans":                     true,
+	"overflow-wrap":               true,
+	"paint-order":                 true,
+	"pointer-events":              true,
+	"print-color-adjust":          true,
+	"quotes":                      true,
+	"ruby-align":                  true,
+	"ruby-position":               true,
+	"shape-rendering":             true,
+	"stroke":                      true,
+	"stroke-dasharray":            true,
+	"stroke-dashoffset":           true,
+	"stroke-linecap":              true,
+	"stroke-linejoin":             true,
+	"stroke-miterlimit":           true,
+	"stroke-opacity":              true,
+	"stroke-width":                true,
+	"tab-size":                    true,
+	"text-align":                  true,
+	"text-align-last":             true,
+	"text-anchor":                 true,
+	"text-combine-upright":        true,
+	"text-decoration-skip-ink":    true,
+	"text-emphasis":               true,
+	"text-emphasis-color":         true,
+	"text-emphasis-position":      true,
+	"text-emphasis-style":         true,
+	"text-indent":                 true,
+	"text-justify":                true,
+	"text-orientation":            true,
+	"text-rendering":              true,
+	"text-shadow":                 true,
+	"text-size-adjust":            true,
+	"text-transform":              true,
+	"text-underline-offset":       true,
+	"text-underline-position":     true,
+	"text-wrap":                   true,
+	"text-wrap-mode":              true,
+	"text-wrap-style":             true,
+	"visibility":                  true,
+	"white-space":                 true,
+	"white-space-collapse":        true,
+	"widows":                      true,
+	"word-break":                  true,
+	"word-spacing":                true,
+	"word-wrap":                   true,
+	"writing-mode":                true,
+}
+
+// propertyCategories maps the standard properties to the category of their value.
+var propertyCategories = map[string]ValueCategory{
+	"accent-color":                  CategoryColor,
+	"align-content":                 CategoryKeyword,
+	"align-items":                   CategoryKeyword,
+	"align-self":                    CategoryKeyword,
+	"align-tracks":                  CategoryOther,
+	"all":                           CategoryShorthand,
+	"anchor-name":                   CategoryOther,
+	"animation":                     CategoryShorthand,
+	"animation-composition":         CategoryKeyword,
+	"animation-delay":               CategoryTime,
+	"animation-direction":           CategoryKeyword,
+	"animation-duration":            CategoryTime,
+	"animation-fill-mode":           CategoryKeyword,
+	"animation-iteration-count":     CategoryNumber,
+	"animation-name":                CategoryOther,
+	"animation-play-state":          CategoryKeyword,
+	"animation-range":               CategoryShorthand,
+	"animation-range-end":           CategoryOther,
+	"animation-range-start":         CategoryOther,
+	"animation-timeline":            CategoryOther,
+	"animation-timing-function":     CategoryOther,
+	"appearance":                    CategoryKeyword,
+	"aspect-ratio":                  CategoryOther,
+	"backdrop-filter":               CategoryOther,
+	"backface-visibility":           CategoryKeyword,
+	"background":                    CategoryShorthand,
+	"background-attachment":         CategoryKeyword,
+	"background-blend-mode":         CategoryKeyword,
+	"background-clip":               CategoryKeyword,
+	"background-color":              CategoryColor,
+	"background-image":              CategoryImage,
+	"background-origin":             CategoryKeyword,
+	"background-position":           CategoryOther,
+	"background-position-x":         CategoryOther,
+	"background-position-y":         CategoryOther,
+	"background-repeat":             CategoryKeyword,
+	"background-size":               CategoryLength,
+	"block-size":                    CategoryLength,
+	"border":                        CategoryShorthand,
+	"border-block":                  CategoryShorthand,
+	"border-block-color":            CategoryShorthand,
+	"border-block-end":              CategoryShorthand,
+	"border-block-end-color":        CategoryColor,
+	"border-block-end-style":        CategoryKeyword,
+	"border-block-end-width":        CategoryLength,
+	"border-block-start":            CategoryShorthand,
+	"border-block-start-color":      CategoryColor,
+	"border-block-start-style":      CategoryKeyword,
+	"border-block-start-width":      CategoryLength,
+	"border-block-style":            CategoryShorthand,
+	"border-block-width":            CategoryShorthand,
+	"border-bottom":                 CategoryShorthand,
+	"border-bottom-color":           CategoryColor,
+	"border-bottom-left-radius":     CategoryLength,
+	"border-bottom-right-radius":    CategoryLength,
+	"border-bottom-style":           CategoryKeyword,
+	"border-bottom-width":           CategoryLength,
+	"border-collapse":               CategoryKeyword,
+	"border-color":                  CategoryShorthand,
+	"border-end-end-radius":         CategoryLength,
+	"border-end-start-radius":       CategoryLength,
+	"border-image":                  CategoryShorthand,
+	"border-image-outset":           CategoryLength,
+	"border-image-repeat":           CategoryKeyword,
+	"border-image-slice":            CategoryNumber,
+	"border-image-source":           CategoryImage,
+	"border-image-width":            CategoryLength,
+	"border-inline":                 CategoryShorthand,
+	"border-inline-color":           CategoryShorthand,
+	"border-inline-end":             CategoryShorthand,
+	"border-inline-end-color":       CategoryColor,
+	"border-inline-end-style":       CategoryKeyword,
+	"border-inline-end-width":       CategoryLength,
+	"border-inline-start":           CategoryShorthand,
+	"border-inline-start-color":     CategoryColor,
+	"border-inline-start-style":     CategoryKeyword,
+	"border-inline-start-width":     CategoryLength,
+	"border-inline-style":           CategoryShorthand,
+	"border-inline-width":           CategoryShorthand,
+	"border-left":                   CategoryShorthand,
+	"border-left-color":             CategoryColor,
+	"border-left-style":             CategoryKeyword,
+	"border-left-width":             CategoryLength,
+	"border-radius":                 CategoryShorthand,
+	"border-right":                  CategoryShorthand,
+	"border-right-color":            CategoryColor,
+	"border-right-style":            CategoryKeyword,
+	"border-right-width":            CategoryLength,
+	"border-spacing":                CategoryLength,
+	"border-start-end-radius":       CategoryLength,
+	"border-start-start-radius":     CategoryLength,
+	"border-style":                  CategoryShorthand,
+	"border-top":                    CategoryShorthand,
+	"border-top-color":              CategoryColor,
+	"border-top-left-radius":        CategoryLength,
+	"border-top-right-radius":       CategoryLength,
+	"border-top-style":              CategoryKeyword,
+	"border-top-width":              CategoryLength,
+	"border-width":                  CategoryShorthand,
+	"bottom":                        CategoryLength,
+	"box-decoration-break":          CategoryKeyword,
+	"box-shadow":                    CategoryOther,
+	"box-sizing":                    CategoryKeyword,
+	"break-after":                   CategoryKeyword,
+	"break-before":                  CategoryKeyword,
+	"break-inside":                  CategoryKeyword,
+	"caption-side":                  CategoryKeyword,
+	"caret":                         CategoryShorthand,
+	"caret-color":                   CategoryColor,
+	"caret-shape":                   CategoryKeyword,
+	"clear":                         CategoryKeyword,
+	"clip":                          CategoryOther,
+	"clip-path":                     CategoryOther,
+	"clip-rule":                     CategoryKeyword,
+	"color":                         CategoryColor,
+	"color-interpolation":           CategoryKeyword,
+	"color-interpolation-filters":   CategoryKeyword,
+	"color-rendering":               CategoryKeyword,
+	"color-scheme":                  CategoryOther,
+	"column-count":                  CategoryNumber,
+	"column-fill":                   CategoryKeyword,
+	"column-gap":                    CategoryLength,
+	"column-rule":                   CategoryShorthand,
+	"column-rule-color":             CategoryColor,
+	"column-rule-style":             CategoryKeyword,
+	"column-rule-width":             CategoryLength,
+	"column-span":                   CategoryKeyword,
+	"column-width":                  CategoryLength,
+	"columns":                       CategoryShorthand,
+	"contain":                       CategoryKeyword,
+	"contain-intrinsic-block-size":  CategoryLength,
+	"contain-intrinsic-height":      CategoryLength,
+	"contain-intrinsic-inline-size": CategoryLength,
+	"contain-intrinsic-size":        CategoryShorthand,
+	"contain-intrinsic-width":       CategoryLength,
+	"container":                     CategoryShorthand,
+	"container-name":                CategoryOther,
+	"container-type":                CategoryKeyword,
+	"content":                       CategoryOther,
+	"content-visibility":            CategoryKeyword,
+	"counter-increment":             CategoryOther,
+	"counter-reset":                 CategoryOther,
+	"counter-set":                   CategoryOther,
+	"cursor":                        CategoryOther,
+	"cx":                            CategoryLength,
+	"cy":                            CategoryLength,
+	"d":                             CategoryOther,
+	"direction":                     CategoryKeyword,
+	"display":                       CategoryKeyword,
+	"dominant-baseline":             CategoryKeyword,
+	"empty-cells":                   CategoryKeyword,
+	"field-sizing":                  CategoryKeyword,
+	"fill":                          CategoryOther,
+	"fill-opacity":                  CategoryNumber,
+	"fill-rule":                     CategoryKeyword,
+	"filter":                        CategoryOther,
+	"flex":                          CategoryShorthand,
+	"flex-basis":                    CategoryLength,
+	"flex-direction":                CategoryOther,
+	"flex-flow":                     CategoryShorthand,
+	"flex-grow":                     CategoryNumber,
+	"flex-shrink":                   CategoryNumber,
+	"flex-wrap":                     CategoryOther,
+	"float":                         CategoryKeyword,
+	"flood-color":                   CategoryColor,
+	"flood-opacity":                 CategoryNumber,
+	"font":                          CategoryShorthand,
+	"font-family":                   CategoryOther,
+	"font-feature-settings":         CategoryOther,
+	"font-kerning":                  CategoryKeyword,
+	"font-language-override":        CategoryOther,
+	"font-optical-sizing":           CategoryKeyword,
+	"font-palette":                  CategoryOther,
+	"font-size":                     CategoryLength,
+	"font-size-adjust":              CategoryNumber,
+	"font-stretch":                  CategoryKeyword,
+	"font-style":                    CategoryKeyword,
+	"font-synthesis":                CategoryShorthand,
+	"font-synthesis-position":       CategoryKeyword,
+	"font-synthesis-small-caps":     CategoryKeyword,
+	"font-synthesis-style":          CategoryKeyword,
+	"font-synthesis-weight":         CategoryKeyword,
+	"font-variant":                  CategoryShorthand,
+	"font-variant-alternates":       CategoryKeyword,
+	"font-variant-caps":             CategoryKeyword,
+	"font-variant-east-asian":       CategoryKeyword,
+	"font-variant-emoji":            CategoryKeyword,
+	"font-variant-ligatures":        CategoryKeyword,
+	"font-variant-numeric":          CategoryKeyword,
+	"font-variant-position":         CategoryKeyword,
+	"font-variation-settings":       CategoryOther,
+	"font-weight":                   CategoryNumber,
+	"forced-color-adjust":           CategoryKeyword,
+	"gap":                           CategoryShorthand,
+	"grid":                          CategoryShorthand,
+	"grid-area":                     CategoryShorthand,
+	"grid-auto-columns":             CategoryOther,
+	"grid-auto-flow":                CategoryKeyword,
+	"grid-auto-rows":                CategoryOther,
+	"grid-column":                   CategoryShorthand,
+	"grid-column-end":               CategoryOther,
+	"grid-column-gap":               CategoryLength,
+	"grid-column-start":             CategoryOther,
+	"grid-gap":                      CategoryShorthand,
+	"grid-row":                      CategoryShorthand,
+	"grid-row-end":                  CategoryOther,
+	"grid-row-gap":                  CategoryLength,
+	"grid-row-start":                CategoryOther,
+	"grid-template":                 CategoryShorthand,
+	"grid-template-areas":           CategoryOther,
+	"grid-template-columns":         CategoryOther,
+	"grid-template-rows":            CategoryOther,
+	"hanging-punctuation":           CategoryKeyword,
+	"height":                        CategoryLength,
+	"hyphenate-character":           CategoryOther,
+	"hyphenate-limit-chars":         CategoryOther,
+	"hyphens":                       CategoryKeyword,
+	"image-orientation":             CategoryKeyword,
+	"image-rendering":               CategoryKeyword,
+	"image-resolution":              CategoryOther,
+	"ime-mode":                      CategoryKeyword,
+	"initial-letter":                CategoryNumber,
+	"inline-size":                   CategoryLength,
+	"inset":                         CategoryShorthand,
+	"inset-area":                    CategoryOther,
+	"inset-block":                   CategoryShorthand,
+	"inset-block-end":               CategoryLength,
+	"inset-block-start":             CategoryLength,
+	"inset-inline":                  CategoryShorthand,
+	"inset-inline-end":              CategoryLength,
+	"inset-inline-start":            CategoryLength,
+	"isolation":                     CategoryKeyword,
+	"justify-content":               CategoryKeyword,
+	"justify-items":                 CategoryKeyword,
+	"justify-self":                  CategoryKeyword,
+	"justify-tracks":                CategoryOther,
+	"left":                          CategoryLength,
+	"letter-spacing":                CategoryLength,
+	"lighting-color":                CategoryColor,
+	"line-break":                    CategoryKeyword,
+	"line-clamp":                    CategoryNumber,
+	"line-height":                   CategoryLength,
+	"line-height-step":              CategoryLength,
+	"list-style":                    CategoryShorthand,
+	"list-style-image":              CategoryImage,
+	"list-style-position":           CategoryKeyword,
+	"list-style-type":               CategoryKeyword,
+	"margin":                        CategoryShorthand,
+	"margin-block":                  CategoryShorthand,
+	"margin-block-end":              CategoryLength,
+	"margin-block-start":            CategoryLength,
+	"margin-bottom":                 CategoryLength,
+	"margin-inline":                 CategoryShorthand,
+	"margin-inline-end":             CategoryLength,
+	"margin-inline-start":           CategoryLength,
+	"margin-left":                   CategoryLength,
+	"margin-right":                  CategoryLength,
+	"margin-top":                    CategoryLength,
+	"margin-trim":                   CategoryKeyword,
+	"marker":                        CategoryShorthand,
+	"marker-end":                    CategoryOther,
+	"marker-mid":                    CategoryOther,
+	"marker-start":                  CategoryOther,
+	"mask":                          CategoryShorthand,
+	"mask-border":                   CategoryShorthand,
+	"mask-border-mode":              CategoryKeyword,
+	"mask-border-outset":            CategoryLength,
+	"mask-border-repeat":            CategoryKeyword,
+	"mask-border-slice":             CategoryNumber,
+	"mask-border-source":            CategoryImage,
+	"mask-border-width":             CategoryLength,
+	"mask-clip":                     CategoryKeyword,
+	"mask-composite":                CategoryKeyword,
+	"mask-image":                    CategoryImage,
+	"mask-mode":                     CategoryKeyword,
+	"mask-origin":                   CategoryKeyword,
+	"mask-position":                 CategoryOther,
+	"mask-repeat":                   CategoryKeyword,
+	"mask-size":                     CategoryLength,
+	"mask-type":                     CategoryKeyword,
+	"masonry-auto-flow":             CategoryKeyword,
+	"math-depth":                    CategoryNumber,
+	"math-shift":                    CategoryKeyword,
+	"math-style":                    CategoryKeyword,
+	"max-block-size":                CategoryLength,
+	"max-height":                    CategoryLength,
+	"max-inline-size":               CategoryLength,
+	"max-width":                     CategoryLength,
+	"min-block-size":                CategoryLength,
+	"min-height":                    CategoryLength,
+	"min-inline-size":               CategoryLength,
+	"min-width":                     CategoryLength,
+	"mix-blend-mode":                CategoryKeyword,
+	"object-fit":                    CategoryKeyword,
+	"object-position":               CategoryOther,
+	"offset":                        CategoryShorthand,
+	"offset-anchor":                 CategoryOther,
+	"offset-distance":               CategoryLength,
+	"offset-path":                   CategoryOther,
+	"offset-position":               CategoryOther,
+	"offset-rotate":                 CategoryOther,
+	"opacity":                       CategoryNumber,
+	"order":                         CategoryNumber,
+	"orphans":                       CategoryNumber,
+	"outline":                       CategoryShorthand,
+	"outline-color":                 CategoryColor,
+	"outline-offset":                CategoryLength,
+	"outline-style":                 CategoryKeyword,
+	"outline-width":                 CategoryLength,
+	"overflow":                      CategoryShorthand,
+	"overflow-anchor":               CategoryKeyword,
+	"overflow-block":                CategoryKeyword,
+	"overflow-clip-margin":          CategoryLength,
+	"overflow-inline":               CategoryKeyword,
+	"overflow-wrap":                 CategoryKeyword,
+	"overflow-x":                    CategoryKeyword,
+	"overflow-y":                    CategoryKeyword,
+	"overlay":                       CategoryKeyword,
+	"overscroll-behavior":           CategoryShorthand,
+	"overscroll-behavior-block":     CategoryKeyword,
+	"overscroll-behavior-inline":    CategoryKeyword,
+	"overscroll-behavior-x":         CategoryKeyword,
+	"overscroll-behavior-y":         CategoryKeyword,
+	"padding":                       CategoryShorthand,
+	"padding-block":                 CategoryShorthand,
+	"padding-block-end":             CategoryLength,
+	"padding-block-start":           CategoryLength,
+	"padding-bottom":                CategoryLength,
+	"padding-inline":                CategoryShorthand,
+	"padding-inline-end":            CategoryLength,
+	"padding-inline-start":          CategoryLength,
+	"padding-left":                  CategoryLength,
+	"padding-right":                 CategoryLength,
+	"padding-top":                   CategoryLength,
+	"page":                          CategoryOther,
+	"page-break-after":              CategoryKeyword,
+	"page-break-before":             CategoryKeyword,
+	"page-break-inside":             CategoryKeyword,
+	"paint-order":                   CategoryKeyword,
+	"perspective":                   CategoryLength,
+	"perspective-origin":            CategoryOther,
+	"place-content":                 CategoryShorthand,
+	"place-items":                   CategoryShorthand,
+	"place-self":                    CategoryShorthand,
+	"pointer-events":                CategoryKeyword,
+	"position":                      CategoryKeyword,
+	"position-anchor":               CategoryOther,
+	"position-area":                 CategoryOther,
+	"position-try":                  CategoryShorthand,
+	"position-try-fallbacks":        CategoryOther,
+	"position-try-order":            CategoryKeyword,
+	"position-visibility":           CategoryKeyword,
+	"print-color-adjust":            CategoryKeyword,
+	"quotes":                        CategoryOther,
+	"r":                             CategoryLength,
+	"resize":                        CategoryKeyword,
+	"right":                         CategoryLength,
+	"rotate":                        CategoryOther,
+	"row-gap":                       CategoryLength,
+	"ruby-align":                    CategoryKeyword,
+	"ruby-merge":                    CategoryKeyword,
+	"ruby-position":                 CategoryKeyword,
+	"rx":                            CategoryLength,
+	"ry":                            CategoryLength,
+	"scale":                         CategoryOther,
+	"scroll-behavior":               CategoryKeyword,
+	"scroll-margin":                 CategoryShorthand,
+	"scroll-margin-block":           CategoryShorthand,
+	"scroll-margin-block-end":       CategoryLength,
+	"scroll-margin-block-start":     CategoryLength,
+	"scroll-margin-bottom":          CategoryLength,
+	"scroll-margin-inline":          CategoryShorthand,
+	"scroll-margin-inline-end":      CategoryLength,
+	"scroll-margin-inline-start":    CategoryLength,
+	"scroll-margin-left":            CategoryLength,
+	"scroll-margin-right":           CategoryLength,
+	"scroll-margin-top":             CategoryLength,
+	"scroll-padding":                CategoryShorthand,
+	"scroll-padding-block":          CategoryShorthand,
+	"scroll-padding-block-end":      CategoryLength,
+	"scroll-padding-block-start":    CategoryLength,
+	"scroll-padding-bottom":         CategoryLength,
+	"scroll-padding-inline":         CategoryShorthand,
+	"scroll-padding-inline-end":     CategoryLength,
+	"scroll-padding-inline-start":   CategoryLength,
+	"scroll-padding-left":           CategoryLength,
+	"scroll-padding-right":          CategoryLength,
+	"scroll-padding-top":            CategoryLength,
+	"scroll-snap-align":             CategoryKeyword,
+	"scroll-snap-stop":              CategoryKeyword,
+	"scroll-snap-type":              CategoryOther,
+	"scroll-timeline":               CategoryShorthand,
+	"scroll-timeline-axis":          CategoryKeyword,
+	"scroll-timeline-name":          CategoryOther,
+	"scrollbar-color":               CategoryColor,
+	"scrollbar-gutter":              CategoryKeyword,
+	"scrollbar-width":               CategoryKeyword,
+	"shape-image-threshold":         CategoryNumber,
+	"shape-margin":                  CategoryLength,
+	"shape-outside":                 CategoryOther,
+	"shape-rendering":               CategoryKeyword,
+	"speak":                         CategoryOther,
+	"speak-as":                      CategoryOther,
+	"stop-color":                    CategoryColor,
+	"stop-opacity":                  CategoryNumber,
+	"stroke":                        CategoryOther,
+	"stroke-dasharray":              CategoryOther,
+	"stroke-dashoffset":             CategoryLength,
+	"stroke-linecap":                CategoryKeyword,
+	"stroke-linejoin":               CategoryKeyword,
+	"stroke-miterlimit":             CategoryNumber,
+	"stroke-opacity":                CategoryNumber,
+	"stroke-width":                  CategoryLength,
+	"tab-size":                      CategoryLength,
+	"table-layout":                  CategoryKeyword,
+	"text-align":                    CategoryKeyword,
+	"text-align-last":               CategoryKeyword,
+	"text-anchor":                   CategoryKeyword,
+	"text-box":                      CategoryShorthand,
+	"text-box-edge":                 CategoryKeyword,
+	"text-box-trim":                 CategoryKeyword,
+	"text-combine-upright":          CategoryKeyword,
+	"text-decoration":               CategoryShorthand,
+	"text-decoration-color":         CategoryColor,
+	"text-decoration-line":          CategoryKeyword,
+	"text-decoration-skip":          CategoryOther,
+	"text-decoration-skip-ink":      CategoryKeyword,
+	"text-decoration-style":         CategoryKeyword,
+	"text-decoration-thickness":     CategoryLength,
+	"text-emphasis":                 CategoryShorthand,
+	"text-emphasis-color":           CategoryColor,
+	"text-emphasis-position":        CategoryKeyword,
+	"text-emphasis-style":           CategoryOther,
+	"text-indent":                   CategoryLength,
+	"text-justify":                  CategoryKeyword,
+	"text-orientation":              CategoryKeyword,
+	"text-overflow":                 CategoryKeyword,
+	"text-rendering":                CategoryKeyword,
+	"text-shadow":                   CategoryOther,
+	"text-size-adjust":              CategoryOther,
+	"text-spacing-trim":             CategoryKeyword,
+	"text-transform":                CategoryKeyword,
+	"text-underline-offset":         CategoryLength,
+	"text-underline-position":       CategoryKeyword,
+	"text-wrap":                     CategoryShorthand,
+	"text-wrap-mode":                CategoryKeyword,
+	"text-wrap-style":               CategoryKeyword,
+	"timeline-scope":                CategoryOther,
+	"top":                           CategoryLength,
+	"touch-action":                  CategoryKeyword,
+	"transform":                     CategoryOther,
+	"transform-box":                 CategoryKeyword,
+	"transform-origin":              CategoryOther,
+	"transform-style":               CategoryKeyword,
+	"transition":                    CategoryShorthand,
+	"transition-behavior":           CategoryKeyword,
+	"transition-delay":              CategoryTime,
+	"transition-duration":           CategoryTime,
+	"transition-property":           CategoryOther,
+	"transition-timing-function":    CategoryOther,
+	"translate":                     CategoryOther,
+	"unicode-bidi":                  CategoryKeyword,
+	"user-select":                   CategoryKeyword,
+	"vector-effect":                 CategoryKeyword,
+	"vertical-align":                CategoryLength,
+	"view-timeline":                 CategoryShorthand,
+	"view-timeline-axis":            CategoryKeyword,
+	"view-timeline-inset":           CategoryOther,
+	"view-timeline-name":            CategoryOther,
+	"view-transition-class":         CategoryOther,
+	"view-transition-name":          CategoryOther,
+	"visibility":                    CategoryKeyword,
+	"white-space":                   CategoryShorthand,
+	"white-space-collapse":          CategoryKeyword,
+	"widows":                        CategoryNumber,
+	"width":                         CategoryLength,
+	"will-change":                   CategoryOther,
+	"word-break":                    CategoryKeyword,
+	"word-spacing":                  CategoryLength,
+	"word-wrap":                     CategoryKeyword,
+	"writing-mode":                  CategoryKeyword,
+	"x":                             CategoryLength,
+	"y":                             CategoryLength,
+	"z-index":                       CategoryNumber,
+	"zoom":                          CategoryNumber,
+}
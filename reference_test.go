@@ -0,0 +1,933 @@
+package css
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestReference checks Unmarshal against a reference parse of the
+// stylesheets of testdata: the tokenization and parsing of CSS Syntax
+// Level 3, written out as the specification words them, with the merge of
+// Unmarshal on top. Selectors and values are compared by their component
+// values, not their text, so that the normalizations of Unmarshal, such as
+// the spaces of "rgb(0, 0, 0)", do not count, while a value cut short or
+// run on into the next declaration does.
+//
+// The mismatches that are known, listed in testdata/reference/known.txt,
+// are expected; a listed mismatch that no longer occurs fails the test too,
+// so that the list is kept to those left.
+func TestReference(t *testing.T) {
+	known := readKnownMismatches(t, filepath.Join("testdata", "reference", "known.txt"))
+	var files []string
+	for _, dir := range []string{"reference", "corpus", "v1", "bench"} {
+		names, err := filepath.Glob(filepath.Join("testdata", dir, "*.css"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, names...)
+	}
+	compared := 0
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := Unmarshal(b)
+		var mismatches []string
+		switch {
+		case err == nil:
+			compared++
+			mismatches = compareReference(got, refStyles(string(b)))
+		case filepath.Base(filepath.Dir(name)) == "reference":
+			// The stylesheets of testdata/reference are all well-formed,
+			// and so an error is a mismatch.
+			mismatches = []string{fmt.Sprintf("Unmarshal: %v", err)}
+		}
+		name = filepath.ToSlash(name)
+		for _, m := range mismatches {
+			m = name + ": " + m
+			if known[m] {
+				delete(known, m)
+				continue
+			}
+			t.Error(m)
+		}
+	}
+	if compared < len(files)/2 {
+		t.Errorf("only %d of %d stylesheets are read without error", compared, len(files))
+	}
+	for m := range known {
+		t.Errorf("known mismatch no longer occurs: %s", m)
+	}
+}
+
+// TestCompareReference checks that compareReference reports the values
+// Unmarshal could get wrong, and not its normalizations.
+func TestCompareReference(t *testing.T) {
+	const src = `.a, DIV > p { content: "x; y"; margin: 0 auto !important; color: rgb(0,0,0) } .a { margin: 1px }`
+	tests := []struct {
+		name string
+		got  map[Rule]map[string]string
+		want []string
+	}{
+		{"normalized", map[Rule]map[string]string{
+			".a":      {"content": `"x; y"`, "margin": "0 auto !important", "color": "rgb(0, 0, 0)"},
+			"div > p": {"content": "'x; y'", "margin": "0 auto ! important", "color": "rgb( 0 , 0 , 0 )"},
+		}, nil},
+		{"cut short", map[Rule]map[string]string{
+			".a":      {"content": `"x`, "margin": "0 auto !important", "color": "rgb(0, 0, 0)"},
+			"div > p": {"content": `"x; y"`, "margin": "0", "color": "rgb(0, 0, 0)"},
+		}, []string{
+			`.a { content: "x" }, want "x; y"`,
+			`div>p { margin: 0 }, want 0 auto !important`,
+		}},
+		{"run on", map[Rule]map[string]string{
+			".a":      {"content": `"x; y"; margin: 0 auto`, "color": "rgb(0, 0, 0)"},
+			"div > p": {"content": `"x; y"`, "margin": "0 auto !important", "color": "rgb(0, 0, 0)", "y": ""},
+			"div p":   {},
+		}, []string{
+			`.a { content: "x; y"; margin: 0 auto }, want "x; y"`,
+			`.a { margin } is missing`,
+			`div>p { y } is extra`,
+			`selector div p is extra`,
+		}},
+	}
+	for _, tt := range tests {
+		got := compareReference(tt.got, refStyles(src))
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got mismatches\n%s\nwant\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+// TestRefTokens checks the reference tokenizer on the cases of CSS Syntax
+// that the parser is most likely to read otherwise.
+func TestRefTokens(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"url(  a.png  ) url( 'a' )", `url("a.png") url("a")`},
+		{"url(a b) url(a\\)b)", "BAD-URL url(\"a)b\")"},
+		{"\"a\\\nb\" 'c\nd", `"ab" BAD-STRING"c" d`},
+		{"1e3 +.5 -0.0 1E-2PX 50%", "1000 0.5 -0 0.01px 50%"},
+		{"\\31 0 \\66oo a\\ b", "10 foo a\\ b"},
+		{"a/**/b a/*c*/ b /* open", "a/**/b a b"},
+		{"<!-- a --> -x --y", "<!-- a --> -x --y"},
+		{"U+0025-00FF f( a , b )", "U/**/25/**/-0ff f(a,b)"},
+	}
+	for _, tt := range tests {
+		if got := refCanon(refTokens(tt.src), false); got != tt.want {
+			t.Errorf("refCanon(refTokens(%q)) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+// readKnownMismatches reads a file of mismatches, one per line, with blank
+// lines and lines starting with '#' left out.
+func readKnownMismatches(t *testing.T, name string) map[string]bool {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	known := make(map[string]bool)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && line[0] != '#' {
+			known[line] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return known
+}
+
+// compareReference returns the differences between the result of
+// Unmarshal and want, the reference result, in the order of the selectors
+// and properties of want.
+func compareReference(got map[Rule]map[string]string, want *refResult) []string {
+	var mismatches []string
+	keys := make(map[string]Rule)
+	for sel := range got {
+		k := refCanon(refTokens(string(sel)), true)
+		if other, ok := keys[k]; ok {
+			mismatches = append(mismatches, fmt.Sprintf("selectors %q and %q are one", other, sel))
+		}
+		keys[k] = sel
+	}
+	for _, k := range want.order {
+		sel, ok := keys[k]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("selector %s is missing", k))
+			continue
+		}
+		delete(keys, k)
+		props := got[sel]
+		wantProps := want.styles[k]
+		for _, p := range wantProps.order {
+			v, ok := props[p]
+			if !ok {
+				mismatches = append(mismatches, fmt.Sprintf("%s { %s } is missing", k, p))
+				continue
+			}
+			if v := refValue(v); v != wantProps.values[p] {
+				mismatches = append(mismatches, fmt.Sprintf("%s { %s: %s }, want %s", k, p, v, wantProps.values[p]))
+			}
+		}
+		for p := range props {
+			if _, ok := wantProps.values[p]; !ok {
+				mismatches = append(mismatches, fmt.Sprintf("%s { %s } is extra", k, p))
+			}
+		}
+	}
+	var extra []string
+	for k := range keys {
+		if !want.partial && !want.skipped[k] {
+			extra = append(extra, fmt.Sprintf("selector %s is extra", k))
+		}
+	}
+	sort.Strings(extra)
+	return append(mismatches, extra...)
+}
+
+// refValue returns the value v of Unmarshal in the form of refStyles.
+func refValue(v string) string {
+	toks := refTokens(v)
+	important := false
+	if rest, ok := refImportant(toks); ok {
+		toks, important = rest, true
+	}
+	return refDeclValue(toks, important)
+}
+
+// refDeclValue returns the value toks, with the !important flag, as
+// refStyles keeps it.
+func refDeclValue(toks []refToken, important bool) string {
+	v := refCanon(toks, false)
+	if important {
+		v += " !important"
+	}
+	return v
+}
+
+type refResult struct {
+	styles map[string]*refProps
+	order  []string // the selectors in the order they are first seen
+	// skipped holds the selectors of rules the reference does not resolve,
+	// nested rules and rules in @layer blocks, which are not compared.
+	skipped map[string]bool
+	// partial is set if Unmarshal may have selectors the reference lacks,
+	// as nested rules give.
+	partial bool
+}
+
+type refProps struct {
+	values    map[string]string
+	important map[string]bool
+	order     []string
+}
+
+// refStyles returns what Unmarshal should give for src, by the reference
+// parse: for each selector of the top-level style rules, its declarations
+// merged in source order, a later one replacing an earlier one of the same
+// property unless only the earlier is !important.
+func refStyles(src string) *refResult {
+	res := &refResult{styles: make(map[string]*refProps), skipped: make(map[string]bool)}
+	for _, rule := range refParse(src) {
+		if rule.at != "" {
+			if strings.EqualFold(rule.at, "layer") && rule.block != nil {
+				// The layer order of the merge is not modelled.
+				for _, r := range rule.block.rules() {
+					for _, sel := range refSelectors(r.prelude) {
+						res.skipped[sel] = true
+					}
+				}
+				res.partial = true
+			}
+			continue
+		}
+		sels := refSelectors(rule.prelude)
+		decls, nested := rule.block.declarations()
+		if nested {
+			for _, sel := range sels {
+				res.skipped[sel] = true
+			}
+			res.partial = true
+			continue
+		}
+		for _, sel := range sels {
+			props := res.styles[sel]
+			if props == nil {
+				props = &refProps{values: make(map[string]string), important: make(map[string]bool)}
+				res.styles[sel] = props
+				res.order = append(res.order, sel)
+			}
+			for _, d := range decls {
+				name := d.name
+				if !strings.HasPrefix(name, "--") {
+					name = strings.ToLower(name)
+				}
+				if _, ok := props.values[name]; !ok {
+					props.order = append(props.order, name)
+				} else if props.important[name] && !d.important {
+					continue
+				}
+				props.values[name] = refDeclValue(d.value, d.important)
+				props.important[name] = d.important
+			}
+		}
+	}
+	for sel := range res.skipped {
+		if res.styles[sel] != nil {
+			delete(res.styles, sel)
+			for i, s := range res.order {
+				if s == sel {
+					res.order = append(res.order[:i], res.order[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return res
+}
+
+// refSelectors returns the selectors of the selector list prelude, split at
+// its top-level commas.
+func refSelectors(prelude []refToken) []string {
+	var sels []string
+	start := 0
+	for i, tok := range prelude {
+		if tok.kind == refComma {
+			sels = append(sels, refCanon(prelude[start:i], true))
+			start = i + 1
+		}
+	}
+	return append(sels, refCanon(prelude[start:], true))
+}
+
+// refCanon writes the component values toks in a canonical form: comments
+// are left out, numbers are written by value, whitespace is one space, and
+// none is kept at the ends of a block or around ','. In a selector, there is also none around the combinators '>', '+'
+// and '~', the names that are not case-sensitive are lowered, and
+// attribute values are quoted.
+func refCanon(toks []refToken, selector bool) string {
+	var b strings.Builder
+	refCanonTo(&b, toks, selector)
+	return b.String()
+}
+
+func refCanonTo(b *strings.Builder, toks []refToken, selector bool) {
+	tight := func(tok refToken) bool {
+		return tok.kind == refComma || selector && tok.kind == refDelim && strings.Contains(">+~", tok.value)
+	}
+	word := func(tok refToken) bool {
+		switch tok.kind {
+		case refIdent, refFunction, refAtKeyword, refHash, refNumber, refPercentage, refDimension, refURL:
+			return true
+		}
+		return false
+	}
+	space := false
+	var prev *refToken
+	for i := range toks {
+		tok := &toks[i]
+		if tok.kind == refWhitespace {
+			space = true
+			continue
+		}
+		switch {
+		case prev == nil:
+		case space && !tight(*prev) && !tight(*tok):
+			b.WriteByte(' ')
+		case !space && word(*prev) && word(*tok):
+			// Keep apart the tokens of "a/**/b", which are not "ab".
+			b.WriteString("/**/")
+		}
+		space = false
+		switch tok.kind {
+		case refIdent:
+			switch {
+			case !selector:
+				b.WriteString(refName(tok.value))
+			case prev != nil && prev.kind == refDelim && prev.value == "=":
+				// An attribute value is a string, quoted or not.
+				b.WriteString(strconv.Quote(tok.value))
+			case prev != nil && prev.kind == refDelim && prev.value == ".":
+				b.WriteString(refName(tok.value))
+			default:
+				// Type selectors and the names of pseudo-classes and
+				// attributes are not case-sensitive.
+				b.WriteString(refName(strings.ToLower(tok.value)))
+			}
+		case refFunction:
+			if selector {
+				b.WriteString(refName(strings.ToLower(tok.value)) + "(")
+			} else {
+				b.WriteString(refName(tok.value) + "(")
+			}
+			refCanonTo(b, tok.block, selector)
+			b.WriteByte(')')
+		case refOpen:
+			b.WriteString(tok.value)
+			refCanonTo(b, tok.block, selector)
+			b.WriteString(refClosers[tok.value])
+		case refAtKeyword:
+			b.WriteString("@" + refName(tok.value))
+		case refHash:
+			b.WriteString("#" + refName(tok.value))
+		case refString:
+			b.WriteString(strconv.Quote(tok.value))
+		case refBadString:
+			b.WriteString("BAD-STRING" + strconv.Quote(tok.value))
+		case refURL:
+			// The same as url() of a string.
+			b.WriteString("url(" + strconv.Quote(tok.value) + ")")
+		case refBadURL:
+			b.WriteString("BAD-URL")
+		case refNumber:
+			b.WriteString(strconv.FormatFloat(tok.num, 'g', -1, 64))
+		case refPercentage:
+			b.WriteString(strconv.FormatFloat(tok.num, 'g', -1, 64) + "%")
+		case refDimension:
+			b.WriteString(strconv.FormatFloat(tok.num, 'g', -1, 64) + refName(strings.ToLower(tok.value)))
+		default:
+			b.WriteString(tok.value)
+		}
+		prev = tok
+	}
+}
+
+// refName returns the name v with a backslash before each code point that
+// is not a name code point, so that it is not taken for a string or delim.
+func refName(v string) string {
+	var b strings.Builder
+	for _, c := range v {
+		if !refIsIdent(c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// refImportant returns the component values toks without the "!important"
+// ending them, and whether they did.
+func refImportant(toks []refToken) ([]refToken, bool) {
+	n := len(toks)
+	for n > 0 && toks[n-1].kind == refWhitespace {
+		n--
+	}
+	if n == 0 || toks[n-1].kind != refIdent || !strings.EqualFold(toks[n-1].value, "important") {
+		return toks, false
+	}
+	n--
+	for n > 0 && toks[n-1].kind == refWhitespace {
+		n--
+	}
+	if n == 0 || toks[n-1].kind != refDelim || toks[n-1].value != "!" {
+		return toks, false
+	}
+	return toks[:n-1], true
+}
+
+// The kinds of refToken. The blocks of refOpen and the arguments of
+// refFunction are parsed into component values.
+const (
+	refIdent = iota
+	refFunction
+	refAtKeyword
+	refHash
+	refString
+	refBadString
+	refURL
+	refBadURL
+	refDelim
+	refNumber
+	refPercentage
+	refDimension
+	refWhitespace
+	refCDO
+	refCDC
+	refColon
+	refSemicolon
+	refComma
+	refOpen  // one of "([{"
+	refClose // one of ")]}"
+)
+
+var refClosers = map[string]string{"(": ")", "[": "]", "{": "}"}
+
+// refToken is a token or component value of the reference parse. Value is
+// the name, string or unit with escapes resolved, or the text of a delim.
+type refToken struct {
+	kind  int
+	value string
+	num   float64
+	block []refToken // of a function or simple block
+}
+
+// refTokens returns the component values of src.
+func refTokens(src string) []refToken {
+	z := &refTokenizer{in: refPreprocess(src)}
+	var toks []refToken
+	for {
+		tok, ok := z.next()
+		if !ok {
+			break
+		}
+		toks = append(toks, tok)
+	}
+	p := &refParser{toks: toks}
+	return p.componentValues(func(refToken) bool { return false })
+}
+
+// refPreprocess filters the code points of src as section 3.3 says.
+func refPreprocess(src string) []rune {
+	src = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\f", "\n", "\x00", "�").Replace(src)
+	return []rune(src)
+}
+
+// refTokenizer is the tokenizer of section 4.
+type refTokenizer struct {
+	in []rune
+	i  int
+}
+
+// at returns the code point n after the next one, or -1 past the end.
+func (z *refTokenizer) at(n int) rune {
+	if z.i+n < len(z.in) {
+		return z.in[z.i+n]
+	}
+	return -1
+}
+
+func refIsIdentStart(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80 || c == '_'
+}
+
+func refIsIdent(c rune) bool {
+	return refIsIdentStart(c) || c >= '0' && c <= '9' || c == '-'
+}
+
+func refIsDigit(c rune) bool { return c >= '0' && c <= '9' }
+
+func refIsHex(c rune) bool {
+	return refIsDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func refIsSpace(c rune) bool { return c == ' ' || c == '\t' || c == '\n' }
+
+func refIsNonPrintable(c rune) bool {
+	return c >= 0 && c <= 8 || c == 0xb || c >= 0xe && c <= 0x1f || c == 0x7f
+}
+
+// validEscape is section 4.3.8, for the code points n and n+1 on.
+func (z *refTokenizer) validEscape(n int) bool {
+	return z.at(n) == '\\' && z.at(n+1) != '\n'
+}
+
+// startsIdent is section 4.3.9, for the code points n on.
+func (z *refTokenizer) startsIdent(n int) bool {
+	switch c := z.at(n); {
+	case c == '-':
+		return refIsIdentStart(z.at(n+1)) || z.at(n+1) == '-' || z.validEscape(n+1)
+	case refIsIdentStart(c):
+		return true
+	case c == '\\':
+		return z.validEscape(n)
+	}
+	return false
+}
+
+// startsNumber is section 4.3.10, for the code points n on.
+func (z *refTokenizer) startsNumber(n int) bool {
+	switch c := z.at(n); {
+	case c == '+' || c == '-':
+		return refIsDigit(z.at(n+1)) || z.at(n+1) == '.' && refIsDigit(z.at(n+2))
+	case c == '.':
+		return refIsDigit(z.at(n + 1))
+	}
+	return refIsDigit(z.at(n))
+}
+
+// next is section 4.3.1, with comments left out.
+func (z *refTokenizer) next() (refToken, bool) {
+	for z.at(0) == '/' && z.at(1) == '*' {
+		z.i += 2
+		for z.i < len(z.in) && !(z.at(0) == '*' && z.at(1) == '/') {
+			z.i++
+		}
+		z.i += 2
+	}
+	if z.i >= len(z.in) {
+		return refToken{}, false
+	}
+	c := z.at(0)
+	switch {
+	case refIsSpace(c):
+		for refIsSpace(z.at(0)) {
+			z.i++
+		}
+		return refToken{kind: refWhitespace, value: " "}, true
+	case c == '"' || c == '\'':
+		z.i++
+		return z.string(c), true
+	case c == '#' && (refIsIdent(z.at(1)) || z.validEscape(1)):
+		z.i++
+		return refToken{kind: refHash, value: z.identSequence()}, true
+	case c == '(' || c == '[' || c == '{':
+		z.i++
+		return refToken{kind: refOpen, value: string(c)}, true
+	case c == ')' || c == ']' || c == '}':
+		z.i++
+		return refToken{kind: refClose, value: string(c)}, true
+	case c == ',':
+		z.i++
+		return refToken{kind: refComma, value: ","}, true
+	case c == ':':
+		z.i++
+		return refToken{kind: refColon, value: ":"}, true
+	case c == ';':
+		z.i++
+		return refToken{kind: refSemicolon, value: ";"}, true
+	case (c == '+' || c == '-' || c == '.' || refIsDigit(c)) && z.startsNumber(0):
+		return z.numeric(), true
+	case c == '-' && z.at(1) == '-' && z.at(2) == '>':
+		z.i += 3
+		return refToken{kind: refCDC, value: "-->"}, true
+	case c == '<' && z.at(1) == '!' && z.at(2) == '-' && z.at(3) == '-':
+		z.i += 4
+		return refToken{kind: refCDO, value: "<!--"}, true
+	case c == '@' && z.startsIdent(1):
+		z.i++
+		return refToken{kind: refAtKeyword, value: z.identSequence()}, true
+	case z.startsIdent(0):
+		return z.identLike(), true
+	}
+	z.i++
+	return refToken{kind: refDelim, value: string(c)}, true
+}
+
+// escape is section 4.3.7, past the backslash.
+func (z *refTokenizer) escape() rune {
+	c := z.at(0)
+	if c < 0 {
+		return utf8.RuneError
+	}
+	z.i++
+	if !refIsHex(c) {
+		return c
+	}
+	n := int64(0)
+	for j := 0; ; j++ {
+		d, _ := strconv.ParseInt(string(c), 16, 64)
+		n = n*16 + d
+		if j == 5 || !refIsHex(z.at(0)) {
+			break
+		}
+		c = z.at(0)
+		z.i++
+	}
+	if refIsSpace(z.at(0)) {
+		z.i++
+	}
+	if n == 0 || n >= 0xd800 && n <= 0xdfff || n > utf8.MaxRune {
+		return utf8.RuneError
+	}
+	return rune(n)
+}
+
+// identSequence is section 4.3.11.
+func (z *refTokenizer) identSequence() string {
+	var b strings.Builder
+	for {
+		switch {
+		case refIsIdent(z.at(0)):
+			b.WriteRune(z.at(0))
+			z.i++
+		case z.validEscape(0):
+			z.i++
+			b.WriteRune(z.escape())
+		default:
+			return b.String()
+		}
+	}
+}
+
+// numeric is section 4.3.3, with the number of section 4.3.12.
+func (z *refTokenizer) numeric() refToken {
+	start := z.i
+	if c := z.at(0); c == '+' || c == '-' {
+		z.i++
+	}
+	for refIsDigit(z.at(0)) {
+		z.i++
+	}
+	if z.at(0) == '.' && refIsDigit(z.at(1)) {
+		for z.i++; refIsDigit(z.at(0)); z.i++ {
+		}
+	}
+	if c := z.at(0); c == 'e' || c == 'E' {
+		if refIsDigit(z.at(1)) {
+			z.i++
+		} else if (z.at(1) == '+' || z.at(1) == '-') && refIsDigit(z.at(2)) {
+			z.i += 2
+		}
+		for refIsDigit(z.at(0)) {
+			z.i++
+		}
+	}
+	num, _ := strconv.ParseFloat(string(z.in[start:z.i]), 64)
+	switch {
+	case z.startsIdent(0):
+		return refToken{kind: refDimension, num: num, value: z.identSequence()}
+	case z.at(0) == '%':
+		z.i++
+		return refToken{kind: refPercentage, num: num}
+	}
+	return refToken{kind: refNumber, num: num}
+}
+
+// identLike is section 4.3.4.
+func (z *refTokenizer) identLike() refToken {
+	name := z.identSequence()
+	if z.at(0) != '(' {
+		return refToken{kind: refIdent, value: name}
+	}
+	z.i++
+	if !strings.EqualFold(name, "url") {
+		return refToken{kind: refFunction, value: name}
+	}
+	for refIsSpace(z.at(0)) && refIsSpace(z.at(1)) {
+		z.i++
+	}
+	if c, d := z.at(0), z.at(1); c == '"' || c == '\'' || refIsSpace(c) && (d == '"' || d == '\'') {
+		return refToken{kind: refFunction, value: name}
+	}
+	return z.url()
+}
+
+// url is section 4.3.6.
+func (z *refTokenizer) url() refToken {
+	var b strings.Builder
+	for refIsSpace(z.at(0)) {
+		z.i++
+	}
+	for {
+		c := z.at(0)
+		switch {
+		case c < 0:
+			return refToken{kind: refURL, value: b.String()}
+		case c == ')':
+			z.i++
+			return refToken{kind: refURL, value: b.String()}
+		case refIsSpace(c):
+			for refIsSpace(z.at(0)) {
+				z.i++
+			}
+			if z.at(0) == ')' || z.at(0) < 0 {
+				z.i++
+				return refToken{kind: refURL, value: b.String()}
+			}
+			return z.badURL()
+		case c == '"' || c == '\'' || c == '(' || refIsNonPrintable(c):
+			return z.badURL()
+		case c == '\\':
+			if !z.validEscape(0) {
+				return z.badURL()
+			}
+			z.i++
+			b.WriteRune(z.escape())
+		default:
+			b.WriteRune(c)
+			z.i++
+		}
+	}
+}
+
+// badURL consumes the remnants of a bad url, as section 4.3.14 says.
+func (z *refTokenizer) badURL() refToken {
+	for {
+		switch {
+		case z.at(0) < 0:
+			return refToken{kind: refBadURL}
+		case z.at(0) == ')':
+			z.i++
+			return refToken{kind: refBadURL}
+		case z.validEscape(0):
+			z.i++
+			z.escape()
+		default:
+			z.i++
+		}
+	}
+}
+
+// string is section 4.3.5, past the opening quote.
+func (z *refTokenizer) string(quote rune) refToken {
+	var b strings.Builder
+	for {
+		c := z.at(0)
+		switch {
+		case c < 0:
+			return refToken{kind: refString, value: b.String()}
+		case c == quote:
+			z.i++
+			return refToken{kind: refString, value: b.String()}
+		case c == '\n':
+			return refToken{kind: refBadString, value: b.String()}
+		case c == '\\':
+			z.i++
+			switch z.at(0) {
+			case -1:
+			case '\n':
+				z.i++
+			default:
+				b.WriteRune(z.escape())
+			}
+		default:
+			b.WriteRune(c)
+			z.i++
+		}
+	}
+}
+
+// refParser is the parser of section 5, over tokens.
+type refParser struct {
+	toks []refToken
+	i    int
+}
+
+// componentValues consumes component values, section 5.4.7, up to the end
+// of input or a token for which stop is true, which is not consumed.
+func (p *refParser) componentValues(stop func(refToken) bool) []refToken {
+	var vals []refToken
+	for p.i < len(p.toks) && !stop(p.toks[p.i]) {
+		vals = append(vals, p.componentValue())
+	}
+	return vals
+}
+
+// componentValue is section 5.4.7, with the simple blocks of section 5.4.8
+// and the functions of section 5.4.9.
+func (p *refParser) componentValue() refToken {
+	tok := p.toks[p.i]
+	p.i++
+	var closer string
+	switch tok.kind {
+	case refOpen:
+		closer = refClosers[tok.value]
+	case refFunction:
+		closer = ")"
+	default:
+		return tok
+	}
+	tok.block = p.componentValues(func(t refToken) bool { return t.kind == refClose && t.value == closer })
+	p.i++
+	return tok
+}
+
+// refRule is a rule of a list of rules. An at-rule has a name.
+type refRule struct {
+	at      string
+	prelude []refToken
+	block   *refToken
+}
+
+// refParse parses a stylesheet into its rules, as section 5.3.3 says.
+func refParse(src string) []refRule {
+	return refRules(refTokens(src), true)
+}
+
+// refRules is section 5.4.1, over component values.
+func refRules(vals []refToken, top bool) []refRule {
+	var rules []refRule
+	for i := 0; i < len(vals); i++ {
+		switch v := vals[i]; {
+		case v.kind == refWhitespace:
+		case (v.kind == refCDO || v.kind == refCDC) && top:
+		case v.kind == refAtKeyword:
+			rule := refRule{at: v.value}
+			for i++; i < len(vals); i++ {
+				if vals[i].kind == refSemicolon {
+					break
+				}
+				if vals[i].kind == refOpen && vals[i].value == "{" {
+					rule.block = &vals[i]
+					break
+				}
+				rule.prelude = append(rule.prelude, vals[i])
+			}
+			rules = append(rules, rule)
+		default:
+			var rule refRule
+			for ; i < len(vals); i++ {
+				if vals[i].kind == refOpen && vals[i].value == "{" {
+					rule.block = &vals[i]
+					break
+				}
+				rule.prelude = append(rule.prelude, vals[i])
+			}
+			if rule.block != nil {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	return rules
+}
+
+// rules returns the rules of the block.
+func (tok *refToken) rules() []refRule {
+	return refRules(tok.block, false)
+}
+
+// refDecl is a declaration, its value without the !important flag.
+type refDecl struct {
+	name      string
+	value     []refToken
+	important bool
+}
+
+// declarations returns the declarations of the block, section 5.4.5, and
+// whether it holds a '{' block, as nested rules give, which the reference
+// leaves to nesting.
+func (tok *refToken) declarations() ([]refDecl, bool) {
+	var decls []refDecl
+	nested := false
+	vals := tok.block
+	for i := 0; i < len(vals); i++ {
+		start := i
+		for i < len(vals) && vals[i].kind != refSemicolon {
+			if vals[i].kind == refOpen && vals[i].value == "{" {
+				nested = true
+			}
+			i++
+		}
+		item := vals[start:i]
+		for len(item) > 0 && item[0].kind == refWhitespace {
+			item = item[1:]
+		}
+		if len(item) == 0 || item[0].kind != refIdent {
+			continue
+		}
+		d := refDecl{name: item[0].value}
+		rest := item[1:]
+		for len(rest) > 0 && rest[0].kind == refWhitespace {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 || rest[0].kind != refColon {
+			continue
+		}
+		d.value, d.important = refImportant(rest[1:])
+		decls = append(decls, d)
+	}
+	return decls, nested
+}
@@ -0,0 +1,193 @@
+package css
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Limits applied by ParseURL unless overridden with options.
+const (
+	DefaultMaxImportDepth       = 16
+	DefaultMaxImportBytes int64 = 16 << 20
+	DefaultMaxFetches           = 64
+)
+
+// ParseURL fetches the stylesheet at rawURL with client, or
+// http.DefaultClient if nil, and inlines the stylesheets it imports.
+// Import URLs are resolved against the URL of the importing stylesheet,
+// after redirects, and url() references in declaration values are
+// rewritten to absolute URLs the same way. Fetching stops with an error
+// once ctx is done or any of the MaxImportDepth, MaxImportBytes and
+// MaxFetches limits is exceeded.
+func ParseURL(ctx context.Context, rawURL string, client *http.Client, opts ...Option) (*StyleSheet, error) {
+	o := options{
+		maxImportDepth: DefaultMaxImportDepth,
+		maxImportBytes: DefaultMaxImportBytes,
+		maxFetches:     DefaultMaxFetches,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.ctx == nil {
+		o.ctx = ctx
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	imp := &httpImporter{ctx: ctx, client: client, opts: o}
+	name, b, err := imp.load(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := parseImports(imp, name, b, nil, o)
+	absoluteURLs(sheet)
+	return sheet, err
+}
+
+type httpImporter struct {
+	ctx     context.Context
+	client  *http.Client
+	opts    options
+	fetches int
+	read    int64
+}
+
+func (i *httpImporter) resolve(base, ref string) (string, bool) {
+	u, err := resolveURL(base, ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	return u.String(), true
+}
+
+func (i *httpImporter) load(name string) (string, []byte, error) {
+	if i.opts.maxFetches > 0 && i.fetches >= i.opts.maxFetches {
+		return "", nil, &LimitError{Limit: LimitFetches, Max: int64(i.opts.maxFetches)}
+	}
+	i.fetches++
+
+	req, err := http.NewRequestWithContext(i.ctx, http.MethodGet, name, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", nil, fmt.Errorf("GET %s: %s", name, resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if i.opts.maxImportBytes > 0 {
+		body = io.LimitReader(body, i.opts.maxImportBytes-i.read+1)
+	}
+	b, err := io.ReadAll(body)
+	i.read += int64(len(b))
+	if err != nil {
+		return "", nil, err
+	}
+	if i.opts.maxImportBytes > 0 && i.read > i.opts.maxImportBytes {
+		return "", nil, &LimitError{Limit: LimitImportBytes, Max: i.opts.maxImportBytes}
+	}
+	return resp.Request.URL.String(), b, nil
+}
+
+func resolveURL(base, ref string) (*url.URL, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	return b.ResolveReference(r), nil
+}
+
+// absoluteURLs rewrites the url() references of every declaration relative
+// to the stylesheet the declaration was read from.
+func absoluteURLs(sheet *StyleSheet) {
+	Walk(sheet, func(n Node) bool {
+		d, ok := n.(*Declaration)
+		if !ok {
+			return true
+		}
+		d.Value = rewriteURLs(d.Value, func(ref string) string {
+			if ref == "" || strings.HasPrefix(ref, "#") || isAbsoluteURL(ref) {
+				return ref
+			}
+			u, err := resolveURL(d.Pos.Filename, ref)
+			if err != nil {
+				return ref
+			}
+			return u.String()
+		})
+		return true
+	})
+}
+
+// rewriteURLs replaces the reference of every url() in value by the result
+// of fn, keeping the original quoting.
+func rewriteURLs(value string, fn func(ref string) string) string {
+	var (
+		b     strings.Builder
+		quote byte
+	)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if quote != 0 {
+			if c == '\\' && i+1 < len(value) {
+				b.WriteByte(c)
+				i++
+				c = value[i]
+			} else if c == quote {
+				quote = 0
+			}
+			b.WriteByte(c)
+			continue
+		}
+		if c == '"' || c == '\'' {
+			quote = c
+			b.WriteByte(c)
+			continue
+		}
+		if len(value)-i < 4 || !strings.EqualFold(value[i:i+4], "url(") || (i > 0 && isNameRune(rune(value[i-1]))) {
+			b.WriteByte(c)
+			continue
+		}
+
+		start := i + len("url(")
+		from := start
+		for from < len(value) && value[from] == ' ' {
+			from++
+		}
+		if from < len(value) && (value[from] == '"' || value[from] == '\'') {
+			if n := strings.IndexByte(value[from+1:], value[from]); n >= 0 {
+				from += n + 2
+			}
+		}
+		end := strings.IndexByte(value[from:], ')')
+		if end < 0 {
+			b.WriteString(value[i:])
+			break
+		}
+		end += from
+		ref := strings.TrimSpace(value[start:end])
+		q := ""
+		if len(ref) >= 2 && (ref[0] == '"' || ref[0] == '\'') && ref[len(ref)-1] == ref[0] {
+			q, ref = ref[:1], ref[1:len(ref)-1]
+		}
+		b.WriteString(value[i:start])
+		b.WriteString(q + fn(ref) + q)
+		b.WriteByte(')')
+		i = end
+	}
+	return b.String()
+}
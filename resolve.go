@@ -0,0 +1,203 @@
+package css
+
+import "strings"
+
+// ResolveOptions configures Resolve.
+type ResolveOptions struct {
+	// Env maps the names of env() variables, such as
+	// "safe-area-inset-bottom", to their values. If it is nil, env() is
+	// left as it is; otherwise a name it lacks takes the fallback of the
+	// env() if there is one.
+	Env map[string]string
+}
+
+// Resolve returns a copy of sheet with the var() references in its values
+// replaced by the values of the custom properties declared by the
+// top-level rules with :root among their selectors, and the env() references by those of opts.Env.
+// References are resolved inside out, so var() and env() nested in calc()
+// or in the fallbacks of others resolve too. The fallback of a var() is
+// used if no rule of the sheet declares the custom property; one declared
+// only in other rules may apply to an element at run time, so its var() is
+// kept, as is a var() whose value depends on a reference cycle.
+func Resolve(sheet *StyleSheet, opts ResolveOptions) *StyleSheet {
+	return ResolveScoped(sheet, nil, opts)
+}
+
+// ResolveScoped is like Resolve for an element inside the elements the
+// selectors scopes match, such as ".theme-dark", outermost first. The
+// custom properties declared by the top-level rules with one of scopes
+// among their selectors are used as well as those of :root, those of a
+// later scope taking precedence over those of an earlier one and of :root,
+// as they would by inheritance. As in a browser, a custom property
+// resolves where it is declared, so a var() in its value sees only the
+// scopes up to its own.
+func ResolveScoped(sheet *StyleSheet, scopes []string, opts ResolveOptions) *StyleSheet {
+	out := sheet.Clone()
+	r := &resolver{
+		opts:     opts,
+		root:     make(map[string]string),
+		declared: make(map[string]bool),
+		resolved: make(map[string]string),
+		active:   make(map[string]bool),
+		cyclic:   make(map[string]bool),
+		done:     make(map[*Declaration]bool),
+	}
+	Walk(out, func(n Node) bool {
+		if d, ok := n.(*Declaration); ok && strings.HasPrefix(d.Property, "--") {
+			r.declared[d.Property] = true
+		}
+		return true
+	})
+	r.scope(out, ":root")
+	for _, sel := range scopes {
+		r.scope(out, sel)
+	}
+	Walk(out, func(n Node) bool {
+		if d, ok := n.(*Declaration); ok && !r.done[d] {
+			d.Value = r.value(d.Value)
+		}
+		return true
+	})
+	return out
+}
+
+// scope adds the custom properties declared by the top-level rules of sheet
+// with the selector sel to those in scope, replacing those declared before,
+// and resolves them and the other declarations of the rules.
+func (r *resolver) scope(sheet *StyleSheet, sel string) {
+	scope := Rule(strings.Join(strings.Fields(sel), " "))
+	if rule, err := newRule(sel); err == nil && len(rule.Selectors) == 1 {
+		scope = rule.Selectors[0]
+	}
+	var rules []*RuleNode
+	values := make(map[string]string)
+	important := make(map[string]bool)
+	for _, n := range sheet.Rules {
+		rule, ok := n.(*RuleNode)
+		if !ok || !hasSelector(rule.Selectors, scope) {
+			continue
+		}
+		rules = append(rules, rule)
+		for _, d := range rule.Declarations {
+			if strings.HasPrefix(d.Property, "--") && (d.Important || !important[d.Property]) {
+				values[d.Property], important[d.Property] = d.Value, d.Important
+			}
+		}
+	}
+	for p, v := range values {
+		r.root[p] = v
+		delete(r.resolved, p)
+		delete(r.cyclic, p)
+	}
+	for p := range values {
+		if _, ok := r.customProperty(p); !ok {
+			r.cyclic[p] = true
+		}
+	}
+	for _, rule := range rules {
+		for i := range rule.Declarations {
+			if d := &rule.Declarations[i]; !r.done[d] {
+				d.Value, r.done[d] = r.value(d.Value), true
+			}
+		}
+	}
+}
+
+func hasSelector(sels []Rule, sel Rule) bool {
+	for _, s := range sels {
+		if s == sel {
+			return true
+		}
+	}
+	return false
+}
+
+type resolver struct {
+	opts     ResolveOptions
+	root     map[string]string     // values of the custom properties in scope
+	declared map[string]bool       // custom properties declared anywhere
+	resolved map[string]string     // resolved values of root
+	active   map[string]bool       // custom properties being resolved
+	cyclic   map[string]bool       // custom properties in scope depending on a cycle
+	done     map[*Declaration]bool // declarations resolved in their scope
+	cycle    bool                  // whether resolving the innermost met a cycle
+}
+
+// value returns s with its var() and env() references resolved.
+func (r *resolver) value(s string) string {
+	if !strings.Contains(s, "(") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		end := i + 1
+		switch {
+		case c == '"' || c == '\'':
+			end = skipString(s, i)
+		case c == '\\' || isNameRune(rune(c)):
+			end = skipName(s, i)
+			if end < len(s) && s[end] == '(' {
+				args, next := parenthesized(s, end)
+				if text, ok := r.function(asciiLower(s[i:end]), args); ok {
+					b.WriteString(text)
+					i = next
+					continue
+				}
+			}
+		}
+		b.WriteString(s[i:end])
+		i = end
+	}
+	return b.String()
+}
+
+// function returns the text of the call of the function name with the
+// arguments args, and false if it is not a reference that resolves.
+func (r *resolver) function(name, args string) (string, bool) {
+	if name != "var" && name != "env" {
+		return "", false
+	}
+	ref, fallback, hasFallback := strings.Cut(args, ",")
+	ref = strings.TrimSpace(ref)
+	switch {
+	case name == "env" && r.opts.Env == nil:
+		return "", false
+	case name == "env":
+		if v, ok := r.opts.Env[ref]; ok {
+			return v, true
+		}
+	case r.declared[ref]:
+		if _, ok := r.root[ref]; ok && !r.cyclic[ref] {
+			return r.customProperty(ref)
+		}
+		return "", false
+	}
+	if !hasFallback {
+		return "", false
+	}
+	return r.value(strings.TrimSpace(fallback)), true
+}
+
+// customProperty returns the resolved value of the custom property name in
+// scope, and false if it depends on a cycle.
+func (r *resolver) customProperty(name string) (string, bool) {
+	if v, ok := r.resolved[name]; ok {
+		return v, true
+	}
+	if r.active[name] {
+		r.cycle = true
+		return "", false
+	}
+	outer := r.cycle
+	r.active[name], r.cycle = true, false
+	v := r.value(r.root[name])
+	delete(r.active, name)
+	cycle := r.cycle
+	r.cycle = outer || cycle
+	if cycle {
+		return "", false
+	}
+	r.resolved[name] = v
+	return v, true
+}
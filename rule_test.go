@@ -0,0 +1,48 @@
+package css
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestRuleKeyWhitespace checks that the whitespace and formatting variants
+// of one selector all give the key of NormalizeRule, both written as a key
+// and parsed from a rule.
+func TestRuleKeyWhitespace(t *testing.T) {
+	const want Rule = "ul > li.item:hover ~ a[href] b"
+	variants := []string{
+		"ul > li.item:hover ~ a[href] b",
+		"ul>li.item:hover~a[href] b",
+		"  ul > li.item:hover ~ a[href] b  ",
+		"ul  >  li.item:hover  ~  a[href]  b",
+		"ul\t>\tli.item:hover\t~\ta[href]\tb",
+		"ul >\n  li.item:hover ~\n a[href]\nb\n",
+		"ul >\r\n li.item:hover\r\n~ a[href] b",
+		"ul> li.item:hover ~a[href]   b",
+		"ul >li.item:hover\n\n~ a[href] b",
+		"\nul\n>\nli.item:hover\n~\na[href]\nb\n",
+		"ul > li.item:hover ~ a[ href ] b",
+		"ul \f> li.item:hover ~ a[href] b",
+	}
+	for _, v := range variants {
+		if got := NormalizeRule(v); got != want {
+			t.Errorf("NormalizeRule(%q) = %q, want %q", v, got, want)
+		}
+		css, err := Unmarshal([]byte(fmt.Sprintf("%s{color:red}", v)))
+		if err != nil {
+			t.Errorf("Unmarshal of %q: %v", v, err)
+			continue
+		}
+		if _, ok := css[want]; !ok || len(css) != 1 {
+			t.Errorf("Unmarshal of %q keys %q, want %q", v, keys(css), want)
+		}
+	}
+}
+
+func keys(css map[Rule]map[string]string) []Rule {
+	var rules []Rule
+	for r := range css {
+		rules = append(rules, r)
+	}
+	return rules
+}
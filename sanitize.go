@@ -0,0 +1,237 @@
+package css
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"text/scanner"
+)
+
+// Policy lists what Sanitize keeps of an untrusted stylesheet. It is plain
+// data, so that it can be read from a configuration file, and its zero
+// value is the strictest policy: only relative URLs, no @import of another
+// origin and every property not denied.
+type Policy struct {
+	// Properties lists the properties kept, or is nil to keep every one.
+	// Custom properties must be listed by name, or all kept with "--*".
+	Properties []string
+	// DeniedProperties and DeniedFunctions list properties and functions,
+	// like "filter" and "image", removed in addition to -moz-binding,
+	// behavior and expression(), which are always removed.
+	DeniedProperties []string
+	DeniedFunctions  []string
+	// URLSchemes lists the schemes allowed in url(), src() and the strings
+	// of image() and image-set(), such as "https" and "data". Relative URLs
+	// are always allowed; one starting with "//" needs http or https.
+	URLSchemes []string
+	// ImportOrigins lists the origins, such as "https://cdn.example.com",
+	// that @import may load from besides relative URLs.
+	ImportOrigins []string
+	// Diagnostics, if set, is called for every removed declaration and
+	// at-rule, in source order, so that the user can be told about them.
+	Diagnostics func(Diagnostic) `json:"-"`
+}
+
+// Codes of the diagnostics reported by Sanitize.
+const (
+	DiagDisallowedProperty = "disallowed-property"
+	DiagDisallowedFunction = "disallowed-function"
+	DiagDisallowedURL      = "disallowed-url"
+	DiagDisallowedImport   = "disallowed-import"
+)
+
+var (
+	deniedProperties = []string{"-moz-binding", "behavior", "-ms-behavior"}
+	deniedFunctions  = []string{"expression"}
+)
+
+// Sanitize returns a copy of sheet without the declarations and at-rules
+// policy does not allow. Names are compared unescaped, as a browser reads
+// them, and a declaration is removed if any function it calls, at any
+// depth, is denied or refers to a disallowed URL. At-rules are removed for
+// the same reasons found in their prelude and @import for another origin;
+// the rest of their block is sanitized in place.
+func Sanitize(sheet *StyleSheet, policy Policy) *StyleSheet {
+	out := sheet.Clone()
+	Transform(out, func(n Node) []Node {
+		switch n := n.(type) {
+		case *AtRule:
+			code, msg := policy.atRule(n)
+			if code != "" {
+				policy.report(code, n.Pos, "@"+n.Name, msg)
+				return nil
+			}
+		case *Declaration:
+			code, msg := policy.declaration(n)
+			if code != "" {
+				policy.report(code, n.Pos, n.Property, msg)
+				return nil
+			}
+		}
+		return []Node{n}
+	})
+	return out
+}
+
+func (p Policy) report(code string, pos scanner.Position, what, msg string) {
+	options{diagnostics: p.Diagnostics}.diagnose(SeverityWarning, code, pos, "removed %s: %s", what, msg)
+}
+
+// atRule returns the code and reason for removing a, or "" to keep it.
+func (p Policy) atRule(a *AtRule) (code, msg string) {
+	if asciiLower(unescape(a.Name)) != "import" {
+		return p.value(a.Prelude)
+	}
+	imp, err := ParseImport(a.Prelude)
+	if err != nil {
+		return DiagDisallowedImport, "unreadable URL"
+	}
+	if ref := imp.URL; !p.importsFrom(ref) {
+		return DiagDisallowedImport, fmt.Sprintf("%q is not of an allowed origin", ref)
+	}
+	return "", ""
+}
+
+// declaration returns the code and reason for removing d, or "" to keep it.
+func (p Policy) declaration(d *Declaration) (code, msg string) {
+	prop := unescape(d.Property)
+	if !strings.HasPrefix(prop, "--") {
+		prop = asciiLower(prop)
+	}
+	if contains(deniedProperties, prop) || contains(p.DeniedProperties, prop) {
+		return DiagDisallowedProperty, "denied property"
+	}
+	if p.Properties != nil && !contains(p.Properties, prop) && !(strings.HasPrefix(prop, "--") && contains(p.Properties, "--*")) {
+		return DiagDisallowedProperty, "not an allowed property"
+	}
+	return p.value(d.Value)
+}
+
+// value returns the code and reason for removing the value or prelude s,
+// or "" to keep it.
+func (p Policy) value(s string) (code, msg string) {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\'':
+			i = skipString(s, i)
+			continue
+		case c != '\\' && !isNameRune(rune(c)):
+			i++
+			continue
+		}
+		end := skipName(s, i)
+		if end == len(s) || s[end] != '(' {
+			i = end
+			continue
+		}
+		name := asciiLower(unescape(s[i:end]))
+		args, next := parenthesized(s, end)
+		i = next
+		if contains(deniedFunctions, name) || contains(p.DeniedFunctions, name) {
+			return DiagDisallowedFunction, fmt.Sprintf("%s() is denied", name)
+		}
+		var refs []string
+		switch name {
+		case "url":
+			if ref, q := unquote(strings.TrimSpace(args)); q == 0 {
+				refs = append(refs, unescape(ref))
+			} else {
+				refs = append(refs, ref)
+			}
+		case "src", "image", "image-set", "-webkit-image-set":
+			for _, item := range splitSelectorList(args) {
+				for j, f := range Fields(item) {
+					if ref, q := unquote(strings.TrimSuffix(f, ",")); q != 0 && (j == 0 || name != "src") {
+						refs = append(refs, ref)
+					}
+				}
+			}
+		}
+		for _, ref := range refs {
+			if !p.allowsURL(ref) {
+				return DiagDisallowedURL, fmt.Sprintf("URL %q has a disallowed scheme", ref)
+			}
+		}
+		if name != "url" {
+			if code, msg = p.value(args); code != "" {
+				return code, msg
+			}
+		}
+	}
+	return "", ""
+}
+
+// allowsURL reports whether the policy allows the unquoted and unescaped
+// reference ref in a value.
+func (p Policy) allowsURL(ref string) bool {
+	scheme, network := urlScheme(ref)
+	switch {
+	case network:
+		return containsFold(p.URLSchemes, "http") || containsFold(p.URLSchemes, "https")
+	case scheme == "":
+		return true
+	}
+	return containsFold(p.URLSchemes, scheme)
+}
+
+// importsFrom reports whether the policy allows @import of ref.
+func (p Policy) importsFrom(ref string) bool {
+	scheme, network := urlScheme(ref)
+	if scheme == "" && !network {
+		return true
+	}
+	u, err := url.Parse(cleanURL(ref))
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, origin := range p.ImportOrigins {
+		o, err := url.Parse(origin)
+		if err == nil && strings.EqualFold(o.Host, u.Host) && (scheme == "" || strings.EqualFold(o.Scheme, scheme)) {
+			return true
+		}
+	}
+	return false
+}
+
+// urlScheme returns the lowercased scheme of ref as a browser reads it, or
+// "" if it has none, and whether it is a network-path reference such as
+// "//host/a.png". Anything before a ':' that is not followed by a '/', '?'
+// or '#' counts as a scheme, even if it is not a valid one.
+func urlScheme(ref string) (scheme string, network bool) {
+	ref = cleanURL(ref)
+	if strings.HasPrefix(ref, "//") {
+		return "", true
+	}
+	i := strings.IndexAny(ref, ":/?#")
+	if i <= 0 || ref[i] != ':' {
+		return "", false
+	}
+	return asciiLower(ref[:i]), false
+}
+
+// cleanURL returns ref without the leading spaces and control characters,
+// the tabs and newlines a browser ignores in it, and with backslashes read
+// as slashes.
+func cleanURL(ref string) string {
+	ref = strings.TrimLeftFunc(ref, func(r rune) bool { return r <= ' ' })
+	return strings.NewReplacer("\t", "", "\n", "", "\r", "", `\`, "/").Replace(ref)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,456 @@
+package css
+
+import (
+	"io"
+	"strings"
+	"text/scanner"
+	"unicode/utf8"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+// Token kinds.
+const (
+	TokenIdent     TokenKind = iota
+	TokenFunction            // a name and its '(', as in "rgb("
+	TokenAtKeyword           // as in "@media"
+	TokenHash                // as in "#fff"
+	TokenString              // quotes included
+	TokenURL                 // an unquoted url(), as in "url(a.png)"
+	TokenNumber              // a number, percentage or dimension, as in "1.5em"
+	TokenWhitespace
+	TokenComment // as in "/* note */"
+	TokenColon
+	TokenSemicolon
+	TokenComma
+	TokenBrace // one of "{}()[]"
+	TokenDelim // any other character, or "<!--" or "-->"
+)
+
+func (k TokenKind) String() string {
+	switch k {
+	case TokenIdent:
+		return "ident"
+	case TokenFunction:
+		return "function"
+	case TokenAtKeyword:
+		return "at-keyword"
+	case TokenHash:
+		return "hash"
+	case TokenString:
+		return "string"
+	case TokenURL:
+		return "url"
+	case TokenNumber:
+		return "number"
+	case TokenWhitespace:
+		return "whitespace"
+	case TokenComment:
+		return "comment"
+	case TokenColon:
+		return "colon"
+	case TokenSemicolon:
+		return "semicolon"
+	case TokenComma:
+		return "comma"
+	case TokenBrace:
+		return "brace"
+	}
+	return "delim"
+}
+
+// Token is a token of a stylesheet. Text is the source text of the token,
+// escapes included, so that the texts of all tokens make up the source.
+type Token struct {
+	Kind TokenKind
+	Text string
+	Pos  scanner.Position
+}
+
+// Scanner splits a stylesheet into tokens, following the tokenization of
+// CSS Syntax Level 3 with comments kept. Malformed input is not an error:
+// a string ended by a newline or the end of input lacks its closing quote,
+// and a comment or url() ended by the end of input lacks its end.
+//
+// A Scanner reads its input as tokens are asked for, holding only the
+// input of the token being read. It is the tokenizer of ListSelectors and
+// ScanAll. Parse and Unmarshal read with a tokenizer of their own, whose
+// tokens are those of the grammar, such as a whole selector or value, and
+// Decoder and the selective parse only look for the ends of statements
+// and blocks.
+type Scanner struct {
+	r     io.Reader
+	in    *errReader
+	o     options
+	src   string // the input read and not yet returned as tokens
+	buf   []byte
+	ascii bool // whether src is all ASCII
+	eof   bool
+	err   error
+	i     int
+	pos   scanner.Position
+}
+
+// scanAhead is how many bytes past the end of a token the scanner may look
+// at to tell where it ends, as in "1e+5" or "<!--".
+const scanAhead = 8
+
+// scanChunk is the least the scanner reads at a time.
+const scanChunk = 4096
+
+// NewScanner returns a Scanner reading from r. Of opts, only Filename and
+// MaxInputBytes apply.
+func NewScanner(r io.Reader, opts ...Option) *Scanner {
+	o := newOptions(opts)
+	return &Scanner{r: r, o: o, pos: scanner.Position{Filename: o.filename, Line: 1, Column: 1}}
+}
+
+// Next returns the next token, or io.EOF at the end of input. An error
+// reading the input, such as a *LimitError, is returned once the tokens
+// read before it are.
+func (s *Scanner) Next() (Token, error) {
+	for s.i == len(s.src) && !s.eof {
+		s.fill()
+	}
+	if s.i == len(s.src) {
+		if s.err != nil {
+			return Token{}, s.err
+		}
+		return Token{}, io.EOF
+	}
+	start, pos := s.i, s.pos
+	kind := s.token()
+	// A token running up to the end of what is read may go on past it.
+	for s.i+scanAhead > len(s.src) && !s.eof {
+		s.i = start
+		s.fill()
+		start = s.i
+		kind = s.token()
+	}
+	text := s.src[start:s.i]
+	if s.ascii {
+		advanceASCII(&s.pos, text)
+	} else {
+		for j := 0; j < len(text); j++ {
+			advancePosition(&s.pos, text[j])
+		}
+	}
+	return Token{kind, text, pos}, nil
+}
+
+// token moves past the token at i and returns its kind, reading it a byte
+// class at a time if the input read is ASCII.
+func (s *Scanner) token() TokenKind {
+	if s.ascii {
+		return s.scanASCII()
+	}
+	return s.scan()
+}
+
+// fill drops the input before i and reads more of it, into a buffer as
+// large as what is left, so that a long token takes few reads.
+func (s *Scanner) fill() {
+	if s.in == nil {
+		s.in = &errReader{r: newDecoder(s.r), max: s.o.maxInputBytes}
+	}
+	n := len(s.src) - s.i
+	if n < scanChunk {
+		n = scanChunk
+	}
+	if len(s.buf) < n {
+		s.buf = make([]byte, n)
+	}
+	n, err := s.in.Read(s.buf[:n])
+	s.src, s.i = s.src[s.i:]+string(s.buf[:n]), 0
+	s.ascii = isASCII(s.src)
+	if err != nil {
+		s.eof, s.err = true, s.in.err
+	}
+}
+
+// Byte classes of ASCII input for scanASCII.
+const (
+	asciiOther = iota
+	asciiSpace
+	asciiLetter // or '_', which start names
+	asciiDigit
+	asciiDash
+	asciiDot
+	asciiPunct // one of "{}()[]:;,"
+)
+
+var asciiClasses = func() (classes [utf8.RuneSelf]uint8) {
+	for c := range classes {
+		switch {
+		case isSelectorSpace(byte(c)):
+			classes[c] = asciiSpace
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			classes[c] = asciiLetter
+		case c >= '0' && c <= '9':
+			classes[c] = asciiDigit
+		case c == '-':
+			classes[c] = asciiDash
+		case c == '.':
+			classes[c] = asciiDot
+		case strings.IndexByte("{}()[]:;,", byte(c)) >= 0:
+			classes[c] = asciiPunct
+		}
+	}
+	return classes
+}()
+
+// asciiName reports whether the ASCII byte c may be in a name.
+func asciiName(c byte) bool {
+	class := asciiClasses[c]
+	return class == asciiLetter || class == asciiDigit || class == asciiDash
+}
+
+// scanASCII is scan for input that is all ASCII. It reads whitespace,
+// names, numbers and punctuation, the bulk of a stylesheet, by the class
+// of their bytes, and leaves the other tokens, and names followed by '(',
+// to scan.
+func (s *Scanner) scanASCII() TokenKind {
+	src, i := s.src, s.i
+	switch asciiClasses[src[i]] {
+	case asciiSpace:
+		for i++; i < len(src) && asciiClasses[src[i]] == asciiSpace; i++ {
+		}
+		s.i = i
+		return TokenWhitespace
+	case asciiLetter:
+		for i++; i < len(src) && asciiName(src[i]); i++ {
+		}
+		if i < len(src) && src[i] == '\\' {
+			i = skipName(src, i)
+		}
+		if i < len(src) && src[i] != '(' {
+			s.i = i
+			return TokenIdent
+		}
+	case asciiDigit:
+		s.i = numberEnd(src, i)
+		return TokenNumber
+	case asciiDot:
+		if i+1 < len(src) && asciiClasses[src[i+1]] != asciiDigit {
+			s.i++
+			return TokenDelim
+		}
+	case asciiPunct:
+		s.i++
+		switch src[i] {
+		case ':':
+			return TokenColon
+		case ';':
+			return TokenSemicolon
+		case ',':
+			return TokenComma
+		}
+		return TokenBrace
+	}
+	return s.scan()
+}
+
+// scan moves past the token at i and returns its kind. It is the rune
+// path, which reads input of any runes, of which scanASCII is the fast
+// path for ASCII.
+func (s *Scanner) scan() TokenKind {
+	src, i := s.src, s.i
+	c := src[i]
+	switch {
+	case isSelectorSpace(c):
+		for s.i++; s.i < len(src) && isSelectorSpace(src[s.i]); s.i++ {
+		}
+		return TokenWhitespace
+	case strings.HasPrefix(src[i:], "/*"):
+		if end := strings.Index(src[i+2:], "*/"); end >= 0 {
+			s.i = i + 2 + end + 2
+		} else {
+			s.i = len(src)
+		}
+		return TokenComment
+	case c == '"' || c == '\'':
+		s.i = s.stringEnd(i)
+		return TokenString
+	case c == '#' && (i+1 < len(src) && isNameRune(rune(src[i+1])) || validEscape(src, i+1)):
+		s.i = skipName(src, i+1)
+		return TokenHash
+	case strings.ContainsRune("{}()[]", rune(c)):
+		s.i++
+		return TokenBrace
+	case c == ':':
+		s.i++
+		return TokenColon
+	case c == ';':
+		s.i++
+		return TokenSemicolon
+	case c == ',':
+		s.i++
+		return TokenComma
+	case startsNumber(src, i):
+		s.i = numberEnd(src, i)
+		return TokenNumber
+	case strings.HasPrefix(src[i:], "<!--"):
+		s.i += 4
+		return TokenDelim
+	case strings.HasPrefix(src[i:], "-->"):
+		s.i += 3
+		return TokenDelim
+	case c == '@' && startsIdent(src, i+1):
+		s.i = skipName(src, i+1)
+		return TokenAtKeyword
+	case startsIdent(src, i):
+		return s.identLike()
+	}
+	_, n := utf8.DecodeRuneInString(src[i:])
+	s.i += n
+	return TokenDelim
+}
+
+// identLike moves past the identifier, function or url() at i.
+func (s *Scanner) identLike() TokenKind {
+	src := s.src
+	end := skipName(src, s.i)
+	if end == len(src) || src[end] != '(' {
+		s.i = end
+		return TokenIdent
+	}
+	name := src[s.i:end]
+	s.i = end + 1
+	if !strings.EqualFold(unescape(name), "url") {
+		return TokenFunction
+	}
+	j := s.i
+	for j < len(src) && isSelectorSpace(src[j]) {
+		j++
+	}
+	if j < len(src) && (src[j] == '"' || src[j] == '\'') {
+		return TokenFunction
+	}
+	for ; j < len(src) && src[j] != ')'; j++ {
+		if src[j] == '\\' && j+1 < len(src) {
+			j = skipEscape(src, j) - 1
+		}
+	}
+	s.i = len(src)
+	if j < len(src) {
+		s.i = j + 1
+	}
+	return TokenURL
+}
+
+// stringEnd returns the index past the string starting at i, which ends
+// before an unescaped newline if it is not closed first.
+func (s *Scanner) stringEnd(i int) int {
+	src := s.src
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '\n':
+			return j
+		case src[i]:
+			return j + 1
+		}
+	}
+	return len(src)
+}
+
+// isASCII reports whether s is all ASCII.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// advanceASCII moves pos past the ASCII text, as advancePosition does a
+// byte at a time.
+func advanceASCII(pos *scanner.Position, text string) {
+	pos.Offset += len(text)
+	pos.Column += len(text)
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			pos.Line++
+			pos.Column = len(text) - i
+		}
+	}
+}
+
+// validEscape reports whether src has a valid escape at i: a backslash not
+// followed by a newline.
+func validEscape(src string, i int) bool {
+	return i+1 < len(src) && src[i] == '\\' && src[i+1] != '\n'
+}
+
+// startsIdent reports whether an identifier starts at i.
+func startsIdent(src string, i int) bool {
+	if i >= len(src) {
+		return false
+	}
+	if src[i] == '-' {
+		i++
+		if i < len(src) && src[i] == '-' {
+			return true
+		}
+	}
+	if i >= len(src) {
+		return false
+	}
+	c := src[i]
+	return c == '_' || c >= 0x80 || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || validEscape(src, i)
+}
+
+// startsNumber reports whether a number starts at i.
+func startsNumber(src string, i int) bool {
+	if src[i] == '+' || src[i] == '-' {
+		i++
+	}
+	if i < len(src) && src[i] == '.' {
+		i++
+	}
+	return i < len(src) && src[i] >= '0' && src[i] <= '9'
+}
+
+// numberEnd returns the index past the number, percentage or dimension
+// starting at i.
+func numberEnd(src string, i int) int {
+	i = numericEnd(src, i)
+	switch {
+	case i < len(src) && src[i] == '%':
+		i++
+	case startsIdent(src, i):
+		i = skipName(src, i)
+	}
+	return i
+}
+
+// numericEnd returns the index past the numeric part of the number starting
+// at i.
+func numericEnd(src string, i int) int {
+	digits := func(i int) int {
+		for i < len(src) && src[i] >= '0' && src[i] <= '9' {
+			i++
+		}
+		return i
+	}
+	if src[i] == '+' || src[i] == '-' {
+		i++
+	}
+	i = digits(i)
+	if i+1 < len(src) && src[i] == '.' && src[i+1] >= '0' && src[i+1] <= '9' {
+		i = digits(i + 1)
+	}
+	if i+1 < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if src[j] == '+' || src[j] == '-' {
+			j++
+		}
+		if j < len(src) && src[j] >= '0' && src[j] <= '9' {
+			i = digits(j)
+		}
+	}
+	return i
+}
@@ -0,0 +1,160 @@
+package css
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestScanAll(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{".a{color:red}", "DELIM(.) IDENT(a) LBRACE IDENT(color) COLON IDENT(red) RBRACE"},
+		{"#fff #1a 1.5em -2px +.5% 1e3", "HASH(#fff) WS HASH(#1a) WS NUMBER(1.5em) WS NUMBER(-2px) WS NUMBER(+.5%) WS NUMBER(1e3)"},
+		{`url(a.png) url( "b" ) rgb(0,0,0)`, `URL("url(a.png)") WS FUNCTION(url) WS STRING("\"b\"") WS RPAREN WS FUNCTION(rgb) NUMBER(0) COMMA NUMBER(0) COMMA NUMBER(0) RPAREN`},
+		{"\"a\\\"b\" 'open\nx", `STRING("\"a\\\"b\"") WS STRING('open) WS("\n") IDENT(x)`},
+		{"<!-- --> @media screen{}", "DELIM(<!--) WS DELIM(-->) WS AT(@media) WS IDENT(screen) LBRACE RBRACE"},
+		{`\31 23 .\:x a\ b`, `IDENT("\\31 23") WS DELIM(.) IDENT("\\:x") WS IDENT("a\\ b")`},
+		{"/* c */ /* open", `COMMENT("/* c */") WS COMMENT("/* open")`},
+		{"--x: { a } !important;", "IDENT(--x) COLON WS LBRACE WS IDENT(a) WS RBRACE WS DELIM(!) IDENT(important) SEMI"},
+		{"é.ü{x:\"ñ\"} → 中", `IDENT(é) DELIM(.) IDENT(ü) LBRACE IDENT(x) COLON STRING("\"ñ\"") RBRACE WS IDENT(→) WS IDENT(中)`},
+		{"[a=b] ~= |= *", "LBRACKET IDENT(a) DELIM(=) IDENT(b) RBRACKET WS DELIM(~) DELIM(=) WS DELIM(|) DELIM(=) WS DELIM(*)"},
+	}
+	for _, tt := range tests {
+		toks, err := ScanAll(strings.NewReader(tt.src))
+		if err != nil {
+			t.Errorf("ScanAll(%q): %v", tt.src, err)
+			continue
+		}
+		if got := FormatTokens(toks); got != tt.want {
+			t.Errorf("ScanAll(%q):\ngot  %s\nwant %s", tt.src, got, tt.want)
+		}
+		if toks, _ := ScanAll(iotest.OneByteReader(strings.NewReader(tt.src))); FormatTokens(toks) != tt.want {
+			t.Errorf("ScanAll(%q) read a byte at a time gives %s", tt.src, FormatTokens(toks))
+		}
+		var text strings.Builder
+		for _, tok := range toks {
+			text.WriteString(tok.Text)
+		}
+		if text.String() != tt.src {
+			t.Errorf("the tokens of %q make up %q", tt.src, text.String())
+		}
+		want, err := ParseTokens(tt.want)
+		if err != nil {
+			t.Errorf("ParseTokens(%q): %v", tt.want, err)
+			continue
+		}
+		if got := FormatTokens(want); got != tt.want {
+			t.Errorf("FormatTokens(ParseTokens(%q)) = %q", tt.want, got)
+		}
+	}
+}
+
+// TestCorpusTokens checks that the .tokens files of testdata/corpus read
+// back as they are written.
+func TestCorpusTokens(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "corpus", "*.tokens"))
+	if err != nil || len(files) == 0 {
+		t.Fatal("no .tokens files", err)
+	}
+	for _, name := range files {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		toks, err := ParseTokens(string(b))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got, want := FormatTokens(toks), strings.Join(strings.Fields(string(b)), " "); got != want {
+			t.Errorf("%s reads back as\n%s", name, got)
+		}
+	}
+}
+
+func TestParseTokensErrors(t *testing.T) {
+	for _, s := range []string{"IDENT", "IDENT(a", `STRING("a)`, "FOO(a)", "( )", "LBRACE ;"} {
+		if toks, err := ParseTokens(s); err == nil {
+			t.Errorf("ParseTokens(%q) = %v, want an error", s, toks)
+		}
+	}
+}
+
+// TestScannerStreaming checks that the tokens of the stylesheets of
+// testdata do not depend on how the input is read: whole, or a byte at a
+// time so that every token spans reads.
+func TestScannerStreaming(t *testing.T) {
+	for _, name := range testSheets(t) {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := NewScanner(iotest.OneByteReader(bytes.NewReader(b)))
+		sameTokens(t, name+" read a byte at a time", s, wholeScanner(string(b), isASCII(string(b))))
+	}
+}
+
+// TestScannerASCII checks that the byte path of ASCII input gives the
+// tokens of the rune path, over the stylesheets of testdata.
+func TestScannerASCII(t *testing.T) {
+	for _, name := range testSheets(t) {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isASCII(string(b)) {
+			t.Errorf("%s is not ASCII", name)
+			continue
+		}
+		sameTokens(t, name+" on the byte path", wholeScanner(string(b), true), wholeScanner(string(b), false))
+	}
+	for _, src := range []string{"a{}", "\u00e9{}", "a{content:'\u00e9'}"} {
+		s := NewScanner(strings.NewReader(src))
+		if s.fill(); s.ascii != isASCII(src) {
+			t.Errorf("input %q is read on the byte path: %v, want %v", src, s.ascii, !s.ascii)
+		}
+	}
+}
+
+// testSheets returns the stylesheets of testdata.
+func testSheets(t *testing.T) []string {
+	var files []string
+	for _, dir := range []string{"corpus", "v1", "bench"} {
+		names, err := filepath.Glob(filepath.Join("testdata", dir, "*.css"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, names...)
+	}
+	return files
+}
+
+// wholeScanner returns a Scanner that has read src in full, and scans it
+// on the byte path if ascii is set.
+func wholeScanner(src string, ascii bool) *Scanner {
+	s := NewScanner(nil)
+	s.src, s.ascii, s.eof = src, ascii, true
+	return s
+}
+
+// sameTokens checks that s gives the tokens, positions and errors that
+// want does.
+func sameTokens(t *testing.T, name string, s, want *Scanner) {
+	t.Helper()
+	for n := 0; ; n++ {
+		wtok, werr := want.Next()
+		tok, err := s.Next()
+		if tok != wtok || err != werr {
+			t.Errorf("%s: token %d is %v, %v, want %v, %v", name, n, tok, err, wtok, werr)
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package css
+
+import (
+	"sort"
+	"strings"
+)
+
+// ScopeSeparator separates the conditions and the selector of the keys
+// ScopedKeys gives, as in "@media (min-width: 600px) :: .card".
+const ScopeSeparator = " :: "
+
+// ScopedKeys makes Unmarshal, UnmarshalWithPositions and UnmarshalCompact
+// also return the rules inside @media and @supports blocks, keyed by
+// ScopedRule of their scope and selector instead of being left out. The
+// scope is the @media and @supports conditions holding the rule, outermost
+// first, each written as "@media" or "@supports" and its normalized
+// prelude and separated by ScopeSeparator. Rules of the same scope and
+// selector are merged; those of different scopes are not. Unflatten builds
+// the blocks back from such keys.
+func ScopedKeys(scoped bool) Option {
+	return func(o *options) {
+		o.scopedKeys = scoped
+	}
+}
+
+// ScopedRule returns the key of the rules for selector in scope, as given
+// by ScopedKeys: selector alone if scope is empty.
+func ScopedRule(scope, selector string) Rule {
+	if scope == "" {
+		return Rule(selector)
+	}
+	return Rule(scope + ScopeSeparator + selector)
+}
+
+// SplitScopedRule splits a key given by ScopedKeys into its scope and
+// selector. The scope of a key without ScopeSeparator is empty.
+func SplitScopedRule(r Rule) (scope, selector string) {
+	i := strings.LastIndex(string(r), ScopeSeparator)
+	if i < 0 || !strings.HasPrefix(string(r), "@") {
+		return "", string(r)
+	}
+	return string(r[:i]), string(r[i+len(ScopeSeparator):])
+}
+
+// scopedRules calls fn, in source order, for the rules inside @media and
+// @supports blocks of nodes, and of @layer blocks within them, with their
+// scope. Top-level rules are left to layeredRules.
+func scopedRules(nodes []Node, scope string, fn func(*RuleNode, string)) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			if scope != "" {
+				fn(n, scope)
+			}
+		case *AtRule:
+			switch name := asciiLower(n.Name); name {
+			case "media", "supports":
+				prelude := normalizeSpace(strings.TrimSpace(n.Prelude))
+				if name == "media" {
+					prelude = mediaKey(n.Prelude)
+				}
+				cond := strings.TrimSpace("@" + name + " " + prelude)
+				if scope != "" {
+					cond = scope + ScopeSeparator + cond
+				}
+				scopedRules(n.Rules, cond, fn)
+			case "layer":
+				scopedRules(n.Rules, scope, fn)
+			}
+		}
+	}
+}
+
+// addScoped merges node, of the rules of scope, under its scoped keys.
+func (f *flattener) addScoped(node *RuleNode, scope string) {
+	scoped := *node
+	scoped.Selectors = make([]Rule, len(node.Selectors))
+	for i, r := range node.Selectors {
+		scoped.Selectors[i] = ScopedRule(scope, string(r))
+	}
+	f.add(&scoped, nil)
+}
+
+// Unflatten returns a stylesheet of the rules of css, a map such as Unmarshal
+// returns, with a rule for each selector, sorted, and a declaration for each
+// property, sorted and !important if its value ends with !important. Keys
+// given by ScopedKeys give rules in nested @media and @supports blocks, one
+// for each scope, after the top-level rules, so that Marshal writes the
+// rules Unmarshal read with ScopedKeys back in their blocks.
+func Unflatten(css map[Rule]map[string]string) *StyleSheet {
+	keys := make([]Rule, 0, len(css))
+	for r := range css {
+		keys = append(keys, r)
+	}
+	// Sorted by each condition in turn, so that the rules of a scope and of
+	// the scopes within it are next to each other.
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := scopeParts(keys[i]), scopeParts(keys[j])
+		for k := 0; k < len(a)-1 && k < len(b)-1; k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a[len(a)-1] < b[len(b)-1]
+	})
+	sheet := &StyleSheet{}
+	var path []string    // conditions of the block rules are added to
+	var blocks []*AtRule // open blocks of path, outermost first
+	for _, r := range keys {
+		parts := scopeParts(r)
+		conds, sel := parts[:len(parts)-1], parts[len(parts)-1]
+		keep := 0
+		for keep < len(path) && keep < len(conds) && path[keep] == conds[keep] {
+			keep++
+		}
+		path, blocks = path[:keep], blocks[:keep]
+		for _, cond := range conds[keep:] {
+			name, prelude, _ := strings.Cut(strings.TrimPrefix(cond, "@"), " ")
+			at := &AtRule{Name: name, Prelude: prelude, Rules: []Node{}}
+			if len(blocks) == 0 {
+				sheet.Rules = append(sheet.Rules, at)
+			} else {
+				b := blocks[len(blocks)-1]
+				b.Rules = append(b.Rules, at)
+			}
+			path, blocks = append(path, cond), append(blocks, at)
+		}
+		rule := &RuleNode{Selectors: []Rule{Rule(sel)}, Declarations: unflattenStyles(css[r])}
+		if len(blocks) == 0 {
+			sheet.Rules = append(sheet.Rules, rule)
+		} else {
+			b := blocks[len(blocks)-1]
+			b.Rules = append(b.Rules, rule)
+		}
+	}
+	return sheet
+}
+
+// scopeParts returns the conditions of the scope of r followed by its
+// selector.
+func scopeParts(r Rule) []string {
+	scope, sel := SplitScopedRule(r)
+	if scope == "" {
+		return []string{sel}
+	}
+	return append(strings.Split(scope, ScopeSeparator), sel)
+}
+
+// unflattenStyles returns the declarations of styles, sorted by property.
+func unflattenStyles(styles map[string]string) []Declaration {
+	decls := make([]Declaration, 0, len(styles))
+	for prop, value := range styles {
+		v, important := importance(value)
+		decls = append(decls, Declaration{Property: prop, Value: v, Important: important})
+	}
+	sort.Slice(decls, func(i, j int) bool { return decls[i].Property < decls[j].Property })
+	return decls
+}
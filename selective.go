@@ -0,0 +1,223 @@
+package css
+
+import (
+	"strings"
+	"text/scanner"
+)
+
+// FullScan makes UnmarshalSelectors read the whole stylesheet instead of
+// stopping once it has read a rule for each wanted selector, so that later
+// rules for them, which may override the earlier ones, are merged too.
+func FullScan(full bool) Option {
+	return func(o *options) {
+		o.fullScan = full
+	}
+}
+
+// UnmarshalSelectors is like Unmarshal but returns only the selectors of
+// want, and parses only the top-level rules, and those of @layer blocks,
+// that may declare styles for them: those with one of want among their
+// selectors, compared as the parser normalizes them, and those that nest
+// rules or have comments in their selector list. The blocks of the other
+// rules and of at-rules are skipped by matching braces, their declarations
+// neither parsed nor checked, which makes picking a few selectors out of a
+// large stylesheet several times faster than Unmarshal. The @layer and
+// @import statements are read as well, and @layer blocks wherever they
+// are, so that the layers rank as they do for Unmarshal. Reading stops
+// after the first rule for the last of want not seen yet, unless FullScan
+// is set.
+func UnmarshalSelectors(b []byte, want []string, opts ...Option) (map[Rule]map[string]string, error) {
+	o := newOptions(opts)
+	s := &selection{src: string(b), o: o, pos: o.start}
+	s.wanted = make(map[Rule]bool, len(want))
+	for _, w := range want {
+		s.wanted[s.key(w)] = true
+	}
+	s.seen = make(map[Rule]bool, len(s.wanted))
+	if !s.pos.IsValid() {
+		s.pos = scanner.Position{Filename: o.filename, Line: 1, Column: 1}
+	}
+	sheet := &StyleSheet{}
+	if sheet.Rules = s.rules(0, len(s.src), true); s.err != nil {
+		return nil, s.err
+	}
+	sheet.Layers = declaredLayers(sheet.Rules)
+	f := flatten(sheet, o)
+	for sel := range f.css {
+		if !s.wanted[sel] {
+			delete(f.css, sel)
+		}
+	}
+	err := s.errs.err(o)
+	if err == nil && f.err != nil {
+		err = f.err
+	}
+	return f.css, err
+}
+
+// selection is the state of UnmarshalSelectors.
+type selection struct {
+	src          string
+	o            options
+	pos          scanner.Position // of src[at]
+	at           int
+	wanted, seen map[Rule]bool
+	errs         ErrorList
+	err          error // other than a parse error, which stops it
+}
+
+func (s *selection) key(sel string) Rule {
+	return Rule(ruleKey(sel, s.o))
+}
+
+// done reports whether reading should stop.
+func (s *selection) done() bool {
+	return s.err != nil || len(s.errs) > 0 && !s.o.collectErrors || !s.o.fullScan && len(s.seen) >= len(s.wanted)
+}
+
+// rules returns the nodes to parse of the statements of src[i:end]: the
+// wanted rules if cascaded is set, and the @layer and @import statements,
+// along with the at-rules holding @layer blocks, with only those blocks
+// and statements in them.
+func (s *selection) rules(i, end int, cascaded bool) []Node {
+	var nodes []Node
+	for i < end && !s.done() {
+		start := i
+		i = nextSpecial(s.src[:end], i, "{;}")
+		if i == end {
+			break
+		}
+		prelude := strings.TrimSpace(s.src[start:i])
+		name := ""
+		if strings.HasPrefix(prelude, "@") {
+			name = asciiLower(prelude[1:skipName(prelude, 1)])
+		}
+		if s.src[i] != '{' {
+			if i++; name == "layer" || name == "import" {
+				nodes = append(nodes, s.parse(start, i)...)
+			}
+			continue
+		}
+		close, nested := blockEnd(s.src[:end], i)
+		open := i
+		i = close
+		if name != "" {
+			if name != "layer" && !hasFold(s.src[open:close], "@layer") {
+				continue
+			}
+			s.advance(start)
+			at := &AtRule{Name: prelude[1:skipName(prelude, 1)], Prelude: strings.TrimSpace(prelude[skipName(prelude, 1):]), Pos: s.pos}
+			inner := close
+			if inner > open && s.src[inner-1] == '}' {
+				inner--
+			}
+			at.Rules = append([]Node{}, s.rules(open+1, inner, cascaded && name == "layer")...)
+			nodes = append(nodes, at)
+			continue
+		}
+		if !cascaded {
+			continue
+		}
+		keep := nested || strings.Contains(prelude, "/*")
+		for _, sel := range splitSelectorList(prelude) {
+			if k := s.key(sel); s.wanted[k] {
+				keep, s.seen[k] = true, true
+			}
+		}
+		if keep {
+			nodes = append(nodes, s.parse(start, close)...)
+		}
+	}
+	return nodes
+}
+
+// parse returns the nodes parsed from src[start:end].
+func (s *selection) parse(start, end int) []Node {
+	s.advance(start)
+	o := s.o
+	o.start = s.pos
+	part, err := parseReader(strings.NewReader(s.src[start:end]), o.filename, o)
+	switch err := err.(type) {
+	case nil:
+	case ErrorList:
+		s.errs = append(s.errs, err...)
+	case *ParseError:
+		s.errs = append(s.errs, err)
+	default:
+		s.err = err
+	}
+	return part.Rules
+}
+
+// advance moves pos on to the offset i of src, past s.at.
+func (s *selection) advance(i int) {
+	for ; s.at < i; s.at++ {
+		c := s.src[s.at]
+		if c == '\r' {
+			if c = '\n'; s.at+1 < len(s.src) && s.src[s.at+1] == '\n' {
+				c = ' '
+			}
+		}
+		advancePosition(&s.pos, c)
+	}
+}
+
+// hasFold reports whether s contains the lower case ASCII substr in any
+// case.
+func hasFold(s, substr string) bool {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextSpecial returns the index of the first byte of s from i on that is
+// one of chars, outside of comments, strings and unquoted url() arguments,
+// as the parser reads them, or len(s). Like Decoder.scan, it finds where
+// statements and blocks end without tokenizing them.
+func nextSpecial(s string, i int, chars string) int {
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '\\':
+			i += 2
+		case c == '"' || c == '\'':
+			for i++; i < len(s) && s[i] != c && s[i] != '\n'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			i++
+		case (c == 'u' || c == 'U') && unquotedURLEnd(s, i) > 0:
+			i = unquotedURLEnd(s, i)
+		case c == '/' && strings.HasPrefix(s[i:], "/*"):
+			if j := strings.Index(s[i+2:], "*/"); j >= 0 {
+				i += j + 4
+			} else {
+				i = len(s)
+			}
+		case strings.IndexByte(chars, c) >= 0:
+			return i
+		default:
+			i++
+		}
+	}
+	return len(s)
+}
+
+// blockEnd returns the index just past the '}' closing the block opened at
+// i, or len(s), and whether the block has blocks in it.
+func blockEnd(s string, i int) (int, bool) {
+	depth, nested := 0, false
+	for i < len(s) {
+		if s[i] == '{' {
+			nested = nested || depth > 0
+			depth++
+		} else if depth--; depth == 0 {
+			return i + 1, nested
+		}
+		i = nextSpecial(s, i+1, "{}")
+	}
+	return len(s), nested
+}
@@ -0,0 +1,318 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeSelector returns the selector list s in canonical form: members
+// separated by ", ", one space around combinators and for descendants,
+// lowercased type selectors, pseudo-class and pseudo-element names, and
+// attribute values in double quotes. Classes, ids and escapes keep their
+// case and spelling. Parse normalizes selectors this way unless
+// RawSelectors is set, so that ".a>.b" and ".a > .b" are the same Rule.
+func NormalizeSelector(s string) (string, error) {
+	return normalizeSelector(s, options{})
+}
+
+// NormalizeRule returns the selector s as the Rule that Parse and Unmarshal
+// key its rules by, for looking them up by hand: s normalized as by
+// NormalizeSelector, so that ".a>.b", " .a > .b " and ".a\n>\t.b" give the
+// same Rule. A selector NormalizeSelector cannot read is only trimmed, with
+// each run of whitespace outside of strings made one space, as all keys are
+// under RawSelectors.
+func NormalizeRule(s string) Rule {
+	return Rule(ruleKey(s, options{}))
+}
+
+// ruleKey returns the selector s as Parse keys it with the options o.
+func ruleKey(s string, o options) string {
+	if !o.rawSelectors {
+		if n, err := normalizeSelector(s, o); err == nil {
+			return n
+		}
+	}
+	return normalizeSpace(s)
+}
+
+// String returns the selector in the canonical form of NormalizeSelector,
+// or as it is if it cannot be read.
+func (rule Rule) String() string {
+	s, err := NormalizeSelector(string(rule))
+	if err != nil {
+		return string(rule)
+	}
+	return s
+}
+
+// selectorArgPseudos are the functional pseudo-classes and pseudo-elements
+// whose argument is a selector list.
+var selectorArgPseudos = map[string]bool{
+	"is": true, "not": true, "has": true, "where": true, "matches": true, "-webkit-any": true,
+	"-moz-any": true, "host": true, "host-context": true, "slotted": true, "cue": true,
+}
+
+func normalizeSelector(s string, o options) (string, error) {
+	members := splitSelectorList(s)
+	for i, m := range members {
+		m = strings.TrimSpace(m)
+		if m == "" {
+			return "", fmt.Errorf("empty selector in %q", s)
+		}
+		n, err := normalizeComplex(m, o)
+		if err != nil {
+			return "", err
+		}
+		members[i] = n
+	}
+	return strings.Join(members, ", "), nil
+}
+
+// normalizeComplex normalizes a complex selector, which may start with a
+// combinator, as relative and nested selectors do.
+func normalizeComplex(s string, o options) (string, error) {
+	var (
+		b     strings.Builder
+		space bool
+		comb  string
+		start = true // at the start of a compound
+	)
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case isSelectorSpace(c):
+			space = true
+			i++
+			continue
+		case c == '>' || c == '+' || c == '~' || strings.HasPrefix(s[i:], "||"):
+			if comb != "" {
+				return "", fmt.Errorf("selector %q has two combinators in a row", s)
+			}
+			comb = s[i : i+1]
+			if c == '|' {
+				comb = "||"
+			}
+			i += len(comb)
+			continue
+		}
+		switch {
+		case comb != "" && b.Len() > 0:
+			b.WriteString(" " + comb + " ")
+			start = true
+		case comb != "":
+			b.WriteString(comb + " ")
+			start = true
+		case space && b.Len() > 0:
+			b.WriteByte(' ')
+			start = true
+		}
+		space, comb = false, ""
+		var end int
+		switch {
+		case c == '[':
+			end = skipBracket(s, i)
+			attr, err := normalizeAttribute(s[i:end])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(attr)
+		case c == ':':
+			name := i + 1
+			if name < len(s) && s[name] == ':' {
+				name++
+			}
+			end = skipName(s, name)
+			b.WriteString(s[i:name] + asciiLower(s[name:end]))
+			if end < len(s) && s[end] == '(' {
+				args, next := parenthesized(s, end)
+				if !strings.HasSuffix(s[:next], ")") {
+					return "", fmt.Errorf("selector %q has an unclosed (", s)
+				}
+				args, err := normalizePseudoArgs(asciiLower(s[name:end]), args, o)
+				if err != nil {
+					return "", err
+				}
+				b.WriteString("(" + args + ")")
+				end = next
+			}
+		case c == '.' || c == '#':
+			end = skipName(s, i+1)
+			b.WriteString(s[i:end])
+		case c == '"' || c == '\'':
+			end = skipString(s, i)
+			b.WriteString(s[i:end])
+		case c == '\\' || isNameRune(rune(c)) || c == '*':
+			end = i + 1
+			if c != '*' {
+				end = skipName(s, i)
+			}
+			if end < len(s) && s[end] == '|' && !strings.HasPrefix(s[end:], "||") {
+				if end++; end < len(s) && s[end] == '*' {
+					end++
+				} else {
+					end = skipName(s, end)
+				}
+			}
+			if start {
+				b.WriteString(o.typeSelector(s[i:end]))
+			} else {
+				b.WriteString(s[i:end])
+			}
+		case c == ']' || c == ')' || c == '(':
+			return "", fmt.Errorf("selector %q has an unbalanced %c", s, c)
+		default:
+			end = i + 1
+			b.WriteByte(c)
+		}
+		start = false
+		i = end
+	}
+	if comb != "" {
+		return "", fmt.Errorf("selector %q ends with a combinator", s)
+	}
+	return b.String(), nil
+}
+
+// normalizePseudoArgs normalizes the argument of the functional pseudo-class
+// or pseudo-element name.
+func normalizePseudoArgs(name, args string, o options) (string, error) {
+	switch {
+	case selectorArgPseudos[name]:
+		return normalizeSelector(args, o)
+	case strings.HasPrefix(name, "nth-"):
+		anb, sel := args, ""
+		for j := 1; j+3 < len(args); j++ {
+			if isSelectorSpace(args[j-1]) && strings.EqualFold(args[j:j+2], "of") && isSelectorSpace(args[j+2]) {
+				anb, sel = args[:j], args[j+3:]
+				break
+			}
+		}
+		anb = asciiLower(strings.Join(strings.Fields(anb), ""))
+		if sel == "" {
+			return anb, nil
+		}
+		sel, err := normalizeSelector(sel, o)
+		if err != nil {
+			return "", err
+		}
+		return anb + " of " + sel, nil
+	}
+	return strings.Join(strings.Fields(args), " "), nil
+}
+
+// normalizeAttribute normalizes the attribute selector attr, brackets
+// included, quoting its value with double quotes and putting one space
+// before its flag.
+func normalizeAttribute(attr string) (string, error) {
+	if !strings.HasSuffix(attr, "]") {
+		return "", fmt.Errorf("attribute selector %q is not closed", attr)
+	}
+	s := strings.TrimSpace(attr[1 : len(attr)-1])
+	i := 0
+	if strings.HasPrefix(s, "*|") {
+		i = 2
+	} else if strings.HasPrefix(s, "|") {
+		i = 1
+	}
+	i = skipName(s, i)
+	if i < len(s) && s[i] == '|' && !strings.HasPrefix(s[i:], "|=") {
+		i = skipName(s, i+1)
+	}
+	name := s[:i]
+	if name == "" {
+		return "", fmt.Errorf("attribute selector %q has no name", attr)
+	}
+	rest := strings.TrimSpace(s[i:])
+	if rest == "" {
+		return "[" + name + "]", nil
+	}
+	op := "="
+	if rest[0] != '=' {
+		if len(rest) < 2 || rest[1] != '=' || !strings.ContainsRune("~|^$*", rune(rest[0])) {
+			return "", fmt.Errorf("attribute selector %q has an invalid operator", attr)
+		}
+		op = rest[:2]
+	}
+	rest = strings.TrimSpace(rest[len(op):])
+	var value string
+	var end int
+	switch {
+	case rest == "":
+		return "", fmt.Errorf("attribute selector %q has no value", attr)
+	case rest[0] == '"':
+		end = skipString(rest, 0)
+		value = rest[:end]
+	case rest[0] == '\'':
+		end = skipString(rest, 0)
+		inner := strings.TrimSuffix(rest[1:end], "'")
+		value = `"` + strings.NewReplacer(`\'`, `'`, `"`, `\"`).Replace(inner) + `"`
+	default:
+		end = skipName(rest, 0)
+		if end == 0 {
+			return "", fmt.Errorf("attribute selector %q has an invalid value", attr)
+		}
+		value = `"` + rest[:end] + `"`
+	}
+	out := "[" + name + op + value
+	switch flag := strings.TrimSpace(rest[end:]); {
+	case flag == "":
+	case skipName(flag, 0) == len(flag):
+		out += " " + asciiLower(flag)
+	default:
+		return "", fmt.Errorf("attribute selector %q has an invalid flag", attr)
+	}
+	return out + "]", nil
+}
+
+func isSelectorSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// ReplaceIDSelectors returns a copy of sheet in which each id selector of
+// the selectors of rules, such as the "#nav" of "ul#nav > li", is rewritten
+// to the attribute selector [id="nav"], which matches the same elements
+// with the specificity of a class. Attribute values, strings and at-rule
+// preludes are left as they are.
+func ReplaceIDSelectors(sheet *StyleSheet) *StyleSheet {
+	out := sheet.Clone()
+	Walk(out, func(n Node) bool {
+		if r, ok := n.(*Rule); ok {
+			*r = Rule(replaceIDs(string(*r)))
+		}
+		return true
+	})
+	return out
+}
+
+// replaceIDs returns the selector sel with its id selectors rewritten to
+// attribute selectors.
+func replaceIDs(sel string) string {
+	if strings.IndexByte(sel, '#') < 0 {
+		return sel
+	}
+	var b strings.Builder
+	brackets := 0
+	for i := 0; i < len(sel); {
+		c := sel[i]
+		end := i + 1
+		switch {
+		case c == '"' || c == '\'':
+			end = skipString(sel, i)
+		case c == '\\':
+			end = skipEscape(sel, i)
+		case c == '[':
+			brackets++
+		case c == ']':
+			brackets--
+		case c == '#' && brackets == 0:
+			if name := skipName(sel, i+1); name > i+1 {
+				b.WriteString("[id=" + QuoteString(unescape(sel[i+1:name])) + "]")
+				i = name
+				continue
+			}
+		}
+		b.WriteString(sel[i:end])
+		i = end
+	}
+	return b.String()
+}
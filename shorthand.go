@@ -0,0 +1,625 @@
+package css
+
+import "strings"
+
+// ExpandShorthands makes Parse, Unmarshal and the other parsing functions
+// replace each shorthand declaration they understand, as Get does, with
+// the longhands it sets, in the order of the shorthand's longhands and
+// with its !important flag and position. Intermediate shorthands are left
+// out, so that border gives the width, style and color of each side. The
+// longhands have the shorthand as their Origin. Shorthands that cannot
+// be split, such as those holding var(), are kept as written.
+func ExpandShorthands() Option {
+	return func(o *options) {
+		o.expand = true
+	}
+}
+
+// CollapseShorthands makes Marshal write the longhands ExpandShorthands
+// made of a shorthand as the shorthand again, if they still follow one
+// another with the values it gave them. Longhands written as such, and
+// expanded ones that were changed, removed or moved apart, are written as
+// they are.
+func CollapseShorthands() MarshalOption {
+	return func(o *marshalOptions) {
+		o.collapse = true
+	}
+}
+
+// expandShorthands expands the shorthand declarations of the rules in
+// nodes, at any depth, as described for ExpandShorthands.
+func expandShorthands(nodes []Node) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			n.Declarations = expandDeclarations(n.Declarations)
+			expandShorthands(n.Rules)
+		case *AtRule:
+			expandShorthands(n.Rules)
+		}
+	}
+}
+
+func expandDeclarations(decls []Declaration) []Declaration {
+	var out []Declaration
+	for i, d := range decls {
+		longhands := expandDeclaration(d, &Origin{Property: d.Property, Value: d.Value, Pos: d.Pos})
+		if longhands == nil {
+			if out != nil {
+				out = append(out, d)
+			}
+			continue
+		}
+		if out == nil {
+			out = append(make([]Declaration, 0, len(decls)+len(longhands)), decls[:i]...)
+		}
+		longhands[0].Comments = d.Comments
+		longhands[len(longhands)-1].TrailingComments = d.TrailingComments
+		out = append(out, longhands...)
+	}
+	if out == nil {
+		return decls
+	}
+	return out
+}
+
+// expandDeclaration returns the longhands of the shorthand declaration d,
+// with origin as their Origin, or nil if d is not a shorthand that can be
+// split.
+func expandDeclaration(d Declaration, origin *Origin) []Declaration {
+	prop := asciiLower(d.Property)
+	m := expandShorthand(prop, d.Value)
+	if m == nil {
+		return nil
+	}
+	var out []Declaration
+	for _, l := range shorthandLonghands(prop) {
+		if v, ok := m[l]; ok && shorthandLonghands(l) == nil {
+			out = append(out, Declaration{Property: l, Value: v, Important: d.Important, Pos: d.Pos, Origin: origin})
+		}
+	}
+	return out
+}
+
+// collapseShorthands returns decls with each run of longhands expanded
+// from one shorthand replaced by the shorthand, as described for
+// CollapseShorthands.
+func collapseShorthands(decls []Declaration) []Declaration {
+	var out []Declaration
+	for i := 0; i < len(decls); {
+		o, j := decls[i].Origin, i+1
+		for o != nil && j < len(decls) && decls[j].Origin == o {
+			j++
+		}
+		run := decls[i:j]
+		if o != nil && isExpansion(run, o) {
+			if out == nil {
+				out = append(make([]Declaration, 0, len(decls)), decls[:i]...)
+			}
+			out = append(out, Declaration{
+				Property: o.Property, Value: o.Value, Important: run[0].Important, Pos: o.Pos,
+				Comments: run[0].Comments, TrailingComments: run[len(run)-1].TrailingComments,
+			})
+		} else if out != nil {
+			out = append(out, run...)
+		}
+		i = j
+	}
+	if out == nil {
+		return decls
+	}
+	return out
+}
+
+// isExpansion reports whether run holds exactly the longhands the
+// shorthand o expands to, in order and with one importance.
+func isExpansion(run []Declaration, o *Origin) bool {
+	want := expandDeclaration(Declaration{Property: o.Property, Value: o.Value}, o)
+	if len(want) != len(run) {
+		return false
+	}
+	for i, w := range want {
+		d := run[i]
+		if d.Property != w.Property || d.Value != w.Value || d.Important != run[0].Important {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the value css, as returned by Unmarshal, gives property for
+// selector, which is read as Parse reads selectors, so that ".card " finds
+// ".card". A longhand that is not set is derived from a shorthand setting
+// it, such as margin-top from margin, and a shorthand that is not set is
+// put together from its longhands if they are all set with the same
+// importance. Shorthands of margin, padding, inset, border, border-radius
+// and background are understood, as are two-axis ones such as overflow and
+// gap. Since css
+// does not keep the order of declarations, a longhand set directly takes
+// precedence over a shorthand, and the more specific of two shorthands,
+// such as border-top over border, is used.
+func Get(css map[Rule]map[string]string, selector, property string) (string, bool) {
+	sel := Rule(strings.Join(strings.Fields(selector), " "))
+	if r, err := newRule(selector); err == nil && len(r.Selectors) == 1 {
+		sel = r.Selectors[0]
+	}
+	styles, ok := css[sel]
+	if !ok {
+		return "", false
+	}
+	return getProperty(styles, options{}.property(strings.TrimSpace(property)))
+}
+
+func getProperty(styles map[string]string, prop string) (string, bool) {
+	if v, ok := styles[prop]; ok {
+		return v, true
+	}
+	for _, s := range shorthandOrder {
+		v, ok := styles[s]
+		if !ok {
+			continue
+		}
+		v, important := importance(v)
+		if l, ok := expandShorthand(s, v)[prop]; ok {
+			if important {
+				l += " !important"
+			}
+			return l, true
+		}
+	}
+	return composeShorthand(styles, prop)
+}
+
+// shorthandOrder lists the shorthands understood by Get, the more specific
+// first.
+var shorthandOrder = []string{
+	"border-top", "border-right", "border-bottom", "border-left",
+	"border-width", "border-style", "border-color", "border",
+	"margin", "padding", "inset", "border-radius", "background",
+	"overflow", "overscroll-behavior", "gap", "place-content", "place-items", "place-self",
+}
+
+// boxShorthands maps the shorthands taking one value per side of the box to
+// their longhands in top, right, bottom, left order.
+var boxShorthands = map[string][4]string{
+	"margin":       {"margin-top", "margin-right", "margin-bottom", "margin-left"},
+	"padding":      {"padding-top", "padding-right", "padding-bottom", "padding-left"},
+	"inset":        {"top", "right", "bottom", "left"},
+	"border-width": {"border-top-width", "border-right-width", "border-bottom-width", "border-left-width"},
+	"border-style": {"border-top-style", "border-right-style", "border-bottom-style", "border-left-style"},
+	"border-color": {"border-top-color", "border-right-color", "border-bottom-color", "border-left-color"},
+}
+
+// pairShorthands maps the shorthands taking one value per axis to their two
+// longhands; a single value sets both.
+var pairShorthands = map[string][2]string{
+	"overflow":            {"overflow-x", "overflow-y"},
+	"overscroll-behavior": {"overscroll-behavior-x", "overscroll-behavior-y"},
+	"gap":                 {"row-gap", "column-gap"},
+	"place-content":       {"align-content", "justify-content"},
+	"place-items":         {"align-items", "justify-items"},
+	"place-self":          {"align-self", "justify-self"},
+}
+
+var boxSides = [4]string{"top", "right", "bottom", "left"}
+
+// radiusCorners lists the longhands of border-radius in the order its values
+// are given.
+var radiusCorners = [4]string{
+	"border-top-left-radius", "border-top-right-radius", "border-bottom-right-radius", "border-bottom-left-radius",
+}
+
+// backgroundLonghands lists the longhands of background in the order
+// composeShorthand writes them.
+var backgroundLonghands = []string{
+	"background-color", "background-image", "background-repeat", "background-attachment",
+	"background-position", "background-size", "background-origin", "background-clip",
+}
+
+// expandShorthand returns the longhands the shorthand prop sets to value,
+// including intermediate shorthands such as border-top for border, or nil
+// if prop is not understood or value cannot be split.
+func expandShorthand(prop, value string) map[string]string {
+	if strings.Contains(strings.ToLower(value), "var(") {
+		return nil
+	}
+	if isWideKeyword(value) {
+		m := make(map[string]string)
+		for _, l := range shorthandLonghands(prop) {
+			m[l] = value
+		}
+		return m
+	}
+	if longhands, ok := boxShorthands[prop]; ok {
+		parts, ok := spreadBox(splitComponents(value))
+		if !ok {
+			return nil
+		}
+		m := make(map[string]string, 4)
+		for i, l := range longhands {
+			m[l] = parts[i]
+		}
+		return m
+	}
+	if prop == "border-radius" {
+		h, v, ok := splitRadius(value)
+		if !ok {
+			return nil
+		}
+		m := make(map[string]string, 4)
+		for i, l := range radiusCorners {
+			m[l] = h[i]
+			if v != h {
+				m[l] += " " + v[i]
+			}
+		}
+		return m
+	}
+	if longhands, ok := pairShorthands[prop]; ok {
+		parts := Fields(value)
+		switch len(parts) {
+		case 1:
+			parts = append(parts, parts[0])
+		case 2:
+		default:
+			return nil
+		}
+		return map[string]string{longhands[0]: parts[0], longhands[1]: parts[1]}
+	}
+	switch {
+	case prop == "border":
+		w, s, c, ok := splitBorder(value)
+		if !ok {
+			return nil
+		}
+		m := map[string]string{"border-width": w, "border-style": s, "border-color": c}
+		for _, side := range boxSides {
+			m["border-"+side] = value
+			m["border-"+side+"-width"], m["border-"+side+"-style"], m["border-"+side+"-color"] = w, s, c
+		}
+		return m
+	case strings.HasPrefix(prop, "border-") && isBoxSide(prop[len("border-"):]):
+		w, s, c, ok := splitBorder(value)
+		if !ok {
+			return nil
+		}
+		return map[string]string{prop + "-width": w, prop + "-style": s, prop + "-color": c}
+	case prop == "background":
+		return splitBackground(value)
+	}
+	return nil
+}
+
+// shorthandLonghands returns the properties expandShorthand sets for prop.
+func shorthandLonghands(prop string) []string {
+	if l, ok := boxShorthands[prop]; ok {
+		return l[:]
+	}
+	if l, ok := pairShorthands[prop]; ok {
+		return l[:]
+	}
+	if prop == "border-radius" {
+		return radiusCorners[:]
+	}
+	switch {
+	case prop == "border":
+		out := []string{"border-width", "border-style", "border-color"}
+		for _, side := range boxSides {
+			out = append(out, "border-"+side, "border-"+side+"-width", "border-"+side+"-style", "border-"+side+"-color")
+		}
+		return out
+	case strings.HasPrefix(prop, "border-") && isBoxSide(prop[len("border-"):]):
+		return []string{prop + "-width", prop + "-style", prop + "-color"}
+	case prop == "background":
+		return backgroundLonghands
+	}
+	return nil
+}
+
+func isBoxSide(s string) bool {
+	return s == "top" || s == "right" || s == "bottom" || s == "left"
+}
+
+func isWideKeyword(value string) bool {
+	switch strings.ToLower(value) {
+	case "inherit", "initial", "unset", "revert", "revert-layer":
+		return true
+	}
+	return false
+}
+
+// splitBorder splits the value of border or one of its sides into its
+// width, style and color, giving the initial value to those left out.
+func splitBorder(value string) (width, style, color string, ok bool) {
+	parts := splitComponents(value)
+	if len(parts) == 0 || len(parts) > 3 {
+		return "", "", "", false
+	}
+	for _, p := range parts {
+		switch {
+		case width == "" && borderWidth(p) == nil:
+			width = p
+		case style == "" && borderStyle(p) == nil:
+			style = p
+		case color == "" && colorValue(p) == nil:
+			color = p
+		default:
+			return "", "", "", false
+		}
+	}
+	if width == "" {
+		width = "medium"
+	}
+	if style == "" {
+		style = "none"
+	}
+	if color == "" {
+		color = "currentcolor"
+	}
+	return width, style, color, true
+}
+
+var (
+	backgroundRepeat     = keywordValue("repeat", "repeat-x", "repeat-y", "no-repeat", "space", "round")
+	backgroundAttachment = keywordValue("scroll", "fixed", "local")
+	backgroundBox        = keywordValue("border-box", "padding-box", "content-box", "text")
+	backgroundPosition   = either(lengthValue(true, true), keywordValue("left", "right", "top", "bottom", "center"))
+	backgroundSize       = either(lengthValue(true, false), keywordValue("auto", "cover", "contain"))
+)
+
+// imageFunctions are the functions producing an image.
+var imageFunctions = map[string]bool{
+	"url": true, "image": true, "image-set": true, "-webkit-image-set": true, "cross-fade": true, "element": true,
+	"linear-gradient": true, "radial-gradient": true, "conic-gradient": true, "repeating-linear-gradient": true,
+	"repeating-radial-gradient": true, "repeating-conic-gradient": true,
+}
+
+// splitBackground returns the longhands of the background value, one value
+// per layer joined by commas, or nil if value cannot be split. Only the
+// last layer may have a color.
+func splitBackground(value string) map[string]string {
+	layers := splitSelectorList(value)
+	values := make(map[string][]string, len(backgroundLonghands))
+	color := "transparent"
+	for i, layer := range layers {
+		l := map[string]string{
+			"background-image": "none", "background-repeat": "repeat", "background-attachment": "scroll",
+			"background-position": "0% 0%", "background-size": "auto",
+		}
+		var pos, size, boxes []string
+		var repeat []string
+		parts := splitComponents(strings.ReplaceAll(layer, "/", " / "))
+		for j := 0; j < len(parts); j++ {
+			p := parts[j]
+			name, fn := functionName(strings.ToLower(p))
+			switch {
+			case p == "/":
+				if len(pos) == 0 {
+					return nil
+				}
+				for j+1 < len(parts) && len(size) < 2 && backgroundSize(parts[j+1]) == nil {
+					j++
+					size = append(size, parts[j])
+				}
+				if len(size) == 0 {
+					return nil
+				}
+			case strings.EqualFold(p, "none") || fn && imageFunctions[name]:
+				l["background-image"] = p
+			case backgroundRepeat(p) == nil:
+				repeat = append(repeat, p)
+			case backgroundAttachment(p) == nil:
+				l["background-attachment"] = p
+			case backgroundBox(p) == nil:
+				boxes = append(boxes, p)
+			case backgroundPosition(p) == nil:
+				pos = append(pos, p)
+			case i == len(layers)-1 && colorValue(p) == nil:
+				color = p
+			default:
+				return nil
+			}
+		}
+		if len(repeat) > 0 {
+			l["background-repeat"] = strings.Join(repeat, " ")
+		}
+		if len(pos) > 0 {
+			l["background-position"] = strings.Join(pos, " ")
+		}
+		if len(size) > 0 {
+			l["background-size"] = strings.Join(size, " ")
+		}
+		l["background-origin"], l["background-clip"] = "padding-box", "border-box"
+		switch len(boxes) {
+		case 0:
+		case 1:
+			l["background-origin"], l["background-clip"] = boxes[0], boxes[0]
+		case 2:
+			l["background-origin"], l["background-clip"] = boxes[0], boxes[1]
+		default:
+			return nil
+		}
+		for _, p := range backgroundLonghands[1:] {
+			values[p] = append(values[p], l[p])
+		}
+	}
+	m := map[string]string{"background-color": color}
+	for _, p := range backgroundLonghands[1:] {
+		m[p] = strings.Join(values[p], ", ")
+	}
+	return m
+}
+
+// composeShorthand puts the shorthand prop together from the values styles
+// has or derives for its longhands, if they all have the same importance.
+func composeShorthand(styles map[string]string, prop string) (string, bool) {
+	var parts []string
+	box, isBox := boxShorthands[prop]
+	pair, isPair := pairShorthands[prop]
+	switch {
+	case isPair:
+		for _, l := range pair {
+			v, ok := getProperty(styles, l)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, v)
+		}
+	case isBox:
+		parts = make([]string, 4)
+		for i, l := range box {
+			v, ok := getProperty(styles, l)
+			if !ok {
+				return "", false
+			}
+			parts[i] = v
+		}
+	case prop == "border":
+		v, ok := getProperty(styles, "border-top")
+		if !ok {
+			return "", false
+		}
+		for _, side := range boxSides[1:] {
+			if w, ok := getProperty(styles, "border-"+side); !ok || w != v {
+				return "", false
+			}
+		}
+		return v, true
+	case strings.HasPrefix(prop, "border-") && isBoxSide(prop[len("border-"):]):
+		for _, l := range shorthandLonghands(prop) {
+			v, ok := getProperty(styles, l)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, v)
+		}
+	case prop == "border-radius":
+		for _, l := range radiusCorners {
+			v, ok := getProperty(styles, l)
+			if !ok {
+				return "", false
+			}
+			parts = append(parts, v)
+		}
+	case prop == "background":
+		for _, l := range backgroundLonghands {
+			v, ok := getProperty(styles, l)
+			if !ok || len(splitSelectorList(v)) > 1 {
+				return "", false
+			}
+			parts = append(parts, v)
+		}
+	default:
+		return "", false
+	}
+	important := false
+	for i, p := range parts {
+		v, imp := importance(p)
+		if i > 0 && imp != important {
+			return "", false
+		}
+		parts[i], important = v, imp
+	}
+	var v string
+	switch {
+	case isBox:
+		v = compactBox(parts)
+	case prop == "border-radius":
+		var h, w [4]string
+		for i, p := range parts {
+			c := Fields(p)
+			if len(c) == 0 || len(c) > 2 {
+				return "", false
+			}
+			h[i], w[i] = c[0], c[len(c)-1]
+		}
+		v = joinRadius(h, w)
+	case isPair && parts[0] == parts[1]:
+		v = parts[0]
+	case prop == "background":
+		v = strings.Join(parts[:5], " ") + " / " + strings.Join(parts[5:], " ")
+	default:
+		v = strings.Join(parts, " ")
+	}
+	if important {
+		v += " !important"
+	}
+	return v, true
+}
+
+// compactBox joins the top, right, bottom and left values of a box
+// shorthand in the shortest form that keeps them.
+func compactBox(v []string) string {
+	switch {
+	case v[0] == v[1] && v[1] == v[2] && v[2] == v[3]:
+		return v[0]
+	case v[0] == v[2] && v[1] == v[3]:
+		return v[0] + " " + v[1]
+	case v[1] == v[3]:
+		return v[0] + " " + v[1] + " " + v[2]
+	}
+	return strings.Join(v, " ")
+}
+
+// splitRadius returns the horizontal and vertical radii of the corners set
+// by the border-radius value, in the order of radiusCorners. Each side of
+// a '/' gives one to four radii, spread over the corners as the sides of a
+// box shorthand are; with no '/', the vertical radii are the horizontal
+// ones.
+func splitRadius(value string) (h, v [4]string, ok bool) {
+	before, after, slash := cutSlash(value)
+	if h, ok = spreadBox(splitComponents(before)); !ok {
+		return h, v, false
+	}
+	if !slash {
+		return h, h, true
+	}
+	v, ok = spreadBox(splitComponents(after))
+	return h, v, ok
+}
+
+// joinRadius writes the border-radius of the horizontal radii h and
+// vertical radii v in its shortest form.
+func joinRadius(h, v [4]string) string {
+	if h == v {
+		return compactBox(h[:])
+	}
+	return compactBox(h[:]) + " / " + compactBox(v[:])
+}
+
+// spreadBox returns the four values one to four values of a box shorthand
+// give the sides or corners.
+func spreadBox(parts []string) (box [4]string, ok bool) {
+	switch len(parts) {
+	case 1:
+		return [4]string{parts[0], parts[0], parts[0], parts[0]}, true
+	case 2:
+		return [4]string{parts[0], parts[1], parts[0], parts[1]}, true
+	case 3:
+		return [4]string{parts[0], parts[1], parts[2], parts[1]}, true
+	case 4:
+		return [4]string{parts[0], parts[1], parts[2], parts[3]}, true
+	}
+	return box, false
+}
+
+// cutSlash slices value around its first '/' outside of strings and
+// parentheses.
+func cutSlash(value string) (before, after string, found bool) {
+	depth := 0
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '"' || c == '\'':
+			i = skipString(value, i) - 1
+		case c == '\\':
+			i++
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '/' && depth == 0:
+			return value[:i], value[i+1:], true
+		}
+	}
+	return value, "", false
+}
@@ -0,0 +1,104 @@
+package css
+
+import (
+	"encoding/json"
+	"strings"
+	"text/scanner"
+)
+
+// MarshalWithSourceMap is like Marshal but also returns a version 3 source
+// map of the CSS, naming file as the generated file. Each rule, at-rule and
+// declaration is mapped to the position it was parsed from, and the
+// sources of the map are the filenames of those positions in order of first
+// use; sources parsed without a filename are listed as "". Nodes without a
+// position, such as those built by hand, are not mapped.
+func MarshalWithSourceMap(sheet *StyleSheet, file string, opts ...MarshalOption) (css, sourceMap []byte, err error) {
+	e := &encoder{sm: &sourceMapper{sources: map[string]int{}}}
+	for _, opt := range opts {
+		opt(&e.opts)
+	}
+	e.nodes(sheet.Rules, 0)
+	sourceMap, err = json.Marshal(struct {
+		Version  int      `json:"version"`
+		File     string   `json:"file,omitempty"`
+		Sources  []string `json:"sources"`
+		Names    []string `json:"names"`
+		Mappings string   `json:"mappings"`
+	}{3, file, e.sm.names, []string{}, e.sm.mappings.String()})
+	return e.buf.Bytes(), sourceMap, err
+}
+
+// sourceMapper builds the mappings of a source map as an encoder writes.
+// Fields prefixed with prev hold the values the next segment is relative
+// to.
+type sourceMapper struct {
+	sources  map[string]int
+	names    []string
+	mappings strings.Builder
+
+	scanned  int // bytes of the output accounted for in line and col
+	line     int
+	col      int
+	segments int // segments on the current line
+
+	prevCol, prevSource, prevLine, prevSourceCol int
+}
+
+// mark maps the end of out, which is what the encoder writes next, to pos.
+func (m *sourceMapper) mark(out []byte, pos scanner.Position) {
+	if pos.Line <= 0 {
+		return
+	}
+	for _, c := range string(out[m.scanned:]) {
+		switch {
+		case c == '\n':
+			m.mappings.WriteByte(';')
+			m.line++
+			m.col, m.prevCol, m.segments = 0, 0, 0
+		case c >= 0x10000:
+			m.col += 2
+		default:
+			m.col++
+		}
+	}
+	m.scanned = len(out)
+	src, ok := m.sources[pos.Filename]
+	if !ok {
+		src = len(m.names)
+		m.sources[pos.Filename] = src
+		m.names = append(m.names, pos.Filename)
+	}
+	if m.segments > 0 {
+		m.mappings.WriteByte(',')
+	}
+	m.segments++
+	line, col := pos.Line-1, pos.Column-1
+	if col < 0 {
+		col = 0
+	}
+	for _, v := range [...]int{m.col - m.prevCol, src - m.prevSource, line - m.prevLine, col - m.prevSourceCol} {
+		writeVLQ(&m.mappings, v)
+	}
+	m.prevCol, m.prevSource, m.prevLine, m.prevSourceCol = m.col, src, line, col
+}
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes v as a base64 VLQ, with its sign in the lowest bit.
+func writeVLQ(b *strings.Builder, v int) {
+	u := v << 1
+	if v < 0 {
+		u = -v<<1 | 1
+	}
+	for {
+		digit := u & 31
+		u >>= 5
+		if u > 0 {
+			digit |= 32
+		}
+		b.WriteByte(base64Digits[digit])
+		if u == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package css
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// readVLQ reads the base64 VLQ at the start of s, returning its value and
+// the rest of s.
+func readVLQ(t *testing.T, s string) (int, string) {
+	t.Helper()
+	u, shift := 0, 0
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(base64Digits, s[i])
+		if digit < 0 {
+			t.Fatalf("%q is not a base64 digit", s[i])
+		}
+		u |= digit & 31 << shift
+		shift += 5
+		if digit&32 == 0 {
+			if u&1 != 0 {
+				return -(u >> 1), s[i+1:]
+			}
+			return u >> 1, s[i+1:]
+		}
+	}
+	t.Fatalf("unterminated VLQ %q", s)
+	return 0, ""
+}
+
+func TestVLQ(t *testing.T) {
+	tests := []struct {
+		v    int
+		want string
+	}{
+		{0, "A"},
+		{1, "C"},
+		{-1, "D"},
+		{15, "e"},
+		{-15, "f"},
+		{16, "gB"},
+		{31, "+B"},
+		{32, "gC"},
+		{-32, "hC"},
+		{1000, "w+B"},
+		{1 << 30, "ggggggC"},
+		{-(1 << 30), "hgggggC"},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		writeVLQ(&b, tt.v)
+		if b.String() != tt.want {
+			t.Errorf("writeVLQ(%d) = %q, want %q", tt.v, b.String(), tt.want)
+		}
+		if got, rest := readVLQ(t, b.String()); got != tt.v || rest != "" {
+			t.Errorf("readVLQ(%q) = %d, %q, want %d", b.String(), got, rest, tt.v)
+		}
+	}
+	var b strings.Builder
+	for v := -70000; v <= 70000; v += 7 {
+		b.Reset()
+		writeVLQ(&b, v)
+		if got, _ := readVLQ(t, b.String()); got != v {
+			t.Fatalf("%d round trips to %d through %q", v, got, b.String())
+		}
+	}
+}
+
+// TestSourceMapMappings checks that the mappings of MarshalWithSourceMap
+// decode to the source position of each node.
+func TestSourceMapMappings(t *testing.T) {
+	sheet, err := Parse([]byte(".a {\n  color: red;\n}\n\n@media print {\n  .b { top: 0 }\n}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, sm, err := MarshalWithSourceMap(sheet, "out.css")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct{ Mappings string }
+	if err := json.Unmarshal(sm, &m); err != nil {
+		t.Fatal(err)
+	}
+	// Decode each segment as "generated line:col>source line:col", 1-based.
+	var got []string
+	var fields [4]int
+	for line, segs := range strings.Split(m.Mappings, ";") {
+		fields[0] = 0
+		for _, seg := range strings.Split(segs, ",") {
+			if seg == "" {
+				continue
+			}
+			for i := range fields {
+				var d int
+				d, seg = readVLQ(t, seg)
+				fields[i] += d
+			}
+			got = append(got, fmt.Sprintf("%d:%d>%d:%d", line+1, fields[0]+1, fields[2]+1, fields[3]+1))
+		}
+	}
+	want := "1:1>1:1 2:3>2:3 5:1>5:1 6:3>6:3 7:5>6:8"
+	if strings.Join(got, " ") != want {
+		t.Errorf("mappings of\n%s\ndecode to %q, want %q", out, strings.Join(got, " "), want)
+	}
+}
@@ -0,0 +1,296 @@
+package css
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Specificity is the specificity of a selector as its counts of id
+// selectors; class, attribute and pseudo-class selectors; and type and
+// pseudo-element selectors.
+type Specificity [3]int
+
+// Less reports whether s has lower specificity than o.
+func (s Specificity) Less(o Specificity) bool {
+	for i := range s {
+		if s[i] != o[i] {
+			return s[i] < o[i]
+		}
+	}
+	return false
+}
+
+// String returns s as "(a,b,c)".
+func (s Specificity) String() string {
+	return fmt.Sprintf("(%d,%d,%d)", s[0], s[1], s[2])
+}
+
+func (s Specificity) add(o Specificity) Specificity {
+	return Specificity{s[0] + o[0], s[1] + o[1], s[2] + o[2]}
+}
+
+// Specificity computes the specificity of the selector rule. The arguments
+// of :is(), :not() and :has() count as their most specific selector and
+// :where() counts as zero. Those of :host(), :host-context() and ::slotted()
+// count in addition to the pseudo-class or pseudo-element itself, and
+// namespace prefixes such as svg| count as nothing.
+func (rule Rule) Specificity() Specificity {
+	var best Specificity
+	for _, sel := range splitSelectorList(string(rule)) {
+		if s := compoundSpecificity(sel); best.Less(s) {
+			best = s
+		}
+	}
+	return best
+}
+
+// SortBySpecificity returns a copy of rules sorted from the least to the most
+// specific. The sort is stable: rules of equal specificity keep their order
+// in rules, which is taken to be source order, so that ties are won by the
+// later rule as in the cascade.
+func SortBySpecificity(rules []Rule) []Rule {
+	sorted := append([]Rule(nil), rules...)
+	specificity := make(map[Rule]Specificity, len(rules))
+	for _, r := range rules {
+		specificity[r] = r.Specificity()
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return specificity[sorted[i]].Less(specificity[sorted[j]])
+	})
+	return sorted
+}
+
+// CascadeEntry is a selector of a rule, as ordered by CascadeOrder.
+type CascadeEntry struct {
+	// Selector is the selector, resolved against its parents' for nested
+	// rules.
+	Selector    Rule
+	Rule        *RuleNode
+	Specificity Specificity
+	// Index is the position of the selector in source order among all the
+	// entries.
+	Index int
+}
+
+// CascadeOrder returns an entry for each selector of the rules of sheet,
+// including those nested in rules and at-rules other than @keyframes, in
+// the order the cascade considers them for an element they all match: by
+// specificity, then source order. Of two normal declarations of the same
+// property, that of the later entry wins, and the same holds among
+// !important ones, which all win over normal ones. A rule with several
+// selectors has an entry for each, since its specificity depends on the
+// one that matches. Conditions such as those of @media are not evaluated.
+func (sheet *StyleSheet) CascadeOrder() []CascadeEntry {
+	var entries []CascadeEntry
+	var walk func(nodes []Node, parents []Rule)
+	walk = func(nodes []Node, parents []Rule) {
+		for _, n := range nodes {
+			switch n := n.(type) {
+			case *RuleNode:
+				sels := n.Selectors
+				if parents != nil {
+					sels = nestSelectors(parents, sels, DialectCSS)
+				}
+				for _, sel := range sels {
+					entries = append(entries, CascadeEntry{sel, n, sel.Specificity(), len(entries)})
+				}
+				walk(n.Rules, sels)
+			case *AtRule:
+				if name, _ := Canonical(asciiLower(n.Name)); name != "keyframes" {
+					walk(n.Rules, parents)
+				}
+			}
+		}
+	}
+	walk(sheet.Rules, nil)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Specificity.Less(entries[j].Specificity)
+	})
+	return entries
+}
+
+// splitSelectorList splits a selector list on the commas outside of
+// parentheses, brackets and strings.
+func splitSelectorList(s string) []string {
+	var (
+		parts []string
+		depth int
+		quote byte
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func compoundSpecificity(sel string) Specificity {
+	var s Specificity
+	for i := 0; i < len(sel); {
+		c := sel[i]
+		switch {
+		case c == '#':
+			s[0]++
+			i = skipName(sel, i+1)
+		case c == '.':
+			s[1]++
+			i = skipName(sel, i+1)
+		case c == '[':
+			s[1]++
+			i = skipBracket(sel, i)
+		case strings.HasPrefix(sel[i:], "::"):
+			s[2]++
+			end := skipName(sel, i+2)
+			name := strings.ToLower(sel[i+2 : end])
+			i = end
+			if i < len(sel) && sel[i] == '(' {
+				var args string
+				args, i = parenthesized(sel, i)
+				if name == "slotted" {
+					s = s.add(Rule(args).Specificity())
+				}
+			}
+		case c == ':':
+			end := skipName(sel, i+1)
+			name := strings.ToLower(sel[i+1 : end])
+			i = end
+			var args string
+			if i < len(sel) && sel[i] == '(' {
+				args, i = parenthesized(sel, i)
+			}
+			switch name {
+			case "where":
+			case "is", "not", "has", "matches", "-webkit-any", "-moz-any":
+				s = s.add(Rule(args).Specificity())
+			case "host", "host-context":
+				s[1]++
+				s = s.add(Rule(args).Specificity())
+			case "before", "after", "first-line", "first-letter":
+				s[2]++
+			case "nth-child", "nth-last-child":
+				s[1]++
+				if j := strings.Index(strings.ToLower(args), " of "); j >= 0 {
+					s = s.add(Rule(args[j+4:]).Specificity())
+				}
+			default:
+				s[1]++
+			}
+		case c == '*':
+			i = skipNamespace(sel, i+1)
+		case isNameRune(rune(c)) || c == '\\':
+			end := skipName(sel, i)
+			if i = skipNamespace(sel, end); i == end {
+				s[2]++
+			}
+		default:
+			i++
+		}
+	}
+	return s
+}
+
+// skipName returns the index just past the identifier starting at i.
+func skipName(s string, i int) int {
+	for i < len(s) {
+		switch {
+		case s[i] == '\\':
+			i = skipEscape(s, i)
+		case isNameRune(rune(s[i])):
+			i++
+		default:
+			return i
+		}
+	}
+	return len(s)
+}
+
+// skipNamespace returns the index just past the '|' at i that ends a
+// namespace prefix, or i if there is none. A "||" is the column combinator.
+func skipNamespace(s string, i int) int {
+	if i < len(s) && s[i] == '|' && (i+1 == len(s) || s[i+1] != '|') {
+		return i + 1
+	}
+	return i
+}
+
+// skipEscape returns the index just past the escape whose backslash is at
+// i.
+func skipEscape(s string, i int) int {
+	i++
+	if i < len(s) && !isHexDigit(rune(s[i])) {
+		return i + 1
+	}
+	for n := 0; n < 6 && i < len(s) && isHexDigit(rune(s[i])); n++ {
+		i++
+	}
+	if i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// skipBracket returns the index just past the attribute selector starting
+// at i.
+func skipBracket(s string, i int) int {
+	var quote byte
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == ']':
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// parenthesized returns the text between the parenthesis at i and its match
+// and the index just past the match.
+func parenthesized(s string, i int) (string, int) {
+	depth := 0
+	var quote byte
+	for j := i; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '\\':
+			j++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return s[i+1 : j], j + 1
+			}
+		}
+	}
+	return s[i+1:], len(s)
+}
@@ -0,0 +1,83 @@
+package css
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestHasSelector checks that :has() with a relative selector argument is
+// parsed and keyed in canonical form, and counts as its most specific
+// argument.
+func TestHasSelector(t *testing.T) {
+	tests := []struct {
+		src  string
+		key  Rule
+		spec Specificity
+	}{
+		{".card:has(> img)", ".card:has(> img)", Specificity{0, 1, 1}},
+		{".card:has( >img )", ".card:has(> img)", Specificity{0, 1, 1}},
+		{".card:has(+ .x, .y .z)", ".card:has(+ .x, .y .z)", Specificity{0, 3, 0}},
+		{"a:has(#x)", "a:has(#x)", Specificity{1, 0, 1}},
+		{":has(img)", ":has(img)", Specificity{0, 0, 1}},
+		{"li:has(> a.b:hover)", "li:has(> a.b:hover)", Specificity{0, 2, 2}},
+		{"p:has(~ h2):not(.x)", "p:has(~ h2):not(.x)", Specificity{0, 1, 2}},
+		{"div:has(:is(.a, #b))", "div:has(:is(.a, #b))", Specificity{1, 0, 1}},
+	}
+	for _, tt := range tests {
+		css, err := Unmarshal([]byte(tt.src + " { color: red }"))
+		if err != nil {
+			t.Errorf("Unmarshal of %q: %v", tt.src, err)
+			continue
+		}
+		if _, ok := css[tt.key]; !ok || len(css) != 1 {
+			t.Errorf("Unmarshal of %q keys %q, want %q", tt.src, keys(css), tt.key)
+		}
+		if got := tt.key.Specificity(); got != tt.spec {
+			t.Errorf("Specificity of %q = %v, want %v", tt.key, got, tt.spec)
+		}
+	}
+}
+
+// TestSpecificity checks the specificity of selectors with namespace
+// prefixes and with the arguments of shadow DOM pseudo-classes and
+// pseudo-elements.
+func TestSpecificity(t *testing.T) {
+	tests := []struct {
+		sel  Rule
+		want Specificity
+	}{
+		{"svg|rect", Specificity{0, 0, 1}},
+		{"ns|*", Specificity{0, 0, 0}},
+		{"*|a.b", Specificity{0, 1, 1}},
+		{"|a", Specificity{0, 0, 1}},
+		{"[xlink|href]", Specificity{0, 1, 0}},
+		{"svg|a svg|b", Specificity{0, 0, 2}},
+		{"col || td", Specificity{0, 0, 2}},
+		{":host", Specificity{0, 1, 0}},
+		{":host(.dark)", Specificity{0, 2, 0}},
+		{":host(#x, .y)", Specificity{1, 1, 0}},
+		{":host-context(body.dark) p", Specificity{0, 2, 2}},
+		{"::slotted(span)", Specificity{0, 0, 2}},
+		{"::slotted(.a.b)", Specificity{0, 2, 1}},
+		{"::part(label)", Specificity{0, 0, 1}},
+	}
+	for _, tt := range tests {
+		if got := tt.sel.Specificity(); got != tt.want {
+			t.Errorf("Specificity of %q = %v, want %v", tt.sel, got, tt.want)
+		}
+	}
+}
+
+// TestSortBySpecificityStable checks that rules of equal specificity keep
+// their source order, whatever their order relative to the others.
+func TestSortBySpecificityStable(t *testing.T) {
+	rules := []Rule{"#x", ".c", "a", ".b", "div", "#y", ".a", "p", "a.z", ".d"}
+	want := []Rule{"a", "div", "p", ".c", ".b", ".a", ".d", "a.z", "#x", "#y"}
+	got := SortBySpecificity(rules)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortBySpecificity(%q) = %q, want %q", rules, got, want)
+	}
+	if rules[0] != "#x" || rules[9] != ".d" {
+		t.Errorf("SortBySpecificity changed its argument to %q", rules)
+	}
+}
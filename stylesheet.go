@@ -0,0 +1,438 @@
+// Package css parses CSS stylesheets into a tree of rules and at-rules.
+//
+// Parsing keeps no shared state, so stylesheets may be parsed, linted and
+// marshaled from many goroutines at once. RegisterPropertySyntax is the
+// only call that changes package state, and it is safe to use concurrently
+// with parsing.
+//
+// Only the mutating methods of StyleSheet and RuleNode, Transform, and
+// callbacks of Walk that change nodes write to a stylesheet; everything
+// else only reads it, so a stylesheet may be shared by any number of
+// goroutines that do not change it. To update a shared stylesheet, change a
+// Clone of it and publish the clone in its place, for example with an
+// atomic.Pointer. The maps returned by Unmarshal and its variants are not
+// shared with one another or between selectors.
+//
+// A selector with rules both outside and inside @media blocks has a rule
+// for each media scope, which the package never merges across scopes.
+// Parse keeps each rule in its block, and Ungroup gives it with the blocks
+// holding it. Unmarshal leaves out the rules of @media blocks, unless
+// ScopedKeys keys them by their scope, and UnmarshalScoped keys them by
+// MediaScope. MergeMedia merges only blocks of the same query list, and
+// Conflicts only reports rules of the same scope. ComputeStyle and Matcher
+// ignore the rules of @media blocks, and read those of the blocks matching
+// a device in the stylesheet ForEnv returns for it.
+//
+// The text Marshal writes, with or without Minify, depends only on the
+// stylesheet and the options: it is the same byte for byte on every
+// platform, Go version and run for a given version of the package. Nodes
+// are written in the order of the tree, maps are never ranged over in
+// writing them, and numbers are rewritten as text, not through float
+// formatting. A change to the output is a change of the package, which
+// shows in the .out and .min golden files of its corpus.
+package css
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"text/scanner"
+)
+
+// StyleSheet is the ordered result of parsing one or more stylesheets.
+type StyleSheet struct {
+	Rules []Node
+	// Comments lists the comments of the source in order, including those
+	// also held by nodes. Lint reads its control comments from them.
+	Comments []Comment
+	// Layers lists the names of the cascade layers the sheet declares, in
+	// the order they are first declared in by @layer statements and blocks
+	// and the layer() of @import rules, which is the order of their
+	// precedence. Sublayers are named in full, as "base.reset", and
+	// anonymous layers are left out. ComputeStyle, Unmarshal and Matcher
+	// order layers by it before the layers the rules declare themselves.
+	Layers []string
+}
+
+// Comment is a comment of a stylesheet.
+type Comment struct {
+	Text string           // between the "/*" and "*/"
+	Pos  scanner.Position // of the "/*"
+}
+
+// Node is an entry of a StyleSheet: a *RuleNode or an *AtRule. Walk and
+// Transform also pass the selectors and declarations of rules as *Rule and
+// *Declaration nodes, which do not belong in the Rules lists.
+type Node interface {
+	node()
+}
+
+// RuleNode is a single rule block. Pos is the position of its first
+// selector; Pos.Filename names the source it was parsed from. Open and
+// Close are the positions of the '{' and '}' of the block; Close is zero if
+// the input ends first.
+//
+// A selector list such as "h1, h2, h3" is one RuleNode holding the three
+// selectors and the declarations once, and Marshal writes it grouped again.
+// Parse neither splits groups nor merges rules sharing a selector; only
+// Unmarshal and its variants do, applying the declarations of a group to
+// each of its selectors merged in source order with every other rule of
+// the selector, alone or grouped.
+//
+// Rules holds the rules nested in the block, whose selectors are relative
+// to the rule's: "&" in them stands for the rule's selector, and those
+// without "&" match descendants of it. They apply after Declarations;
+// declarations written after a nested rule are held in a nested rule of
+// selector "&" to keep their order, which has no Open or Close.
+//
+// Comments holds the text between the "/*" and "*/" of the comments before
+// the rule and in its selector list. Comments at the end of a block, with
+// no rule or declaration after them, are dropped.
+type RuleNode struct {
+	Selectors    []Rule
+	Declarations []Declaration
+	Rules        []Node
+	Comments     []string
+	Pos          scanner.Position
+	Open, Close  scanner.Position
+}
+
+// AtRule is an at-rule such as @import or @media. Name excludes the '@' and
+// Prelude holds the raw text between the name and the ';' or block. At-rules
+// with a block hold either nested Rules, like @media, or Declarations, like
+// @font-face; both are nil for statement at-rules and for those read as
+// BlockRaw, whose Block holds their block as written, braces included.
+// Open and Close are set for at-rules with a block like those of a
+// RuleNode. Comments holds the comments before the at-rule and in its
+// prelude. Data holds what the Parse hook of its AtRuleHandler stored.
+// PreludePos is the position of the first byte of Prelude, if parsed.
+type AtRule struct {
+	Name         string
+	Prelude      string
+	PreludePos   scanner.Position
+	Rules        []Node
+	Declarations []Declaration
+	Block        string
+	Data         interface{}
+	Comments     []string
+	Pos          scanner.Position
+	Open, Close  scanner.Position
+}
+
+// PreludePosition returns the position of the byte at offset i of
+// n.Prelude, such as the Start of a MediaFeature parsed from it, in the
+// source of n. It is zero if n has no PreludePos.
+func (n *AtRule) PreludePosition(i int) scanner.Position {
+	pos := n.PreludePos
+	if !pos.IsValid() {
+		return scanner.Position{}
+	}
+	for j := 0; j < i && j < len(n.Prelude); j++ {
+		advancePosition(&pos, n.Prelude[j])
+	}
+	return pos
+}
+
+// declarationAtRules lists the at-rules whose block holds declarations
+// rather than rules.
+var declarationAtRules = map[string]bool{
+	"font-face":           true,
+	"page":                true,
+	"counter-style":       true,
+	"property":            true,
+	"font-palette-values": true,
+	"viewport":            true,
+	"-ms-viewport":        true,
+}
+
+// knownAtRules lists the standard at-rules, without vendor prefixes.
+var knownAtRules = map[string]bool{
+	"charset": true, "import": true, "namespace": true, "media": true, "supports": true,
+	"font-face": true, "font-feature-values": true, "font-palette-values": true, "keyframes": true,
+	"page": true, "counter-style": true, "property": true, "layer": true, "container": true,
+	"scope": true, "starting-style": true, "document": true, "viewport": true, "view-transition": true,
+	"position-try": true,
+}
+
+// Clone returns a deep copy of sheet, sharing no nodes, slices or
+// declarations with it.
+func (sheet *StyleSheet) Clone() *StyleSheet {
+	return &StyleSheet{Rules: cloneNodes(sheet.Rules), Comments: append([]Comment(nil), sheet.Comments...), Layers: cloneStrings(sheet.Layers)}
+}
+
+func cloneNodes(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]Node, len(nodes))
+	for i, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			r := *n
+			r.Selectors = append([]Rule(nil), n.Selectors...)
+			r.Comments = cloneStrings(n.Comments)
+			r.Declarations = cloneDeclarations(n.Declarations)
+			r.Rules = cloneNodes(n.Rules)
+			out[i] = &r
+		case *AtRule:
+			at := *n
+			at.Comments = cloneStrings(n.Comments)
+			at.Declarations = cloneDeclarations(n.Declarations)
+			at.Rules = cloneNodes(n.Rules)
+			out[i] = &at
+		}
+	}
+	return out
+}
+
+func cloneDeclarations(decls []Declaration) []Declaration {
+	if decls == nil {
+		return nil
+	}
+	out := append(make([]Declaration, 0, len(decls)), decls...)
+	for i := range out {
+		out[i].Comments = cloneStrings(out[i].Comments)
+		out[i].TrailingComments = cloneStrings(out[i].TrailingComments)
+	}
+	return out
+}
+
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string(nil), s...)
+}
+
+// Raw returns the text of n in src, the source it was parsed from, from its
+// first selector to the '}' of its block, comments and line endings as they
+// are written, or the rest of src if the input ended first. It returns nil
+// for a rule with no text of its own, such as one made by a transform or
+// the "&" rule holding the declarations after nested rules. The rule of a
+// selector list is one text for all of its selectors.
+func (n *RuleNode) Raw(src []byte) []byte {
+	return rawText(n, src)
+}
+
+// Raw is like the Raw method of RuleNode for the at-rule n, whose text
+// starts at its '@' and ends at its ';' if it has no block.
+func (n *AtRule) Raw(src []byte) []byte {
+	return rawText(n, src)
+}
+
+func rawText(n Node, src []byte) []byte {
+	from, to, ok := nodeSpan(n, src)
+	if !ok {
+		return nil
+	}
+	return src[from:to:to]
+}
+
+// nodeSpan returns the offsets in src of the text of the rule or at-rule n,
+// and reports whether n has one.
+func nodeSpan(n Node, src []byte) (from, to int, ok bool) {
+	switch n := n.(type) {
+	case *RuleNode:
+		if !n.Pos.IsValid() || !n.Open.IsValid() {
+			return 0, 0, false
+		}
+		from, to = n.Pos.Offset, n.Close.Offset+1
+		if !n.Close.IsValid() {
+			to = len(src)
+		}
+	case *AtRule:
+		if !n.Pos.IsValid() {
+			return 0, 0, false
+		}
+		from = n.Pos.Offset
+		switch {
+		case n.Open.IsValid() && n.Close.IsValid():
+			to = n.Close.Offset + 1
+		case n.Open.IsValid() || from > len(src):
+			to = len(src)
+		default:
+			to = from + nextSpecial(string(src[from:]), 0, ";{}") + 1
+		}
+	default:
+		return 0, 0, false
+	}
+	if to > len(src) {
+		to = len(src)
+	}
+	return from, to, from <= to
+}
+
+func (*RuleNode) node()    {}
+func (*AtRule) node()      {}
+func (*Rule) node()        {}
+func (*Declaration) node() {}
+
+// Declaration is a property/value pair in source order. Pos is the position
+// of the property name. Important is set by a trailing !important flag,
+// which is not part of Value. ValuePos and ValueEnd delimit the value as
+// written, flag included: ValueEnd is just past its last character.
+// Comments holds the comments before the declaration, and TrailingComments
+// those in its value and after it on the line of its ';'. Origin is set
+// for the longhands ExpandShorthands makes of a shorthand, and nil for
+// declarations as written. Raw is set for a value Lenient mode could not
+// read and kept verbatim, without normalizing its whitespace.
+type Declaration struct {
+	Property  string
+	Value     string
+	Important bool
+	Pos       scanner.Position
+
+	ValuePos, ValueEnd scanner.Position
+	Comments           []string
+	TrailingComments   []string
+	Origin             *Origin
+	Raw                bool
+}
+
+// Origin is the shorthand declaration longhands were expanded from. The
+// longhands of one shorthand share their Origin.
+type Origin struct {
+	Property string
+	Value    string // without its !important flag
+	Pos      scanner.Position
+}
+
+// text returns the value of d as written, with its !important flag.
+func (d Declaration) text() string {
+	if d.Important {
+		return d.Value + " !important"
+	}
+	return d.Value
+}
+
+// importance splits the !important flag off value.
+func importance(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	i := strings.LastIndexByte(value, '!')
+	if i < 0 || !strings.EqualFold(strings.TrimSpace(value[i+1:]), "important") {
+		return value, false
+	}
+	return strings.TrimSpace(value[:i]), true
+}
+
+// NamedSource is stylesheet content along with the name used to attribute
+// its rules and errors, typically a file path.
+type NamedSource struct {
+	Name string
+	Data []byte
+}
+
+// Parse parses the stylesheet b into its ordered rules. Input with a UTF-16
+// byte order mark, or starting with an @charset rule naming windows-1252 or
+// Latin-1, is transcoded to UTF-8 first; other input must be valid UTF-8,
+// or an error wrapping an *EncodingError is returned. A ';', '{' or '}'
+// in a string, escaped or in an unquoted url() argument, as in
+// content: "a;b", belongs to the value holding it, so that the
+// declarations and rules after it read as they would without it.
+func Parse(b []byte, opts ...Option) (*StyleSheet, error) {
+	return ParseReader(bytes.NewReader(b), opts...)
+}
+
+// ParseReader is like Parse but reads the stylesheet from r.
+func ParseReader(r io.Reader, opts ...Option) (*StyleSheet, error) {
+	o := newOptions(opts)
+	return parseReader(r, o.filename, o)
+}
+
+// ParseFiles parses each source and concatenates the rules in argument
+// order, so later files take precedence in the cascade. On error the rules
+// parsed so far are returned together with an error naming the offending
+// source.
+func ParseFiles(files ...NamedSource) (*StyleSheet, error) {
+	return ParseFilesWith(files)
+}
+
+// ParseFilesWith is like ParseFiles but parses each source with opts, such
+// as Lenient or WithDiagnostics. Each source is named by its Name, whatever
+// the Filename option.
+func ParseFilesWith(files []NamedSource, opts ...Option) (*StyleSheet, error) {
+	o := newOptions(opts)
+	sheet := &StyleSheet{}
+	for _, f := range files {
+		s, err := parseReader(bytes.NewReader(f.Data), f.Name, o)
+		sheet.Rules = append(sheet.Rules, s.Rules...)
+		sheet.Comments = append(sheet.Comments, s.Comments...)
+		sheet.Layers = declaredLayers(sheet.Rules)
+		if err != nil {
+			return sheet, err
+		}
+	}
+	return sheet, nil
+}
+
+// Combine returns a stylesheet of copies of the rules and comments of
+// sheets, in argument order. The order of Rules is the source order that
+// the cascade of ComputeStyle and the merges of Flatten and Unmarshal
+// follow, so rules of later sheets win ties over earlier ones, and every
+// rule keeps the Pos.Filename of its source. Combining combined sheets
+// gives the same order as combining their parts at once. An @import or
+// @charset of a sheet other than the first is kept in its place, where a
+// browser would ignore it. The Layers of sheets are merged in order, each
+// layer in the place of its first declaration.
+func Combine(sheets ...*StyleSheet) *StyleSheet {
+	out := &StyleSheet{}
+	l := newLayerOrder(nil)
+	for _, s := range sheets {
+		c := s.Clone()
+		out.Rules = append(out.Rules, c.Rules...)
+		out.Comments = append(out.Comments, c.Comments...)
+		for _, name := range c.Layers {
+			l.declare(name)
+		}
+	}
+	out.Layers = l.names
+	return out
+}
+
+// ParseAll parses each source on its own, on up to workers goroutines or
+// GOMAXPROCS of them if workers is zero or less. The sheet and error of a
+// source are at its index in the results, and a failing source does not
+// stop the others. Once ctx is done, sources not yet started fail with
+// ctx.Err() and those in progress stop with an error wrapping it. A
+// WithDiagnostics callback may be called from several goroutines at once.
+func ParseAll(ctx context.Context, sources []NamedSource, workers int, opts ...Option) ([]*StyleSheet, []error) {
+	sheets := make([]*StyleSheet, len(sources))
+	errs := make([]error, len(sources))
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(sources) {
+		workers = len(sources)
+	}
+	o := newOptions(opts)
+	o.ctx = ctx
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				sheets[i], errs[i] = parseReader(bytes.NewReader(sources[i].Data), sources[i].Name, o)
+			}
+		}()
+	}
+	i := 0
+send:
+	for ; i < len(sources); i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(next)
+	for ; i < len(sources); i++ {
+		errs[i] = ctx.Err()
+	}
+	wg.Wait()
+	return sheets, errs
+}
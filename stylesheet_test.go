@@ -0,0 +1,153 @@
+package css
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestParseAll checks that the results of ParseAll are in the order of its
+// sources, and that a failing source in the middle fails alone, with an
+// error naming it.
+func TestParseAll(t *testing.T) {
+	var sources []NamedSource
+	for i := 0; i < 20; i++ {
+		sources = append(sources, NamedSource{
+			Name: fmt.Sprintf("c%d.css", i),
+			Data: []byte(fmt.Sprintf(".c%d { color: red }", i)),
+		})
+	}
+	const bad = 10
+	sources[bad].Data = []byte(".c10 { color: red }\n}")
+	for _, workers := range []int{0, 1, 3, 50} {
+		sheets, errs := ParseAll(context.Background(), sources, workers)
+		if len(sheets) != len(sources) || len(errs) != len(sources) {
+			t.Fatalf("%d workers: got %d sheets and %d errors for %d sources", workers, len(sheets), len(errs), len(sources))
+		}
+		for i, sheet := range sheets {
+			if i == bad {
+				if errs[i] == nil || !strings.Contains(errs[i].Error(), "c10.css: line 2:") {
+					t.Errorf("%d workers: got %v for the failing source, want an error at line 2 of c10.css", workers, errs[i])
+				}
+				continue
+			}
+			if errs[i] != nil {
+				t.Errorf("%d workers: source %d: %v", workers, i, errs[i])
+				continue
+			}
+			if want := Rule(fmt.Sprintf(".c%d", i)); len(sheet.Rules) != 1 || sheet.Rules[0].(*RuleNode).Selectors[0] != want {
+				t.Errorf("%d workers: sheet %d is not that of %s", workers, i, want)
+			}
+		}
+	}
+}
+
+func TestParseAllCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sources := []NamedSource{{"a.css", []byte(".a{}")}, {"b.css", []byte(".b{}")}}
+	_, errs := ParseAll(ctx, sources, 1)
+	for i, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("source %d: got %v, want %v", i, err, context.Canceled)
+		}
+	}
+}
+
+// TestSharedSheet has goroutines read a shared stylesheet while another
+// updates it by publishing changed clones, as the package doc describes;
+// run with -race.
+func TestSharedSheet(t *testing.T) {
+	first, err := Parse([]byte(".a, .b { color: red } .c { top: 0 }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shared atomic.Pointer[StyleSheet]
+	shared.Store(first)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				sheet := shared.Load()
+				if _, err := Marshal(sheet); err != nil {
+					t.Error(err)
+					return
+				}
+				ComputeStyle(sheet, ElementDesc{Classes: []string{"a"}})
+				Walk(sheet, func(n Node) bool { return true })
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			sheet := shared.Load().Clone()
+			rule, err := sheet.AddRule(fmt.Sprintf(".r%d", j))
+			if err == nil {
+				err = rule.Set("left", "0")
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sheet.Rules[0].(*RuleNode).Set("color", "blue")
+			sheet.RemoveRule(".c")
+			shared.Store(sheet)
+		}
+	}()
+	wg.Wait()
+	if got, err := Marshal(first); err != nil || string(got) != string(want) {
+		t.Errorf("the first sheet changed to %s, %v", got, err)
+	}
+	if n := len(shared.Load().Rules); n != 51 {
+		t.Errorf("the last sheet has %d rules, want 51", n)
+	}
+}
+
+// TestGroupedSelectors checks that Parse keeps a group as one rule, which
+// Marshal writes grouped, and that Unmarshal merges a selector used alone
+// and in groups in source order.
+func TestGroupedSelectors(t *testing.T) {
+	const src = "h1, h2 { color: red; top: 0 } h1 { color: blue } h2, h3 { top: 1px }"
+	sheet, err := Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(sheet.Rules); n != 3 {
+		t.Fatalf("Parse gives %d rules, want 3", n)
+	}
+	if sels := sheet.Rules[0].(*RuleNode).Selectors; !reflect.DeepEqual(sels, []Rule{"h1", "h2"}) {
+		t.Errorf("the first rule has the selectors %q, want h1 and h2", sels)
+	}
+	out, err := Marshal(sheet, Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "h1,h2{color:red;top:0}h1{color:#00f}h2,h3{top:1px}"; string(out) != want {
+		t.Errorf("Marshal gives %s, want %s", out, want)
+	}
+	css, err := Unmarshal([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[Rule]map[string]string{
+		"h1": {"color": "blue", "top": "0"},
+		"h2": {"color": "red", "top": "1px"},
+		"h3": {"top": "1px"},
+	}
+	if !reflect.DeepEqual(css, want) {
+		t.Errorf("Unmarshal gives %v, want %v", css, want)
+	}
+}
@@ -0,0 +1,87 @@
+package css
+
+import "sort"
+
+// propertiesByLength holds the known properties by the length of their
+// name, each list sorted, for suggestProperty to compare a name only with
+// those close enough in length.
+var propertiesByLength = func() map[int][]string {
+	m := make(map[int][]string)
+	for name := range knownProperties {
+		m[len(name)] = append(m[len(name)], name)
+	}
+	for _, names := range m {
+		sort.Strings(names)
+	}
+	return m
+}()
+
+// suggestProperty returns the known property name, lower-cased, is most
+// likely a typo of, or "" if none is close: within one edit, counting the
+// swap of two adjacent letters as one, for names of up to four letters,
+// and two for longer ones. Ties go to the name first in alphabetical order.
+func suggestProperty(name string) string {
+	max := 2
+	if len(name) <= 4 {
+		max = 1
+	}
+	best, bestDist := "", max+1
+	for n := len(name) - max; n <= len(name)+max; n++ {
+		for _, known := range propertiesByLength[n] {
+			if d := editDistance(name, known, bestDist); d < bestDist {
+				best, bestDist = known, d
+			}
+		}
+	}
+	return best
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b, the Levenshtein distance with swaps of adjacent bytes counting as one
+// edit, or limit if it is limit or more.
+func editDistance(a, b string, limit int) int {
+	if diff := len(a) - len(b); diff >= limit || -diff >= limit {
+		return limit
+	}
+	// Three rows of the matrix: two rows back, the previous and the current.
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d := prev[j-1] + cost
+			if v := prev[j] + 1; v < d {
+				d = v
+			}
+			if v := cur[j-1] + 1; v < d {
+				d = v
+			}
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				if v := prev2[j-2] + 1; v < d {
+					d = v
+				}
+			}
+			cur[j] = d
+			if d < rowMin {
+				rowMin = d
+			}
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	if d := prev[len(b)]; d < limit {
+		return d
+	}
+	return limit
+}
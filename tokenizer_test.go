@@ -0,0 +1,203 @@
+package css
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"text/scanner"
+)
+
+func TestModeAccepts(t *testing.T) {
+	tests := []struct {
+		mode     tokenMode
+		accepted string
+		rejected []rune
+	}{
+		{modeSelector, `a-_:>[]="'(),+~*|\` + "\u00e9", []rune{'#', '.', ' ', '\t', '\n', ';', '{', '}', scanner.EOF}},
+		{modeName, `a-_>[]="'(),+~*|\` + "\u00e9", []rune{':', '#', '.', ' ', '\t', '\n', ';', '{', '}', scanner.EOF}},
+		{modeValue, ` a:#.@,!()"'/` + "\n\t\u00e9", []rune{';', '{', '}', scanner.EOF}},
+		{modePrelude, ` a:#.@,!()"'/` + "\n\t\u00e9", []rune{';', '{', '}', scanner.EOF}},
+		{modeString, ` a;{}:#.@()"'/*` + "\t\u00e9", []rune{'\n', scanner.EOF}},
+		{modeURL, ` a;{}:#.@()"'/*` + "\n\t\u00e9", []rune{scanner.EOF}},
+		{modeComment, ` a;{}:#.@()"'/*` + "\n\t\u00e9", []rune{scanner.EOF}},
+	}
+	for _, tt := range tests {
+		for _, ch := range tt.accepted {
+			if !tt.mode.accepts(ch, 1) {
+				t.Errorf("mode %d does not accept %q", tt.mode, ch)
+			}
+		}
+		for _, ch := range tt.rejected {
+			if tt.mode.accepts(ch, 1) {
+				t.Errorf("mode %d accepts %q", tt.mode, ch)
+			}
+		}
+	}
+	// An '@' starts an at-keyword, and so no selector or name.
+	if modeSelector.accepts('@', 0) || modeName.accepts('@', 0) {
+		t.Error("an '@' is accepted at the start of a selector")
+	}
+}
+
+func TestModeCloses(t *testing.T) {
+	tests := []struct {
+		mode            tokenMode
+		ch, quote, prev rune
+		want            bool
+	}{
+		{modeString, '"', '"', 0, true},
+		{modeString, '\'', '"', 0, false},
+		{modeString, '\'', '\'', 0, true},
+		{modeURL, ')', 0, 0, true},
+		{modeURL, '(', 0, 0, false},
+		{modeComment, '/', 0, '*', true},
+		{modeComment, '/', 0, 'a', false},
+		{modeComment, '*', 0, '*', false},
+		{modeValue, ';', 0, 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.mode.closes(tt.ch, tt.quote, tt.prev); got != tt.want {
+			t.Errorf("mode %d closes(%q, %q, %q) = %v, want %v", tt.mode, tt.ch, tt.quote, tt.prev, got, tt.want)
+		}
+	}
+}
+
+// TestSetMode checks that the tokenizer reads the same input as the mode
+// set for it says, and keeps to that mode.
+func TestSetMode(t *testing.T) {
+	const src = `a:b.c "d e" f; g`
+	tests := []struct {
+		mode tokenMode
+		want []string
+	}{
+		{modeSelector, []string{"a:b", ".", "c", `"d e"`, "f", ";", "g"}},
+		{modeName, []string{"a", ":", "b", ".", "c", `"d`, "e\"", "f", ";", "g"}},
+		{modeValue, []string{`a:b.c "d e" f`, ";", "g"}},
+		{modePrelude, []string{`a:b.c "d e" f`}},
+	}
+	for _, tt := range tests {
+		tz := newTokenizer(strings.NewReader(src), "", options{})
+		tz.setMode(tt.mode)
+		var got []string
+		for len(got) < 10 {
+			tok, err := tz.next()
+			if err == io.EOF || tok.kind == tokenPrelude && len(got) > 0 {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, strings.TrimSpace(tok.value))
+			if tz.mode != tt.mode {
+				t.Errorf("mode %d changed to %d after %q", tt.mode, tz.mode, tok.value)
+			}
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("mode %d reads %q, want %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+// TestTokenStreams checks the token streams of testdata/tokens.
+func TestTokenStreams(t *testing.T) {
+	files, err := os.ReadDir(filepath.Join("testdata", "tokens"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join("testdata", "tokens", f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range tokenCases(string(b)) {
+			ts := newTokenStream(strings.NewReader(c.src), "", options{})
+			var toks []tokenEntry
+			for {
+				tok, ok := ts.next()
+				if !ok {
+					break
+				}
+				toks = append(toks, tok)
+			}
+			if ts.err != nil {
+				t.Errorf("%s: %s: %v", f.Name(), c.name, ts.err)
+				continue
+			}
+			if got := formatTokenEntries(toks); got != c.want {
+				t.Errorf("%s: %s:\ngot  %s\nwant %s", f.Name(), c.name, got, c.want)
+			}
+		}
+	}
+}
+
+type tokenCase struct {
+	name, src, want string
+}
+
+// tokenCases reads the cases of a file of testdata/tokens: a "-- name --"
+// line, the input up to a line starting with "=>", and the tokens from it
+// up to the next case, with the text before the first case left out.
+func tokenCases(s string) []tokenCase {
+	var (
+		cases []tokenCase
+		c     *tokenCase
+		src   []string
+		want  bool
+	)
+	for _, line := range strings.Split(s, "\n") {
+		switch {
+		case strings.HasPrefix(line, "-- ") && strings.HasSuffix(line, " --"):
+			cases = append(cases, tokenCase{name: strings.TrimSuffix(line[3:], " --")})
+			c, src, want = &cases[len(cases)-1], nil, false
+		case c == nil:
+		case want:
+			c.want = strings.TrimSpace(c.want + " " + line)
+		case strings.HasPrefix(line, "=>"):
+			c.src, c.want, want = strings.Join(src, "\n"), strings.TrimSpace(line[2:]), true
+		default:
+			src = append(src, line)
+		}
+	}
+	return cases
+}
+
+// tokenEntryNames are the names of the kinds of token of the parser in
+// the notation of testdata/tokens.
+var tokenEntryNames = map[tokenType]string{
+	tokenBlockStart:     "LBRACE",
+	tokenBlockEnd:       "RBRACE",
+	tokenStyleSeparator: "COLON",
+	tokenStatementEnd:   "SEMI",
+	tokenSelector:       "SEL",
+	tokenValue:          "VALUE",
+	tokenAtKeyword:      "AT",
+	tokenPrelude:        "PRELUDE",
+}
+
+// formatTokenEntries writes toks as FormatTokens does, with the names of
+// tokenEntryNames.
+func formatTokenEntries(toks []tokenEntry) string {
+	var b bytes.Buffer
+	for i, tok := range toks {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tokenEntryNames[tok.kind])
+		switch tok.kind {
+		case tokenBlockStart, tokenBlockEnd, tokenStyleSeparator, tokenStatementEnd:
+			continue
+		}
+		b.WriteByte('(')
+		if bareTokenText(tok.value) {
+			b.WriteString(tok.value)
+		} else {
+			b.WriteString(strconv.Quote(tok.value))
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
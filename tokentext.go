@@ -0,0 +1,191 @@
+package css
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ScanAll returns the tokens NewScanner(r, opts...) gives, up to the end
+// of input or the first error, which is returned with the tokens read
+// before it.
+func ScanAll(r io.Reader, opts ...Option) ([]Token, error) {
+	s := NewScanner(r, opts...)
+	var toks []Token
+	for {
+		t, err := s.Next()
+		if err == io.EOF {
+			return toks, nil
+		}
+		if err != nil {
+			return toks, err
+		}
+		toks = append(toks, t)
+	}
+}
+
+// tokenNames are the names of the kinds of token in the notation of
+// FormatTokens, but for TokenBrace, named by its text.
+var tokenNames = map[TokenKind]string{
+	TokenIdent:      "IDENT",
+	TokenFunction:   "FUNCTION",
+	TokenAtKeyword:  "AT",
+	TokenHash:       "HASH",
+	TokenString:     "STRING",
+	TokenURL:        "URL",
+	TokenNumber:     "NUMBER",
+	TokenWhitespace: "WS",
+	TokenComment:    "COMMENT",
+	TokenColon:      "COLON",
+	TokenSemicolon:  "SEMI",
+	TokenComma:      "COMMA",
+	TokenDelim:      "DELIM",
+}
+
+// braceNames are the names of the TokenBrace tokens by their text.
+var braceNames = map[string]string{
+	"{": "LBRACE", "}": "RBRACE", "(": "LPAREN", ")": "RPAREN", "[": "LBRACKET", "]": "RBRACKET",
+}
+
+// fixedTokens maps the names of the tokens FormatTokens writes without
+// their text to their kind and text.
+var fixedTokens = map[string]Token{
+	"COLON":    {Kind: TokenColon, Text: ":"},
+	"SEMI":     {Kind: TokenSemicolon, Text: ";"},
+	"COMMA":    {Kind: TokenComma, Text: ","},
+	"LBRACE":   {Kind: TokenBrace, Text: "{"},
+	"RBRACE":   {Kind: TokenBrace, Text: "}"},
+	"LPAREN":   {Kind: TokenBrace, Text: "("},
+	"RPAREN":   {Kind: TokenBrace, Text: ")"},
+	"LBRACKET": {Kind: TokenBrace, Text: "["},
+	"RBRACKET": {Kind: TokenBrace, Text: "]"},
+	"WS":       {Kind: TokenWhitespace, Text: " "},
+}
+
+// FormatTokens writes toks in a compact notation for golden strings, such
+// as
+//
+//	DELIM(.) IDENT(a) WS LBRACE WS IDENT(color) COLON WS IDENT(red) SEMI WS RBRACE
+//
+// with a name for the kind of each token, separated by spaces, followed by
+// its text in parentheses. Colons, semicolons, commas, braces, written as
+// LBRACE, RPAREN or RBRACKET and so on, and whitespace of a single space
+// are written by name alone, and a function without its '('. Text with
+// spaces, parentheses, quotes or backslashes, or that is empty or not
+// printable, is written as a quoted Go string, as in STRING("\"a\"").
+// ParseTokens reads the notation back. Positions are left out.
+func FormatTokens(toks []Token) string {
+	var b strings.Builder
+	for i, t := range toks {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		name, ok := tokenNames[t.Kind]
+		if t.Kind == TokenBrace {
+			name, ok = braceNames[t.Text]
+		}
+		if !ok {
+			name = "DELIM"
+		}
+		if f, ok := fixedTokens[name]; ok && f.Text == t.Text {
+			b.WriteString(name)
+			continue
+		}
+		text := t.Text
+		if t.Kind == TokenFunction {
+			text = strings.TrimSuffix(text, "(")
+		}
+		b.WriteString(name)
+		b.WriteByte('(')
+		if bareTokenText(text) {
+			b.WriteString(text)
+		} else {
+			b.WriteString(strconv.Quote(text))
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// bareTokenText reports whether FormatTokens writes text unquoted.
+func bareTokenText(text string) bool {
+	if text == "" || !utf8.ValidString(text) {
+		return false
+	}
+	for _, c := range text {
+		if strings.ContainsRune(` ()"\`, c) || !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTokens reads tokens written in the notation of FormatTokens, with
+// any whitespace between them, so that tests may compare token streams
+// with golden strings. The tokens have no positions.
+func ParseTokens(s string) ([]Token, error) {
+	kinds := make(map[string]TokenKind, len(tokenNames))
+	for k, n := range tokenNames {
+		kinds[n] = k
+	}
+	var toks []Token
+	for i := 0; ; {
+		for i < len(s) && isSelectorSpace(s[i]) {
+			i++
+		}
+		if i == len(s) {
+			return toks, nil
+		}
+		start := i
+		for i < len(s) && s[i] >= 'A' && s[i] <= 'Z' {
+			i++
+		}
+		name := s[start:i]
+		if i == len(s) || s[i] != '(' {
+			t, ok := fixedTokens[name]
+			switch {
+			case name == "":
+				return toks, fmt.Errorf("token %d: unexpected %q", len(toks)+1, s[i])
+			case !ok:
+				return toks, fmt.Errorf("token %d: %s without its text", len(toks)+1, name)
+			}
+			toks = append(toks, t)
+			continue
+		}
+		kind, ok := kinds[name]
+		if f, fixed := fixedTokens[name]; fixed {
+			kind, ok = f.Kind, true
+		}
+		if !ok {
+			return toks, fmt.Errorf("token %d: unknown token name %q", len(toks)+1, name)
+		}
+		i++
+		var text string
+		if i < len(s) && s[i] == '"' {
+			q, err := strconv.QuotedPrefix(s[i:])
+			if err != nil {
+				return toks, fmt.Errorf("token %d: %s(%s: %v", len(toks)+1, name, s[i:], err)
+			}
+			text, _ = strconv.Unquote(q)
+			i += len(q)
+		} else {
+			end := strings.IndexByte(s[i:], ')')
+			if end < 0 {
+				return toks, fmt.Errorf("token %d: %s( is not closed", len(toks)+1, name)
+			}
+			text = s[i : i+end]
+			i += end
+		}
+		if i == len(s) || s[i] != ')' {
+			return toks, fmt.Errorf("token %d: %s( is not closed", len(toks)+1, name)
+		}
+		i++
+		if kind == TokenFunction {
+			text += "("
+		}
+		toks = append(toks, Token{Kind: kind, Text: text})
+	}
+}
@@ -0,0 +1,155 @@
+package css
+
+import "text/scanner"
+
+// PrefixRemoval records a prefixed declaration or at-rule dropped or renamed
+// by RemovePrefixes or UnprefixLone.
+type PrefixRemoval struct {
+	// Name is the prefixed property, value or at-rule name.
+	Name string
+	// Renamed is set when the item was rewritten to its standard name
+	// instead of being dropped.
+	Renamed bool
+	Pos     scanner.Position
+}
+
+// strippedPrefixes are the vendor prefixes RemovePrefixes acts on.
+var strippedPrefixes = map[string]bool{"-webkit-": true, "-moz-": true, "-ms-": true, "-o-": true}
+
+// unprefixable lists the standard properties whose prefixed forms differ
+// only by the prefix, so UnprefixLone can rename them safely.
+var unprefixable = func() map[string]bool {
+	m := map[string]bool{
+		"transform": true, "transform-origin": true, "transform-style": true, "perspective": true,
+		"perspective-origin": true, "backface-visibility": true, "transition": true,
+		"transition-property": true, "transition-duration": true, "transition-delay": true,
+		"transition-timing-function": true, "animation": true, "animation-name": true,
+		"animation-duration": true, "animation-delay": true, "animation-timing-function": true,
+		"animation-iteration-count": true, "animation-direction": true, "animation-fill-mode": true,
+		"animation-play-state": true, "box-shadow": true, "box-sizing": true, "border-radius": true,
+		"background-clip": true, "background-size": true, "columns": true, "column-count": true,
+		"column-gap": true, "column-width": true, "column-rule": true, "filter": true,
+		"flex": true, "flex-direction": true, "flex-wrap": true, "flex-flow": true,
+		"flex-grow": true, "flex-shrink": true, "flex-basis": true, "font-feature-settings": true,
+		"text-decoration": true, "text-overflow": true, "writing-mode": true, "keyframes": true,
+	}
+	for p := range prefixedProperties {
+		m[p] = true
+	}
+	return m
+}()
+
+// RemovePrefixes returns a copy of sheet without the -webkit-, -moz-, -ms-
+// and -o- prefixed declarations that have a standard equivalent in the same
+// block, and without prefixed at-rules such as @-webkit-keyframes that have
+// a standard twin with the same prelude alongside them. Declarations using
+// a prefixed value, like display: -webkit-box, are dropped when the block
+// also sets the property to an unprefixed value. Items for which keep
+// returns true are left in place; keep may be nil. Prefixed items without a
+// standard equivalent are kept. The removed items are returned in source
+// order.
+func RemovePrefixes(sheet *StyleSheet, keep func(prefixedProp string) bool) (*StyleSheet, []PrefixRemoval) {
+	u := unprefixer{keep: keep}
+	return &StyleSheet{Rules: u.nodes(sheet.Rules), Comments: sheet.Comments}, u.removed
+}
+
+// UnprefixLone is like RemovePrefixes but additionally renames prefixed
+// properties and at-rules that have no standard equivalent to their
+// standard name, when the standard name differs from the prefixed one only
+// by the prefix. Renamed items are reported with Renamed set.
+func UnprefixLone(sheet *StyleSheet, keep func(prefixedProp string) bool) (*StyleSheet, []PrefixRemoval) {
+	u := unprefixer{keep: keep, rename: true}
+	return &StyleSheet{Rules: u.nodes(sheet.Rules), Comments: sheet.Comments}, u.removed
+}
+
+type unprefixer struct {
+	keep    func(string) bool
+	rename  bool
+	removed []PrefixRemoval
+}
+
+// prefixed returns the standard name of name if it carries one of the
+// prefixes RemovePrefixes handles and is not kept by the caller.
+func (u *unprefixer) prefixed(name string) (string, bool) {
+	base, prefix := Canonical(name)
+	if !strippedPrefixes[prefix] || (u.keep != nil && u.keep(name)) {
+		return "", false
+	}
+	return base, true
+}
+
+func (u *unprefixer) nodes(nodes []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	standard := make(map[string]bool)
+	for _, n := range nodes {
+		if at, ok := n.(*AtRule); ok {
+			standard[at.Name+" "+at.Prelude] = true
+		}
+	}
+
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case *RuleNode:
+			r := *n
+			r.Declarations = u.declarations(n.Declarations)
+			r.Rules = u.nodes(n.Rules)
+			out = append(out, &r)
+		case *AtRule:
+			at := *n
+			if base, ok := u.prefixed(n.Name); ok {
+				switch {
+				case standard[base+" "+n.Prelude]:
+					u.removed = append(u.removed, PrefixRemoval{Name: n.Name, Pos: n.Pos})
+					continue
+				case u.rename && unprefixable[base]:
+					u.removed = append(u.removed, PrefixRemoval{Name: n.Name, Renamed: true, Pos: n.Pos})
+					at.Name = base
+					standard[base+" "+n.Prelude] = true
+				}
+			}
+			at.Rules = u.nodes(n.Rules)
+			at.Declarations = u.declarations(n.Declarations)
+			out = append(out, &at)
+		}
+	}
+	return out
+}
+
+func (u *unprefixer) declarations(decls []Declaration) []Declaration {
+	if decls == nil {
+		return nil
+	}
+	standard := make(map[string]bool, len(decls))
+	for _, d := range decls {
+		if _, p := Canonical(d.Property); p != "" {
+			continue
+		}
+		if _, p := CanonicalValue(d.Value); p == "" {
+			standard[d.Property] = true
+		}
+	}
+
+	out := make([]Declaration, 0, len(decls))
+	for _, d := range decls {
+		if base, ok := u.prefixed(d.Property); ok {
+			switch {
+			case standard[base]:
+				u.removed = append(u.removed, PrefixRemoval{Name: d.Property, Pos: d.Pos})
+				continue
+			case u.rename && unprefixable[base]:
+				u.removed = append(u.removed, PrefixRemoval{Name: d.Property, Renamed: true, Pos: d.Pos})
+				d.Property = base
+				standard[base] = true
+			}
+		} else if _, p := CanonicalValue(d.Value); strippedPrefixes[p] && standard[d.Property] &&
+			(u.keep == nil || !u.keep(d.Value)) {
+			u.removed = append(u.removed, PrefixRemoval{Name: d.Value, Pos: d.Pos})
+			continue
+		}
+		out = append(out, d)
+	}
+	return out
+}
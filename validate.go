@@ -0,0 +1,468 @@
+package css
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ValueValidator checks the value of a declaration, with any !important
+// flag removed, and returns an error describing why it is invalid.
+type ValueValidator func(value string) error
+
+var (
+	syntaxMu         sync.RWMutex
+	propertySyntaxes = make(map[string]ValueValidator)
+)
+
+// RegisterPropertySyntax sets the validator Lint uses to check the values
+// of property when LintOptions.CheckValues is set, replacing any built-in
+// one. A nil validator disables value checks for the property.
+func RegisterPropertySyntax(name string, v ValueValidator) {
+	syntaxMu.Lock()
+	defer syntaxMu.Unlock()
+	if v == nil {
+		delete(propertySyntaxes, name)
+		return
+	}
+	propertySyntaxes[name] = v
+}
+
+func propertySyntax(name string) ValueValidator {
+	syntaxMu.RLock()
+	defer syntaxMu.RUnlock()
+	return propertySyntaxes[name]
+}
+
+// globalKeywords are valid for every property.
+var globalKeywords = map[string]bool{"inherit": true, "initial": true, "unset": true, "revert": true, "revert-layer": true}
+
+// validateValue checks value against the syntax registered for property.
+// Values using var() and similar substitutions, vendor prefixes or hacks
+// are not checked.
+func validateValue(property, value string) error {
+	v := propertySyntax(property)
+	if v == nil {
+		return nil
+	}
+	value, _ = importance(value)
+	lower := strings.ToLower(value)
+	if globalKeywords[lower] || strings.Contains(lower, "var(") || strings.Contains(lower, "env(") || strings.Contains(lower, "attr(") {
+		return nil
+	}
+	if _, p := CanonicalValue(value); p != "" {
+		return nil
+	}
+	return v(value)
+}
+
+func init() {
+	colors := oneOf(colorValue)
+	for _, p := range []string{
+		"color", "background-color", "border-top-color", "border-right-color", "border-bottom-color",
+		"border-left-color", "outline-color", "text-decoration-color", "caret-color", "column-rule-color",
+	} {
+		propertySyntaxes[p] = colors
+	}
+	propertySyntaxes["border-color"] = repeat(1, 4, colorValue)
+
+	size := oneOf(lengthValue(true, false), keywordValue("auto", "min-content", "max-content", "fit-content"))
+	for _, p := range []string{"width", "height", "min-width", "min-height"} {
+		propertySyntaxes[p] = size
+	}
+	maxSize := oneOf(lengthValue(true, false), keywordValue("none", "min-content", "max-content", "fit-content"))
+	propertySyntaxes["max-width"] = maxSize
+	propertySyntaxes["max-height"] = maxSize
+	propertySyntaxes["flex-basis"] = oneOf(lengthValue(true, false), keywordValue("auto", "content", "min-content", "max-content", "fit-content"))
+
+	padding := lengthValue(true, false)
+	propertySyntaxes["padding"] = repeat(1, 4, padding)
+	margin := either(lengthValue(true, true), keywordValue("auto"))
+	propertySyntaxes["margin"] = repeat(1, 4, margin)
+	for _, side := range []string{"top", "right", "bottom", "left"} {
+		propertySyntaxes["padding-"+side] = oneOf(padding)
+		propertySyntaxes["margin-"+side] = oneOf(margin)
+		propertySyntaxes[side] = oneOf(margin)
+		propertySyntaxes["border-"+side+"-width"] = oneOf(borderWidth)
+		propertySyntaxes["border-"+side+"-style"] = oneOf(borderStyle)
+	}
+	propertySyntaxes["border-width"] = repeat(1, 4, borderWidth)
+	propertySyntaxes["border-style"] = repeat(1, 4, borderStyle)
+	propertySyntaxes["outline-width"] = oneOf(borderWidth)
+	propertySyntaxes["outline-style"] = oneOf(borderStyle, keywordValue("auto"))
+	propertySyntaxes["border-radius"] = radiusValue
+
+	spacing := oneOf(lengthValue(false, true), keywordValue("normal"))
+	propertySyntaxes["letter-spacing"] = spacing
+	propertySyntaxes["word-spacing"] = spacing
+	propertySyntaxes["text-indent"] = oneOf(lengthValue(true, true))
+	gap := either(lengthValue(true, false), keywordValue("normal"))
+	propertySyntaxes["row-gap"] = oneOf(gap)
+	propertySyntaxes["column-gap"] = oneOf(gap)
+	propertySyntaxes["gap"] = repeat(1, 2, gap)
+	propertySyntaxes["font-size"] = oneOf(lengthValue(true, false), keywordValue(
+		"xx-small", "x-small", "small", "medium", "large", "x-large", "xx-large", "xxx-large",
+		"larger", "smaller", "math"))
+	propertySyntaxes["line-height"] = oneOf(numberValue(false), lengthValue(true, false), keywordValue("normal"))
+	propertySyntaxes["vertical-align"] = oneOf(lengthValue(true, true), keywordValue(
+		"baseline", "sub", "super", "text-top", "text-bottom", "middle", "top", "bottom"))
+
+	propertySyntaxes["z-index"] = oneOf(integerValue(true), keywordValue("auto"))
+	propertySyntaxes["order"] = oneOf(integerValue(true))
+	propertySyntaxes["column-count"] = oneOf(integerValue(false), keywordValue("auto"))
+	propertySyntaxes["orphans"] = oneOf(integerValue(false))
+	propertySyntaxes["widows"] = oneOf(integerValue(false))
+	propertySyntaxes["opacity"] = oneOf(numberValue(false), percentageValue)
+	propertySyntaxes["flex-grow"] = oneOf(numberValue(false))
+	propertySyntaxes["flex-shrink"] = oneOf(numberValue(false))
+	propertySyntaxes["font-weight"] = oneOf(fontWeight, keywordValue("normal", "bold", "bolder", "lighter"))
+
+	for p, words := range map[string][]string{
+		"position":              {"static", "relative", "absolute", "fixed", "sticky"},
+		"float":                 {"left", "right", "none", "inline-start", "inline-end"},
+		"clear":                 {"left", "right", "both", "none", "inline-start", "inline-end"},
+		"visibility":            {"visible", "hidden", "collapse"},
+		"overflow-x":            overflowKeywords,
+		"overflow-y":            overflowKeywords,
+		"text-align":            {"left", "right", "center", "justify", "start", "end", "match-parent", "justify-all"},
+		"box-sizing":            {"content-box", "border-box"},
+		"white-space":           {"normal", "nowrap", "pre", "pre-wrap", "pre-line", "break-spaces"},
+		"font-style":            {"normal", "italic", "oblique"},
+		"text-transform":        {"none", "capitalize", "uppercase", "lowercase", "full-width", "full-size-kana"},
+		"flex-direction":        {"row", "row-reverse", "column", "column-reverse"},
+		"flex-wrap":             {"nowrap", "wrap", "wrap-reverse"},
+		"pointer-events":        {"auto", "none", "visiblepainted", "visiblefill", "visiblestroke", "visible", "painted", "fill", "stroke", "all", "bounding-box"},
+		"table-layout":          {"auto", "fixed"},
+		"border-collapse":       {"collapse", "separate"},
+		"resize":                {"none", "both", "horizontal", "vertical", "block", "inline"},
+		"text-overflow":         {"clip", "ellipsis"},
+		"word-break":            {"normal", "break-all", "keep-all", "break-word", "auto-phrase"},
+		"overflow-wrap":         {"normal", "break-word", "anywhere"},
+		"object-fit":            {"fill", "contain", "cover", "none", "scale-down"},
+		"direction":             {"ltr", "rtl"},
+		"list-style-position":   {"inside", "outside"},
+		"background-attachment": {"scroll", "fixed", "local"},
+	} {
+		propertySyntaxes[p] = oneOf(keywordValue(words...))
+	}
+	propertySyntaxes["overflow"] = repeat(1, 2, keywordValue(overflowKeywords...))
+	propertySyntaxes["display"] = repeat(1, 3, keywordValue(
+		"block", "inline", "run-in", "flow", "flow-root", "table", "flex", "grid", "ruby", "math",
+		"list-item", "contents", "none", "inline-block", "inline-table", "inline-flex", "inline-grid",
+		"table-row-group", "table-header-group", "table-footer-group", "table-row", "table-cell",
+		"table-column-group", "table-column", "table-caption", "ruby-base", "ruby-text",
+		"ruby-base-container", "ruby-text-container"))
+	alignment := []string{
+		"normal", "stretch", "center", "start", "end", "flex-start", "flex-end", "self-start", "self-end",
+		"left", "right", "baseline", "first", "last", "space-between", "space-around", "space-evenly",
+		"safe", "unsafe", "auto",
+	}
+	for _, p := range []string{"justify-content", "align-items", "align-self", "align-content", "justify-items", "justify-self"} {
+		propertySyntaxes[p] = repeat(1, 2, keywordValue(alignment...))
+	}
+}
+
+var overflowKeywords = []string{"visible", "hidden", "clip", "scroll", "auto"}
+
+// component validates one space separated component of a value.
+type component func(string) error
+
+// oneOf returns a validator accepting a single component matching any of
+// cs, reporting the error of the first one.
+func oneOf(cs ...component) ValueValidator {
+	return func(value string) error {
+		if parts := splitComponents(value); len(parts) != 1 {
+			return fmt.Errorf("expected one component, got %d", len(parts))
+		}
+		return anyOf(cs, value)
+	}
+}
+
+// repeat returns a validator accepting between min and max components each
+// matching c.
+func repeat(min, max int, c component) ValueValidator {
+	return func(value string) error {
+		parts := splitComponents(value)
+		if len(parts) < min || len(parts) > max {
+			return fmt.Errorf("expected %d to %d components, got %d", min, max, len(parts))
+		}
+		for _, p := range parts {
+			if err := c(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// either returns a component matching any of cs.
+func either(cs ...component) component {
+	return func(s string) error {
+		return anyOf(cs, s)
+	}
+}
+
+func anyOf(cs []component, s string) error {
+	var first error
+	for _, c := range cs {
+		err := c(s)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// splitComponents splits value on the whitespace outside of parentheses,
+// brackets and strings that is not escaped.
+func splitComponents(value string) []string {
+	var (
+		parts []string
+		depth int
+		quote byte
+		start = -1
+	)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '\\':
+			i++
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && (c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'):
+			if start >= 0 {
+				parts = append(parts, value[start:i])
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		parts = append(parts, value[start:])
+	}
+	return parts
+}
+
+func keywordValue(words ...string) component {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return func(s string) error {
+		if !set[strings.ToLower(s)] {
+			return fmt.Errorf("unknown keyword %q", s)
+		}
+		return nil
+	}
+}
+
+// colorFunctions are the functions producing a color.
+var colorFunctions = map[string]bool{
+	"rgb": true, "rgba": true, "hsl": true, "hsla": true, "hwb": true, "lab": true, "lch": true,
+	"oklab": true, "oklch": true, "color": true, "color-mix": true, "light-dark": true,
+}
+
+func colorValue(s string) error {
+	lower := strings.ToLower(s)
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		switch len(hex) {
+		case 3, 4, 6, 8:
+		default:
+			return fmt.Errorf("invalid hex color %q", s)
+		}
+		for _, c := range hex {
+			if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+				return fmt.Errorf("invalid hex color %q", s)
+			}
+		}
+		return nil
+	}
+	if name, ok := functionName(lower); ok {
+		if !colorFunctions[name] {
+			return fmt.Errorf("unknown color function %s()", name)
+		}
+		return nil
+	}
+	if namedColors[lower] != "" || lower == "transparent" || lower == "currentcolor" {
+		return nil
+	}
+	return fmt.Errorf("unknown color %q", s)
+}
+
+// mathFunctions are the functions that may stand for a number or dimension.
+var mathFunctions = map[string]bool{
+	"calc": true, "min": true, "max": true, "clamp": true, "round": true, "mod": true, "rem": true,
+	"abs": true, "sign": true, "sin": true, "cos": true, "tan": true, "asin": true, "acos": true,
+	"atan": true, "atan2": true, "pow": true, "sqrt": true, "hypot": true, "log": true, "exp": true,
+}
+
+// functionName returns the name of the function s calls, if it is a
+// complete function call.
+func functionName(s string) (string, bool) {
+	i := strings.IndexByte(s, '(')
+	if i <= 0 || !strings.HasSuffix(s, ")") {
+		return "", false
+	}
+	return s[:i], true
+}
+
+var lengthUnits = map[string]bool{
+	"px": true, "em": true, "rem": true, "ex": true, "rex": true, "ch": true, "rch": true, "cap": true,
+	"rcap": true, "ic": true, "ric": true, "lh": true, "rlh": true, "vw": true, "vh": true, "vi": true,
+	"vb": true, "vmin": true, "vmax": true, "svw": true, "svh": true, "lvw": true, "lvh": true,
+	"dvw": true, "dvh": true, "svi": true, "svb": true, "lvi": true, "lvb": true, "dvi": true,
+	"dvb": true, "svmin": true, "svmax": true, "lvmin": true, "lvmax": true, "dvmin": true,
+	"dvmax": true, "cqw": true, "cqh": true, "cqi": true, "cqb": true, "cqmin": true, "cqmax": true,
+	"cm": true, "mm": true, "q": true, "in": true, "pt": true, "pc": true,
+}
+
+// splitNumber splits a numeric token into its number and unit.
+func splitNumber(s string) (float64, string, bool) {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0, "", false
+	}
+	if i+1 < len(s) && (s[i] == 'e' || s[i] == 'E') && (s[i+1] >= '0' && s[i+1] <= '9' || s[i+1] == '-' || s[i+1] == '+') {
+		j := i + 1
+		if s[j] == '-' || s[j] == '+' {
+			j++
+		}
+		if j < len(s) && s[j] >= '0' && s[j] <= '9' {
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+			i = j
+		}
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return n, strings.ToLower(s[i:]), true
+}
+
+// lengthValue accepts lengths, and percentages if percent is set. Negative
+// values are rejected unless negative is set.
+func lengthValue(percent, negative bool) component {
+	return func(s string) error {
+		if name, ok := functionName(strings.ToLower(s)); ok && mathFunctions[name] {
+			return nil
+		}
+		n, unit, ok := splitNumber(s)
+		switch {
+		case !ok:
+			return fmt.Errorf("expected a length, got %q", s)
+		case n < 0 && !negative:
+			return fmt.Errorf("negative length %q", s)
+		case unit == "" && n == 0:
+		case unit == "%" && percent:
+		case unit == "":
+			return fmt.Errorf("length %q is missing a unit", s)
+		case !lengthUnits[unit]:
+			return fmt.Errorf("unknown unit %q in %q", unit, s)
+		}
+		return nil
+	}
+}
+
+func percentageValue(s string) error {
+	if n, unit, ok := splitNumber(s); !ok || unit != "%" || n < 0 {
+		return fmt.Errorf("expected a percentage, got %q", s)
+	}
+	return nil
+}
+
+func numberValue(negative bool) component {
+	return func(s string) error {
+		if name, ok := functionName(strings.ToLower(s)); ok && mathFunctions[name] {
+			return nil
+		}
+		n, unit, ok := splitNumber(s)
+		switch {
+		case !ok || unit != "":
+			return fmt.Errorf("expected a number, got %q", s)
+		case n < 0 && !negative:
+			return fmt.Errorf("negative number %q", s)
+		}
+		return nil
+	}
+}
+
+func integerValue(negative bool) component {
+	return func(s string) error {
+		if name, ok := functionName(strings.ToLower(s)); ok && mathFunctions[name] {
+			return nil
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(s, "+"))
+		switch {
+		case err != nil:
+			return fmt.Errorf("expected an integer, got %q", s)
+		case n < 1 && !negative:
+			return fmt.Errorf("expected a positive integer, got %q", s)
+		}
+		return nil
+	}
+}
+
+func fontWeight(s string) error {
+	if err := numberValue(false)(s); err != nil {
+		return err
+	}
+	if n, _, ok := splitNumber(s); ok && (n < 1 || n > 1000) {
+		return fmt.Errorf("font weight %q out of range 1 to 1000", s)
+	}
+	return nil
+}
+
+var borderWidth = either(lengthValue(false, false), keywordValue("thin", "medium", "thick"))
+
+var borderStyle = keywordValue("none", "hidden", "dotted", "dashed", "solid", "double", "groove", "ridge", "inset", "outset")
+
+// radiusValue accepts one to four radii, optionally followed by a slash
+// and one to four vertical radii.
+func radiusValue(value string) error {
+	radii := repeat(1, 4, lengthValue(true, false))
+	parts := strings.Split(value, "/")
+	if len(parts) > 2 {
+		return fmt.Errorf("more than one / in %q", value)
+	}
+	for _, p := range parts {
+		if err := radii(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
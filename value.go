@@ -0,0 +1,233 @@
+package css
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Value is a number, percentage or dimension, such as a component of a
+// declaration value like "1.5rem".
+type Value struct {
+	Number float64
+	// Unit is the lowercased unit, "%" for a percentage or empty for a
+	// plain number.
+	Unit string
+}
+
+// ParseValue parses a number, percentage or dimension such as "12px".
+func ParseValue(s string) (Value, error) {
+	s = strings.TrimSpace(s)
+	n, unit, ok := splitNumber(s)
+	if !ok {
+		return Value{}, fmt.Errorf("%q is not a number", s)
+	}
+	if unit != "%" && skipName(unit, 0) != len(unit) {
+		return Value{}, fmt.Errorf("invalid unit in %q", s)
+	}
+	return Value{n, unit}, nil
+}
+
+// Fields splits a declaration value into its components, such as "center"
+// and "top" for "center top", at the whitespace outside of parentheses,
+// brackets and strings, so that "translate(10px, 20px) scale(2)" has two.
+// Commas separating components are kept with the component before them.
+func Fields(value string) []string {
+	return splitComponents(value)
+}
+
+// Function is a function call in a value. Args holds its arguments, split
+// at the commas outside of nested parentheses and strings and trimmed, so
+// that attr(data-size px, 10px) has the attribute name and type as its first
+// argument and the fallback as its second. Functions whose last argument
+// is a fallback that may hold commas itself, such as var() and attr(),
+// have its parts in the remaining arguments.
+type Function struct {
+	Name string
+	Args []string
+}
+
+// ParseFunction parses s, a single component of a value as returned by
+// Fields, as a function call, with the name lowercased.
+func ParseFunction(s string) (Function, bool) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexByte(s, '(')
+	if i <= 0 || skipName(s, 0) != i {
+		return Function{}, false
+	}
+	args, end := parenthesized(s, i)
+	if end != len(s) || !strings.HasSuffix(s, ")") {
+		return Function{}, false
+	}
+	f := Function{Name: asciiLower(s[:i])}
+	if strings.TrimSpace(args) == "" {
+		return f, true
+	}
+	for _, a := range splitSelectorList(args) {
+		f.Args = append(f.Args, strings.TrimSpace(a))
+	}
+	return f, true
+}
+
+func (f Function) String() string {
+	return f.Name + "(" + strings.Join(f.Args, ", ") + ")"
+}
+
+// String returns v as CSS, with the number rounded to six decimals and
+// without trailing zeros, so that "0.1px" plus "0.2px" gives "0.3px".
+func (v Value) String() string {
+	n := math.Round(v.Number*1e6) / 1e6
+	if n == 0 {
+		n = 0 // not -0
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64) + v.Unit
+}
+
+// Add returns v plus w, in the unit of v. The units must be the same, or
+// absolute ones of the same kind, such as px and in; otherwise the error is
+// the *ConvertError of converting w to the unit of v.
+func (v Value) Add(w Value) (Value, error) {
+	w, err := w.Convert(v.Unit, ConvertContext{})
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{v.Number + w.Number, v.Unit}, nil
+}
+
+// Mul returns v scaled by n, as in a spacing scale of "base * 1.5".
+func (v Value) Mul(n float64) Value {
+	return Value{v.Number * n, v.Unit}
+}
+
+// Compare returns -1, 0 or +1 as v is less than, equal to or greater than
+// w, which must have a unit v can be added to.
+func (v Value) Compare(w Value) (int, error) {
+	w, err := w.Convert(v.Unit, ConvertContext{})
+	switch {
+	case err != nil:
+		return 0, err
+	case v.Number < w.Number:
+		return -1, nil
+	case v.Number > w.Number:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// ConvertContext gives the sizes em, rem and the viewport units are relative
+// to, in px. A zero field is unknown.
+type ConvertContext struct {
+	RootFontSize   float64 // rem
+	FontSize       float64 // em, of the element or, for font-size, its parent
+	ViewportWidth  float64 // vw, vmin, vmax
+	ViewportHeight float64 // vh, vmin, vmax
+}
+
+// ErrMissingContext matches every *ConvertError caused by a size missing
+// from the ConvertContext with errors.Is.
+var ErrMissingContext = errors.New("missing conversion context")
+
+// ConvertError reports that a value cannot be converted to a unit.
+type ConvertError struct {
+	From, To string
+	// Missing names the ConvertContext field the conversion needs, such as
+	// "FontSize", if that is why it failed; otherwise the units are not
+	// compatible.
+	Missing string
+}
+
+func (e *ConvertError) Error() string {
+	from, to := unitName(e.From), unitName(e.To)
+	if e.Missing != "" {
+		return fmt.Sprintf("converting %s to %s needs ConvertContext.%s", from, to, e.Missing)
+	}
+	return fmt.Sprintf("cannot convert %s to %s", from, to)
+}
+
+func unitName(unit string) string {
+	if unit == "" {
+		return "number"
+	}
+	return unit
+}
+
+func (e *ConvertError) Is(target error) bool {
+	return target == ErrMissingContext && e.Missing != ""
+}
+
+type unitScale struct {
+	kind string
+	size float64
+}
+
+// unitScales maps the absolute units Convert knows to their kind and their
+// size in the canonical unit of the kind: px, deg, ms or Hz.
+var unitScales = map[string]unitScale{
+	"px": {"length", 1}, "in": {"length", 96}, "cm": {"length", 96 / 2.54}, "mm": {"length", 96 / 25.4},
+	"q": {"length", 96 / 101.6}, "pt": {"length", 96.0 / 72}, "pc": {"length", 16},
+	"deg": {"angle", 1}, "rad": {"angle", 180 / math.Pi}, "grad": {"angle", 0.9}, "turn": {"angle", 360},
+	"ms": {"time", 1}, "s": {"time", 1000},
+	"hz": {"frequency", 1}, "khz": {"frequency", 1000},
+}
+
+// Convert returns v in the unit to. Absolute lengths, angles, times and
+// frequencies convert within their kind; em, rem, vw, vh, vmin and vmax
+// convert to and from lengths given the sizes of ctx they depend on. A
+// conversion needing a size ctx lacks fails with a *ConvertError matching
+// ErrMissingContext.
+func (v Value) Convert(to string, ctx ConvertContext) (Value, error) {
+	to = asciiLower(to)
+	if v.Unit == to {
+		return v, nil
+	}
+	from, fromOK, fromMissing := scaleOf(v.Unit, ctx)
+	into, intoOK, intoMissing := scaleOf(to, ctx)
+	if !fromOK || !intoOK || from.kind != into.kind {
+		return Value{}, &ConvertError{From: v.Unit, To: to}
+	}
+	if missing := fromMissing + intoMissing; missing != "" {
+		if fromMissing != "" {
+			missing = fromMissing
+		}
+		return Value{}, &ConvertError{From: v.Unit, To: to, Missing: missing}
+	}
+	return Value{v.Number * from.size / into.size, to}, nil
+}
+
+// scaleOf returns the scale of unit and whether Convert knows it. For a
+// relative length, missing names the field of ctx it needs if that is zero.
+func scaleOf(unit string, ctx ConvertContext) (scale unitScale, ok bool, missing string) {
+	if s, ok := unitScales[unit]; ok {
+		return s, true, ""
+	}
+	var size float64
+	switch unit {
+	case "em":
+		size, missing = ctx.FontSize, "FontSize"
+	case "rem":
+		size, missing = ctx.RootFontSize, "RootFontSize"
+	case "vw":
+		size, missing = ctx.ViewportWidth/100, "ViewportWidth"
+	case "vh":
+		size, missing = ctx.ViewportHeight/100, "ViewportHeight"
+	case "vmin", "vmax":
+		switch {
+		case ctx.ViewportWidth == 0:
+			missing = "ViewportWidth"
+		case ctx.ViewportHeight == 0:
+			missing = "ViewportHeight"
+		case unit == "vmin":
+			size = math.Min(ctx.ViewportWidth, ctx.ViewportHeight) / 100
+		default:
+			size = math.Max(ctx.ViewportWidth, ctx.ViewportHeight) / 100
+		}
+	default:
+		return unitScale{}, false, ""
+	}
+	if size != 0 {
+		missing = ""
+	}
+	return unitScale{"length", size}, true, missing
+}
@@ -0,0 +1,94 @@
+package css
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithValueFunction makes the parser replace each call of the function
+// name, compared ASCII case-insensitively, in the values of declarations
+// with the text fn returns for its arguments, such as `theme(colors.primary)`
+// or `asset-url("logo.svg")` defined by a build step. The arguments are
+// split at top-level commas and trimmed, with quotes kept as written. Calls
+// in the arguments, whether of name or another function given
+// WithValueFunction, are replaced first, so that nested calls and calls in
+// the fallbacks of var() or in calc() resolve inside out; the returned text
+// is not scanned again. An error from fn stops the parse with a
+// *ParseError at the declaration that unwraps to it. A later
+// WithValueFunction for the same name replaces an earlier one.
+func WithValueFunction(name string, fn func(args []string) (string, error)) Option {
+	return func(o *options) {
+		if o.valueFuncs == nil {
+			o.valueFuncs = make(map[string]func([]string) (string, error))
+		}
+		o.valueFuncs[asciiLower(name)] = fn
+	}
+}
+
+// callValueFunctions replaces the calls of the functions given
+// WithValueFunction in the values of sheet.
+func callValueFunctions(sheet *StyleSheet, o options) error {
+	var err error
+	Walk(sheet, func(n Node) bool {
+		d, ok := n.(*Declaration)
+		if !ok || err != nil {
+			return err == nil
+		}
+		v, ferr := callFunctions(d.Value, o.valueFuncs)
+		if ferr != nil {
+			err = &ParseError{Pos: d.Pos, Msg: ferr.Error(), Err: ferr}
+			return false
+		}
+		d.Value = v
+		return true
+	})
+	return err
+}
+
+// callFunctions returns s with the calls of the functions of fns replaced
+// by their text, those in their arguments first.
+func callFunctions(s string, fns map[string]func([]string) (string, error)) (string, error) {
+	if !strings.Contains(s, "(") {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		end := i + 1
+		switch {
+		case c == '"' || c == '\'':
+			end = skipString(s, i)
+		case c == '\\' || isNameRune(rune(c)):
+			end = skipName(s, i)
+			if end == len(s) || s[end] != '(' {
+				break
+			}
+			name := asciiLower(s[i:end])
+			fn, ok := fns[name]
+			if !ok {
+				break
+			}
+			args, next := parenthesized(s, end)
+			args, err := callFunctions(args, fns)
+			if err != nil {
+				return "", err
+			}
+			var list []string
+			if strings.TrimSpace(args) != "" {
+				for _, a := range splitSelectorList(args) {
+					list = append(list, strings.TrimSpace(a))
+				}
+			}
+			text, err := fn(list)
+			if err != nil {
+				return "", fmt.Errorf("%s(%s): %w", name, args, err)
+			}
+			b.WriteString(text)
+			i = next
+			continue
+		}
+		b.WriteString(s[i:end])
+		i = end
+	}
+	return b.String(), nil
+}
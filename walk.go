@@ -0,0 +1,101 @@
+package css
+
+// Walk calls fn for each node of sheet in source order, depth first. The
+// selectors and declarations of a rule are visited as *Rule and
+// *Declaration nodes after the rule itself, followed by its nested rules;
+// an at-rule is followed by its declarations and then its rules. The
+// pointers refer to the stylesheet, so fn may change the nodes in place.
+// If fn returns false, the children of the node are skipped.
+func Walk(sheet *StyleSheet, fn func(node Node) bool) {
+	walkNodes(sheet.Rules, fn)
+}
+
+func walkNodes(nodes []Node, fn func(Node) bool) {
+	for _, n := range nodes {
+		if !fn(n) {
+			continue
+		}
+		switch n := n.(type) {
+		case *RuleNode:
+			for i := range n.Selectors {
+				fn(&n.Selectors[i])
+			}
+			walkDeclarations(n.Declarations, fn)
+			walkNodes(n.Rules, fn)
+		case *AtRule:
+			walkDeclarations(n.Declarations, fn)
+			walkNodes(n.Rules, fn)
+		}
+	}
+}
+
+func walkDeclarations(decls []Declaration, fn func(Node) bool) {
+	for i := range decls {
+		fn(&decls[i])
+	}
+}
+
+// Transform visits the nodes of sheet in the order of Walk and replaces
+// each one with the nodes fn returns for it: returning nil deletes the
+// node, returning it alone keeps it, and returning more nodes inserts them
+// in its place, in order. The children of the returned nodes are
+// transformed next, but the returned nodes are not passed to fn again, so
+// inserted siblings are never visited themselves.
+//
+// Rules and at-rules may replace one another, but a *Rule selector can
+// only be replaced with selectors and a *Declaration with declarations;
+// returned nodes of another kind are dropped. A rule left without
+// selectors is kept, and it is up to fn to delete it.
+func Transform(sheet *StyleSheet, fn func(node Node) []Node) {
+	sheet.Rules = transformNodes(sheet.Rules, fn)
+}
+
+func transformNodes(nodes []Node, fn func(Node) []Node) []Node {
+	if nodes == nil {
+		return nil
+	}
+	out := make([]Node, 0, len(nodes))
+	for _, n := range nodes {
+		for _, r := range fn(n) {
+			switch r := r.(type) {
+			case *RuleNode:
+				r.Selectors = transformSelectors(r.Selectors, fn)
+				r.Declarations = transformDeclarations(r.Declarations, fn)
+				r.Rules = transformNodes(r.Rules, fn)
+				out = append(out, r)
+			case *AtRule:
+				r.Declarations = transformDeclarations(r.Declarations, fn)
+				r.Rules = transformNodes(r.Rules, fn)
+				out = append(out, r)
+			}
+		}
+	}
+	return out
+}
+
+func transformSelectors(sels []Rule, fn func(Node) []Node) []Rule {
+	out := make([]Rule, 0, len(sels))
+	for i := range sels {
+		for _, r := range fn(&sels[i]) {
+			if r, ok := r.(*Rule); ok {
+				out = append(out, *r)
+			}
+		}
+	}
+	return out
+}
+
+func transformDeclarations(decls []Declaration, fn func(Node) []Node) []Declaration {
+	if decls == nil {
+		return nil
+	}
+	out := make([]Declaration, 0, len(decls))
+	for i := range decls {
+		for _, r := range fn(&decls[i]) {
+			if r, ok := r.(*Declaration); ok {
+				out = append(out, *r)
+			}
+		}
+	}
+	return out
+}
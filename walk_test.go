@@ -0,0 +1,81 @@
+package css
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// TestTransform checks that Transform deletes the current node, inserts
+// nodes before and after it, and goes on to the nodes that follow, never
+// passing the inserted nodes themselves to fn.
+func TestTransform(t *testing.T) {
+	sheet, err := Parse([]byte(".a { color: red; top: 0 } .b { top: 1px } .c { left: 0 }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var visited []string
+	Transform(sheet, func(n Node) []Node {
+		switch n := n.(type) {
+		case *RuleNode:
+			visited = append(visited, string(n.Selectors[0]))
+			switch n.Selectors[0] {
+			case ".a":
+				before := &RuleNode{Selectors: []Rule{".before"}, Declarations: []Declaration{{Property: "top", Value: "2px"}}}
+				after := &RuleNode{Selectors: []Rule{".after"}}
+				return []Node{before, n, after}
+			case ".b":
+				return nil
+			}
+		case *Rule:
+			visited = append(visited, "sel "+string(*n))
+		case *Declaration:
+			visited = append(visited, n.Property)
+			if n.Property == "top" {
+				return nil
+			}
+		}
+		return []Node{n}
+	})
+	want := []string{".a", "sel .before", "top", "sel .a", "color", "top", "sel .after", ".b", ".c", "sel .c", "left"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited %q, want %q", visited, want)
+	}
+	out, err := Marshal(sheet, Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := ".before{}.a{color:red}.after{}.c{left:0}"; string(out) != want {
+		t.Errorf("Transform gives %q, want %q", out, want)
+	}
+}
+
+// TestTransformNested checks deletions and insertions among nested rules
+// and at-rules.
+func TestTransformNested(t *testing.T) {
+	sheet, err := Parse([]byte("@media print { .a { top: 0 } .b { top: 0 } } .c { top: 0 }"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	Transform(sheet, func(node Node) []Node {
+		switch node := node.(type) {
+		case *RuleNode:
+			if node.Selectors[0] == ".a" {
+				return nil
+			}
+			n++
+			clone := *node
+			clone.Selectors = []Rule{Rule(fmt.Sprintf("%s-%d", node.Selectors[0], n))}
+			return []Node{node, &clone}
+		}
+		return []Node{node}
+	})
+	out, err := Marshal(sheet, Minify())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "@media print{.b{top:0}.b-1{top:0}}.c{top:0}.c-2{top:0}"; string(out) != want {
+		t.Errorf("Transform gives %q, want %q", out, want)
+	}
+}